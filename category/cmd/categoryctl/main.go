@@ -0,0 +1,277 @@
+// Command categoryctl imports and exports categories as CSV or JSON files, either through a running
+// category service or directly against the category database.
+//
+//	categoryctl import [-format csv|json] [-dry-run] [-all-or-nothing] [-dsn DSN | -token TOKEN] FILE
+//	categoryctl export [-format csv|json] [-out FILE] [-dsn DSN | -token TOKEN]
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/mysql"
+	"github.com/micro/go-micro/v2"
+	grpcclient "github.com/micro/go-micro/v2/client/grpc"
+	"github.com/micro/go-micro/v2/metadata"
+	"github.com/micro/go-micro/v2/registry"
+	"github.com/micro/go-plugins/registry/consul/v2"
+	"github.com/tongs-dev/shopping-platform/category/domain/repository"
+	categoryService "github.com/tongs-dev/shopping-platform/category/domain/service"
+	categorypb "github.com/tongs-dev/shopping-platform/category/proto/category"
+)
+
+// chunkSize is the size of the file chunks streamed to the service
+const chunkSize = 32 * 1024
+
+// target selects where the categories are imported to or exported from
+type target struct {
+	// dsn connects directly to the database when set, otherwise the service is called
+	dsn           string
+	defaultLocale string
+	registryAddr  string
+	serviceName   string
+	token         string
+}
+
+// registerTargetFlags adds the flags selecting the target to a subcommand
+func registerTargetFlags(flags *flag.FlagSet) *target {
+	t := &target{}
+	flags.StringVar(&t.dsn, "dsn", "", "MySQL DSN to use the database directly instead of the service")
+	flags.StringVar(&t.defaultLocale, "default-locale", "en", "default locale of the categories, with -dsn")
+	flags.StringVar(&t.registryAddr, "registry", "127.0.0.1:8500", "Consul address to find the service")
+	flags.StringVar(&t.serviceName, "service", "go.micro.service.category", "name of the category service")
+	flags.StringVar(&t.token, "token", os.Getenv("CATEGORY_TOKEN"), "access token for the service, defaults to $CATEGORY_TOKEN")
+	return t
+}
+
+// parseFormat maps the -format flag to a data format
+func parseFormat(format string) (categoryService.DataFormat, error) {
+	switch strings.ToLower(format) {
+	case "csv":
+		return categoryService.FormatCSV, nil
+	case "json":
+		return categoryService.FormatJSON, nil
+	default:
+		return 0, fmt.Errorf("unknown format %q, expected csv or json", format)
+	}
+}
+
+// newLocalService opens the database and returns the category service running in-process
+func (t *target) newLocalService() (categoryService.ICategoryService, *gorm.DB, error) {
+	db, err := gorm.Open("mysql", t.dsn)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Ensure singular table naming convention, as the service does
+	db.SingularTable(true)
+	defaultLocale, err := categoryService.NormalizeLocale(t.defaultLocale)
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	return categoryService.NewCategoryService(repository.NewCategoryRepository(db), defaultLocale), db, nil
+}
+
+// newClient returns a client of the running category service and a context carrying the access token
+func (t *target) newClient() (categorypb.CategoryService, context.Context) {
+	consulRegistry := consul.NewRegistry(func(options *registry.Options) {
+		options.Addrs = []string{t.registryAddr}
+	})
+	// The category service serves gRPC
+	service := micro.NewService(micro.Client(grpcclient.NewClient()), micro.Registry(consulRegistry))
+
+	ctx := context.Background()
+	if t.token != "" {
+		ctx = metadata.Set(ctx, "Authorization", "Bearer "+t.token)
+	}
+	return categorypb.NewCategoryService(t.serviceName, service.Client()), ctx
+}
+
+// runImport imports a file and prints its report, failing when any row was not imported
+func runImport(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	format := flags.String("format", "csv", "file format, csv or json")
+	dryRun := flags.Bool("dry-run", false, "validate every row without keeping any change")
+	allOrNothing := flags.Bool("all-or-nothing", false, "keep no change unless every row is imported")
+	t := registerTargetFlags(flags)
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		return fmt.Errorf("import needs exactly one file")
+	}
+
+	dataFormat, err := parseFormat(*format)
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	options := categoryService.ImportOptions{Format: dataFormat, DryRun: *dryRun, AllOrNothing: *allOrNothing}
+	var report *categoryService.ImportReport
+	if t.dsn != "" {
+		report, err = importLocal(t, file, options)
+	} else {
+		report, err = importRemote(t, file, options)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, rowError := range report.Errors {
+		fmt.Fprintf(os.Stderr, "row %d (%s): %s\n", rowError.Row, rowError.CategoryName, rowError.Message)
+	}
+	state := "committed"
+	if !report.Committed {
+		state = "not committed"
+	}
+	fmt.Printf("%d rows, %d imported, %d failed, %s\n", report.Rows, report.Imported, len(report.Errors), state)
+	if len(report.Errors) > 0 {
+		return fmt.Errorf("%d rows failed", len(report.Errors))
+	}
+	return nil
+}
+
+// importLocal imports a file directly into the database
+func importLocal(t *target, file io.Reader, options categoryService.ImportOptions) (*categoryService.ImportReport, error) {
+	service, db, err := t.newLocalService()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	return service.ImportCategories(file, options)
+}
+
+// importRemote streams a file to the service in chunks and waits for its report
+func importRemote(t *target, file io.Reader, options categoryService.ImportOptions) (*categoryService.ImportReport, error) {
+	client, ctx := t.newClient()
+	stream, err := client.ImportCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	// The options are read from the first chunk
+	request := &categorypb.ImportCategoriesRequest{
+		Format:       categorypb.DataFormat(options.Format),
+		DryRun:       options.DryRun,
+		AllOrNothing: options.AllOrNothing,
+	}
+	buffer := make([]byte, chunkSize)
+	for {
+		n, err := io.ReadFull(file, buffer)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			request.Last = true
+		} else if err != nil {
+			return nil, err
+		}
+		request.Data = buffer[:n]
+		if err := stream.Send(request); err != nil {
+			return nil, err
+		}
+		if request.Last {
+			break
+		}
+		request = &categorypb.ImportCategoriesRequest{}
+	}
+
+	response := &categorypb.ImportCategoriesResponse{}
+	if err := stream.RecvMsg(response); err != nil {
+		return nil, err
+	}
+	report := &categoryService.ImportReport{
+		Rows:      int(response.Rows),
+		Imported:  int(response.Imported),
+		Committed: response.Committed,
+	}
+	for _, rowError := range response.Errors {
+		report.Errors = append(report.Errors, categoryService.ImportRowError{
+			Row:          int(rowError.Row),
+			CategoryName: rowError.CategoryName,
+			Message:      rowError.Message,
+		})
+	}
+	return report, nil
+}
+
+// runExport writes all categories to a file or stdout
+func runExport(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	format := flags.String("format", "csv", "file format, csv or json")
+	out := flags.String("out", "", "file to write, defaults to stdout")
+	t := registerTargetFlags(flags)
+	flags.Parse(args)
+
+	dataFormat, err := parseFormat(*format)
+	if err != nil {
+		return err
+	}
+
+	var writer io.Writer = os.Stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		writer = file
+	}
+
+	if t.dsn != "" {
+		service, db, err := t.newLocalService()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		return service.ExportCategories(writer, dataFormat)
+	}
+
+	client, ctx := t.newClient()
+	stream, err := client.ExportCategories(ctx, &categorypb.ExportCategoriesRequest{Format: categorypb.DataFormat(dataFormat)})
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+	for {
+		response, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := writer.Write(response.Data); err != nil {
+			return err
+		}
+	}
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "usage: categoryctl import|export [flags]")
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "import":
+		err = runImport(os.Args[2:])
+	case "export":
+		err = runExport(os.Args[2:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q, expected import or export\n", os.Args[1])
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,15 @@
+package cache
+
+import "time"
+
+// Cache stores encoded lookup results by key until they expire or the cache is cleared.
+type Cache interface {
+	// Get returns the value of a key, ok is false for missing and expired keys.
+	Get(key string) (value []byte, ok bool, err error)
+
+	// Set stores the value of a key for ttl.
+	Set(key string, value []byte, ttl time.Duration) error
+
+	// Clear removes all values.
+	Clear() error
+}
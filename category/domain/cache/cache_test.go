@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// TestLRUCache tests expiry, eviction and clearing of the LRUCache.
+func TestLRUCache(t *testing.T) {
+	t.Run("GetAndSet", func(t *testing.T) {
+		c := NewLRUCache(2)
+		assert.NoError(t, c.Set("a", []byte("1"), time.Minute))
+
+		value, ok, err := c.Get("a")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, "1", string(value))
+
+		_, ok, err = c.Get("b")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("Expiry", func(t *testing.T) {
+		c := NewLRUCache(2)
+		assert.NoError(t, c.Set("a", []byte("1"), time.Millisecond))
+		time.Sleep(5 * time.Millisecond)
+
+		_, ok, err := c.Get("a")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		assert.Equal(t, 0, c.Len())
+	})
+
+	t.Run("EvictsLeastRecentlyUsed", func(t *testing.T) {
+		c := NewLRUCache(2)
+		assert.NoError(t, c.Set("a", []byte("1"), time.Minute))
+		assert.NoError(t, c.Set("b", []byte("2"), time.Minute))
+		// Using a makes b the least recently used value
+		_, _, _ = c.Get("a")
+		assert.NoError(t, c.Set("c", []byte("3"), time.Minute))
+
+		_, ok, _ := c.Get("b")
+		assert.False(t, ok)
+		_, ok, _ = c.Get("a")
+		assert.True(t, ok)
+		_, ok, _ = c.Get("c")
+		assert.True(t, ok)
+		assert.Equal(t, 2, c.Len())
+	})
+
+	t.Run("Clear", func(t *testing.T) {
+		c := NewLRUCache(2)
+		assert.NoError(t, c.Set("a", []byte("1"), time.Minute))
+		assert.NoError(t, c.Clear())
+
+		_, ok, _ := c.Get("a")
+		assert.False(t, ok)
+		assert.Equal(t, 0, c.Len())
+	})
+}
+
+// TestRedisCache tests the RedisCache against an in-memory Redis server.
+func TestRedisCache(t *testing.T) {
+	server := miniredis.RunT(t)
+
+	t.Run("GetAndSet", func(t *testing.T) {
+		c := NewRedisCache(RedisOptions{Addr: server.Addr(), Prefix: "get"})
+		defer c.Close()
+		assert.NoError(t, c.Set("id:1", []byte(`{"id":1}`), time.Minute))
+
+		value, ok, err := c.Get("id:1")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, `{"id":1}`, string(value))
+
+		_, ok, err = c.Get("id:2")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("ClearIsShared", func(t *testing.T) {
+		first := NewRedisCache(RedisOptions{Addr: server.Addr(), Prefix: "clear", GenerationRefresh: time.Millisecond})
+		second := NewRedisCache(RedisOptions{Addr: server.Addr(), Prefix: "clear", GenerationRefresh: time.Millisecond})
+		defer first.Close()
+		defer second.Close()
+		assert.NoError(t, first.Set("id:1", []byte("1"), time.Minute))
+
+		_, ok, err := second.Get("id:1")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+
+		assert.NoError(t, first.Clear())
+		time.Sleep(5 * time.Millisecond)
+		_, ok, err = second.Get("id:1")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("ServerDown", func(t *testing.T) {
+		c := NewRedisCache(RedisOptions{Addr: "127.0.0.1:1", Timeout: 100 * time.Millisecond})
+		_, ok, err := c.Get("id:1")
+		assert.Error(t, err)
+		assert.False(t, ok)
+	})
+}
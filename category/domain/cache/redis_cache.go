@@ -0,0 +1,143 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisOptions configure the connection of a RedisCache.
+type RedisOptions struct {
+	Addr     string
+	Password string
+	DB       int
+	// Prefix namespaces the keys, so several services can share a server
+	Prefix string
+	// PoolSize is the maximum number of connections to the server
+	PoolSize int
+	Timeout  time.Duration
+	// GenerationRefresh is how often the generation is read again, see RedisCache
+	GenerationRefresh time.Duration
+}
+
+// RedisCache is a Cache on a Redis compatible server, shared by all instances of the service. Clearing
+// increments a generation counter on the server that is part of every key, so the values of older
+// generations are never read again and expire on their own. Instances pick up a generation incremented by
+// another instance within GenerationRefresh.
+type RedisCache struct {
+	options RedisOptions
+	client  *redis.Client
+
+	mu           sync.Mutex
+	generation   int64
+	generationAt time.Time
+}
+
+// NewRedisCache creates a RedisCache. Connections are opened when they are first needed.
+func NewRedisCache(options RedisOptions) *RedisCache {
+	if options.Prefix == "" {
+		options.Prefix = "category"
+	}
+	if options.PoolSize < 1 {
+		options.PoolSize = 10
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = time.Second
+	}
+	if options.GenerationRefresh <= 0 {
+		options.GenerationRefresh = time.Second
+	}
+	client := redis.NewClient(&redis.Options{
+		Addr:         options.Addr,
+		Password:     options.Password,
+		DB:           options.DB,
+		PoolSize:     options.PoolSize,
+		DialTimeout:  options.Timeout,
+		ReadTimeout:  options.Timeout,
+		WriteTimeout: options.Timeout,
+		// A failing cache falls back to the database, so commands are not retried
+		MaxRetries: -1,
+	})
+	return &RedisCache{options: options, client: client}
+}
+
+// Get returns the value of a key in the current generation.
+func (c *RedisCache) Get(key string) ([]byte, bool, error) {
+	fullKey, err := c.key(key)
+	if err != nil {
+		return nil, false, err
+	}
+	value, err := c.client.Get(context.Background(), fullKey).Bytes()
+	if err == redis.Nil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+// Set stores the value of a key in the current generation for ttl.
+func (c *RedisCache) Set(key string, value []byte, ttl time.Duration) error {
+	fullKey, err := c.key(key)
+	if err != nil {
+		return err
+	}
+	if ttl < time.Millisecond {
+		ttl = time.Millisecond
+	}
+	return c.client.Set(context.Background(), fullKey, value, ttl).Err()
+}
+
+// Clear starts a new generation, dropping the values of all instances.
+func (c *RedisCache) Clear() error {
+	generation, err := c.client.Incr(context.Background(), c.generationKey()).Result()
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation > c.generation {
+		c.generation = generation
+		c.generationAt = time.Now()
+	}
+	return nil
+}
+
+// Close closes the connections to the server.
+func (c *RedisCache) Close() error {
+	return c.client.Close()
+}
+
+// Helper function to build the server key of a key in the current generation
+func (c *RedisCache) key(key string) (string, error) {
+	c.mu.Lock()
+	stale := time.Since(c.generationAt) >= c.options.GenerationRefresh
+	c.mu.Unlock()
+
+	if stale {
+		generation, err := c.client.Get(context.Background(), c.generationKey()).Int64()
+		if err != nil && err != redis.Nil {
+			return "", err
+		}
+		c.mu.Lock()
+		// A concurrent Clear may already have moved past the generation read
+		if generation > c.generation {
+			c.generation = generation
+		}
+		c.generationAt = time.Now()
+		c.mu.Unlock()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.options.Prefix + ":" + strconv.FormatInt(c.generation, 10) + ":" + key, nil
+}
+
+// Helper function to name the generation counter
+func (c *RedisCache) generationKey() string {
+	return c.options.Prefix + ":generation"
+}
@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 type Category struct{
 	ID int64 `gorm:"primary_key;not_null;auto_increment" json:"id"`
 	CategoryName string `gorm:"unique_index,not_null" json:"category_name"`
@@ -7,5 +9,18 @@ type Category struct{
 	CategoryParent int64 `json:"category_parent"`
 	CategoryImage string `json:"category_image"`
 	CategoryDescription string `json:"category_description"`
+	// SortOrder is the 1-based position among the siblings under the same parent
+	SortOrder int32 `json:"sort_order"`
+	// Hidden categories and categories outside their publish window are only listed for admins
+	Hidden bool `json:"hidden"`
+	PublishAt *time.Time `json:"publish_at"`
+	UnpublishAt *time.Time `json:"unpublish_at"`
+	// Slug identifies the category in storefront URLs, unique across all categories
+	Slug string `gorm:"unique_index;not_null" json:"slug"`
+	SeoTitle string `json:"seo_title"`
+	SeoKeywords string `json:"seo_keywords"`
+	SeoDescription string `json:"seo_description"`
+	// Locale is the locale the name and description are in, set when a category is localized for a response
+	Locale string `gorm:"-" json:"locale"`
 }
 
@@ -0,0 +1,52 @@
+package model
+
+import "encoding/json"
+
+// AttributeType is the type of the values of a product attribute.
+type AttributeType string
+
+const (
+	AttributeString  AttributeType = "string"
+	AttributeNumber  AttributeType = "number"
+	AttributeEnum    AttributeType = "enum"
+	AttributeBoolean AttributeType = "boolean"
+)
+
+// CategoryAttribute defines a product attribute of the products in a category, e.g. the size of shoes.
+type CategoryAttribute struct {
+	ID         int64         `gorm:"primary_key;not_null;auto_increment" json:"id"`
+	CategoryID int64         `gorm:"unique_index:idx_category_attribute;not_null" json:"category_id"`
+	Name       string        `gorm:"unique_index:idx_category_attribute;not_null" json:"name"`
+	Type       AttributeType `gorm:"not_null" json:"type"`
+	// Unit of number attributes, e.g. "cm" or "in"
+	Unit string `json:"unit"`
+	// EnumValues are the allowed values of enum attributes, stored as a JSON array in EnumValuesData
+	EnumValues     []string `gorm:"-" json:"enum_values"`
+	EnumValuesData string   `gorm:"column:enum_values;type:text" json:"-"`
+	Required       bool     `json:"required"`
+	// Inheritable attributes also apply to the products of all subcategories
+	Inheritable bool `json:"inheritable"`
+}
+
+// BeforeSave stores the enum values in their column.
+func (a *CategoryAttribute) BeforeSave() error {
+	if len(a.EnumValues) == 0 {
+		a.EnumValuesData = ""
+		return nil
+	}
+	data, err := json.Marshal(a.EnumValues)
+	if err != nil {
+		return err
+	}
+	a.EnumValuesData = string(data)
+	return nil
+}
+
+// AfterFind reads the enum values from their column.
+func (a *CategoryAttribute) AfterFind() error {
+	a.EnumValues = nil
+	if a.EnumValuesData == "" {
+		return nil
+	}
+	return json.Unmarshal([]byte(a.EnumValuesData), &a.EnumValues)
+}
@@ -0,0 +1,10 @@
+package model
+
+// CategoryTranslation holds the name and description of a category in one locale other than the default.
+type CategoryTranslation struct {
+	ID                  int64  `gorm:"primary_key;not_null;auto_increment" json:"id"`
+	CategoryID          int64  `gorm:"unique_index:idx_category_locale;not_null" json:"category_id"`
+	Locale              string `gorm:"unique_index:idx_category_locale;not_null" json:"locale"`
+	CategoryName        string `json:"category_name"`
+	CategoryDescription string `json:"category_description"`
+}
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// OutboxEvent is a category change event waiting in the outbox until it is published to the broker. Events
+// are written in the transaction of the change, so a change is never committed without its event.
+type OutboxEvent struct {
+	ID         int64  `gorm:"primary_key;not_null;auto_increment" json:"id"`
+	EventType  string `gorm:"not_null" json:"event_type"`
+	CategoryID int64  `json:"category_id"`
+	// Payload is the JSON encoded event as it is published
+	Payload   string    `gorm:"type:text" json:"payload"`
+	CreatedAt time.Time `json:"created_at"`
+	// PublishedAt is nil until the event was published
+	PublishedAt *time.Time `gorm:"index" json:"published_at"`
+	// Attempts counts the failed publishing attempts
+	Attempts int32 `json:"attempts"`
+}
@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+// OutboxLease names the service instance that publishes the outbox. Only the holder publishes, so events are
+// not published once per instance; it renews the lease on every batch and another instance takes over once
+// ExpiresAt has passed.
+type OutboxLease struct {
+	Name      string    `gorm:"primary_key" json:"name"`
+	Holder    string    `gorm:"not_null" json:"holder"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -3,13 +3,34 @@ package repository
 import (
 	"github.com/jinzhu/gorm"
 	"github.com/tongs-dev/shopping-platform/category/domain/model"
+	"strings"
+	"time"
 )
 
+// CategoryFilter decides which categories the Find methods list.
+type CategoryFilter struct {
+	// IncludeHidden also lists hidden categories and categories outside their publish window at Now
+	IncludeHidden bool
+	Now           time.Time
+}
+
 // ICategoryRepository defines the interface for interacting with the Category repository.
 type ICategoryRepository interface {
 	// InitTable initializes the Category table in the database.
 	InitTable() error
 
+	// AddSlugColumn adds the slug column without its unique index to a Category table created before slugs.
+	AddSlugColumn() error
+
+	// MigrateTable adds the tables, columns and indexes missing from an existing database.
+	MigrateTable() error
+
+	// FindCategoriesWithoutSlug retrieves the Categories whose slug is still empty.
+	FindCategoriesWithoutSlug() ([]model.Category, error)
+
+	// UpdateCategorySlug sets the slug of a Category.
+	UpdateCategorySlug(categoryID int64, slug string) error
+
 	// FindCategoryByID retrieves a Category by its ID.
 	FindCategoryByID(int64) (*model.Category, error)
 
@@ -23,16 +44,82 @@ type ICategoryRepository interface {
 	UpdateCategory(*model.Category) error
 
 	// FindAll retrieves all Categories from the database.
-	FindAll() ([]model.Category, error)
+	FindAll(CategoryFilter) ([]model.Category, error)
 
 	// FindCategoryByName retrieves a Category by its name.
 	FindCategoryByName(string) (*model.Category, error)
 
+	// FindCategoryBySlug retrieves a Category by its slug.
+	FindCategoryBySlug(string) (*model.Category, error)
+
+	// FindCategoriesBySlugs retrieves the Categories having one of the given slugs.
+	FindCategoriesBySlugs([]string, CategoryFilter) ([]model.Category, error)
+
+	// FindSlugsWithPrefix retrieves the slugs equal to a base slug or extending it with "-<suffix>".
+	FindSlugsWithPrefix(string) ([]string, error)
+
 	// FindCategoryByLevel retrieves Categories by their level.
-	FindCategoryByLevel(uint32) ([]model.Category, error)
+	FindCategoryByLevel(uint32, CategoryFilter) ([]model.Category, error)
 
 	// FindCategoryByParent retrieves Categories by their parent category ID.
-	FindCategoryByParent(int64) ([]model.Category, error)
+	FindCategoryByParent(int64, CategoryFilter) ([]model.Category, error)
+
+	// FindCategoriesByParents retrieves the children of several parent categories at once.
+	FindCategoriesByParents([]int64, CategoryFilter) ([]model.Category, error)
+
+	// MoveCategory moves a Category under a new parent at a sibling position and recomputes the levels of its subtree.
+	MoveCategory(categoryID int64, parentID int64, level uint32, position int32) error
+
+	// DeleteCategoryTree deletes a Category together with all its descendants.
+	DeleteCategoryTree(int64) error
+
+	// DeleteCategoryPromoteChildren deletes a Category and moves its children under another parent.
+	DeleteCategoryPromoteChildren(categoryID int64, parentID int64, childLevel uint32) error
+
+	// UpsertTranslation creates or replaces the translation of a Category in one locale.
+	UpsertTranslation(*model.CategoryTranslation) error
+
+	// DeleteTranslation deletes the translation of a Category in one locale.
+	DeleteTranslation(categoryID int64, locale string) error
+
+	// FindTranslations retrieves the translations of several Categories in several locales at once.
+	FindTranslations(categoryIDs []int64, locales []string) ([]model.CategoryTranslation, error)
+
+	// CreateAttribute inserts a new attribute definition of a Category.
+	CreateAttribute(*model.CategoryAttribute) (int64, error)
+
+	// UpdateAttribute replaces an attribute definition.
+	UpdateAttribute(*model.CategoryAttribute) error
+
+	// DeleteAttribute deletes an attribute definition by its ID.
+	DeleteAttribute(int64) error
+
+	// FindAttributeByID retrieves an attribute definition by its ID.
+	FindAttributeByID(int64) (*model.CategoryAttribute, error)
+
+	// FindAttributesByCategories retrieves the attribute definitions of several Categories at once.
+	FindAttributesByCategories([]int64) ([]model.CategoryAttribute, error)
+
+	// CreateOutboxEvent writes a category change event to the outbox.
+	CreateOutboxEvent(*model.OutboxEvent) error
+
+	// FindPendingOutboxEvents retrieves the oldest events that were not published yet.
+	FindPendingOutboxEvents(limit int) ([]model.OutboxEvent, error)
+
+	// MarkOutboxEventPublished records that an event was published.
+	MarkOutboxEventPublished(eventID int64, publishedAt time.Time) error
+
+	// MarkOutboxEventFailed counts a failed attempt to publish an event.
+	MarkOutboxEventFailed(eventID int64) error
+
+	// DeletePublishedOutboxEvents deletes the events published before a point in time.
+	DeletePublishedOutboxEvents(before time.Time) error
+
+	// AcquireOutboxLease takes or renews the lease to publish the outbox for ttl and reports whether holder has it.
+	AcquireOutboxLease(name string, holder string, ttl time.Duration) (bool, error)
+
+	// Transaction runs fn with a repository bound to one database transaction, committing if fn returns nil.
+	Transaction(fn func(ICategoryRepository) error) error
 }
 
 // NewCategoryRepository creates and returns a new instance of CategoryRepository.
@@ -44,18 +131,50 @@ func NewCategoryRepository(db *gorm.DB) ICategoryRepository {
 // interactions with the database using GORM.
 type CategoryRepository struct {
 	mysqlDb *gorm.DB
+	// inTransaction is set on repositories handed out by Transaction, whose mysqlDb is the transaction
+	inTransaction bool
 }
 
 // InitTable initializes the Category table in the database if it does not already exist.
 func (r *CategoryRepository) InitTable() error {
-	// Creates the Category table based on the Category model
-	err := r.mysqlDb.CreateTable(&model.Category{}).Error
+	// Creates the Category, CategoryTranslation, CategoryAttribute and OutboxEvent tables based on the models
+	err := r.mysqlDb.CreateTable(&model.Category{}, &model.CategoryTranslation{}, &model.CategoryAttribute{}, &model.OutboxEvent{}, &model.OutboxLease{}).Error
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// AddSlugColumn adds the slug column to a Category table created before slugs existed. The unique index is
+// left to MigrateTable, as it cannot be created while the existing rows all have an empty slug.
+func (r *CategoryRepository) AddSlugColumn() error {
+	scope := r.mysqlDb.NewScope(&model.Category{})
+	if !scope.Dialect().HasTable(scope.TableName()) || scope.Dialect().HasColumn(scope.TableName(), "slug") {
+		return nil
+	}
+	return r.mysqlDb.Exec("ALTER TABLE " + scope.QuotedTableName() + " ADD " + scope.Quote("slug") + " varchar(255) NOT NULL DEFAULT ''").Error
+}
+
+// MigrateTable adds the tables, columns and indexes of the models that are missing from the database.
+func (r *CategoryRepository) MigrateTable() error {
+	return r.mysqlDb.AutoMigrate(&model.Category{}, &model.CategoryTranslation{}, &model.CategoryAttribute{}, &model.OutboxEvent{}, &model.OutboxLease{}).Error
+}
+
+// FindCategoriesWithoutSlug retrieves the Categories whose slug is empty from the database, oldest first.
+func (r *CategoryRepository) FindCategoriesWithoutSlug() ([]model.Category, error) {
+	var categories []model.Category
+	err := r.mysqlDb.Where("slug = ?", "").Order("id").Find(&categories).Error
+	if err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+// UpdateCategorySlug sets the slug of a Category in the database.
+func (r *CategoryRepository) UpdateCategorySlug(categoryID int64, slug string) error {
+	return r.mysqlDb.Model(&model.Category{}).Where("id = ?", categoryID).Update("slug", slug).Error
+}
+
 // FindCategoryByID retrieves a Category by its ID from the database.
 func (r *CategoryRepository) FindCategoryByID(categoryID int64) (*model.Category, error) {
 	category := &model.Category{}
@@ -77,31 +196,40 @@ func (r *CategoryRepository) CreateCategory(category *model.Category) (int64, er
 	return category.ID, nil
 }
 
-// DeleteCategoryByID deletes a Category from the database by its ID.
+// DeleteCategoryByID deletes a Category with its translations and attributes from the database by its ID.
 func (r *CategoryRepository) DeleteCategoryByID(categoryID int64) error {
-	// Deletes the category with the given ID from the database
-	err := r.mysqlDb.Where("id = ?", categoryID).Delete(&model.Category{}).Error
-	if err != nil {
-		return err
-	}
-	return nil
+	return r.transaction(func(tx *gorm.DB) error {
+		// Deletes the category with the given ID from the database
+		if err := tx.Where("id = ?", categoryID).Delete(&model.Category{}).Error; err != nil {
+			return err
+		}
+		return deleteCategoryData(tx, []int64{categoryID})
+	})
 }
 
-// UpdateCategory updates an existing Category's information in the database.
+// UpdateCategory updates an existing Category's information in the database. Empty fields are left
+// unchanged, except for the visibility fields which are always written so they can be cleared. The sort
+// order is never written here, positions change through MoveCategory so siblings stay numbered 1..n.
 func (r *CategoryRepository) UpdateCategory(category *model.Category) error {
-	// Updates the category record with new information
-	err := r.mysqlDb.Model(category).Update(category).Error
-	if err != nil {
-		return err
-	}
-	return nil
+	return r.transaction(func(tx *gorm.DB) error {
+		// Updates the category record with new information
+		err := tx.Model(category).Omit("sort_order").Update(category).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(category).Updates(map[string]interface{}{
+			"hidden":       category.Hidden,
+			"publish_at":   category.PublishAt,
+			"unpublish_at": category.UnpublishAt,
+		}).Error
+	})
 }
 
-// FindAll retrieves all Categories from the database.
-func (r *CategoryRepository) FindAll() ([]model.Category, error) {
+// FindAll retrieves all Categories from the database in sibling order.
+func (r *CategoryRepository) FindAll(filter CategoryFilter) ([]model.Category, error) {
 	var categories []model.Category
 	// Retrieves all categories from the database
-	err := r.mysqlDb.Find(&categories).Error
+	err := listQuery(r.mysqlDb, filter).Find(&categories).Error
 	if err != nil {
 		return nil, err
 	}
@@ -111,32 +239,467 @@ func (r *CategoryRepository) FindAll() ([]model.Category, error) {
 // FindCategoryByName retrieves a Category by its name from the database.
 func (r *CategoryRepository) FindCategoryByName(categoryName string) (*model.Category, error) {
 	category := &model.Category{}
-	// Retrieves the category that matches the provided category name
-	err := r.mysqlDb.Where("category_name = ?", categoryName).Find(category).Error
+	// Retrieves the first category that matches the provided category name, names are not unique
+	err := r.mysqlDb.Where("category_name = ?", categoryName).Order("id").First(category).Error
+	if err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+// FindCategoryBySlug retrieves a Category by its slug from the database.
+func (r *CategoryRepository) FindCategoryBySlug(slug string) (*model.Category, error) {
+	category := &model.Category{}
+	err := r.mysqlDb.Where("slug = ?", slug).First(category).Error
 	if err != nil {
 		return nil, err
 	}
 	return category, nil
 }
 
-// FindCategoryByLevel retrieves Categories by their level from the database.
-func (r *CategoryRepository) FindCategoryByLevel(level uint32) ([]model.Category, error) {
+// FindCategoriesBySlugs retrieves the Categories having one of the given slugs from the database.
+func (r *CategoryRepository) FindCategoriesBySlugs(slugs []string, filter CategoryFilter) ([]model.Category, error) {
+	var categories []model.Category
+	if len(slugs) == 0 {
+		return categories, nil
+	}
+	err := listQuery(r.mysqlDb, filter).Where("slug IN (?)", slugs).Find(&categories).Error
+	if err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+// FindSlugsWithPrefix retrieves the slugs that are equal to base or start with "base-" from the database.
+func (r *CategoryRepository) FindSlugsWithPrefix(base string) ([]string, error) {
+	var slugs []string
+	escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(base)
+	err := r.mysqlDb.Model(&model.Category{}).Where("slug = ? OR slug LIKE ?", base, escaped+"-%").Pluck("slug", &slugs).Error
+	if err != nil {
+		return nil, err
+	}
+	return slugs, nil
+}
+
+// FindCategoryByLevel retrieves Categories by their level from the database in sibling order.
+func (r *CategoryRepository) FindCategoryByLevel(level uint32, filter CategoryFilter) ([]model.Category, error) {
 	var categories []model.Category
 	// Retrieves all categories that match the provided level
-	err := r.mysqlDb.Where("category_level = ?", level).Find(&categories).Error
+	err := listQuery(r.mysqlDb, filter).Where("category_level = ?", level).Find(&categories).Error
 	if err != nil {
 		return nil, err
 	}
 	return categories, nil
 }
 
-// FindCategoryByParent retrieves Categories by their parent category ID from the database.
-func (r *CategoryRepository) FindCategoryByParent(parent int64) ([]model.Category, error) {
+// FindCategoryByParent retrieves Categories by their parent category ID from the database in sibling order.
+func (r *CategoryRepository) FindCategoryByParent(parent int64, filter CategoryFilter) ([]model.Category, error) {
 	var categories []model.Category
 	// Retrieves all categories that belong to the given parent category
-	err := r.mysqlDb.Where("category_parent = ?", parent).Find(&categories).Error
+	err := listQuery(r.mysqlDb, filter).Where("category_parent = ?", parent).Find(&categories).Error
+	if err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+// FindCategoriesByParents retrieves the Categories whose parent is one of the given IDs in sibling order.
+func (r *CategoryRepository) FindCategoriesByParents(parents []int64, filter CategoryFilter) ([]model.Category, error) {
+	var categories []model.Category
+	if len(parents) == 0 {
+		return categories, nil
+	}
+	// Retrieves one whole level of a tree in a single query
+	err := listQuery(r.mysqlDb, filter).Where("category_parent IN (?)", parents).Find(&categories).Error
 	if err != nil {
 		return nil, err
 	}
 	return categories, nil
 }
+
+// MoveCategory sets the parent and level of a Category, places it at the 1-based position among its new
+// siblings, closes the gap it leaves among its old siblings and updates the levels of all its descendants, in
+// one transaction. A position of 0 or past the last sibling appends the category after the last sibling.
+func (r *CategoryRepository) MoveCategory(categoryID int64, parentID int64, level uint32, position int32) error {
+	return r.transaction(func(tx *gorm.DB) error {
+		current := &model.Category{}
+		if err := tx.Select("id, category_parent").Where("id = ?", categoryID).First(current).Error; err != nil {
+			return err
+		}
+
+		// Updates with a map so that moving to the top level (parent 0) is written as well
+		err := tx.Model(&model.Category{}).Where("id = ?", categoryID).Updates(map[string]interface{}{
+			"category_parent": parentID,
+			"category_level":  level,
+		}).Error
+		if err != nil {
+			return err
+		}
+		if err := placeAmongSiblings(tx, categoryID, parentID, position); err != nil {
+			return err
+		}
+		if current.CategoryParent != parentID {
+			if err := renumberSiblings(tx, current.CategoryParent); err != nil {
+				return err
+			}
+		}
+		return updateSubtreeLevels(tx, []int64{categoryID}, level+1)
+	})
+}
+
+// DeleteCategoryTree deletes a Category and all its descendants with their translations and attributes in
+// one transaction.
+func (r *CategoryRepository) DeleteCategoryTree(categoryID int64) error {
+	return r.transaction(func(tx *gorm.DB) error {
+		ids := []int64{categoryID}
+		visited := map[int64]bool{categoryID: true}
+		// Collects the subtree one level at a time
+		for level := []int64{categoryID}; len(level) > 0; {
+			children, err := findChildIDs(tx, level, visited)
+			if err != nil {
+				return err
+			}
+			ids = append(ids, children...)
+			level = children
+		}
+		if err := tx.Where("id IN (?)", ids).Delete(&model.Category{}).Error; err != nil {
+			return err
+		}
+		return deleteCategoryData(tx, ids)
+	})
+}
+
+// DeleteCategoryPromoteChildren moves the children of a Category under parentID at childLevel,
+// recomputes the levels below them and deletes the Category, in one transaction.
+func (r *CategoryRepository) DeleteCategoryPromoteChildren(categoryID int64, parentID int64, childLevel uint32) error {
+	return r.transaction(func(tx *gorm.DB) error {
+		children, err := findChildIDs(tx, []int64{categoryID}, map[int64]bool{categoryID: true})
+		if err != nil {
+			return err
+		}
+		if len(children) > 0 {
+			err = tx.Model(&model.Category{}).Where("id IN (?)", children).Updates(map[string]interface{}{
+				"category_parent": parentID,
+				"category_level":  childLevel,
+			}).Error
+			if err != nil {
+				return err
+			}
+			if err = updateSubtreeLevels(tx, children, childLevel+1); err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("id = ?", categoryID).Delete(&model.Category{}).Error; err != nil {
+			return err
+		}
+		return deleteCategoryData(tx, []int64{categoryID})
+	})
+}
+
+// UpsertTranslation creates the translation of a Category in a locale or replaces the existing one.
+func (r *CategoryRepository) UpsertTranslation(translation *model.CategoryTranslation) error {
+	return r.transaction(func(tx *gorm.DB) error {
+		existing := &model.CategoryTranslation{}
+		err := tx.Set("gorm:query_option", "FOR UPDATE").
+			Where("category_id = ? AND locale = ?", translation.CategoryID, translation.Locale).First(existing).Error
+		if gorm.IsRecordNotFoundError(err) {
+			return tx.Create(translation).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		translation.ID = existing.ID
+		// Updates with a map so that a description can be cleared
+		return tx.Model(existing).Updates(map[string]interface{}{
+			"category_name":        translation.CategoryName,
+			"category_description": translation.CategoryDescription,
+		}).Error
+	})
+}
+
+// DeleteTranslation deletes the translation of a Category in a locale from the database.
+func (r *CategoryRepository) DeleteTranslation(categoryID int64, locale string) error {
+	result := r.mysqlDb.Where("category_id = ? AND locale = ?", categoryID, locale).Delete(&model.CategoryTranslation{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// FindTranslations retrieves the translations of the given Categories in any of the given locales.
+func (r *CategoryRepository) FindTranslations(categoryIDs []int64, locales []string) ([]model.CategoryTranslation, error) {
+	var translations []model.CategoryTranslation
+	if len(categoryIDs) == 0 || len(locales) == 0 {
+		return translations, nil
+	}
+	err := r.mysqlDb.Where("category_id IN (?) AND locale IN (?)", categoryIDs, locales).Find(&translations).Error
+	if err != nil {
+		return nil, err
+	}
+	return translations, nil
+}
+
+// CreateAttribute inserts a new attribute definition into the database.
+func (r *CategoryRepository) CreateAttribute(attribute *model.CategoryAttribute) (int64, error) {
+	err := r.mysqlDb.Create(attribute).Error
+	if err != nil {
+		return 0, err
+	}
+	return attribute.ID, nil
+}
+
+// UpdateAttribute writes all fields of an attribute definition except its category to the database.
+func (r *CategoryRepository) UpdateAttribute(attribute *model.CategoryAttribute) error {
+	if err := attribute.BeforeSave(); err != nil {
+		return err
+	}
+	// Updates with a map so that flags, the unit and the enum values can be cleared
+	result := r.mysqlDb.Model(&model.CategoryAttribute{}).Where("id = ?", attribute.ID).Updates(map[string]interface{}{
+		"name":        attribute.Name,
+		"type":        attribute.Type,
+		"unit":        attribute.Unit,
+		"enum_values": attribute.EnumValuesData,
+		"required":    attribute.Required,
+		"inheritable": attribute.Inheritable,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		// MySQL reports unchanged rows as not affected, so check that the attribute exists
+		return r.mysqlDb.Select("id").First(&model.CategoryAttribute{}, attribute.ID).Error
+	}
+	return nil
+}
+
+// DeleteAttribute deletes an attribute definition from the database by its ID.
+func (r *CategoryRepository) DeleteAttribute(attributeID int64) error {
+	result := r.mysqlDb.Where("id = ?", attributeID).Delete(&model.CategoryAttribute{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// FindAttributeByID retrieves an attribute definition by its ID from the database.
+func (r *CategoryRepository) FindAttributeByID(attributeID int64) (*model.CategoryAttribute, error) {
+	attribute := &model.CategoryAttribute{}
+	err := r.mysqlDb.First(attribute, attributeID).Error
+	if err != nil {
+		return nil, err
+	}
+	return attribute, nil
+}
+
+// FindAttributesByCategories retrieves the attribute definitions of the given Categories, ordered by ID.
+func (r *CategoryRepository) FindAttributesByCategories(categoryIDs []int64) ([]model.CategoryAttribute, error) {
+	var attributes []model.CategoryAttribute
+	if len(categoryIDs) == 0 {
+		return attributes, nil
+	}
+	err := r.mysqlDb.Where("category_id IN (?)", categoryIDs).Order("id").Find(&attributes).Error
+	if err != nil {
+		return nil, err
+	}
+	return attributes, nil
+}
+
+// CreateOutboxEvent inserts a category change event into the outbox table.
+func (r *CategoryRepository) CreateOutboxEvent(event *model.OutboxEvent) error {
+	return r.mysqlDb.Create(event).Error
+}
+
+// FindPendingOutboxEvents retrieves up to limit unpublished events from the database, oldest first.
+func (r *CategoryRepository) FindPendingOutboxEvents(limit int) ([]model.OutboxEvent, error) {
+	var events []model.OutboxEvent
+	err := r.mysqlDb.Where("published_at IS NULL").Order("id").Limit(limit).Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// MarkOutboxEventPublished sets the publishing time of an event in the database.
+func (r *CategoryRepository) MarkOutboxEventPublished(eventID int64, publishedAt time.Time) error {
+	return r.mysqlDb.Model(&model.OutboxEvent{}).Where("id = ?", eventID).Update("published_at", publishedAt).Error
+}
+
+// MarkOutboxEventFailed increments the failed attempts of an event in the database.
+func (r *CategoryRepository) MarkOutboxEventFailed(eventID int64) error {
+	return r.mysqlDb.Model(&model.OutboxEvent{}).Where("id = ?", eventID).Update("attempts", gorm.Expr("attempts + 1")).Error
+}
+
+// DeletePublishedOutboxEvents deletes the events published before the given time from the database.
+func (r *CategoryRepository) DeletePublishedOutboxEvents(before time.Time) error {
+	return r.mysqlDb.Where("published_at < ?", before).Delete(&model.OutboxEvent{}).Error
+}
+
+// AcquireOutboxLease gives holder the named lease until ttl from now if it is free, expired or already held by
+// holder, and reports whether holder has it. Expiry is decided by the database clock, so instances with
+// skewed clocks agree on it.
+func (r *CategoryRepository) AcquireOutboxLease(name string, holder string, ttl time.Duration) (bool, error) {
+	expiresAt := gorm.Expr("NOW() + INTERVAL ? SECOND", int64(ttl/time.Second))
+	err := r.mysqlDb.Model(&model.OutboxLease{}).Where("name = ? AND (holder = ? OR expires_at < NOW())", name, holder).
+		Updates(map[string]interface{}{"holder": holder, "expires_at": expiresAt}).Error
+	if err != nil {
+		return false, err
+	}
+
+	lease := &model.OutboxLease{}
+	err = r.mysqlDb.Where("name = ?", name).First(lease).Error
+	if gorm.IsRecordNotFoundError(err) {
+		// First use of the lease; if another instance creates it at the same time, its row is kept
+		err = r.mysqlDb.Exec("INSERT IGNORE INTO "+r.mysqlDb.NewScope(lease).QuotedTableName()+
+			" (name, holder, expires_at) VALUES (?, ?, NOW() + INTERVAL ? SECOND)", name, holder, int64(ttl/time.Second)).Error
+		if err != nil {
+			return false, err
+		}
+		err = r.mysqlDb.Where("name = ?", name).First(lease).Error
+	}
+	if err != nil {
+		return false, err
+	}
+	return lease.Holder == holder, nil
+}
+
+// Transaction runs fn with a repository whose methods all use one database transaction. The transaction is
+// committed if fn returns nil and rolled back otherwise.
+func (r *CategoryRepository) Transaction(fn func(ICategoryRepository) error) error {
+	return r.transaction(func(tx *gorm.DB) error {
+		return fn(&CategoryRepository{mysqlDb: tx, inTransaction: true})
+	})
+}
+
+// Helper function to run fn in a transaction, joining the surrounding one if the repository is bound to it
+func (r *CategoryRepository) transaction(fn func(tx *gorm.DB) error) error {
+	if r.inTransaction {
+		return fn(r.mysqlDb)
+	}
+	return r.mysqlDb.Transaction(fn)
+}
+
+// Helper function to delete the translations and attributes of deleted categories
+func deleteCategoryData(tx *gorm.DB, categoryIDs []int64) error {
+	if err := tx.Where("category_id IN (?)", categoryIDs).Delete(&model.CategoryTranslation{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("category_id IN (?)", categoryIDs).Delete(&model.CategoryAttribute{}).Error
+}
+
+// Matches reports whether the filter lists a category already loaded, applying the conditions of listQuery.
+func (f CategoryFilter) Matches(category *model.Category) bool {
+	if f.IncludeHidden {
+		return true
+	}
+	if category.Hidden {
+		return false
+	}
+	if category.PublishAt != nil && category.PublishAt.After(f.Now) {
+		return false
+	}
+	return category.UnpublishAt == nil || category.UnpublishAt.After(f.Now)
+}
+
+// Helper function to order a listing by sibling position and leave out categories the filter hides,
+// CategoryFilter.Matches must agree with it
+func listQuery(db *gorm.DB, filter CategoryFilter) *gorm.DB {
+	query := db.Order("sort_order, id")
+	if !filter.IncludeHidden {
+		query = query.Where("hidden = ?", false).
+			Where("publish_at IS NULL OR publish_at <= ?", filter.Now).
+			Where("unpublish_at IS NULL OR unpublish_at > ?", filter.Now)
+	}
+	return query
+}
+
+// Helper function to renumber the children of parentID so that the category is at the given position
+func placeAmongSiblings(tx *gorm.DB, categoryID int64, parentID int64, position int32) error {
+	var siblings []model.Category
+	err := tx.Select("id, sort_order").Where("category_parent = ? AND id <> ?", parentID, categoryID).
+		Order("sort_order, id").Find(&siblings).Error
+	if err != nil {
+		return err
+	}
+
+	index := len(siblings)
+	if position > 0 && int(position) <= len(siblings) {
+		index = int(position) - 1
+	}
+	ordered := make([]model.Category, 0, len(siblings)+1)
+	ordered = append(ordered, siblings[:index]...)
+	ordered = append(ordered, model.Category{ID: categoryID, SortOrder: -1})
+	ordered = append(ordered, siblings[index:]...)
+	return writeSortOrders(tx, ordered)
+}
+
+// Helper function to renumber the children of parentID in their current order, closing gaps
+func renumberSiblings(tx *gorm.DB, parentID int64) error {
+	var siblings []model.Category
+	err := tx.Select("id, sort_order").Where("category_parent = ?", parentID).Order("sort_order, id").Find(&siblings).Error
+	if err != nil {
+		return err
+	}
+	return writeSortOrders(tx, siblings)
+}
+
+// Helper function to number the given siblings from 1 in their order, writing only the changed ones
+func writeSortOrders(tx *gorm.DB, ordered []model.Category) error {
+	for i, sibling := range ordered {
+		sortOrder := int32(i + 1)
+		if sibling.SortOrder == sortOrder {
+			continue
+		}
+		err := tx.Model(&model.Category{}).Where("id = ?", sibling.ID).Update("sort_order", sortOrder).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Helper function to set the levels below the given categories, one query pair per level
+func updateSubtreeLevels(tx *gorm.DB, parents []int64, level uint32) error {
+	visited := make(map[int64]bool, len(parents))
+	for _, id := range parents {
+		visited[id] = true
+	}
+	for ; len(parents) > 0; level++ {
+		children, err := findChildIDs(tx, parents, visited)
+		if err != nil {
+			return err
+		}
+		if len(children) > 0 {
+			err = tx.Model(&model.Category{}).Where("id IN (?)", children).Update("category_level", level).Error
+			if err != nil {
+				return err
+			}
+		}
+		parents = children
+	}
+	return nil
+}
+
+// Helper function to find the IDs of the children of several categories, skipping visited ones so that
+// cycles in existing data cannot loop forever
+func findChildIDs(tx *gorm.DB, parents []int64, visited map[int64]bool) ([]int64, error) {
+	var ids []int64
+	err := tx.Model(&model.Category{}).Where("category_parent IN (?)", parents).Order("id").Pluck("id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+	children := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if !visited[id] {
+			visited[id] = true
+			children = append(children, id)
+		}
+	}
+	return children, nil
+}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"errors"
 	"fmt"
 	"github.com/jinzhu/gorm"
 	_ "github.com/jinzhu/gorm/dialects/mysql" // Import MySQL dialect
@@ -8,6 +9,7 @@ import (
 	"github.com/tongs-dev/shopping-platform/category/domain/model"
 	"log"
 	"testing"
+	"time"
 )
 
 // TestCategoryRepository tests the CategoryRepository methods using MySQL database.
@@ -19,6 +21,7 @@ func TestCategoryRepository(t *testing.T) {
 	t.Run("FindCategoryByID", func(t *testing.T) {
 		category := &model.Category{
 			CategoryName: "Test Category",
+			Slug:         "test-category",
 		}
 
 		_, err := repo.CreateCategory(category)
@@ -32,6 +35,7 @@ func TestCategoryRepository(t *testing.T) {
 	t.Run("CreateCategory", func(t *testing.T) {
 		category := &model.Category{
 			CategoryName: "New Category",
+			Slug:         "new-category",
 		}
 		id, err := repo.CreateCategory(category)
 		assert.NoError(t, err)
@@ -41,6 +45,7 @@ func TestCategoryRepository(t *testing.T) {
 	t.Run("DeleteCategoryByID", func(t *testing.T) {
 		category := &model.Category{
 			CategoryName: "Category To Be Deleted",
+			Slug:         "category-to-be-deleted",
 		}
 
 		id, err := repo.CreateCategory(category)
@@ -56,6 +61,7 @@ func TestCategoryRepository(t *testing.T) {
 	t.Run("UpdateCategory", func(t *testing.T) {
 		category := &model.Category{
 			CategoryName: "Old Category Name",
+			Slug:         "old-category-name",
 		}
 		id, err := repo.CreateCategory(category)
 		assert.NoError(t, err)
@@ -67,6 +73,12 @@ func TestCategoryRepository(t *testing.T) {
 		updatedCategory, err := repo.FindCategoryByID(id)
 		assert.NoError(t, err)
 		assert.Equal(t, "Updated Category Name", updatedCategory.CategoryName)
+
+		category.SortOrder = 7
+		err = repo.UpdateCategory(category)
+		assert.NoError(t, err)
+		updatedCategory, _ = repo.FindCategoryByID(id)
+		assert.NotEqual(t, int32(7), updatedCategory.SortOrder, "Sort order only changes through MoveCategory")
 	})
 
 	t.Run("FindAll", func(t *testing.T) {
@@ -74,9 +86,11 @@ func TestCategoryRepository(t *testing.T) {
 
 		category1 := &model.Category{
 			CategoryName: "Category 1",
+			Slug:         "category-1",
 		}
 		category2 := &model.Category{
 			CategoryName: "Category 2",
+			Slug:         "category-2",
 		}
 
 		// Create two categories
@@ -86,14 +100,37 @@ func TestCategoryRepository(t *testing.T) {
 		assert.NoError(t, err)
 
 		// Find all categories
-		categories, err := repo.FindAll()
+		categories, err := repo.FindAll(CategoryFilter{Now: time.Now()})
 		assert.NoError(t, err)
 		assert.Len(t, categories, 2)
 	})
 
+	t.Run("FindAllVisibility", func(t *testing.T) {
+		clearTable(t, db)
+
+		now := time.Now()
+		later := now.Add(time.Hour)
+		earlier := now.Add(-time.Hour)
+		repo.CreateCategory(&model.Category{CategoryName: "Visible", Slug: "visible", SortOrder: 2})
+		repo.CreateCategory(&model.Category{CategoryName: "First", Slug: "first", SortOrder: 1})
+		repo.CreateCategory(&model.Category{CategoryName: "Hidden", Slug: "hidden", Hidden: true})
+		repo.CreateCategory(&model.Category{CategoryName: "Not Yet Published", Slug: "not-yet-published", PublishAt: &later})
+		repo.CreateCategory(&model.Category{CategoryName: "Unpublished", Slug: "unpublished", UnpublishAt: &earlier})
+
+		categories, err := repo.FindAll(CategoryFilter{Now: now})
+		assert.NoError(t, err)
+		assert.Len(t, categories, 2)
+		assert.Equal(t, "First", categories[0].CategoryName)
+
+		categories, err = repo.FindAll(CategoryFilter{IncludeHidden: true, Now: now})
+		assert.NoError(t, err)
+		assert.Len(t, categories, 5)
+	})
+
 	t.Run("FindCategoryByName", func(t *testing.T) {
 		category := &model.Category{
 			CategoryName: "Unique Category",
+			Slug:         "unique-category",
 		}
 
 		// Create the category
@@ -104,18 +141,23 @@ func TestCategoryRepository(t *testing.T) {
 		foundCategory, err := repo.FindCategoryByName("Unique Category")
 		assert.NoError(t, err)
 		assert.Equal(t, "Unique Category", foundCategory.CategoryName)
+
+		// A missing name is an error, not an empty category
+		_, err = repo.FindCategoryByName("Missing Category")
+		assert.True(t, gorm.IsRecordNotFoundError(err))
 	})
 
 	t.Run("FindCategoryByLevel", func(t *testing.T) {
 		category := &model.Category{
 			CategoryName:  "Level 1 Category",
+			Slug:          "level-1-category",
 			CategoryLevel: 1,
 		}
 
 		_, err := repo.CreateCategory(category)
 		assert.NoError(t, err)
 
-		categories, err := repo.FindCategoryByLevel(1)
+		categories, err := repo.FindCategoryByLevel(1, CategoryFilter{Now: time.Now()})
 		assert.NoError(t, err)
 		assert.Len(t, categories, 1)
 	})
@@ -123,21 +165,273 @@ func TestCategoryRepository(t *testing.T) {
 	t.Run("FindCategoryByParent", func(t *testing.T) {
 		parentCategory := &model.Category{
 			CategoryName: "Parent Category",
+			Slug:         "parent-category",
 		}
 		parentID, err := repo.CreateCategory(parentCategory)
 		assert.NoError(t, err)
 
 		childCategory := &model.Category{
 			CategoryName:   "Child Category",
+			Slug:           "child-category",
 			CategoryParent: parentID,
 		}
 		_, err = repo.CreateCategory(childCategory)
 		assert.NoError(t, err)
 
-		categories, err := repo.FindCategoryByParent(parentID)
+		categories, err := repo.FindCategoryByParent(parentID, CategoryFilter{Now: time.Now()})
 		assert.NoError(t, err)
 		assert.Len(t, categories, 1)
 	})
+
+	t.Run("FindCategoriesByParents", func(t *testing.T) {
+		firstID, err := repo.CreateCategory(&model.Category{CategoryName: "First Parent", Slug: "first-parent"})
+		assert.NoError(t, err)
+		secondID, err := repo.CreateCategory(&model.Category{CategoryName: "Second Parent", Slug: "second-parent"})
+		assert.NoError(t, err)
+
+		repo.CreateCategory(&model.Category{CategoryName: "First Child", Slug: "first-child", CategoryParent: firstID})
+		repo.CreateCategory(&model.Category{CategoryName: "Second Child", Slug: "second-child", CategoryParent: secondID})
+		repo.CreateCategory(&model.Category{CategoryName: "Hidden Child", Slug: "hidden-child", CategoryParent: secondID, Hidden: true})
+
+		categories, err := repo.FindCategoriesByParents([]int64{firstID, secondID}, CategoryFilter{IncludeHidden: true})
+		assert.NoError(t, err)
+		assert.Len(t, categories, 3)
+
+		categories, err = repo.FindCategoriesByParents([]int64{firstID, secondID}, CategoryFilter{Now: time.Now()})
+		assert.NoError(t, err)
+		assert.Len(t, categories, 2, "Hidden children are left out")
+
+		categories, err = repo.FindCategoriesByParents(nil, CategoryFilter{IncludeHidden: true})
+		assert.NoError(t, err)
+		assert.Empty(t, categories)
+	})
+
+	t.Run("FindCategoryBySlug", func(t *testing.T) {
+		repo.CreateCategory(&model.Category{CategoryName: "Running", Slug: "running"})
+		repo.CreateCategory(&model.Category{CategoryName: "Running 2", Slug: "running-2"})
+		repo.CreateCategory(&model.Category{CategoryName: "Running Shoes", Slug: "runningshoes"})
+
+		category, err := repo.FindCategoryBySlug("running")
+		assert.NoError(t, err)
+		assert.Equal(t, "Running", category.CategoryName)
+
+		categories, err := repo.FindCategoriesBySlugs([]string{"running", "running-2"}, CategoryFilter{IncludeHidden: true})
+		assert.NoError(t, err)
+		assert.Len(t, categories, 2)
+
+		slugs, err := repo.FindSlugsWithPrefix("running")
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []string{"running", "running-2"}, slugs)
+	})
+
+	t.Run("Translations", func(t *testing.T) {
+		id, _ := repo.CreateCategory(&model.Category{CategoryName: "Translated", Slug: "translated"})
+
+		err := repo.UpsertTranslation(&model.CategoryTranslation{CategoryID: id, Locale: "de", CategoryName: "Ubersetzt"})
+		assert.NoError(t, err)
+		err = repo.UpsertTranslation(&model.CategoryTranslation{CategoryID: id, Locale: "de", CategoryName: "Übersetzt"})
+		assert.NoError(t, err)
+		err = repo.UpsertTranslation(&model.CategoryTranslation{CategoryID: id, Locale: "fr", CategoryName: "Traduit"})
+		assert.NoError(t, err)
+
+		translations, err := repo.FindTranslations([]int64{id}, []string{"de-AT", "de"})
+		assert.NoError(t, err)
+		assert.Len(t, translations, 1)
+		assert.Equal(t, "Übersetzt", translations[0].CategoryName)
+
+		err = repo.DeleteTranslation(id, "fr")
+		assert.NoError(t, err)
+		err = repo.DeleteTranslation(id, "fr")
+		assert.Error(t, err)
+
+		// Deleting the category deletes its translations
+		err = repo.DeleteCategoryByID(id)
+		assert.NoError(t, err)
+		translations, err = repo.FindTranslations([]int64{id}, []string{"de"})
+		assert.NoError(t, err)
+		assert.Empty(t, translations)
+	})
+
+	t.Run("MoveCategory", func(t *testing.T) {
+		rootID, _ := repo.CreateCategory(&model.Category{CategoryName: "Move Root", Slug: "move-root", CategoryLevel: 1})
+		firstID, _ := repo.CreateCategory(&model.Category{CategoryName: "Move First", Slug: "move-first", CategoryLevel: 2, CategoryParent: rootID, SortOrder: 1})
+		secondID, _ := repo.CreateCategory(&model.Category{CategoryName: "Move Second", Slug: "move-second", CategoryLevel: 2, CategoryParent: rootID, SortOrder: 2})
+		movedID, _ := repo.CreateCategory(&model.Category{CategoryName: "Move Moved", Slug: "move-moved", CategoryLevel: 1})
+		childID, _ := repo.CreateCategory(&model.Category{CategoryName: "Move Child", Slug: "move-child", CategoryLevel: 2, CategoryParent: movedID})
+
+		err := repo.MoveCategory(movedID, rootID, 2, 2)
+		assert.NoError(t, err)
+
+		moved, _ := repo.FindCategoryByID(movedID)
+		assert.Equal(t, rootID, moved.CategoryParent)
+		assert.Equal(t, int32(2), moved.SortOrder)
+		first, _ := repo.FindCategoryByID(firstID)
+		assert.Equal(t, int32(1), first.SortOrder)
+		second, _ := repo.FindCategoryByID(secondID)
+		assert.Equal(t, int32(3), second.SortOrder)
+		child, _ := repo.FindCategoryByID(childID)
+		assert.Equal(t, uint32(3), child.CategoryLevel)
+
+		// Moving back to the top level writes parent 0
+		err = repo.MoveCategory(movedID, 0, 1, 0)
+		assert.NoError(t, err)
+		moved, _ = repo.FindCategoryByID(movedID)
+		assert.Equal(t, int64(0), moved.CategoryParent)
+		child, _ = repo.FindCategoryByID(childID)
+		assert.Equal(t, uint32(2), child.CategoryLevel)
+	})
+
+	t.Run("MoveCategoryRenumbersOldSiblings", func(t *testing.T) {
+		fromID, _ := repo.CreateCategory(&model.Category{CategoryName: "Renumber From", Slug: "renumber-from", CategoryLevel: 1})
+		toID, _ := repo.CreateCategory(&model.Category{CategoryName: "Renumber To", Slug: "renumber-to", CategoryLevel: 1})
+		var fromIDs []int64
+		for i, slug := range []string{"renumber-a", "renumber-b", "renumber-c"} {
+			id, _ := repo.CreateCategory(&model.Category{CategoryName: slug, Slug: slug, CategoryLevel: 2, CategoryParent: fromID, SortOrder: int32(i + 1)})
+			fromIDs = append(fromIDs, id)
+		}
+		toChildID, _ := repo.CreateCategory(&model.Category{CategoryName: "Renumber D", Slug: "renumber-d", CategoryLevel: 2, CategoryParent: toID, SortOrder: 1})
+
+		// Moves the middle child, both sibling lists must stay numbered 1..n
+		err := repo.MoveCategory(fromIDs[1], toID, 2, 1)
+		assert.NoError(t, err)
+
+		from, _ := repo.FindCategoryByParent(fromID, CategoryFilter{IncludeHidden: true})
+		assert.Len(t, from, 2)
+		for i, category := range from {
+			assert.Equal(t, int32(i+1), category.SortOrder)
+		}
+		assert.Equal(t, []int64{fromIDs[0], fromIDs[2]}, []int64{from[0].ID, from[1].ID})
+
+		to, _ := repo.FindCategoryByParent(toID, CategoryFilter{IncludeHidden: true})
+		assert.Len(t, to, 2)
+		for i, category := range to {
+			assert.Equal(t, int32(i+1), category.SortOrder)
+		}
+		assert.Equal(t, []int64{fromIDs[1], toChildID}, []int64{to[0].ID, to[1].ID})
+	})
+
+	t.Run("DeleteCategoryTree", func(t *testing.T) {
+		rootID, _ := repo.CreateCategory(&model.Category{CategoryName: "Tree Root", Slug: "tree-root", CategoryLevel: 1})
+		childID, _ := repo.CreateCategory(&model.Category{CategoryName: "Tree Child", Slug: "tree-child", CategoryLevel: 2, CategoryParent: rootID})
+		grandchildID, _ := repo.CreateCategory(&model.Category{CategoryName: "Tree Grandchild", Slug: "tree-grandchild", CategoryLevel: 3, CategoryParent: childID})
+
+		err := repo.DeleteCategoryTree(rootID)
+		assert.NoError(t, err)
+
+		for _, id := range []int64{rootID, childID, grandchildID} {
+			_, err = repo.FindCategoryByID(id)
+			assert.Error(t, err)
+		}
+	})
+
+	t.Run("DeleteCategoryPromoteChildren", func(t *testing.T) {
+		rootID, _ := repo.CreateCategory(&model.Category{CategoryName: "Promote Root", Slug: "promote-root", CategoryLevel: 1})
+		childID, _ := repo.CreateCategory(&model.Category{CategoryName: "Promote Child", Slug: "promote-child", CategoryLevel: 2, CategoryParent: rootID})
+		grandchildID, _ := repo.CreateCategory(&model.Category{CategoryName: "Promote Grandchild", Slug: "promote-grandchild", CategoryLevel: 3, CategoryParent: childID})
+
+		err := repo.DeleteCategoryPromoteChildren(rootID, 0, 1)
+		assert.NoError(t, err)
+
+		_, err = repo.FindCategoryByID(rootID)
+		assert.Error(t, err)
+		child, _ := repo.FindCategoryByID(childID)
+		assert.Equal(t, int64(0), child.CategoryParent)
+		assert.Equal(t, uint32(1), child.CategoryLevel)
+		grandchild, _ := repo.FindCategoryByID(grandchildID)
+		assert.Equal(t, uint32(2), grandchild.CategoryLevel)
+	})
+
+	t.Run("TransactionRollback", func(t *testing.T) {
+		rollback := errors.New("rollback")
+		err := repo.Transaction(func(tx ICategoryRepository) error {
+			// Moves and deletes open their own transaction, which must reuse the outer one
+			rootID, err := tx.CreateCategory(&model.Category{CategoryName: "Rolled Back", Slug: "rolled-back", CategoryLevel: 1})
+			assert.NoError(t, err)
+			_, err = tx.CreateCategory(&model.Category{CategoryName: "Rolled Back Child", Slug: "rolled-back-child", CategoryLevel: 2, CategoryParent: rootID})
+			assert.NoError(t, err)
+			assert.NoError(t, tx.DeleteCategoryTree(rootID))
+			_, err = tx.CreateCategory(&model.Category{CategoryName: "Rolled Back Again", Slug: "rolled-back-again", CategoryLevel: 1})
+			assert.NoError(t, err)
+			return rollback
+		})
+		assert.Equal(t, rollback, err)
+
+		_, err = repo.FindCategoryBySlug("rolled-back-again")
+		assert.True(t, gorm.IsRecordNotFoundError(err))
+	})
+
+	t.Run("CategoryAttributes", func(t *testing.T) {
+		categoryID, _ := repo.CreateCategory(&model.Category{CategoryName: "Attribute Category", Slug: "attribute-category", CategoryLevel: 1})
+		attribute := &model.CategoryAttribute{CategoryID: categoryID, Name: "size", Type: model.AttributeEnum, EnumValues: []string{"S", "M"}, Required: true}
+		attributeID, err := repo.CreateAttribute(attribute)
+		assert.NoError(t, err)
+
+		found, err := repo.FindAttributeByID(attributeID)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"S", "M"}, found.EnumValues)
+
+		// Updating clears the enum values and the required flag
+		err = repo.UpdateAttribute(&model.CategoryAttribute{ID: attributeID, Name: "size", Type: model.AttributeNumber, Unit: "cm"})
+		assert.NoError(t, err)
+		attributes, err := repo.FindAttributesByCategories([]int64{categoryID})
+		assert.NoError(t, err)
+		assert.Len(t, attributes, 1)
+		assert.Empty(t, attributes[0].EnumValues)
+		assert.False(t, attributes[0].Required)
+		assert.Equal(t, "cm", attributes[0].Unit)
+
+		// Deleting the category deletes its attributes
+		assert.NoError(t, repo.DeleteCategoryByID(categoryID))
+		_, err = repo.FindAttributeByID(attributeID)
+		assert.True(t, gorm.IsRecordNotFoundError(err))
+	})
+
+	t.Run("OutboxEvents", func(t *testing.T) {
+		first := &model.OutboxEvent{EventType: "category.created", CategoryID: 1, Payload: "{}", CreatedAt: time.Now()}
+		second := &model.OutboxEvent{EventType: "category.deleted", CategoryID: 1, Payload: "{}", CreatedAt: time.Now()}
+		assert.NoError(t, repo.CreateOutboxEvent(first))
+		assert.NoError(t, repo.CreateOutboxEvent(second))
+
+		assert.NoError(t, repo.MarkOutboxEventFailed(first.ID))
+		pending, err := repo.FindPendingOutboxEvents(10)
+		assert.NoError(t, err)
+		assert.Len(t, pending, 2)
+		assert.Equal(t, first.ID, pending[0].ID)
+		assert.Equal(t, int32(1), pending[0].Attempts)
+
+		publishedAt := time.Now().Add(-time.Hour)
+		assert.NoError(t, repo.MarkOutboxEventPublished(first.ID, publishedAt))
+		pending, _ = repo.FindPendingOutboxEvents(10)
+		assert.Len(t, pending, 1)
+		assert.Equal(t, second.ID, pending[0].ID)
+
+		// Only events published before the cut-off are deleted
+		assert.NoError(t, repo.DeletePublishedOutboxEvents(time.Now()))
+		assert.NoError(t, repo.MarkOutboxEventPublished(second.ID, time.Now()))
+		assert.NoError(t, repo.DeletePublishedOutboxEvents(publishedAt))
+		var remaining int
+		db.Model(&model.OutboxEvent{}).Count(&remaining)
+		assert.Equal(t, 1, remaining)
+	})
+
+	t.Run("AcquireOutboxLease", func(t *testing.T) {
+		leader, err := repo.AcquireOutboxLease("relay", "a", time.Minute)
+		assert.NoError(t, err)
+		assert.True(t, leader)
+
+		leader, err = repo.AcquireOutboxLease("relay", "b", time.Minute)
+		assert.NoError(t, err)
+		assert.False(t, leader, "a valid lease is not taken over")
+
+		leader, err = repo.AcquireOutboxLease("relay", "a", time.Minute)
+		assert.NoError(t, err)
+		assert.True(t, leader, "the holder renews its lease")
+
+		db.Model(&model.OutboxLease{}).Where("name = ?", "relay").Update("expires_at", gorm.Expr("NOW() - INTERVAL 1 HOUR"))
+		leader, err = repo.AcquireOutboxLease("relay", "b", time.Minute)
+		assert.NoError(t, err)
+		assert.True(t, leader, "an expired lease is taken over")
+	})
 }
 
 // setupTestDB initializes a real MySQL test database for unit tests.
@@ -151,13 +445,13 @@ func setupTestDB(t *testing.T) *gorm.DB {
 	}
 
 	// Clear the 'users' table before each test
-	err = db.Exec("DROP TABLE IF EXISTS categories").Error
+	err = db.Exec("DROP TABLE IF EXISTS categories, category_translations, category_attributes, outbox_events, outbox_leases").Error
 	if err != nil {
 		log.Fatalf("Failed to drop 'categories' table: %v", err)
 	}
 
 	// Automatically migrate the User model (creating the table)
-	err = db.AutoMigrate(&model.Category{}).Error
+	err = db.AutoMigrate(&model.Category{}, &model.CategoryTranslation{}, &model.CategoryAttribute{}, &model.OutboxEvent{}, &model.OutboxLease{}).Error
 	assert.NoError(t, err, "Failed to migrate test table")
 
 	fmt.Println("MySQL test database setup complete")
@@ -0,0 +1,218 @@
+package service
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tongs-dev/shopping-platform/category/domain/model"
+)
+
+var (
+	// ErrAttributeNameRequired is returned for attribute definitions without a name.
+	ErrAttributeNameRequired = newError(KindInvalidArgument, "ATTRIBUTE_NAME_REQUIRED", "category_attribute", "name", "attribute needs a name")
+	// ErrAttributeNameTaken is returned when the category already defines an attribute with the name.
+	ErrAttributeNameTaken = newError(KindAlreadyExists, "ATTRIBUTE_NAME_TAKEN", "category_attribute", "name", "category already defines an attribute with this name")
+	// ErrUnknownAttributeType is returned for attribute types other than string, number, enum and boolean.
+	ErrUnknownAttributeType = newError(KindInvalidArgument, "UNKNOWN_ATTRIBUTE_TYPE", "category_attribute", "type", "unknown attribute type")
+	// ErrInvalidEnumValues is returned when an enum attribute has no values, empty or duplicate values, or a
+	// non-enum attribute has values.
+	ErrInvalidEnumValues = newError(KindInvalidArgument, "INVALID_ENUM_VALUES", "category_attribute", "enum_values", "enum attributes need distinct non-empty values, other types none")
+	// ErrUnitNotAllowed is returned when an attribute other than a number has a unit.
+	ErrUnitNotAllowed = newError(KindInvalidArgument, "UNIT_NOT_ALLOWED", "category_attribute", "unit", "only number attributes have a unit")
+)
+
+// AttributeViolation describes why a product attribute value does not match the category schema.
+type AttributeViolation struct {
+	Name    string
+	Message string
+}
+
+// AddCategoryAttribute defines a new product attribute of a Category.
+func (u *CategoryService) AddCategoryAttribute(attribute *model.CategoryAttribute) (int64, error) {
+	if err := normalizeAttribute(attribute); err != nil {
+		return 0, err
+	}
+	if _, err := u.CategoryRepository.FindCategoryByID(attribute.CategoryID); err != nil {
+		return 0, err
+	}
+	if err := u.checkAttributeName(attribute); err != nil {
+		return 0, err
+	}
+	return u.CategoryRepository.CreateAttribute(attribute)
+}
+
+// UpdateCategoryAttribute replaces the definition of an attribute. The attribute stays with its Category.
+func (u *CategoryService) UpdateCategoryAttribute(attribute *model.CategoryAttribute) error {
+	existing, err := u.CategoryRepository.FindAttributeByID(attribute.ID)
+	if err != nil {
+		return err
+	}
+	attribute.CategoryID = existing.CategoryID
+	if err := normalizeAttribute(attribute); err != nil {
+		return err
+	}
+	if err := u.checkAttributeName(attribute); err != nil {
+		return err
+	}
+	return u.CategoryRepository.UpdateAttribute(attribute)
+}
+
+// DeleteCategoryAttribute removes an attribute definition by its ID.
+func (u *CategoryService) DeleteCategoryAttribute(attributeID int64) error {
+	return u.CategoryRepository.DeleteAttribute(attributeID)
+}
+
+// FindCategoryAttributes retrieves the attributes a Category defines itself, or with inherited set its
+// effective schema: its own attributes together with the inheritable attributes of its ancestors. A
+// category may redefine an inherited attribute, e.g. to restrict its enum values; the definition nearest
+// to the category wins. Inherited attributes come first, from the top-level category down.
+func (u *CategoryService) FindCategoryAttributes(categoryID int64, inherited bool) ([]model.CategoryAttribute, error) {
+	if !inherited {
+		if _, err := u.CategoryRepository.FindCategoryByID(categoryID); err != nil {
+			return nil, err
+		}
+		return u.CategoryRepository.FindAttributesByCategories([]int64{categoryID})
+	}
+
+	path, err := u.GetCategoryAncestors(categoryID, true)
+	if err != nil {
+		return nil, err
+	}
+	categoryIDs := make([]int64, 0, len(path))
+	for _, category := range path {
+		categoryIDs = append(categoryIDs, category.ID)
+	}
+	attributes, err := u.CategoryRepository.FindAttributesByCategories(categoryIDs)
+	if err != nil {
+		return nil, err
+	}
+	byCategory := make(map[int64][]model.CategoryAttribute, len(path))
+	for _, attribute := range attributes {
+		byCategory[attribute.CategoryID] = append(byCategory[attribute.CategoryID], attribute)
+	}
+
+	var schema []model.CategoryAttribute
+	position := make(map[string]int)
+	for _, category := range path {
+		for _, attribute := range byCategory[category.ID] {
+			if category.ID != categoryID && !attribute.Inheritable {
+				continue
+			}
+			if i, ok := position[attribute.Name]; ok {
+				schema[i] = attribute
+				continue
+			}
+			position[attribute.Name] = len(schema)
+			schema = append(schema, attribute)
+		}
+	}
+	return schema, nil
+}
+
+// ValidateCategoryAttributes checks product attribute values against the effective schema of a Category and
+// returns the violations: missing required attributes, values not matching their type and attributes the
+// schema does not define. No violations means the values are valid.
+func (u *CategoryService) ValidateCategoryAttributes(categoryID int64, values map[string]string) ([]AttributeViolation, error) {
+	schema, err := u.FindCategoryAttributes(categoryID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var violations []AttributeViolation
+	defined := make(map[string]bool, len(schema))
+	for _, attribute := range schema {
+		defined[attribute.Name] = true
+		value := strings.TrimSpace(values[attribute.Name])
+		if value == "" {
+			if attribute.Required {
+				violations = append(violations, AttributeViolation{Name: attribute.Name, Message: "is required"})
+			}
+			continue
+		}
+		if message := checkAttributeValue(&attribute, value); message != "" {
+			violations = append(violations, AttributeViolation{Name: attribute.Name, Message: message})
+		}
+	}
+
+	var unknown []string
+	for name := range values {
+		if !defined[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		violations = append(violations, AttributeViolation{Name: name, Message: "is not defined for the category"})
+	}
+	return violations, nil
+}
+
+// Helper function to check that the category does not define another attribute with the same name
+func (u *CategoryService) checkAttributeName(attribute *model.CategoryAttribute) error {
+	attributes, err := u.CategoryRepository.FindAttributesByCategories([]int64{attribute.CategoryID})
+	if err != nil {
+		return err
+	}
+	for _, existing := range attributes {
+		if existing.Name == attribute.Name && existing.ID != attribute.ID {
+			return ErrAttributeNameTaken
+		}
+	}
+	return nil
+}
+
+// Helper function to validate an attribute definition and trim its name, unit and enum values
+func normalizeAttribute(attribute *model.CategoryAttribute) error {
+	attribute.Name = strings.TrimSpace(attribute.Name)
+	if attribute.Name == "" {
+		return ErrAttributeNameRequired
+	}
+
+	switch attribute.Type {
+	case model.AttributeString, model.AttributeNumber, model.AttributeEnum, model.AttributeBoolean:
+	default:
+		return ErrUnknownAttributeType
+	}
+
+	attribute.Unit = strings.TrimSpace(attribute.Unit)
+	if attribute.Unit != "" && attribute.Type != model.AttributeNumber {
+		return ErrUnitNotAllowed
+	}
+
+	if (attribute.Type == model.AttributeEnum) != (len(attribute.EnumValues) > 0) {
+		return ErrInvalidEnumValues
+	}
+	seen := make(map[string]bool, len(attribute.EnumValues))
+	for i, value := range attribute.EnumValues {
+		value = strings.TrimSpace(value)
+		if value == "" || seen[value] {
+			return ErrInvalidEnumValues
+		}
+		seen[value] = true
+		attribute.EnumValues[i] = value
+	}
+	return nil
+}
+
+// Helper function to check a non-empty value against the type of its attribute, returning the violation
+func checkAttributeValue(attribute *model.CategoryAttribute, value string) string {
+	switch attribute.Type {
+	case model.AttributeNumber:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return "is not a number"
+		}
+	case model.AttributeBoolean:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return "is not a boolean"
+		}
+	case model.AttributeEnum:
+		for _, allowed := range attribute.EnumValues {
+			if value == allowed {
+				return ""
+			}
+		}
+		return "must be one of " + strings.Join(attribute.EnumValues, ", ")
+	}
+	return ""
+}
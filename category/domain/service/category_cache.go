@@ -0,0 +1,277 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/log"
+	"github.com/tongs-dev/shopping-platform/category/domain/cache"
+	"github.com/tongs-dev/shopping-platform/category/domain/model"
+)
+
+// CacheMetrics counts the cache hits, misses and backend errors of a CachedCategoryService by lookup.
+type CacheMetrics struct {
+	hits   *prometheus.CounterVec
+	misses *prometheus.CounterVec
+	errors *prometheus.CounterVec
+}
+
+// NewCacheMetrics creates the cache counters and registers them with the registerer.
+func NewCacheMetrics(registerer prometheus.Registerer) (*CacheMetrics, error) {
+	metrics := &CacheMetrics{
+		hits: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "category_cache_hits_total",
+			Help: "Category lookups answered from the cache.",
+		}, []string{"lookup"}),
+		misses: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "category_cache_misses_total",
+			Help: "Category lookups loaded from the database.",
+		}, []string{"lookup"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "category_cache_errors_total",
+			Help: "Failed cache backend operations, which fall back to the database.",
+		}, []string{"operation"}),
+	}
+	for _, collector := range []prometheus.Collector{metrics.hits, metrics.misses, metrics.errors} {
+		if err := registerer.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+	return metrics, nil
+}
+
+// CachedCategoryService decorates an ICategoryService with a read-through cache for the category lookups.
+// Every mutation made through it clears the cache; HandleEvent clears it for the changes of other instances.
+// Lookups listing only visible categories are cached as well, so publish windows take effect within the TTL.
+type CachedCategoryService struct {
+	ICategoryService
+	cache   cache.Cache
+	ttl     time.Duration
+	metrics *CacheMetrics
+
+	// generation is incremented by every invalidation and is part of every cache key
+	generation uint64
+
+	mu      sync.Mutex
+	flights map[string]*cacheFlight
+}
+
+// cacheFlight is a load shared by concurrent lookups of the same key.
+type cacheFlight struct {
+	done  chan struct{}
+	value []byte
+	err   error
+}
+
+// NewCachedCategoryService creates a CachedCategoryService caching the lookups of next in backend for ttl.
+// metrics may be nil.
+func NewCachedCategoryService(next ICategoryService, backend cache.Cache, ttl time.Duration, metrics *CacheMetrics) *CachedCategoryService {
+	return &CachedCategoryService{
+		ICategoryService: next,
+		cache:            backend,
+		ttl:              ttl,
+		metrics:          metrics,
+		flights:          make(map[string]*cacheFlight),
+	}
+}
+
+// FindCategoryByID retrieves a Category by its ID, from the cache if possible.
+func (c *CachedCategoryService) FindCategoryByID(categoryID int64, includeHidden bool) (*model.Category, error) {
+	category := &model.Category{}
+	key := "id:" + strconv.FormatInt(categoryID, 10) + ":" + strconv.FormatBool(includeHidden)
+	err := c.lookup("FindCategoryByID", key, category, func() (interface{}, error) {
+		return c.ICategoryService.FindCategoryByID(categoryID, includeHidden)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+// FindCategoryBySlug retrieves a Category by its slug, from the cache if possible.
+func (c *CachedCategoryService) FindCategoryBySlug(slug string, includeHidden bool) (*model.Category, error) {
+	category := &model.Category{}
+	key := "slug:" + strconv.FormatBool(includeHidden) + ":" + slug
+	err := c.lookup("FindCategoryBySlug", key, category, func() (interface{}, error) {
+		return c.ICategoryService.FindCategoryBySlug(slug, includeHidden)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+// FindAllCategory retrieves all Categories, from the cache if possible.
+func (c *CachedCategoryService) FindAllCategory(includeHidden bool) ([]model.Category, error) {
+	var categories []model.Category
+	err := c.lookup("FindAllCategory", "all:"+strconv.FormatBool(includeHidden), &categories, func() (interface{}, error) {
+		return c.ICategoryService.FindAllCategory(includeHidden)
+	})
+	return categories, err
+}
+
+// FindCategoryByLevel retrieves Categories by their level, from the cache if possible.
+func (c *CachedCategoryService) FindCategoryByLevel(level uint32, includeHidden bool) ([]model.Category, error) {
+	var categories []model.Category
+	key := "level:" + strconv.FormatUint(uint64(level), 10) + ":" + strconv.FormatBool(includeHidden)
+	err := c.lookup("FindCategoryByLevel", key, &categories, func() (interface{}, error) {
+		return c.ICategoryService.FindCategoryByLevel(level, includeHidden)
+	})
+	return categories, err
+}
+
+// FindCategoryByParent retrieves Categories by their parent category ID, from the cache if possible.
+func (c *CachedCategoryService) FindCategoryByParent(parent int64, includeHidden bool) ([]model.Category, error) {
+	var categories []model.Category
+	key := "parent:" + strconv.FormatInt(parent, 10) + ":" + strconv.FormatBool(includeHidden)
+	err := c.lookup("FindCategoryByParent", key, &categories, func() (interface{}, error) {
+		return c.ICategoryService.FindCategoryByParent(parent, includeHidden)
+	})
+	return categories, err
+}
+
+// GetCategoryTree retrieves the nested category hierarchy, from the cache if possible.
+func (c *CachedCategoryService) GetCategoryTree(rootID int64, maxDepth uint32, includeHidden bool) ([]*CategoryNode, error) {
+	var nodes []*CategoryNode
+	key := "tree:" + strconv.FormatInt(rootID, 10) + ":" + strconv.FormatUint(uint64(maxDepth), 10) + ":" +
+		strconv.FormatBool(includeHidden)
+	err := c.lookup("GetCategoryTree", key, &nodes, func() (interface{}, error) {
+		return c.ICategoryService.GetCategoryTree(rootID, maxDepth, includeHidden)
+	})
+	return nodes, err
+}
+
+// AddCategory creates a new Category and clears the cache.
+func (c *CachedCategoryService) AddCategory(category *model.Category) (int64, error) {
+	categoryID, err := c.ICategoryService.AddCategory(category)
+	if err == nil {
+		c.Invalidate()
+	}
+	return categoryID, err
+}
+
+// DeleteCategory removes a Category and clears the cache.
+func (c *CachedCategoryService) DeleteCategory(categoryID int64, policy DeletePolicy) error {
+	err := c.ICategoryService.DeleteCategory(categoryID, policy)
+	if err == nil {
+		c.Invalidate()
+	}
+	return err
+}
+
+// UpdateCategory updates an existing Category and clears the cache.
+func (c *CachedCategoryService) UpdateCategory(category *model.Category) error {
+	err := c.ICategoryService.UpdateCategory(category)
+	if err == nil {
+		c.Invalidate()
+	}
+	return err
+}
+
+// MoveCategory moves a Category with its subtree and clears the cache.
+func (c *CachedCategoryService) MoveCategory(categoryID int64, parentID int64, position int32) (*CategoryNode, error) {
+	node, err := c.ICategoryService.MoveCategory(categoryID, parentID, position)
+	if err == nil {
+		c.Invalidate()
+	}
+	return node, err
+}
+
+// ImportCategories creates the Categories of a file and clears the cache if any were kept.
+func (c *CachedCategoryService) ImportCategories(r io.Reader, options ImportOptions) (*ImportReport, error) {
+	report, err := c.ICategoryService.ImportCategories(r, options)
+	if err == nil && report.Committed {
+		c.Invalidate()
+	}
+	return report, err
+}
+
+// HandleEvent clears the cache for a category change event, including the changes of other instances. It
+// is registered as a subscriber of EventTopic.
+func (c *CachedCategoryService) HandleEvent(ctx context.Context, event *CategoryEvent) error {
+	c.Invalidate()
+	return nil
+}
+
+// Invalidate clears the cache. Loads that started before are not stored.
+func (c *CachedCategoryService) Invalidate() {
+	atomic.AddUint64(&c.generation, 1)
+	if err := c.cache.Clear(); err != nil {
+		c.countError("clear")
+		log.Errorf("Error clearing the category cache: %v", err)
+	}
+}
+
+// Helper function to answer a lookup from the cache, or load it once for all concurrent callers and cache it.
+// Values are cached JSON encoded, so every caller decodes its own copy it may modify. Entries are keyed on
+// the generation the lookup started in, so a value loaded before an invalidation is stored under a key that
+// no later lookup reads.
+func (c *CachedCategoryService) lookup(name string, key string, result interface{}, load func() (interface{}, error)) error {
+	cacheKey := key + "@" + strconv.FormatUint(atomic.LoadUint64(&c.generation), 10)
+	data, ok, err := c.cache.Get(cacheKey)
+	if err != nil {
+		c.countError("get")
+	}
+	if ok {
+		if c.metrics != nil {
+			c.metrics.hits.WithLabelValues(name).Inc()
+		}
+		return json.Unmarshal(data, result)
+	}
+	if c.metrics != nil {
+		c.metrics.misses.WithLabelValues(name).Inc()
+	}
+
+	data, err = c.share(cacheKey, func() ([]byte, error) {
+		value, err := load()
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.cache.Set(cacheKey, data, c.ttl); err != nil {
+			c.countError("set")
+		}
+		return data, nil
+	})
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, result)
+}
+
+// Helper function to run load once for concurrent callers of the same flight key
+func (c *CachedCategoryService) share(flightKey string, load func() ([]byte, error)) ([]byte, error) {
+	c.mu.Lock()
+	if flight, ok := c.flights[flightKey]; ok {
+		c.mu.Unlock()
+		<-flight.done
+		return flight.value, flight.err
+	}
+	flight := &cacheFlight{done: make(chan struct{})}
+	c.flights[flightKey] = flight
+	c.mu.Unlock()
+
+	flight.value, flight.err = load()
+	close(flight.done)
+
+	c.mu.Lock()
+	delete(c.flights, flightKey)
+	c.mu.Unlock()
+	return flight.value, flight.err
+}
+
+// Helper function to count a failed backend operation
+func (c *CachedCategoryService) countError(operation string) {
+	if c.metrics != nil {
+		c.metrics.errors.WithLabelValues(operation).Inc()
+	}
+}
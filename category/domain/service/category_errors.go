@@ -0,0 +1,93 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jinzhu/gorm"
+)
+
+// ErrorKind classifies the errors of the category service by what a caller can do about them.
+type ErrorKind int
+
+const (
+	// KindInternal is a failure of the service or its database, the request may succeed later.
+	KindInternal ErrorKind = iota
+	// KindNotFound reports that a category, or another record the request names, does not exist.
+	KindNotFound
+	// KindAlreadyExists reports that a value that must be unique, e.g. a slug, is already taken.
+	KindAlreadyExists
+	// KindInvalidArgument reports a request that is invalid whatever the stored categories are.
+	KindInvalidArgument
+	// KindConflict reports a change that collided with a concurrent change, retrying may succeed.
+	KindConflict
+	// KindFailedPrecondition reports a change the categories are not in a state for, e.g. deleting a
+	// category that still has subcategories.
+	KindFailedPrecondition
+)
+
+// String returns the name of the kind.
+func (k ErrorKind) String() string {
+	switch k {
+	case KindNotFound:
+		return "not found"
+	case KindAlreadyExists:
+		return "already exists"
+	case KindInvalidArgument:
+		return "invalid argument"
+	case KindConflict:
+		return "conflict"
+	case KindFailedPrecondition:
+		return "failed precondition"
+	default:
+		return "internal"
+	}
+}
+
+// Error is a classified error of the category service. The errors declared by the service are Errors, so
+// callers can compare against them with errors.Is or inspect their kind with KindOf.
+type Error struct {
+	Kind ErrorKind
+	// Reason identifies the error for clients, e.g. "SLUG_TAKEN", and does not change with the message
+	Reason string
+	// Resource is the type of the record the error is about, e.g. "category"
+	Resource string
+	// Field is the request field at fault, if there is one
+	Field   string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// KindOf returns the kind of an error, looking through wrapped errors. Missing records reported by the
+// repository are KindNotFound and duplicate keys rejected by the database KindConflict, since they mean
+// another request got there first. All other errors are KindInternal.
+func KindOf(err error) ErrorKind {
+	var serviceErr *Error
+	if errors.As(err, &serviceErr) {
+		return serviceErr.Kind
+	}
+	if isRecordNotFound(err) {
+		return KindNotFound
+	}
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry {
+		return KindConflict
+	}
+	return KindInternal
+}
+
+// mysqlDuplicateEntry is the MySQL error number of unique key violations.
+const mysqlDuplicateEntry = 1062
+
+// Helper function to declare a classified error
+func newError(kind ErrorKind, reason string, resource string, field string, message string) *Error {
+	return &Error{Kind: kind, Reason: reason, Resource: resource, Field: field, Message: message}
+}
+
+// Helper function to detect record-not-found errors, also when wrapped
+func isRecordNotFound(err error) bool {
+	return gorm.IsRecordNotFoundError(err) || errors.Is(err, gorm.ErrRecordNotFound)
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/micro/go-micro/v2/broker"
+	"github.com/prometheus/common/log"
+	"github.com/tongs-dev/shopping-platform/category/domain/model"
+	"github.com/tongs-dev/shopping-platform/category/domain/repository"
+)
+
+// EventTopic is the broker topic category change events are published to.
+const EventTopic = "go.micro.topic.category"
+
+// EventType is the kind of change a CategoryEvent reports.
+type EventType string
+
+const (
+	// EventCreated is recorded for every created category, with After set.
+	EventCreated EventType = "category.created"
+	// EventUpdated is recorded by UpdateCategory, with Before and After set.
+	EventUpdated EventType = "category.updated"
+	// EventDeleted is recorded for every deleted category, including the subtree of cascading deletes, with Before set.
+	EventDeleted EventType = "category.deleted"
+	// EventMoved is recorded when a category gets a new parent, with Before and After set. The descendants of a
+	// moved category get new levels without events of their own.
+	EventMoved EventType = "category.moved"
+)
+
+// CategoryEvent is the JSON body of the messages published to EventTopic. The message headers repeat the
+// event type and carry the outbox ID as "Event-Id", which consumers use to drop events delivered twice.
+type CategoryEvent struct {
+	Type       EventType       `json:"type"`
+	CategoryID int64           `json:"category_id"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Before     *model.Category `json:"before,omitempty"`
+	After      *model.Category `json:"after,omitempty"`
+}
+
+// outboxLeaseName is the lease the relays of all service instances compete for.
+const outboxLeaseName = "category-outbox"
+
+// EventRelay publishes the events of the outbox to the broker, oldest first. An event that cannot be
+// published stays in the outbox and is retried, together with all later events, on the next pass, so events
+// are delivered at least once and in order. Every service instance runs a relay, but only the one holding
+// the outbox lease publishes; the others take over when it stops renewing the lease.
+type EventRelay struct {
+	repository repository.ICategoryRepository
+	broker     broker.Broker
+	// holder identifies this relay in the outbox lease
+	holder string
+	// Interval is the time between two passes over the outbox
+	Interval time.Duration
+	// BatchSize is the number of events loaded at once
+	BatchSize int
+	// Retention is how long published events stay in the outbox, 0 keeps them
+	Retention time.Duration
+	// LeaseTTL is how long the outbox lease lasts without renewal, the time another instance waits to take over
+	LeaseTTL time.Duration
+}
+
+// NewEventRelay creates an EventRelay publishing the outbox of the repository to the broker.
+func NewEventRelay(categoryRepository repository.ICategoryRepository, eventBroker broker.Broker) *EventRelay {
+	return &EventRelay{
+		repository: categoryRepository,
+		broker:     eventBroker,
+		holder:     relayHolder(),
+		Interval:   time.Second,
+		BatchSize:  100,
+		Retention:  24 * time.Hour,
+		LeaseTTL:   30 * time.Second,
+	}
+}
+
+// Helper function to create a name for the relay that differs between instances, also on the same host
+func relayHolder() string {
+	hostname, _ := os.Hostname()
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return hostname + "-" + strconv.Itoa(os.Getpid())
+	}
+	return hostname + "-" + hex.EncodeToString(suffix)
+}
+
+// Run publishes the pending events every Interval until ctx is done.
+func (r *EventRelay) Run(ctx context.Context) {
+	// Connecting is a no-op if the service already connected the broker
+	if err := r.broker.Connect(); err != nil {
+		log.Errorf("Error connecting to the event broker: %v", err)
+	}
+
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+	for {
+		if _, err := r.PublishPending(); err != nil {
+			log.Errorf("Error publishing category events: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PublishPending publishes the pending events of the outbox and returns how many were published. It stops at
+// the first event that cannot be published, and publishes nothing while another instance holds the lease.
+func (r *EventRelay) PublishPending() (int, error) {
+	published := 0
+	for {
+		// Renewed before every batch, so a long pass keeps the lease
+		leader, err := r.repository.AcquireOutboxLease(outboxLeaseName, r.holder, r.LeaseTTL)
+		if err != nil || !leader {
+			return published, err
+		}
+		events, err := r.repository.FindPendingOutboxEvents(r.BatchSize)
+		if err != nil {
+			return published, err
+		}
+		for _, event := range events {
+			message := &broker.Message{
+				Header: map[string]string{
+					// Lets go-micro subscribers decode the body into a CategoryEvent
+					"Content-Type": "application/json",
+					"Event-Id":     strconv.FormatInt(event.ID, 10),
+					"Event-Type":   event.EventType,
+					"Category-Id":  strconv.FormatInt(event.CategoryID, 10),
+				},
+				Body: []byte(event.Payload),
+			}
+			if err := r.broker.Publish(EventTopic, message); err != nil {
+				if markErr := r.repository.MarkOutboxEventFailed(event.ID); markErr != nil {
+					log.Errorf("Error counting a failed attempt of category event %d: %v", event.ID, markErr)
+				}
+				return published, err
+			}
+			if err := r.repository.MarkOutboxEventPublished(event.ID, time.Now()); err != nil {
+				return published, err
+			}
+			published++
+		}
+		if len(events) < r.BatchSize {
+			break
+		}
+	}
+
+	if r.Retention > 0 {
+		if err := r.repository.DeletePublishedOutboxEvents(time.Now().Add(-r.Retention)); err != nil {
+			return published, err
+		}
+	}
+	return published, nil
+}
+
+// Helper function to run fn with a service whose repository is bound to one transaction
+func (u *CategoryService) inTransaction(fn func(tx *CategoryService) error) error {
+	return u.CategoryRepository.Transaction(func(repo repository.ICategoryRepository) error {
+		return fn(&CategoryService{CategoryRepository: repo, DefaultLocale: u.DefaultLocale})
+	})
+}
+
+// Helper function to write a change event to the outbox, in the transaction of the change
+func (u *CategoryService) recordEvent(eventType EventType, categoryID int64, before *model.Category, after *model.Category) error {
+	event := CategoryEvent{
+		Type:       eventType,
+		CategoryID: categoryID,
+		OccurredAt: time.Now().UTC(),
+		Before:     before,
+		After:      after,
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return u.CategoryRepository.CreateOutboxEvent(&model.OutboxEvent{
+		EventType:  string(eventType),
+		CategoryID: categoryID,
+		Payload:    string(payload),
+		CreatedAt:  event.OccurredAt,
+	})
+}
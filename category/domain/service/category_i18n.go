@@ -0,0 +1,136 @@
+package service
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/tongs-dev/shopping-platform/category/domain/model"
+)
+
+var (
+	// ErrInvalidLocale is returned for locales that are not language tags such as "de" or "de-AT".
+	ErrInvalidLocale = newError(KindInvalidArgument, "INVALID_LOCALE", "category_translation", "locale", "invalid locale")
+	// ErrDefaultLocaleTranslation is returned when translating into the default locale, whose name and
+	// description are the category's own fields.
+	ErrDefaultLocaleTranslation = newError(KindInvalidArgument, "DEFAULT_LOCALE_TRANSLATION", "category_translation", "locale", "the default locale is edited through UpdateCategory")
+	// ErrEmptyTranslation is returned for translations without a category name.
+	ErrEmptyTranslation = newError(KindInvalidArgument, "TRANSLATION_NAME_REQUIRED", "category_translation", "category_name", "translation needs a category name")
+)
+
+// localePattern matches a language with optional subtags, e.g. "pt", "pt-BR" or "zh-Hant-TW".
+var localePattern = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})*$`)
+
+// NormalizeLocale validates a locale and brings it into the form translations are stored under: a lowercase
+// language, a title-case script and an uppercase region, separated by hyphens ("pt_br" becomes "pt-BR").
+func NormalizeLocale(locale string) (string, error) {
+	locale = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+	if !localePattern.MatchString(locale) {
+		return "", ErrInvalidLocale
+	}
+
+	parts := strings.Split(locale, "-")
+	for i := 1; i < len(parts); i++ {
+		switch len(parts[i]) {
+		case 2:
+			parts[i] = strings.ToUpper(parts[i])
+		case 4:
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "-"), nil
+}
+
+// Helper function to list the locales tried for a requested locale, most specific first ("de-AT", "de")
+func localeFallbacks(locale string) []string {
+	var fallbacks []string
+	for {
+		fallbacks = append(fallbacks, locale)
+		index := strings.LastIndex(locale, "-")
+		if index < 0 {
+			return fallbacks
+		}
+		locale = locale[:index]
+	}
+}
+
+// UpsertCategoryTranslation creates or replaces the name and description of a Category in a locale.
+func (u *CategoryService) UpsertCategoryTranslation(translation *model.CategoryTranslation) error {
+	locale, err := NormalizeLocale(translation.Locale)
+	if err != nil {
+		return err
+	}
+	if locale == u.DefaultLocale {
+		return ErrDefaultLocaleTranslation
+	}
+	translation.Locale = locale
+	if strings.TrimSpace(translation.CategoryName) == "" {
+		return ErrEmptyTranslation
+	}
+
+	if _, err := u.CategoryRepository.FindCategoryByID(translation.CategoryID); err != nil {
+		return err
+	}
+	return u.CategoryRepository.UpsertTranslation(translation)
+}
+
+// DeleteCategoryTranslation deletes the translation of a Category in a locale, so the locale falls back again.
+func (u *CategoryService) DeleteCategoryTranslation(categoryID int64, locale string) error {
+	locale, err := NormalizeLocale(locale)
+	if err != nil {
+		return err
+	}
+	return u.CategoryRepository.DeleteTranslation(categoryID, locale)
+}
+
+// LocalizeCategories replaces the names and descriptions of the categories with their translations in the
+// locale. A missing translation falls back to the language without region ("de-AT" to "de") and then to the
+// default locale, i.e. the category's own fields. An empty locale means the default locale. Every category's
+// Locale is set to the locale its name is in.
+func (u *CategoryService) LocalizeCategories(categories []*model.Category, locale string) error {
+	for _, category := range categories {
+		category.Locale = u.DefaultLocale
+	}
+	if locale == "" || len(categories) == 0 {
+		return nil
+	}
+
+	locale, err := NormalizeLocale(locale)
+	if err != nil {
+		return err
+	}
+	if locale == u.DefaultLocale {
+		return nil
+	}
+
+	categoryIDs := make([]int64, 0, len(categories))
+	for _, category := range categories {
+		categoryIDs = append(categoryIDs, category.ID)
+	}
+	locales := localeFallbacks(locale)
+	translations, err := u.CategoryRepository.FindTranslations(categoryIDs, locales)
+	if err != nil {
+		return err
+	}
+
+	byLocale := make(map[string]map[int64]model.CategoryTranslation, len(locales))
+	for _, translation := range translations {
+		if byLocale[translation.Locale] == nil {
+			byLocale[translation.Locale] = make(map[int64]model.CategoryTranslation)
+		}
+		byLocale[translation.Locale][translation.CategoryID] = translation
+	}
+
+	for _, category := range categories {
+		for _, candidate := range locales {
+			translation, ok := byLocale[candidate][category.ID]
+			if !ok {
+				continue
+			}
+			category.CategoryName = translation.CategoryName
+			category.CategoryDescription = translation.CategoryDescription
+			category.Locale = candidate
+			break
+		}
+	}
+	return nil
+}
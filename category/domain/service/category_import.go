@@ -0,0 +1,520 @@
+package service
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/tongs-dev/shopping-platform/category/domain/model"
+	"github.com/tongs-dev/shopping-platform/category/domain/repository"
+)
+
+// DataFormat is the file format of category imports and exports.
+type DataFormat int32
+
+const (
+	// FormatCSV is a CSV file with a header row naming the CategoryRecord columns.
+	FormatCSV DataFormat = iota
+	// FormatJSON is a JSON array of CategoryRecord objects.
+	FormatJSON
+)
+
+var (
+	// ErrUnknownDataFormat is returned for a DataFormat outside the defined values.
+	ErrUnknownDataFormat = newError(KindInvalidArgument, "UNKNOWN_DATA_FORMAT", "", "format", "unknown data format")
+	// ErrInvalidImportFile is returned when an import file cannot be read at all, e.g. for a malformed header.
+	ErrInvalidImportFile = newError(KindInvalidArgument, "INVALID_IMPORT_FILE", "", "data", "invalid import file")
+	// errImportRollback rolls back the transaction of dry runs and failed all-or-nothing imports.
+	errImportRollback = errors.New("import rolled back")
+)
+
+// recordColumns are the CSV columns of a CategoryRecord, in export order. Only "name" is required on import.
+var recordColumns = []string{
+	"name", "parent", "slug", "description", "image", "hidden",
+	"publish_at", "unpublish_at", "seo_title", "seo_keywords", "seo_description",
+}
+
+// CategoryRecord is one category in an import or export file. Parent references the parent category by slug
+// or name, either an existing category or another record of the same file; empty means top level.
+type CategoryRecord struct {
+	Name           string `json:"name"`
+	Parent         string `json:"parent,omitempty"`
+	Slug           string `json:"slug,omitempty"`
+	Description    string `json:"description,omitempty"`
+	Image          string `json:"image,omitempty"`
+	Hidden         bool   `json:"hidden,omitempty"`
+	PublishAt      string `json:"publish_at,omitempty"`
+	UnpublishAt    string `json:"unpublish_at,omitempty"`
+	SeoTitle       string `json:"seo_title,omitempty"`
+	SeoKeywords    string `json:"seo_keywords,omitempty"`
+	SeoDescription string `json:"seo_description,omitempty"`
+}
+
+// ImportOptions control how ImportCategories applies a file.
+type ImportOptions struct {
+	Format DataFormat
+	// DryRun validates every row, including parents and slugs, without keeping any change
+	DryRun bool
+	// AllOrNothing keeps no change unless every row is imported
+	AllOrNothing bool
+}
+
+// ImportRowError describes why one row of an import file was not imported.
+type ImportRowError struct {
+	// Row is the 1-based data row (CSV, not counting the header) or array element (JSON)
+	Row          int
+	CategoryName string
+	Message      string
+}
+
+// ImportReport summarizes an import.
+type ImportReport struct {
+	Rows int
+	// Imported counts the rows that were created, or in a dry run or rolled back import would have been
+	Imported int
+	// Committed reports whether the imported rows were kept
+	Committed bool
+	Errors    []ImportRowError
+}
+
+// importRow is a record together with its position in the file.
+type importRow struct {
+	row    int
+	record CategoryRecord
+}
+
+// ImportCategories creates the categories of a CSV or JSON file read from r. Each row is created as soon as
+// it is read; rows referencing a parent defined later in the file wait until the end of the file. Rows failing
+// validation are reported in the ImportReport; the error is only set when the file cannot be read or the
+// database fails, in which case a plain import keeps the rows created before. With DryRun or AllOrNothing the
+// rows are created in one transaction that is rolled back for dry runs and whenever a row fails.
+func (u *CategoryService) ImportCategories(r io.Reader, options ImportOptions) (*ImportReport, error) {
+	report := &ImportReport{}
+	records, err := newRecordReader(r, options.Format, report)
+	if err != nil {
+		return nil, err
+	}
+
+	if !options.DryRun && !options.AllOrNothing {
+		if err := u.importRows(records, report); err != nil {
+			return nil, err
+		}
+		report.Committed = report.Imported > 0
+		return report, nil
+	}
+
+	err = u.CategoryRepository.Transaction(func(repo repository.ICategoryRepository) error {
+		tx := &CategoryService{CategoryRepository: repo, DefaultLocale: u.DefaultLocale}
+		if err := tx.importRows(records, report); err != nil {
+			return err
+		}
+		if options.DryRun || len(report.Errors) > 0 {
+			return errImportRollback
+		}
+		return nil
+	})
+	if err != nil && err != errImportRollback {
+		return nil, err
+	}
+	report.Committed = err == nil && report.Imported > 0
+	return report, nil
+}
+
+// ExportCategories writes all categories to w as CSV or JSON, parents before their children and siblings
+// in sort order, referencing parents by slug so the file can be imported again. Categories whose parent
+// does not exist are written as top-level categories, so no category is left out.
+func (u *CategoryService) ExportCategories(w io.Writer, format DataFormat) error {
+	if format != FormatCSV && format != FormatJSON {
+		return ErrUnknownDataFormat
+	}
+	categories, err := u.CategoryRepository.FindAll(listFilter(true))
+	if err != nil {
+		return err
+	}
+
+	children := make(map[int64][]*model.Category, len(categories))
+	for i := range categories {
+		category := &categories[i]
+		children[category.CategoryParent] = append(children[category.CategoryParent], category)
+	}
+
+	records := make([]CategoryRecord, 0, len(categories))
+	exported := make(map[int64]bool, len(categories))
+	var walk func(categories []*model.Category, parent string)
+	walk = func(categories []*model.Category, parent string) {
+		for _, category := range categories {
+			if exported[category.ID] {
+				continue
+			}
+			exported[category.ID] = true
+			records = append(records, newCategoryRecord(category, parent))
+			walk(children[category.ID], category.Slug)
+		}
+	}
+	walk(children[0], "")
+	// Whatever the walk from the top level did not reach has a missing parent or sits in a parent cycle
+	for i := range categories {
+		walk([]*model.Category{&categories[i]}, "")
+	}
+
+	if format == FormatJSON {
+		return writeJSONRecords(w, records)
+	}
+	return writeCSVRecords(w, records)
+}
+
+// Helper function to create the rows while they are read, retrying rows whose parent is created later in the
+// file until no further row can be created
+func (u *CategoryService) importRows(records recordReader, report *ImportReport) error {
+	// created maps the names and slugs of the rows created so far to their IDs
+	created := make(map[string]int64)
+	var waiting []importRow
+	for {
+		row, err := records.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		imported, err := u.importRow(row, created, report)
+		if err != nil {
+			return err
+		}
+		if !imported {
+			waiting = append(waiting, row)
+		}
+	}
+
+	for progress := true; progress && len(waiting) > 0; {
+		progress = false
+		var stillWaiting []importRow
+		for _, row := range waiting {
+			imported, err := u.importRow(row, created, report)
+			if err != nil {
+				return err
+			}
+			if !imported {
+				stillWaiting = append(stillWaiting, row)
+				continue
+			}
+			progress = true
+		}
+		waiting = stillWaiting
+	}
+
+	for _, row := range waiting {
+		report.addError(row, fmt.Sprintf("parent %q not found", row.record.Parent))
+	}
+	sort.SliceStable(report.Errors, func(i, j int) bool { return report.Errors[i].Row < report.Errors[j].Row })
+	return nil
+}
+
+// Helper function to create one row, reporting false without an error when its parent does not exist yet
+func (u *CategoryService) importRow(row importRow, created map[string]int64, report *ImportReport) (bool, error) {
+	parentID, found, err := u.resolveParent(row.record.Parent, created)
+	if err != nil || !found {
+		return false, err
+	}
+
+	var categoryID int64
+	category, err := row.record.toCategory(parentID)
+	if err == nil {
+		categoryID, err = u.AddCategory(category)
+	}
+	if err != nil {
+		report.addError(row, err.Error())
+		return true, nil
+	}
+	created[category.CategoryName] = categoryID
+	created[category.Slug] = categoryID
+	report.Imported++
+	return true, nil
+}
+
+// Helper function to find the ID of a parent referenced by slug or name, created in this import or existing
+func (u *CategoryService) resolveParent(reference string, created map[string]int64) (int64, bool, error) {
+	if reference == "" {
+		return 0, true, nil
+	}
+	if id, ok := created[reference]; ok {
+		return id, true, nil
+	}
+
+	category, err := u.CategoryRepository.FindCategoryBySlug(reference)
+	if err == nil {
+		return category.ID, true, nil
+	}
+	if !gorm.IsRecordNotFoundError(err) {
+		return 0, false, err
+	}
+
+	category, err = u.CategoryRepository.FindCategoryByName(reference)
+	if gorm.IsRecordNotFoundError(err) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return category.ID, true, nil
+}
+
+// Helper function to record a failed row
+func (r *ImportReport) addError(row importRow, message string) {
+	r.Errors = append(r.Errors, ImportRowError{Row: row.row, CategoryName: row.record.Name, Message: message})
+}
+
+// Helper function to turn a record into a category under parentID
+func (r CategoryRecord) toCategory(parentID int64) (*model.Category, error) {
+	if strings.TrimSpace(r.Name) == "" {
+		return nil, errors.New("name is required")
+	}
+	category := &model.Category{
+		CategoryName:        r.Name,
+		CategoryParent:      parentID,
+		CategoryImage:       r.Image,
+		CategoryDescription: r.Description,
+		Hidden:              r.Hidden,
+		Slug:                r.Slug,
+		SeoTitle:            r.SeoTitle,
+		SeoKeywords:         r.SeoKeywords,
+		SeoDescription:      r.SeoDescription,
+	}
+
+	var err error
+	if category.PublishAt, err = parseRecordTime("publish_at", r.PublishAt); err != nil {
+		return nil, err
+	}
+	if category.UnpublishAt, err = parseRecordTime("unpublish_at", r.UnpublishAt); err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+// Helper function to parse an optional RFC 3339 timestamp column
+func parseRecordTime(column string, value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not an RFC 3339 timestamp: %q", column, value)
+	}
+	return &parsed, nil
+}
+
+// Helper function to turn a category into an export record
+func newCategoryRecord(category *model.Category, parent string) CategoryRecord {
+	record := CategoryRecord{
+		Name:           category.CategoryName,
+		Parent:         parent,
+		Slug:           category.Slug,
+		Description:    category.CategoryDescription,
+		Image:          category.CategoryImage,
+		Hidden:         category.Hidden,
+		SeoTitle:       category.SeoTitle,
+		SeoKeywords:    category.SeoKeywords,
+		SeoDescription: category.SeoDescription,
+	}
+	if category.PublishAt != nil {
+		record.PublishAt = category.PublishAt.Format(time.RFC3339)
+	}
+	if category.UnpublishAt != nil {
+		record.UnpublishAt = category.UnpublishAt.Format(time.RFC3339)
+	}
+	return record
+}
+
+// recordReader reads the records of an import file one at a time. Rows that cannot be parsed are added to
+// the report as row errors and skipped.
+type recordReader interface {
+	// next returns the next row of the file, io.EOF after the last one
+	next() (importRow, error)
+}
+
+// Helper function to create the reader of a file, reading the CSV header or the opening bracket of a JSON array
+func newRecordReader(r io.Reader, format DataFormat, report *ImportReport) (recordReader, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVRecordReader(r, report)
+	case FormatJSON:
+		return newJSONRecordReader(r, report)
+	default:
+		return nil, ErrUnknownDataFormat
+	}
+}
+
+// csvRecordReader reads CSV records, matching columns by the header row.
+type csvRecordReader struct {
+	reader  *csv.Reader
+	header  []string
+	columns map[string]int
+	number  int
+	report  *ImportReport
+}
+
+// Helper function to read the header row of a CSV file
+func newCSVRecordReader(r io.Reader, report *ImportReport) (*csvRecordReader, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading header: %v", ErrInvalidImportFile, err)
+	}
+
+	known := make(map[string]bool, len(recordColumns))
+	for _, column := range recordColumns {
+		known[column] = true
+	}
+	columns := make(map[string]int, len(header))
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(column))
+		if !known[column] {
+			return nil, fmt.Errorf("%w: unknown column %q", ErrInvalidImportFile, column)
+		}
+		columns[column] = i
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, fmt.Errorf("%w: missing column \"name\"", ErrInvalidImportFile)
+	}
+	return &csvRecordReader{reader: reader, header: header, columns: columns, report: report}, nil
+}
+
+// next reads the next CSV row that can be parsed.
+func (c *csvRecordReader) next() (importRow, error) {
+	for {
+		fields, err := c.reader.Read()
+		if err == io.EOF {
+			return importRow{}, io.EOF
+		}
+		c.number++
+		c.report.Rows++
+		if parseErr, ok := err.(*csv.ParseError); ok && parseErr.Err == csv.ErrFieldCount {
+			c.report.addError(importRow{row: c.number}, fmt.Sprintf("expected %d fields, got %d", len(c.header), len(fields)))
+			continue
+		}
+		if err != nil {
+			return importRow{}, fmt.Errorf("%w: %v", ErrInvalidImportFile, err)
+		}
+
+		field := func(column string) string {
+			if i, ok := c.columns[column]; ok {
+				return strings.TrimSpace(fields[i])
+			}
+			return ""
+		}
+		record := CategoryRecord{
+			Name:           field("name"),
+			Parent:         field("parent"),
+			Slug:           field("slug"),
+			Description:    field("description"),
+			Image:          field("image"),
+			PublishAt:      field("publish_at"),
+			UnpublishAt:    field("unpublish_at"),
+			SeoTitle:       field("seo_title"),
+			SeoKeywords:    field("seo_keywords"),
+			SeoDescription: field("seo_description"),
+		}
+		if hidden := field("hidden"); hidden != "" {
+			record.Hidden, err = strconv.ParseBool(hidden)
+			if err != nil {
+				c.report.addError(importRow{row: c.number, record: record}, fmt.Sprintf("hidden is not a boolean: %q", hidden))
+				continue
+			}
+		}
+		return importRow{row: c.number, record: record}, nil
+	}
+}
+
+// jsonRecordReader reads a JSON array of records one element at a time.
+type jsonRecordReader struct {
+	decoder *json.Decoder
+	number  int
+	report  *ImportReport
+}
+
+// Helper function to read the opening bracket of a JSON array
+func newJSONRecordReader(r io.Reader, report *ImportReport) (*jsonRecordReader, error) {
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+		return nil, fmt.Errorf("%w: expected a JSON array", ErrInvalidImportFile)
+	}
+	return &jsonRecordReader{decoder: decoder, report: report}, nil
+}
+
+// next decodes the next array element that can be parsed.
+func (j *jsonRecordReader) next() (importRow, error) {
+	for j.decoder.More() {
+		j.number++
+		j.report.Rows++
+		var record CategoryRecord
+		err := j.decoder.Decode(&record)
+		if _, ok := err.(*json.SyntaxError); ok || err == io.EOF || err == io.ErrUnexpectedEOF {
+			return importRow{}, fmt.Errorf("%w: %v", ErrInvalidImportFile, err)
+		}
+		if err != nil {
+			// The element was read completely, only its content is wrong
+			j.report.addError(importRow{row: j.number, record: record}, err.Error())
+			continue
+		}
+		record.Name = strings.TrimSpace(record.Name)
+		return importRow{row: j.number, record: record}, nil
+	}
+	if _, err := j.decoder.Token(); err != nil {
+		return importRow{}, fmt.Errorf("%w: %v", ErrInvalidImportFile, err)
+	}
+	return importRow{}, io.EOF
+}
+
+// Helper function to write records as CSV with a header row
+func writeCSVRecords(w io.Writer, records []CategoryRecord) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(recordColumns); err != nil {
+		return err
+	}
+	for _, record := range records {
+		err := writer.Write([]string{
+			record.Name, record.Parent, record.Slug, record.Description, record.Image,
+			strconv.FormatBool(record.Hidden), record.PublishAt, record.UnpublishAt,
+			record.SeoTitle, record.SeoKeywords, record.SeoDescription,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// Helper function to write records as a JSON array with one element per line
+func writeJSONRecords(w io.Writer, records []CategoryRecord) error {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+	for i, record := range records {
+		line, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		separator := "\n"
+		if i > 0 {
+			separator = ",\n"
+		}
+		if _, err := io.WriteString(w, separator); err != nil {
+			return err
+		}
+		if _, err := w.Write(line); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "\n]\n")
+	return err
+}
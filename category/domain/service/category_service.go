@@ -1,8 +1,34 @@
 package service
 
 import (
+	"github.com/jinzhu/gorm"
 	"github.com/tongs-dev/shopping-platform/category/domain/model"
 	"github.com/tongs-dev/shopping-platform/category/domain/repository"
+	"io"
+	"time"
+)
+
+var (
+	// ErrParentNotFound is returned when the parent of a category does not exist.
+	ErrParentNotFound = newError(KindFailedPrecondition, "PARENT_NOT_FOUND", "category", "", "parent category not found")
+	// ErrCategoryCycle is returned when a category would become its own ancestor.
+	ErrCategoryCycle = newError(KindFailedPrecondition, "CATEGORY_CYCLE", "category", "", "category cannot be moved under itself or its descendants")
+	// ErrCategoryHasChildren is returned when deleting a category with subcategories under DeleteReject.
+	ErrCategoryHasChildren = newError(KindFailedPrecondition, "CATEGORY_HAS_CHILDREN", "category", "", "category has subcategories")
+	// ErrUnknownDeletePolicy is returned for a DeletePolicy outside the defined values.
+	ErrUnknownDeletePolicy = newError(KindInvalidArgument, "UNKNOWN_DELETE_POLICY", "", "policy", "unknown delete policy")
+)
+
+// DeletePolicy decides what happens to the subcategories of a deleted category.
+type DeletePolicy int32
+
+const (
+	// DeleteReject refuses to delete a category that has subcategories.
+	DeleteReject DeletePolicy = iota
+	// DeleteCascade deletes the category together with its whole subtree.
+	DeleteCascade
+	// DeleteReparent moves the subcategories up to the parent of the deleted category.
+	DeleteReparent
 )
 
 // ICategoryService defines the interface for category data operations.
@@ -10,75 +36,403 @@ type ICategoryService interface {
 	// AddCategory creates a new Category.
 	AddCategory(*model.Category) (int64, error)
 
-	// DeleteCategory removes a Category by its ID.
-	DeleteCategory(int64) error
+	// DeleteCategory removes a Category by its ID, handling its subcategories according to the policy.
+	DeleteCategory(int64, DeletePolicy) error
 
 	// UpdateCategory updates an existing Category's information.
 	UpdateCategory(*model.Category) error
 
-	// FindCategoryByID retrieves a Category by its ID.
-	FindCategoryByID(int64) (*model.Category, error)
+	// FindCategoryByID retrieves a Category by its ID, hidden and unpublished ones only if asked to.
+	FindCategoryByID(categoryID int64, includeHidden bool) (*model.Category, error)
+
+	// FindAllCategory retrieves all Categories, including hidden and unpublished ones only if asked to.
+	FindAllCategory(includeHidden bool) ([]model.Category, error)
+
+	// FindCategoryByName retrieves a Category by its name, hidden and unpublished ones only if asked to.
+	FindCategoryByName(categoryName string, includeHidden bool) (*model.Category, error)
+
+	// FindCategoryBySlug retrieves a Category by its slug, hidden and unpublished ones only if asked to.
+	FindCategoryBySlug(slug string, includeHidden bool) (*model.Category, error)
+
+	// ResolveCategoryPath retrieves the categories along a slug path such as "/men/shoes/running", through
+	// hidden and unpublished ones only if asked to.
+	ResolveCategoryPath(path string, includeHidden bool) ([]model.Category, error)
+
+	// FindCategoryByLevel retrieves Categories by their level, including hidden and unpublished ones only if asked to.
+	FindCategoryByLevel(level uint32, includeHidden bool) ([]model.Category, error)
+
+	// FindCategoryByParent retrieves Categories by their parent category ID, including hidden and unpublished ones only if asked to.
+	FindCategoryByParent(parent int64, includeHidden bool) ([]model.Category, error)
+
+	// GetCategoryTree retrieves the nested category hierarchy, optionally rooted at a category and depth-limited,
+	// including hidden and unpublished subtrees only if asked to.
+	GetCategoryTree(rootID int64, maxDepth uint32, includeHidden bool) ([]*CategoryNode, error)
+
+	// GetCategoryAncestors retrieves the path from the top-level category down to a category, through hidden
+	// and unpublished ones only if asked to.
+	GetCategoryAncestors(categoryID int64, includeHidden bool) ([]model.Category, error)
 
-	// FindAllCategory retrieves all Categories.
-	FindAllCategory() ([]model.Category, error)
+	// MoveCategory moves a Category with its subtree under a new parent and returns the moved subtree.
+	MoveCategory(categoryID int64, parentID int64, position int32) (*CategoryNode, error)
 
-	// FindCategoryByName retrieves a Category by its name.
-	FindCategoryByName(string) (*model.Category, error)
+	// UpsertCategoryTranslation creates or replaces the name and description of a Category in a locale.
+	UpsertCategoryTranslation(*model.CategoryTranslation) error
 
-	// FindCategoryByLevel retrieves Categories by their level.
-	FindCategoryByLevel(uint32) ([]model.Category, error)
+	// DeleteCategoryTranslation deletes the translation of a Category in a locale.
+	DeleteCategoryTranslation(categoryID int64, locale string) error
 
-	// FindCategoryByParent retrieves Categories by their parent category ID.
-	FindCategoryByParent(int64) ([]model.Category, error)
+	// LocalizeCategories translates the names and descriptions of Categories into a locale, with fallback.
+	LocalizeCategories(categories []*model.Category, locale string) error
+
+	// AddCategoryAttribute defines a new product attribute of a Category.
+	AddCategoryAttribute(*model.CategoryAttribute) (int64, error)
+
+	// UpdateCategoryAttribute replaces the definition of an attribute.
+	UpdateCategoryAttribute(*model.CategoryAttribute) error
+
+	// DeleteCategoryAttribute removes an attribute definition by its ID.
+	DeleteCategoryAttribute(int64) error
+
+	// FindCategoryAttributes retrieves the attributes of a Category, with inherited ones if asked to.
+	FindCategoryAttributes(categoryID int64, inherited bool) ([]model.CategoryAttribute, error)
+
+	// ValidateCategoryAttributes checks product attribute values against the attribute schema of a Category.
+	ValidateCategoryAttributes(categoryID int64, values map[string]string) ([]AttributeViolation, error)
+
+	// ImportCategories creates the Categories of a CSV or JSON file and reports the rows that failed.
+	ImportCategories(io.Reader, ImportOptions) (*ImportReport, error)
+
+	// ExportCategories writes all Categories as a CSV or JSON file that ImportCategories accepts.
+	ExportCategories(io.Writer, DataFormat) error
 }
 
-// NewCategoryService creates and returns a new instance of CategoryService.
-func NewCategoryService(categoryRepository repository.ICategoryRepository) ICategoryService {
-	return &CategoryService{CategoryRepository: categoryRepository}
+// NewCategoryService creates and returns a new instance of CategoryService. defaultLocale (e.g. "en") is the
+// locale of the categories' own names and descriptions.
+func NewCategoryService(categoryRepository repository.ICategoryRepository, defaultLocale string) ICategoryService {
+	return &CategoryService{CategoryRepository: categoryRepository, DefaultLocale: defaultLocale}
 }
 
 // CategoryService implements the ICategoryService interface and handles
 // the logic for managing Categories by calling the repository methods.
 type CategoryService struct {
 	CategoryRepository repository.ICategoryRepository
+	DefaultLocale      string
 }
 
-// AddCategory creates a new Category in the repository.
+// AddCategory creates a new Category in the repository. The parent must exist and the level is
+// derived from it, top-level categories (parent 0) being level 1. Without a sort order the category is
+// placed after its last sibling, without a slug one is generated from the name. A created event is
+// recorded in the same transaction.
 func (u *CategoryService) AddCategory(category *model.Category) (int64, error) {
+	var categoryID int64
+	err := u.inTransaction(func(tx *CategoryService) error {
+		var err error
+		if categoryID, err = tx.createCategory(category); err != nil {
+			return err
+		}
+		return tx.recordEvent(EventCreated, categoryID, nil, category)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return categoryID, nil
+}
+
+// Helper function to derive the level, sort order and slug of a new category and create it
+func (u *CategoryService) createCategory(category *model.Category) (int64, error) {
+	level, err := u.childLevel(category.CategoryParent)
+	if err != nil {
+		return 0, err
+	}
+	category.CategoryLevel = level
+
+	if category.SortOrder == 0 {
+		siblings, err := u.CategoryRepository.FindCategoryByParent(category.CategoryParent, repository.CategoryFilter{IncludeHidden: true})
+		if err != nil {
+			return 0, err
+		}
+		category.SortOrder = 1
+		for _, sibling := range siblings {
+			if sibling.SortOrder >= category.SortOrder {
+				category.SortOrder = sibling.SortOrder + 1
+			}
+		}
+	}
+
+	if err := u.assignSlug(category); err != nil {
+		return 0, err
+	}
 	return u.CategoryRepository.CreateCategory(category)
 }
 
-// DeleteCategory deletes a Category by its ID from the repository.
-func (u *CategoryService) DeleteCategory(categoryID int64) error {
-	return u.CategoryRepository.DeleteCategoryByID(categoryID)
+// DeleteCategory deletes a Category by its ID from the repository. DeleteReject fails with
+// ErrCategoryHasChildren while the category has subcategories, DeleteCascade deletes the whole subtree and
+// DeleteReparent moves the subcategories up to the parent of the deleted category. A deleted event is
+// recorded for every deleted category and a moved event for every reparented subcategory.
+func (u *CategoryService) DeleteCategory(categoryID int64, policy DeletePolicy) error {
+	switch policy {
+	case DeleteReject, DeleteCascade, DeleteReparent:
+	default:
+		return ErrUnknownDeletePolicy
+	}
+
+	return u.inTransaction(func(tx *CategoryService) error {
+		switch policy {
+		case DeleteReject:
+			return tx.deleteLeaf(categoryID)
+		case DeleteCascade:
+			return tx.deleteTree(categoryID)
+		default:
+			return tx.deleteReparent(categoryID)
+		}
+	})
 }
 
-// UpdateCategory updates an existing Category in the repository.
+// Helper function to delete a category without subcategories
+func (u *CategoryService) deleteLeaf(categoryID int64) error {
+	children, err := u.CategoryRepository.FindCategoryByParent(categoryID, repository.CategoryFilter{IncludeHidden: true})
+	if err != nil {
+		return err
+	}
+	if len(children) > 0 {
+		return ErrCategoryHasChildren
+	}
+	category, err := u.CategoryRepository.FindCategoryByID(categoryID)
+	if err != nil {
+		return err
+	}
+	if err := u.CategoryRepository.DeleteCategoryByID(categoryID); err != nil {
+		return err
+	}
+	return u.recordEvent(EventDeleted, categoryID, category, nil)
+}
+
+// Helper function to delete a category with its whole subtree
+func (u *CategoryService) deleteTree(categoryID int64) error {
+	nodes, err := u.GetCategoryTree(categoryID, 0, true)
+	if err != nil {
+		return err
+	}
+	if err := u.CategoryRepository.DeleteCategoryTree(categoryID); err != nil {
+		return err
+	}
+
+	// Records the deleted categories parents first
+	for len(nodes) > 0 {
+		node := nodes[0]
+		nodes = append(nodes[1:], node.Children...)
+		category := node.Category
+		if err := u.recordEvent(EventDeleted, category.ID, &category, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Helper function to delete a category and move its subcategories up to its parent
+func (u *CategoryService) deleteReparent(categoryID int64) error {
+	category, err := u.CategoryRepository.FindCategoryByID(categoryID)
+	if err != nil {
+		return err
+	}
+	level, err := u.childLevel(category.CategoryParent)
+	if err != nil {
+		return err
+	}
+	children, err := u.CategoryRepository.FindCategoryByParent(categoryID, repository.CategoryFilter{IncludeHidden: true})
+	if err != nil {
+		return err
+	}
+	if err := u.CategoryRepository.DeleteCategoryPromoteChildren(categoryID, category.CategoryParent, level); err != nil {
+		return err
+	}
+
+	if err := u.recordEvent(EventDeleted, categoryID, category, nil); err != nil {
+		return err
+	}
+	for i := range children {
+		before := children[i]
+		after := before
+		after.CategoryParent = category.CategoryParent
+		after.CategoryLevel = level
+		if err := u.recordEvent(EventMoved, before.ID, &before, &after); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpdateCategory updates an existing Category in the repository. A changed parent must exist and must not
+// be the category itself or one of its descendants; the levels of the moved subtree are recomputed. A changed
+// sort order is taken as the new 1-based sibling position and the siblings are renumbered around it, a moved
+// category without one is appended after its new siblings. The slug is kept unless a new one is given,
+// renaming a category does not change its URL. An updated event is recorded in the same transaction, and a
+// moved event as well if the parent changed.
 func (u *CategoryService) UpdateCategory(category *model.Category) error {
-	return u.CategoryRepository.UpdateCategory(category)
+	return u.inTransaction(func(tx *CategoryService) error {
+		existing, err := tx.CategoryRepository.FindCategoryByID(category.ID)
+		if err != nil {
+			return err
+		}
+
+		if category.Slug != "" {
+			if err := tx.claimSlug(category, category.ID); err != nil {
+				return err
+			}
+		}
+
+		level, err := tx.childLevel(category.CategoryParent)
+		if err != nil {
+			return err
+		}
+		category.CategoryLevel = level
+
+		moved := category.CategoryParent != existing.CategoryParent
+		if moved {
+			if err := tx.checkNoCycle(category.ID, category.CategoryParent); err != nil {
+				return err
+			}
+		}
+		// Positions are only changed by renumbering the siblings, the update below leaves the sort order alone
+		reordered := category.SortOrder != 0 && category.SortOrder != existing.SortOrder
+		if moved || reordered {
+			var position int32
+			if reordered {
+				position = category.SortOrder
+			}
+			if err := tx.CategoryRepository.MoveCategory(category.ID, category.CategoryParent, level, position); err != nil {
+				return err
+			}
+		}
+		if err := tx.CategoryRepository.UpdateCategory(category); err != nil {
+			return err
+		}
+
+		updated, err := tx.CategoryRepository.FindCategoryByID(category.ID)
+		if err != nil {
+			return err
+		}
+		if err := tx.recordEvent(EventUpdated, category.ID, existing, updated); err != nil {
+			return err
+		}
+		if moved {
+			return tx.recordEvent(EventMoved, category.ID, existing, updated)
+		}
+		return nil
+	})
+}
+
+// MoveCategory moves a Category under parentID at the 1-based sibling position (0 appends it) and
+// recomputes the levels of its subtree in one transaction, recording a moved event. It returns the moved
+// category with its subtree.
+func (u *CategoryService) MoveCategory(categoryID int64, parentID int64, position int32) (*CategoryNode, error) {
+	var node *CategoryNode
+	err := u.inTransaction(func(tx *CategoryService) error {
+		existing, err := tx.CategoryRepository.FindCategoryByID(categoryID)
+		if err != nil {
+			return err
+		}
+
+		level, err := tx.childLevel(parentID)
+		if err != nil {
+			return err
+		}
+		if err := tx.checkNoCycle(categoryID, parentID); err != nil {
+			return err
+		}
+		if err := tx.CategoryRepository.MoveCategory(categoryID, parentID, level, position); err != nil {
+			return err
+		}
+
+		nodes, err := tx.GetCategoryTree(categoryID, 0, true)
+		if err != nil {
+			return err
+		}
+		node = nodes[0]
+		return tx.recordEvent(EventMoved, categoryID, existing, &node.Category)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// Helper function to derive the level of a category placed under parentID
+func (u *CategoryService) childLevel(parentID int64) (uint32, error) {
+	if parentID == 0 {
+		return 1, nil
+	}
+	parent, err := u.CategoryRepository.FindCategoryByID(parentID)
+	if gorm.IsRecordNotFoundError(err) {
+		return 0, ErrParentNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	return parent.CategoryLevel + 1, nil
+}
+
+// Helper function to reject moving a category under itself or one of its descendants
+func (u *CategoryService) checkNoCycle(categoryID int64, parentID int64) error {
+	visited := make(map[int64]bool)
+	for id := parentID; id != 0 && !visited[id]; {
+		if id == categoryID {
+			return ErrCategoryCycle
+		}
+		visited[id] = true
+		ancestor, err := u.CategoryRepository.FindCategoryByID(id)
+		if err != nil {
+			return err
+		}
+		id = ancestor.CategoryParent
+	}
+	return nil
+}
+
+// FindCategoryByID retrieves a Category by its ID from the repository. Categories the listings leave out are
+// not found unless includeHidden is set.
+func (u *CategoryService) FindCategoryByID(categoryID int64, includeHidden bool) (*model.Category, error) {
+	category, err := u.CategoryRepository.FindCategoryByID(categoryID)
+	if err != nil {
+		return nil, err
+	}
+	return visibleCategory(category, listFilter(includeHidden))
+}
+
+// FindAllCategory retrieves all Categories from the repository in sibling order.
+func (u *CategoryService) FindAllCategory(includeHidden bool) ([]model.Category, error) {
+	return u.CategoryRepository.FindAll(listFilter(includeHidden))
 }
 
-// FindCategoryByID retrieves a Category by its ID from the repository.
-func (u *CategoryService) FindCategoryByID(categoryID int64) (*model.Category, error) {
-	return u.CategoryRepository.FindCategoryByID(categoryID)
+// FindCategoryByName retrieves a Category by its name from the repository. Categories the listings leave out
+// are not found unless includeHidden is set.
+func (u *CategoryService) FindCategoryByName(categoryName string, includeHidden bool) (*model.Category, error) {
+	category, err := u.CategoryRepository.FindCategoryByName(categoryName)
+	if err != nil {
+		return nil, err
+	}
+	return visibleCategory(category, listFilter(includeHidden))
 }
 
-// FindAllCategory retrieves all Categories from the repository.
-func (u *CategoryService) FindAllCategory() ([]model.Category, error) {
-	return u.CategoryRepository.FindAll()
+// FindCategoryByLevel retrieves Categories by their level from the repository in sibling order.
+func (u *CategoryService) FindCategoryByLevel(level uint32, includeHidden bool) ([]model.Category, error) {
+	return u.CategoryRepository.FindCategoryByLevel(level, listFilter(includeHidden))
 }
 
-// FindCategoryByName retrieves a Category by its name from the repository.
-func (u *CategoryService) FindCategoryByName(categoryName string) (*model.Category, error) {
-	return u.CategoryRepository.FindCategoryByName(categoryName)
+// FindCategoryByParent retrieves Categories by their parent category ID from the repository in sibling order.
+func (u *CategoryService) FindCategoryByParent(parent int64, includeHidden bool) ([]model.Category, error) {
+	return u.CategoryRepository.FindCategoryByParent(parent, listFilter(includeHidden))
 }
 
-// FindCategoryByLevel retrieves Categories by their level from the repository.
-func (u *CategoryService) FindCategoryByLevel(level uint32) ([]model.Category, error) {
-	return u.CategoryRepository.FindCategoryByLevel(level)
+// Helper function to build the listing filter, evaluating publish windows at the current time
+func listFilter(includeHidden bool) repository.CategoryFilter {
+	return repository.CategoryFilter{IncludeHidden: includeHidden, Now: time.Now()}
 }
 
-// FindCategoryByParent retrieves Categories by their parent category ID from the repository.
-func (u *CategoryService) FindCategoryByParent(parent int64) ([]model.Category, error) {
-	return u.CategoryRepository.FindCategoryByParent(parent)
+// Helper function to report a category the filter leaves out as not found, so lookups do not reveal it
+func visibleCategory(category *model.Category, filter repository.CategoryFilter) (*model.Category, error) {
+	if !filter.Matches(category) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return category, nil
 }
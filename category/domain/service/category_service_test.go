@@ -1,11 +1,26 @@
 package service
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	"github.com/jinzhu/gorm"
+	"github.com/micro/go-micro/v2/broker"
+	"github.com/micro/go-micro/v2/broker/memory"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+	"github.com/tongs-dev/shopping-platform/category/domain/cache"
 	"github.com/tongs-dev/shopping-platform/category/domain/model"
+	"github.com/tongs-dev/shopping-platform/category/domain/repository"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 // MockCategoryRepository is a mock type for the ICategoryRepository interface
@@ -19,6 +34,26 @@ func (m *MockCategoryRepository) InitTable() error {
 	return args.Error(0) // Return the error as set up in the mock
 }
 
+func (m *MockCategoryRepository) AddSlugColumn() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockCategoryRepository) MigrateTable() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockCategoryRepository) FindCategoriesWithoutSlug() ([]model.Category, error) {
+	args := m.Called()
+	return args.Get(0).([]model.Category), args.Error(1)
+}
+
+func (m *MockCategoryRepository) UpdateCategorySlug(categoryID int64, slug string) error {
+	args := m.Called(categoryID, slug)
+	return args.Error(0)
+}
+
 func (m *MockCategoryRepository) CreateCategory(category *model.Category) (int64, error) {
 	args := m.Called(category)
 	return args.Get(0).(int64), args.Error(1)
@@ -39,8 +74,8 @@ func (m *MockCategoryRepository) FindCategoryByID(categoryID int64) (*model.Cate
 	return args.Get(0).(*model.Category), args.Error(1)
 }
 
-func (m *MockCategoryRepository) FindAll() ([]model.Category, error) {
-	args := m.Called()
+func (m *MockCategoryRepository) FindAll(filter repository.CategoryFilter) ([]model.Category, error) {
+	args := m.Called(filter)
 	return args.Get(0).([]model.Category), args.Error(1)
 }
 
@@ -49,20 +84,145 @@ func (m *MockCategoryRepository) FindCategoryByName(categoryName string) (*model
 	return args.Get(0).(*model.Category), args.Error(1)
 }
 
-func (m *MockCategoryRepository) FindCategoryByLevel(level uint32) ([]model.Category, error) {
-	args := m.Called(level)
+func (m *MockCategoryRepository) FindCategoryByLevel(level uint32, filter repository.CategoryFilter) ([]model.Category, error) {
+	args := m.Called(level, filter)
 	return args.Get(0).([]model.Category), args.Error(1)
 }
 
-func (m *MockCategoryRepository) FindCategoryByParent(parent int64) ([]model.Category, error) {
-	args := m.Called(parent)
+func (m *MockCategoryRepository) FindCategoryByParent(parent int64, filter repository.CategoryFilter) ([]model.Category, error) {
+	args := m.Called(parent, filter)
 	return args.Get(0).([]model.Category), args.Error(1)
 }
 
+func (m *MockCategoryRepository) FindCategoriesByParents(parents []int64, filter repository.CategoryFilter) ([]model.Category, error) {
+	args := m.Called(parents, filter)
+	return args.Get(0).([]model.Category), args.Error(1)
+}
+
+func (m *MockCategoryRepository) MoveCategory(categoryID int64, parentID int64, level uint32, position int32) error {
+	args := m.Called(categoryID, parentID, level, position)
+	return args.Error(0)
+}
+
+func (m *MockCategoryRepository) DeleteCategoryTree(categoryID int64) error {
+	args := m.Called(categoryID)
+	return args.Error(0)
+}
+
+func (m *MockCategoryRepository) DeleteCategoryPromoteChildren(categoryID int64, parentID int64, childLevel uint32) error {
+	args := m.Called(categoryID, parentID, childLevel)
+	return args.Error(0)
+}
+
+func (m *MockCategoryRepository) FindCategoryBySlug(slug string) (*model.Category, error) {
+	args := m.Called(slug)
+	return args.Get(0).(*model.Category), args.Error(1)
+}
+
+func (m *MockCategoryRepository) FindCategoriesBySlugs(slugs []string, filter repository.CategoryFilter) ([]model.Category, error) {
+	args := m.Called(slugs, filter)
+	return args.Get(0).([]model.Category), args.Error(1)
+}
+
+func (m *MockCategoryRepository) FindSlugsWithPrefix(base string) ([]string, error) {
+	args := m.Called(base)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockCategoryRepository) UpsertTranslation(translation *model.CategoryTranslation) error {
+	args := m.Called(translation)
+	return args.Error(0)
+}
+
+func (m *MockCategoryRepository) DeleteTranslation(categoryID int64, locale string) error {
+	args := m.Called(categoryID, locale)
+	return args.Error(0)
+}
+
+func (m *MockCategoryRepository) FindTranslations(categoryIDs []int64, locales []string) ([]model.CategoryTranslation, error) {
+	args := m.Called(categoryIDs, locales)
+	return args.Get(0).([]model.CategoryTranslation), args.Error(1)
+}
+
+func (m *MockCategoryRepository) CreateAttribute(attribute *model.CategoryAttribute) (int64, error) {
+	args := m.Called(attribute)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockCategoryRepository) UpdateAttribute(attribute *model.CategoryAttribute) error {
+	args := m.Called(attribute)
+	return args.Error(0)
+}
+
+func (m *MockCategoryRepository) DeleteAttribute(attributeID int64) error {
+	args := m.Called(attributeID)
+	return args.Error(0)
+}
+
+func (m *MockCategoryRepository) FindAttributeByID(attributeID int64) (*model.CategoryAttribute, error) {
+	args := m.Called(attributeID)
+	return args.Get(0).(*model.CategoryAttribute), args.Error(1)
+}
+
+func (m *MockCategoryRepository) FindAttributesByCategories(categoryIDs []int64) ([]model.CategoryAttribute, error) {
+	args := m.Called(categoryIDs)
+	return args.Get(0).([]model.CategoryAttribute), args.Error(1)
+}
+
+func (m *MockCategoryRepository) CreateOutboxEvent(event *model.OutboxEvent) error {
+	args := m.Called(event)
+	return args.Error(0)
+}
+
+func (m *MockCategoryRepository) FindPendingOutboxEvents(limit int) ([]model.OutboxEvent, error) {
+	args := m.Called(limit)
+	return args.Get(0).([]model.OutboxEvent), args.Error(1)
+}
+
+func (m *MockCategoryRepository) MarkOutboxEventPublished(eventID int64, publishedAt time.Time) error {
+	args := m.Called(eventID, publishedAt)
+	return args.Error(0)
+}
+
+func (m *MockCategoryRepository) MarkOutboxEventFailed(eventID int64) error {
+	args := m.Called(eventID)
+	return args.Error(0)
+}
+
+func (m *MockCategoryRepository) AcquireOutboxLease(name string, holder string, ttl time.Duration) (bool, error) {
+	args := m.Called(name, holder, ttl)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockCategoryRepository) DeletePublishedOutboxEvents(before time.Time) error {
+	args := m.Called(before)
+	return args.Error(0)
+}
+
+// Transaction runs fn against the mock itself, returning what fn returns
+func (m *MockCategoryRepository) Transaction(fn func(repository.ICategoryRepository) error) error {
+	m.Called(fn)
+	return fn(m)
+}
+
+// Helper function to match an outbox event by type and category
+func withEvent(eventType EventType, categoryID int64) interface{} {
+	return mock.MatchedBy(func(event *model.OutboxEvent) bool {
+		return event.EventType == string(eventType) && event.CategoryID == categoryID
+	})
+}
+
+// Helper function to match the listing filter of the repository calls
+func withHidden(includeHidden bool) interface{} {
+	return mock.MatchedBy(func(filter repository.CategoryFilter) bool {
+		return filter.IncludeHidden == includeHidden
+	})
+}
+
 // Helper function to create a mock repository and service
 func newCategoryService() (*MockCategoryRepository, ICategoryService) {
 	mockRepo := new(MockCategoryRepository)
-	service := NewCategoryService(mockRepo)
+	service := NewCategoryService(mockRepo, "en")
 	return mockRepo, service
 }
 
@@ -77,73 +237,851 @@ type CategoryServiceTestSuite struct {
 func (suite *CategoryServiceTestSuite) SetupTest() {
 	suite.T().Logf("Setup Test")
 	suite.mockRepo, suite.service = newCategoryService()
+	// Mutations run in a transaction and write their events to the outbox
+	suite.mockRepo.On("Transaction", mock.Anything).Return(nil).Maybe()
+	suite.mockRepo.On("CreateOutboxEvent", mock.Anything).Return(nil).Maybe()
 }
 
 // TestCreateCategory tests the CreateCategory method of CategoryService
 func (suite *CategoryServiceTestSuite) TestCreateCategory() {
 	category := &model.Category{CategoryName: "Test Category"}
+	suite.mockRepo.On("FindCategoryByParent", int64(0), withHidden(true)).Return([]model.Category{{ID: 5, SortOrder: 2}}, nil)
+	suite.mockRepo.On("FindSlugsWithPrefix", "test-category").Return([]string{}, nil)
 	suite.mockRepo.On("CreateCategory", category).Return(int64(1), nil)
 
 	userID, err := suite.service.AddCategory(category)
 
 	suite.NoError(err)
 	suite.Equal(int64(1), userID)
+	suite.Equal(uint32(1), category.CategoryLevel)
+	suite.Equal(int32(3), category.SortOrder)
+	suite.Equal("test-category", category.Slug)
+	suite.mockRepo.AssertCalled(suite.T(), "CreateOutboxEvent", withEvent(EventCreated, 1))
 	suite.mockRepo.AssertExpectations(suite.T())
 }
 
+// TestCreateCategoryDerivesLevel tests that AddCategory sets the level below the parent
+func (suite *CategoryServiceTestSuite) TestCreateCategoryDerivesLevel() {
+	category := &model.Category{CategoryName: "Shirts", CategoryParent: 2, CategoryLevel: 7, SortOrder: 1}
+	suite.mockRepo.On("FindCategoryByID", int64(2)).Return(&model.Category{ID: 2, CategoryLevel: 2}, nil)
+	suite.mockRepo.On("FindSlugsWithPrefix", "shirts").Return([]string{}, nil)
+	suite.mockRepo.On("CreateCategory", category).Return(int64(3), nil)
+
+	_, err := suite.service.AddCategory(category)
+
+	suite.NoError(err)
+	suite.Equal(uint32(3), category.CategoryLevel)
+}
+
+// TestCreateCategoryParentNotFound tests that AddCategory rejects a missing parent
+func (suite *CategoryServiceTestSuite) TestCreateCategoryParentNotFound() {
+	suite.mockRepo.On("FindCategoryByID", int64(9)).Return((*model.Category)(nil), gorm.ErrRecordNotFound)
+
+	_, err := suite.service.AddCategory(&model.Category{CategoryName: "Orphan", CategoryParent: 9})
+
+	suite.Equal(ErrParentNotFound, err)
+	suite.mockRepo.AssertNotCalled(suite.T(), "CreateCategory", mock.Anything)
+}
+
+// TestCreateCategorySlugCollision tests that generated slugs get the next free numeric suffix
+func (suite *CategoryServiceTestSuite) TestCreateCategorySlugCollision() {
+	category := &model.Category{CategoryName: "Running Shoes", SortOrder: 1}
+	suite.mockRepo.On("FindSlugsWithPrefix", "running-shoes").Return([]string{"running-shoes", "running-shoes-2"}, nil)
+	suite.mockRepo.On("CreateCategory", category).Return(int64(1), nil)
+
+	_, err := suite.service.AddCategory(category)
+
+	suite.NoError(err)
+	suite.Equal("running-shoes-3", category.Slug)
+}
+
+// TestCreateCategorySlugTaken tests that a requested slug used by another category is rejected
+func (suite *CategoryServiceTestSuite) TestCreateCategorySlugTaken() {
+	suite.mockRepo.On("FindCategoryBySlug", "shoes").Return(&model.Category{ID: 7, Slug: "shoes"}, nil)
+
+	_, err := suite.service.AddCategory(&model.Category{CategoryName: "Shoes", Slug: "Shoes", SortOrder: 1})
+
+	suite.Equal(ErrSlugTaken, err)
+	suite.mockRepo.AssertNotCalled(suite.T(), "CreateCategory", mock.Anything)
+}
+
 // TestDeleteCategory tests the DeleteCategory method of CategoryService
 func (suite *CategoryServiceTestSuite) TestDeleteCategory() {
 	categoryID := int64(1)
+	suite.mockRepo.On("FindCategoryByParent", categoryID, withHidden(true)).Return([]model.Category{}, nil)
+	suite.mockRepo.On("FindCategoryByID", categoryID).Return(&model.Category{ID: 1}, nil)
 	suite.mockRepo.On("DeleteCategoryByID", categoryID).Return(nil)
 
-	err := suite.service.DeleteCategory(categoryID)
+	err := suite.service.DeleteCategory(categoryID, DeleteReject)
 
 	suite.NoError(err)
+	suite.mockRepo.AssertCalled(suite.T(), "CreateOutboxEvent", withEvent(EventDeleted, 1))
 	suite.mockRepo.AssertExpectations(suite.T())
 }
 
+// TestDeleteCategoryRejectsChildren tests that DeleteReject keeps a category with subcategories
+func (suite *CategoryServiceTestSuite) TestDeleteCategoryRejectsChildren() {
+	suite.mockRepo.On("FindCategoryByParent", int64(1), withHidden(true)).Return([]model.Category{{ID: 2, CategoryParent: 1}}, nil)
+
+	err := suite.service.DeleteCategory(1, DeleteReject)
+
+	suite.Equal(ErrCategoryHasChildren, err)
+	suite.mockRepo.AssertNotCalled(suite.T(), "DeleteCategoryByID", mock.Anything)
+}
+
+// TestDeleteCategoryCascade tests that DeleteCascade deletes the subtree, recording an event per category
+func (suite *CategoryServiceTestSuite) TestDeleteCategoryCascade() {
+	suite.mockRepo.On("FindCategoryByID", int64(1)).Return(&model.Category{ID: 1}, nil)
+	suite.mockRepo.On("FindCategoriesByParents", []int64{1}, withHidden(true)).Return([]model.Category{{ID: 2, CategoryParent: 1}}, nil)
+	suite.mockRepo.On("FindCategoriesByParents", []int64{2}, withHidden(true)).Return([]model.Category{}, nil)
+	suite.mockRepo.On("DeleteCategoryTree", int64(1)).Return(nil)
+
+	err := suite.service.DeleteCategory(1, DeleteCascade)
+
+	suite.NoError(err)
+	suite.mockRepo.AssertCalled(suite.T(), "CreateOutboxEvent", withEvent(EventDeleted, 1))
+	suite.mockRepo.AssertCalled(suite.T(), "CreateOutboxEvent", withEvent(EventDeleted, 2))
+	suite.mockRepo.AssertExpectations(suite.T())
+}
+
+// TestDeleteCategoryReparent tests that DeleteReparent moves the children to the grandparent
+func (suite *CategoryServiceTestSuite) TestDeleteCategoryReparent() {
+	suite.mockRepo.On("FindCategoryByID", int64(2)).Return(&model.Category{ID: 2, CategoryLevel: 2, CategoryParent: 1}, nil)
+	suite.mockRepo.On("FindCategoryByID", int64(1)).Return(&model.Category{ID: 1, CategoryLevel: 1}, nil)
+	suite.mockRepo.On("FindCategoryByParent", int64(2), withHidden(true)).Return([]model.Category{{ID: 3, CategoryLevel: 3, CategoryParent: 2}}, nil)
+	suite.mockRepo.On("DeleteCategoryPromoteChildren", int64(2), int64(1), uint32(2)).Return(nil)
+
+	err := suite.service.DeleteCategory(2, DeleteReparent)
+
+	suite.NoError(err)
+	suite.mockRepo.AssertCalled(suite.T(), "CreateOutboxEvent", withEvent(EventDeleted, 2))
+	suite.mockRepo.AssertCalled(suite.T(), "CreateOutboxEvent", withEvent(EventMoved, 3))
+	suite.mockRepo.AssertExpectations(suite.T())
+}
+
+// TestDeleteCategoryUnknownPolicy tests that DeleteCategory rejects undefined policies
+func (suite *CategoryServiceTestSuite) TestDeleteCategoryUnknownPolicy() {
+	err := suite.service.DeleteCategory(1, DeletePolicy(42))
+
+	suite.Equal(ErrUnknownDeletePolicy, err)
+}
+
 // TestUpdateCategory tests the UpdateCategory method of CategoryService
 func (suite *CategoryServiceTestSuite) TestUpdateCategory() {
 	category := &model.Category{ID: 1, CategoryName: "Updated Category"}
+	suite.mockRepo.On("FindCategoryByID", int64(1)).Return(&model.Category{ID: 1, CategoryName: "Category", CategoryLevel: 1}, nil)
+	suite.mockRepo.On("UpdateCategory", category).Return(nil)
+
+	err := suite.service.UpdateCategory(category)
+
+	suite.NoError(err)
+	suite.mockRepo.AssertNotCalled(suite.T(), "MoveCategory", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	suite.mockRepo.AssertExpectations(suite.T())
+}
+
+// TestUpdateCategoryMovesSubtree tests that changing the parent recomputes the levels of the subtree
+func (suite *CategoryServiceTestSuite) TestUpdateCategoryMovesSubtree() {
+	category := &model.Category{ID: 2, CategoryName: "Men", CategoryParent: 3}
+	suite.mockRepo.On("FindCategoryByID", int64(2)).Return(&model.Category{ID: 2, CategoryLevel: 2, CategoryParent: 1}, nil)
+	suite.mockRepo.On("FindCategoryByID", int64(3)).Return(&model.Category{ID: 3, CategoryLevel: 2, CategoryParent: 1}, nil)
+	suite.mockRepo.On("FindCategoryByID", int64(1)).Return(&model.Category{ID: 1, CategoryLevel: 1}, nil)
+	suite.mockRepo.On("MoveCategory", int64(2), int64(3), uint32(3), int32(0)).Return(nil)
 	suite.mockRepo.On("UpdateCategory", category).Return(nil)
 
 	err := suite.service.UpdateCategory(category)
 
 	suite.NoError(err)
+	suite.Equal(uint32(3), category.CategoryLevel)
+	suite.mockRepo.AssertCalled(suite.T(), "CreateOutboxEvent", withEvent(EventUpdated, 2))
+	suite.mockRepo.AssertCalled(suite.T(), "CreateOutboxEvent", withEvent(EventMoved, 2))
 	suite.mockRepo.AssertExpectations(suite.T())
 }
 
+// TestUpdateCategoryReordersSiblings tests that a changed sort order renumbers the siblings instead of being written
+func (suite *CategoryServiceTestSuite) TestUpdateCategoryReordersSiblings() {
+	category := &model.Category{ID: 2, CategoryName: "Men", CategoryParent: 1, SortOrder: 3}
+	suite.mockRepo.On("FindCategoryByID", int64(2)).Return(&model.Category{ID: 2, CategoryLevel: 2, CategoryParent: 1, SortOrder: 1}, nil)
+	suite.mockRepo.On("FindCategoryByID", int64(1)).Return(&model.Category{ID: 1, CategoryLevel: 1}, nil)
+	suite.mockRepo.On("MoveCategory", int64(2), int64(1), uint32(2), int32(3)).Return(nil)
+	suite.mockRepo.On("UpdateCategory", category).Return(nil)
+
+	err := suite.service.UpdateCategory(category)
+
+	suite.NoError(err)
+	suite.mockRepo.AssertCalled(suite.T(), "CreateOutboxEvent", withEvent(EventUpdated, 2))
+	suite.mockRepo.AssertNotCalled(suite.T(), "CreateOutboxEvent", withEvent(EventMoved, 2))
+	suite.mockRepo.AssertExpectations(suite.T())
+}
+
+// TestUpdateCategoryRejectsCycle tests that a category cannot be moved under its own descendant
+func (suite *CategoryServiceTestSuite) TestUpdateCategoryRejectsCycle() {
+	suite.mockRepo.On("FindCategoryByID", int64(1)).Return(&model.Category{ID: 1, CategoryLevel: 1}, nil)
+	suite.mockRepo.On("FindCategoryByID", int64(4)).Return(&model.Category{ID: 4, CategoryLevel: 3, CategoryParent: 2}, nil)
+	suite.mockRepo.On("FindCategoryByID", int64(2)).Return(&model.Category{ID: 2, CategoryLevel: 2, CategoryParent: 1}, nil)
+
+	err := suite.service.UpdateCategory(&model.Category{ID: 1, CategoryName: "Clothing", CategoryParent: 4})
+
+	suite.Equal(ErrCategoryCycle, err)
+	suite.mockRepo.AssertNotCalled(suite.T(), "MoveCategory", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	suite.mockRepo.AssertNotCalled(suite.T(), "UpdateCategory", mock.Anything)
+}
+
 // TestFindCategoryByID tests the FindCategoryByID method of CategoryService
 func (suite *CategoryServiceTestSuite) TestFindCategoryByID() {
 	categoryID := int64(1)
 	expectedCategory := &model.Category{ID: categoryID, CategoryName: "Test Category"}
 	suite.mockRepo.On("FindCategoryByID", categoryID).Return(expectedCategory, nil)
 
-	category, err := suite.service.FindCategoryByID(categoryID)
+	category, err := suite.service.FindCategoryByID(categoryID, false)
 
 	suite.NoError(err)
 	suite.Equal(expectedCategory, category)
 	suite.mockRepo.AssertExpectations(suite.T())
 }
 
+// TestFindCategoryByIDHidden tests that hidden and unpublished categories are only found with includeHidden
+func (suite *CategoryServiceTestSuite) TestFindCategoryByIDHidden() {
+	later := time.Now().Add(time.Hour)
+	suite.mockRepo.On("FindCategoryByID", int64(1)).Return(&model.Category{ID: 1, Hidden: true}, nil)
+	suite.mockRepo.On("FindCategoryByID", int64(2)).Return(&model.Category{ID: 2, PublishAt: &later}, nil)
+
+	_, err := suite.service.FindCategoryByID(1, false)
+	suite.Equal(gorm.ErrRecordNotFound, err)
+	_, err = suite.service.FindCategoryByID(2, false)
+	suite.Equal(gorm.ErrRecordNotFound, err)
+
+	category, err := suite.service.FindCategoryByID(1, true)
+	suite.NoError(err)
+	suite.True(category.Hidden)
+}
+
 // TestFindAllCategory tests the FindAllCategory method of CategoryService
 func (suite *CategoryServiceTestSuite) TestFindAllCategory() {
 	expectedCategories := []model.Category{
 		{ID: 1, CategoryName: "Category 1"},
 		{ID: 2, CategoryName: "Category 2"},
 	}
-	suite.mockRepo.On("FindAll").Return(expectedCategories, nil)
+	suite.mockRepo.On("FindAll", withHidden(false)).Return(expectedCategories, nil)
 
-	categories, err := suite.service.FindAllCategory()
+	categories, err := suite.service.FindAllCategory(false)
 
 	suite.NoError(err)
 	suite.Equal(expectedCategories, categories)
 	suite.mockRepo.AssertExpectations(suite.T())
 }
 
+// TestFindCategoryByParentIncludeHidden tests that the admin flag reaches the repository filter
+func (suite *CategoryServiceTestSuite) TestFindCategoryByParentIncludeHidden() {
+	suite.mockRepo.On("FindCategoryByParent", int64(1), withHidden(true)).Return([]model.Category{{ID: 2, Hidden: true}}, nil)
+
+	categories, err := suite.service.FindCategoryByParent(1, true)
+
+	suite.NoError(err)
+	suite.Len(categories, 1)
+	suite.mockRepo.AssertExpectations(suite.T())
+}
+
+// TestGetCategoryTree tests that GetCategoryTree nests the categories level by level
+func (suite *CategoryServiceTestSuite) TestGetCategoryTree() {
+	suite.mockRepo.On("FindCategoryByParent", int64(0), withHidden(true)).Return([]model.Category{{ID: 1, CategoryName: "Clothing"}}, nil)
+	suite.mockRepo.On("FindCategoriesByParents", []int64{1}, withHidden(true)).Return([]model.Category{
+		{ID: 2, CategoryName: "Men", CategoryParent: 1},
+		{ID: 3, CategoryName: "Women", CategoryParent: 1},
+	}, nil)
+	suite.mockRepo.On("FindCategoriesByParents", mock.AnythingOfType("[]int64"), withHidden(true)).Return([]model.Category{
+		{ID: 4, CategoryName: "Shirts", CategoryParent: 2},
+	}, nil).Once()
+
+	nodes, err := suite.service.GetCategoryTree(0, 3, true)
+
+	suite.NoError(err)
+	suite.Len(nodes, 1)
+	suite.Len(nodes[0].Children, 2)
+	suite.Equal("Shirts", nodes[0].Children[0].Children[0].Category.CategoryName)
+	suite.Empty(nodes[0].Children[1].Children)
+}
+
+// TestGetCategoryTreeMaxDepth tests that GetCategoryTree stops at the requested depth
+func (suite *CategoryServiceTestSuite) TestGetCategoryTreeMaxDepth() {
+	suite.mockRepo.On("FindCategoryByID", int64(2)).Return(&model.Category{ID: 2, CategoryName: "Men"}, nil)
+
+	nodes, err := suite.service.GetCategoryTree(2, 1, false)
+
+	suite.NoError(err)
+	suite.Len(nodes, 1)
+	suite.Empty(nodes[0].Children)
+	suite.mockRepo.AssertNotCalled(suite.T(), "FindCategoriesByParents", mock.Anything, mock.Anything)
+}
+
+// TestGetCategoryAncestors tests that GetCategoryAncestors returns the path from the root
+func (suite *CategoryServiceTestSuite) TestGetCategoryAncestors() {
+	suite.mockRepo.On("FindCategoryByID", int64(4)).Return(&model.Category{ID: 4, CategoryName: "Shirts", CategoryParent: 2}, nil)
+	suite.mockRepo.On("FindCategoryByID", int64(2)).Return(&model.Category{ID: 2, CategoryName: "Men", CategoryParent: 1}, nil)
+	suite.mockRepo.On("FindCategoryByID", int64(1)).Return(&model.Category{ID: 1, CategoryName: "Clothing"}, nil)
+
+	path, err := suite.service.GetCategoryAncestors(4, false)
+
+	suite.NoError(err)
+	suite.Len(path, 3)
+	suite.Equal("Clothing", path[0].CategoryName)
+	suite.Equal("Shirts", path[2].CategoryName)
+}
+
+// TestGetCategoryAncestorsHiddenParent tests that a category below a hidden one is not found without includeHidden
+func (suite *CategoryServiceTestSuite) TestGetCategoryAncestorsHiddenParent() {
+	suite.mockRepo.On("FindCategoryByID", int64(4)).Return(&model.Category{ID: 4, CategoryName: "Shirts", CategoryParent: 2}, nil)
+	suite.mockRepo.On("FindCategoryByID", int64(2)).Return(&model.Category{ID: 2, CategoryName: "Men", Hidden: true}, nil)
+
+	_, err := suite.service.GetCategoryAncestors(4, false)
+
+	suite.Equal(gorm.ErrRecordNotFound, err)
+}
+
+// TestMoveCategory tests that MoveCategory moves the category and returns its subtree
+func (suite *CategoryServiceTestSuite) TestMoveCategory() {
+	suite.mockRepo.On("FindCategoryByID", int64(2)).Return(&model.Category{ID: 2, CategoryName: "Men", CategoryLevel: 3, CategoryParent: 3}, nil)
+	suite.mockRepo.On("MoveCategory", int64(2), int64(0), uint32(1), int32(1)).Return(nil)
+	suite.mockRepo.On("FindCategoriesByParents", []int64{2}, withHidden(true)).Return([]model.Category{{ID: 4, CategoryName: "Shirts", CategoryParent: 2}}, nil)
+	suite.mockRepo.On("FindCategoriesByParents", []int64{4}, withHidden(true)).Return([]model.Category{}, nil)
+
+	node, err := suite.service.MoveCategory(2, 0, 1)
+
+	suite.NoError(err)
+	suite.Equal("Men", node.Category.CategoryName)
+	suite.Len(node.Children, 1)
+	suite.mockRepo.AssertExpectations(suite.T())
+}
+
+// TestMoveCategoryRejectsCycle tests that MoveCategory refuses to move a category under itself
+func (suite *CategoryServiceTestSuite) TestMoveCategoryRejectsCycle() {
+	suite.mockRepo.On("FindCategoryByID", int64(2)).Return(&model.Category{ID: 2, CategoryLevel: 1}, nil)
+
+	_, err := suite.service.MoveCategory(2, 2, 0)
+
+	suite.Equal(ErrCategoryCycle, err)
+	suite.mockRepo.AssertNotCalled(suite.T(), "MoveCategory", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+// TestSlugify tests the slugs generated from category names
+func (suite *CategoryServiceTestSuite) TestSlugify() {
+	suite.Equal("men-s-running-shoes", Slugify("Men's  Running Shoes"))
+	suite.Equal("tv-4k", Slugify(" -TV & 4K- "))
+	suite.Equal("", Slugify("!!!"))
+	suite.Equal("schuhe-für-damen", Slugify("Schuhe für Damen"))
+	suite.Equal("男鞋", Slugify("男鞋"))
+	suite.Equal("обувь-42", Slugify("Обувь 42"))
+	suite.Equal("हिन्दी", Slugify("हिन्दी"), "combining marks stay in the word")
+}
+
+// TestMigrateSlugs tests that categories created before slugs get one before the unique index is created
+func (suite *CategoryServiceTestSuite) TestMigrateSlugs() {
+	suite.mockRepo.On("AddSlugColumn").Return(nil)
+	suite.mockRepo.On("FindCategoriesWithoutSlug").Return([]model.Category{
+		{ID: 1, CategoryName: "Shoes"},
+		{ID: 2, CategoryName: "Обувь"},
+	}, nil)
+	suite.mockRepo.On("FindSlugsWithPrefix", "shoes").Return([]string{"shoes"}, nil)
+	suite.mockRepo.On("FindSlugsWithPrefix", "обувь").Return([]string{}, nil)
+	suite.mockRepo.On("UpdateCategorySlug", int64(1), "shoes-2").Return(nil)
+	suite.mockRepo.On("UpdateCategorySlug", int64(2), "обувь").Return(nil)
+	suite.mockRepo.On("MigrateTable").Return(nil).Run(func(mock.Arguments) {
+		suite.mockRepo.AssertNumberOfCalls(suite.T(), "UpdateCategorySlug", 2)
+	})
+
+	migrated, err := MigrateSlugs(suite.mockRepo)
+
+	suite.NoError(err)
+	suite.Equal(2, migrated)
+	suite.mockRepo.AssertExpectations(suite.T())
+}
+
+// TestResolveCategoryPath tests that a slug path resolves along the parent chain
+func (suite *CategoryServiceTestSuite) TestResolveCategoryPath() {
+	suite.mockRepo.On("FindCategoriesBySlugs", []string{"men", "shoes", "running"}, withHidden(false)).Return([]model.Category{
+		{ID: 3, Slug: "running", CategoryParent: 2},
+		{ID: 1, Slug: "men"},
+		{ID: 2, Slug: "shoes", CategoryParent: 1},
+	}, nil)
+
+	path, err := suite.service.ResolveCategoryPath("/men/shoes/running", false)
+
+	suite.NoError(err)
+	suite.Len(path, 3)
+	suite.Equal(int64(3), path[2].ID)
+}
+
+// TestResolveCategoryPathBrokenChain tests that slugs of unrelated categories do not resolve
+func (suite *CategoryServiceTestSuite) TestResolveCategoryPathBrokenChain() {
+	suite.mockRepo.On("FindCategoriesBySlugs", []string{"women", "running"}, withHidden(false)).Return([]model.Category{
+		{ID: 4, Slug: "women"},
+		{ID: 3, Slug: "running", CategoryParent: 2},
+	}, nil)
+
+	_, err := suite.service.ResolveCategoryPath("women/running", false)
+
+	suite.Equal(ErrCategoryPathNotFound, err)
+}
+
+// TestNormalizeLocale tests the normalized form of locales
+func (suite *CategoryServiceTestSuite) TestNormalizeLocale() {
+	locale, err := NormalizeLocale("pt_br")
+	suite.NoError(err)
+	suite.Equal("pt-BR", locale)
+
+	locale, err = NormalizeLocale("ZH-hant-tw")
+	suite.NoError(err)
+	suite.Equal("zh-Hant-TW", locale)
+
+	_, err = NormalizeLocale("german!")
+	suite.Equal(ErrInvalidLocale, err)
+}
+
+// TestLocalizeCategories tests that translations fall back from region to language to the default locale
+func (suite *CategoryServiceTestSuite) TestLocalizeCategories() {
+	suite.mockRepo.On("FindTranslations", []int64{1, 2, 3}, []string{"de-AT", "de"}).Return([]model.CategoryTranslation{
+		{CategoryID: 1, Locale: "de", CategoryName: "Schuhe"},
+		{CategoryID: 1, Locale: "de-AT", CategoryName: "Schuach"},
+		{CategoryID: 2, Locale: "de", CategoryName: "Hemden", CategoryDescription: "Alle Hemden"},
+	}, nil)
+	categories := []*model.Category{
+		{ID: 1, CategoryName: "Shoes"},
+		{ID: 2, CategoryName: "Shirts", CategoryDescription: "All shirts"},
+		{ID: 3, CategoryName: "Hats"},
+	}
+
+	err := suite.service.LocalizeCategories(categories, "de_at")
+
+	suite.NoError(err)
+	suite.Equal("Schuach", categories[0].CategoryName)
+	suite.Equal("de-AT", categories[0].Locale)
+	suite.Equal("Alle Hemden", categories[1].CategoryDescription)
+	suite.Equal("de", categories[1].Locale)
+	suite.Equal("Hats", categories[2].CategoryName)
+	suite.Equal("en", categories[2].Locale)
+}
+
+// TestLocalizeCategoriesDefaultLocale tests that the default locale does not look up translations
+func (suite *CategoryServiceTestSuite) TestLocalizeCategoriesDefaultLocale() {
+	categories := []*model.Category{{ID: 1, CategoryName: "Shoes"}}
+
+	err := suite.service.LocalizeCategories(categories, "EN")
+
+	suite.NoError(err)
+	suite.Equal("en", categories[0].Locale)
+	suite.mockRepo.AssertNotCalled(suite.T(), "FindTranslations", mock.Anything, mock.Anything)
+}
+
+// TestUpsertCategoryTranslation tests that translations are stored under the normalized locale
+func (suite *CategoryServiceTestSuite) TestUpsertCategoryTranslation() {
+	translation := &model.CategoryTranslation{CategoryID: 1, Locale: "de_at", CategoryName: "Schuhe"}
+	suite.mockRepo.On("FindCategoryByID", int64(1)).Return(&model.Category{ID: 1}, nil)
+	suite.mockRepo.On("UpsertTranslation", translation).Return(nil)
+
+	err := suite.service.UpsertCategoryTranslation(translation)
+
+	suite.NoError(err)
+	suite.Equal("de-AT", translation.Locale)
+	suite.mockRepo.AssertExpectations(suite.T())
+}
+
+// TestUpsertCategoryTranslationDefaultLocale tests that the default locale cannot be translated
+func (suite *CategoryServiceTestSuite) TestUpsertCategoryTranslationDefaultLocale() {
+	err := suite.service.UpsertCategoryTranslation(&model.CategoryTranslation{CategoryID: 1, Locale: "en", CategoryName: "Shoes"})
+
+	suite.Equal(ErrDefaultLocaleTranslation, err)
+	suite.mockRepo.AssertNotCalled(suite.T(), "UpsertTranslation", mock.Anything)
+}
+
+// TestImportCategoriesCSV tests that rows may reference parents defined later in the file
+func (suite *CategoryServiceTestSuite) TestImportCategoriesCSV() {
+	file := "name,parent\nRunning,Shoes\nShoes,\n"
+	suite.mockRepo.On("FindCategoryBySlug", "Shoes").Return((*model.Category)(nil), gorm.ErrRecordNotFound)
+	suite.mockRepo.On("FindCategoryByName", "Shoes").Return((*model.Category)(nil), gorm.ErrRecordNotFound)
+	suite.mockRepo.On("FindCategoryByParent", mock.Anything, withHidden(true)).Return([]model.Category{}, nil)
+	suite.mockRepo.On("FindSlugsWithPrefix", mock.Anything).Return([]string{}, nil)
+	suite.mockRepo.On("FindCategoryByID", int64(1)).Return(&model.Category{ID: 1, CategoryLevel: 1}, nil)
+	suite.mockRepo.On("CreateCategory", mock.MatchedBy(func(category *model.Category) bool {
+		return category.CategoryName == "Shoes"
+	})).Return(int64(1), nil)
+	suite.mockRepo.On("CreateCategory", mock.MatchedBy(func(category *model.Category) bool {
+		return category.CategoryName == "Running" && category.CategoryParent == 1 && category.CategoryLevel == 2
+	})).Return(int64(2), nil)
+
+	report, err := suite.service.ImportCategories(strings.NewReader(file), ImportOptions{Format: FormatCSV})
+
+	suite.NoError(err)
+	suite.Equal(2, report.Rows)
+	suite.Equal(2, report.Imported)
+	suite.True(report.Committed)
+	suite.Empty(report.Errors)
+}
+
+// TestImportCategoriesDryRun tests that a dry run validates the rows in a transaction it rolls back
+func (suite *CategoryServiceTestSuite) TestImportCategoriesDryRun() {
+	file := `[{"name": "Shoes"}, {"name": "Boots", "parent": "missing"}, {"name": "Hats", "color": "red"}]`
+	suite.mockRepo.On("FindCategoryBySlug", "missing").Return((*model.Category)(nil), gorm.ErrRecordNotFound)
+	suite.mockRepo.On("FindCategoryByName", "missing").Return((*model.Category)(nil), gorm.ErrRecordNotFound)
+	suite.mockRepo.On("FindCategoryByParent", int64(0), withHidden(true)).Return([]model.Category{}, nil)
+	suite.mockRepo.On("FindSlugsWithPrefix", "shoes").Return([]string{}, nil)
+	suite.mockRepo.On("CreateCategory", mock.Anything).Return(int64(1), nil)
+
+	report, err := suite.service.ImportCategories(strings.NewReader(file), ImportOptions{Format: FormatJSON, DryRun: true})
+
+	suite.NoError(err)
+	suite.Equal(3, report.Rows)
+	suite.Equal(1, report.Imported)
+	suite.False(report.Committed)
+	suite.Len(report.Errors, 2)
+	suite.Equal(2, report.Errors[0].Row)
+	suite.Equal(3, report.Errors[1].Row)
+	suite.mockRepo.AssertExpectations(suite.T())
+}
+
+// TestImportCategoriesInvalidFile tests that a file with an unknown column is rejected as a whole
+func (suite *CategoryServiceTestSuite) TestImportCategoriesInvalidFile() {
+	_, err := suite.service.ImportCategories(strings.NewReader("name,colour\nShoes,red\n"), ImportOptions{Format: FormatCSV})
+
+	suite.True(errors.Is(err, ErrInvalidImportFile))
+	suite.mockRepo.AssertNotCalled(suite.T(), "CreateCategory", mock.Anything)
+}
+
+// TestImportCategoriesStreaming tests that rows are created as they are read, before the rest of the file
+func (suite *CategoryServiceTestSuite) TestImportCategoriesStreaming() {
+	file := `[{"name": "Shoes"}, {"name": `
+	suite.mockRepo.On("FindCategoryByParent", int64(0), withHidden(true)).Return([]model.Category{}, nil)
+	suite.mockRepo.On("FindSlugsWithPrefix", "shoes").Return([]string{}, nil)
+	suite.mockRepo.On("CreateCategory", mock.Anything).Return(int64(1), nil)
+
+	_, err := suite.service.ImportCategories(strings.NewReader(file), ImportOptions{Format: FormatJSON})
+
+	suite.True(errors.Is(err, ErrInvalidImportFile))
+	suite.mockRepo.AssertNumberOfCalls(suite.T(), "CreateCategory", 1)
+}
+
+// TestExportCategories tests that exports list parents first, reference them by slug and keep orphans
+func (suite *CategoryServiceTestSuite) TestExportCategories() {
+	suite.mockRepo.On("FindAll", withHidden(true)).Return([]model.Category{
+		{ID: 3, CategoryName: "Trail", CategoryParent: 9, Slug: "trail"},
+		{ID: 2, CategoryName: "Running", CategoryParent: 1, Slug: "running", Hidden: true},
+		{ID: 1, CategoryName: "Shoes", Slug: "shoes"},
+		{ID: 4, CategoryName: "Spikes", CategoryParent: 3, Slug: "spikes"},
+	}, nil)
+
+	var buffer bytes.Buffer
+	err := suite.service.ExportCategories(&buffer, FormatCSV)
+
+	suite.NoError(err)
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	suite.Len(lines, 5)
+	suite.Equal("Shoes,,shoes,,,false,,,,,", lines[1])
+	suite.Equal("Running,shoes,running,,,true,,,,,", lines[2])
+	suite.Equal("Trail,,trail,,,false,,,,,", lines[3], "a category with a missing parent is exported at the top level")
+	suite.Equal("Spikes,trail,spikes,,,false,,,,,", lines[4])
+}
+
+// TestAddCategoryAttribute tests that attribute definitions are normalized before they are stored
+func (suite *CategoryServiceTestSuite) TestAddCategoryAttribute() {
+	attribute := &model.CategoryAttribute{CategoryID: 1, Name: " width ", Type: model.AttributeEnum, EnumValues: []string{"narrow", " wide"}}
+	suite.mockRepo.On("FindCategoryByID", int64(1)).Return(&model.Category{ID: 1}, nil)
+	suite.mockRepo.On("FindAttributesByCategories", []int64{1}).Return([]model.CategoryAttribute{{ID: 3, CategoryID: 1, Name: "size"}}, nil)
+	suite.mockRepo.On("CreateAttribute", attribute).Return(int64(4), nil)
+
+	attributeID, err := suite.service.AddCategoryAttribute(attribute)
+
+	suite.NoError(err)
+	suite.Equal(int64(4), attributeID)
+	suite.Equal("width", attribute.Name)
+	suite.Equal([]string{"narrow", "wide"}, attribute.EnumValues)
+	suite.mockRepo.AssertExpectations(suite.T())
+}
+
+// TestAddCategoryAttributeInvalid tests that invalid attribute definitions are rejected
+func (suite *CategoryServiceTestSuite) TestAddCategoryAttributeInvalid() {
+	cases := []struct {
+		attribute model.CategoryAttribute
+		err       error
+	}{
+		{model.CategoryAttribute{CategoryID: 1, Type: model.AttributeString}, ErrAttributeNameRequired},
+		{model.CategoryAttribute{CategoryID: 1, Name: "size", Type: "color"}, ErrUnknownAttributeType},
+		{model.CategoryAttribute{CategoryID: 1, Name: "size", Type: model.AttributeEnum}, ErrInvalidEnumValues},
+		{model.CategoryAttribute{CategoryID: 1, Name: "size", Type: model.AttributeEnum, EnumValues: []string{"S", "S"}}, ErrInvalidEnumValues},
+		{model.CategoryAttribute{CategoryID: 1, Name: "size", Type: model.AttributeString, Unit: "cm"}, ErrUnitNotAllowed},
+	}
+	for _, c := range cases {
+		_, err := suite.service.AddCategoryAttribute(&c.attribute)
+		suite.Equal(c.err, err)
+	}
+	suite.mockRepo.AssertNotCalled(suite.T(), "CreateAttribute", mock.Anything)
+}
+
+// TestAddCategoryAttributeNameTaken tests that a category cannot define two attributes with the same name
+func (suite *CategoryServiceTestSuite) TestAddCategoryAttributeNameTaken() {
+	suite.mockRepo.On("FindCategoryByID", int64(1)).Return(&model.Category{ID: 1}, nil)
+	suite.mockRepo.On("FindAttributesByCategories", []int64{1}).Return([]model.CategoryAttribute{{ID: 3, CategoryID: 1, Name: "size"}}, nil)
+
+	_, err := suite.service.AddCategoryAttribute(&model.CategoryAttribute{CategoryID: 1, Name: "size", Type: model.AttributeNumber})
+
+	suite.Equal(ErrAttributeNameTaken, err)
+}
+
+// TestFindCategoryAttributesInherited tests that the schema merges inheritable ancestor attributes, nearest first
+func (suite *CategoryServiceTestSuite) TestFindCategoryAttributesInherited() {
+	suite.mockRepo.On("FindCategoryByID", int64(2)).Return(&model.Category{ID: 2, CategoryParent: 1}, nil)
+	suite.mockRepo.On("FindCategoryByID", int64(1)).Return(&model.Category{ID: 1}, nil)
+	suite.mockRepo.On("FindAttributesByCategories", []int64{1, 2}).Return([]model.CategoryAttribute{
+		{ID: 1, CategoryID: 1, Name: "brand", Type: model.AttributeString, Inheritable: true},
+		{ID: 2, CategoryID: 1, Name: "internal", Type: model.AttributeString},
+		{ID: 3, CategoryID: 1, Name: "size", Type: model.AttributeNumber, Inheritable: true},
+		{ID: 4, CategoryID: 2, Name: "size", Type: model.AttributeEnum, EnumValues: []string{"S", "M"}},
+		{ID: 5, CategoryID: 2, Name: "width", Type: model.AttributeNumber, Unit: "cm"},
+	}, nil)
+
+	schema, err := suite.service.FindCategoryAttributes(2, true)
+
+	suite.NoError(err)
+	suite.Len(schema, 3)
+	suite.Equal("brand", schema[0].Name)
+	suite.Equal(int64(4), schema[1].ID)
+	suite.Equal("width", schema[2].Name)
+}
+
+// TestValidateCategoryAttributes tests that values are checked for presence, type and definition
+func (suite *CategoryServiceTestSuite) TestValidateCategoryAttributes() {
+	suite.mockRepo.On("FindCategoryByID", int64(1)).Return(&model.Category{ID: 1}, nil)
+	suite.mockRepo.On("FindAttributesByCategories", []int64{1}).Return([]model.CategoryAttribute{
+		{ID: 1, CategoryID: 1, Name: "brand", Type: model.AttributeString, Required: true},
+		{ID: 2, CategoryID: 1, Name: "size", Type: model.AttributeEnum, EnumValues: []string{"S", "M"}},
+		{ID: 3, CategoryID: 1, Name: "weight", Type: model.AttributeNumber, Unit: "kg"},
+		{ID: 4, CategoryID: 1, Name: "waterproof", Type: model.AttributeBoolean},
+	}, nil)
+
+	violations, err := suite.service.ValidateCategoryAttributes(1, map[string]string{
+		"size": "XL", "weight": "1.5", "waterproof": "maybe", "color": "red",
+	})
+
+	suite.NoError(err)
+	suite.Equal([]AttributeViolation{
+		{Name: "brand", Message: "is required"},
+		{Name: "size", Message: "must be one of S, M"},
+		{Name: "waterproof", Message: "is not a boolean"},
+		{Name: "color", Message: "is not defined for the category"},
+	}, violations)
+}
+
+// TestEventRelayPublishesPending tests that the relay publishes pending events in order and marks them published
+func (suite *CategoryServiceTestSuite) TestEventRelayPublishesPending() {
+	eventBroker := memory.NewBroker()
+	suite.NoError(eventBroker.Connect())
+	var received []*broker.Message
+	_, err := eventBroker.Subscribe(EventTopic, func(event broker.Event) error {
+		received = append(received, event.Message())
+		return nil
+	})
+	suite.NoError(err)
+
+	suite.mockRepo.On("AcquireOutboxLease", outboxLeaseName, mock.Anything, 30*time.Second).Return(true, nil)
+	suite.mockRepo.On("FindPendingOutboxEvents", 100).Return([]model.OutboxEvent{
+		{ID: 1, EventType: string(EventCreated), CategoryID: 5, Payload: `{"type":"category.created"}`},
+		{ID: 2, EventType: string(EventDeleted), CategoryID: 5, Payload: `{"type":"category.deleted"}`},
+	}, nil)
+	suite.mockRepo.On("MarkOutboxEventPublished", int64(1), mock.AnythingOfType("time.Time")).Return(nil)
+	suite.mockRepo.On("MarkOutboxEventPublished", int64(2), mock.AnythingOfType("time.Time")).Return(nil)
+	suite.mockRepo.On("DeletePublishedOutboxEvents", mock.AnythingOfType("time.Time")).Return(nil)
+
+	published, err := NewEventRelay(suite.mockRepo, eventBroker).PublishPending()
+
+	suite.NoError(err)
+	suite.Equal(2, published)
+	suite.Len(received, 2)
+	suite.Equal("1", received[0].Header["Event-Id"])
+	suite.Equal("application/json", received[0].Header["Content-Type"])
+	suite.Equal(string(EventDeleted), received[1].Header["Event-Type"])
+	suite.Equal(`{"type":"category.deleted"}`, string(received[1].Body))
+	suite.mockRepo.AssertExpectations(suite.T())
+}
+
+// TestEventRelayKeepsFailedEvents tests that an event the broker rejects stays in the outbox
+func (suite *CategoryServiceTestSuite) TestEventRelayKeepsFailedEvents() {
+	// The broker is not connected, so publishing fails
+	eventBroker := memory.NewBroker()
+
+	suite.mockRepo.On("AcquireOutboxLease", outboxLeaseName, mock.Anything, 30*time.Second).Return(true, nil)
+	suite.mockRepo.On("FindPendingOutboxEvents", 100).Return([]model.OutboxEvent{{ID: 1, EventType: string(EventCreated), CategoryID: 5}}, nil)
+	suite.mockRepo.On("MarkOutboxEventFailed", int64(1)).Return(nil)
+
+	published, err := NewEventRelay(suite.mockRepo, eventBroker).PublishPending()
+
+	suite.Error(err)
+	suite.Equal(0, published)
+	suite.mockRepo.AssertNotCalled(suite.T(), "MarkOutboxEventPublished", mock.Anything, mock.Anything)
+	suite.mockRepo.AssertExpectations(suite.T())
+}
+
+// TestEventRelayWithoutLease tests that a relay whose lease is held by another instance publishes nothing
+func (suite *CategoryServiceTestSuite) TestEventRelayWithoutLease() {
+	eventBroker := memory.NewBroker()
+	suite.NoError(eventBroker.Connect())
+	relay := NewEventRelay(suite.mockRepo, eventBroker)
+	suite.mockRepo.On("AcquireOutboxLease", outboxLeaseName, relay.holder, relay.LeaseTTL).Return(false, nil)
+
+	published, err := relay.PublishPending()
+
+	suite.NoError(err)
+	suite.Equal(0, published)
+	suite.mockRepo.AssertNotCalled(suite.T(), "FindPendingOutboxEvents", mock.Anything)
+	suite.mockRepo.AssertNotCalled(suite.T(), "DeletePublishedOutboxEvents", mock.Anything)
+}
+
+// newCachedCategoryService wraps the service of the suite in a CachedCategoryService with an in-process cache
+func (suite *CategoryServiceTestSuite) newCachedCategoryService() (*CachedCategoryService, *CacheMetrics) {
+	metrics, err := NewCacheMetrics(prometheus.NewRegistry())
+	suite.NoError(err)
+	return NewCachedCategoryService(suite.service, cache.NewLRUCache(100), time.Minute, metrics), metrics
+}
+
+// TestCachedCategoryServiceReadThrough tests that a lookup is loaded once and then answered from the cache
+func (suite *CategoryServiceTestSuite) TestCachedCategoryServiceReadThrough() {
+	cached, metrics := suite.newCachedCategoryService()
+	suite.mockRepo.On("FindCategoryByID", int64(1)).Return(&model.Category{ID: 1, CategoryName: "Shoes"}, nil).Once()
+
+	first, err := cached.FindCategoryByID(1, false)
+	suite.NoError(err)
+	first.CategoryName = "changed by the caller"
+	second, err := cached.FindCategoryByID(1, false)
+
+	suite.NoError(err)
+	suite.Equal("Shoes", second.CategoryName)
+	suite.Equal(float64(1), testutil.ToFloat64(metrics.hits.WithLabelValues("FindCategoryByID")))
+	suite.Equal(float64(1), testutil.ToFloat64(metrics.misses.WithLabelValues("FindCategoryByID")))
+	suite.mockRepo.AssertExpectations(suite.T())
+}
+
+// TestCachedCategoryServiceSkipsErrors tests that failed lookups are not cached
+func (suite *CategoryServiceTestSuite) TestCachedCategoryServiceSkipsErrors() {
+	cached, _ := suite.newCachedCategoryService()
+	suite.mockRepo.On("FindCategoryByID", int64(1)).Return((*model.Category)(nil), gorm.ErrRecordNotFound).Twice()
+
+	_, err := cached.FindCategoryByID(1, false)
+	suite.Equal(gorm.ErrRecordNotFound, err)
+	_, err = cached.FindCategoryByID(1, false)
+	suite.Equal(gorm.ErrRecordNotFound, err)
+
+	suite.mockRepo.AssertExpectations(suite.T())
+}
+
+// TestCachedCategoryServiceInvalidatesOnMutation tests that a mutation clears the cached lookups
+func (suite *CategoryServiceTestSuite) TestCachedCategoryServiceInvalidatesOnMutation() {
+	cached, _ := suite.newCachedCategoryService()
+	suite.mockRepo.On("FindAll", withHidden(false)).Return([]model.Category{{ID: 1}, {ID: 2}}, nil).Once()
+	suite.mockRepo.On("FindAll", withHidden(false)).Return([]model.Category{{ID: 1}}, nil).Once()
+	suite.mockRepo.On("FindCategoryByParent", int64(2), withHidden(true)).Return([]model.Category{}, nil)
+	suite.mockRepo.On("FindCategoryByID", int64(2)).Return(&model.Category{ID: 2}, nil)
+	suite.mockRepo.On("DeleteCategoryByID", int64(2)).Return(nil)
+
+	categories, err := cached.FindAllCategory(false)
+	suite.NoError(err)
+	suite.Len(categories, 2)
+	suite.NoError(cached.DeleteCategory(2, DeleteReject))
+	categories, err = cached.FindAllCategory(false)
+
+	suite.NoError(err)
+	suite.Len(categories, 1)
+	suite.mockRepo.AssertExpectations(suite.T())
+}
+
+// TestCachedCategoryServiceHandleEvent tests that change events of other instances clear the cache
+func (suite *CategoryServiceTestSuite) TestCachedCategoryServiceHandleEvent() {
+	cached, _ := suite.newCachedCategoryService()
+	suite.mockRepo.On("FindCategoryByID", int64(1)).Return(&model.Category{ID: 1, CategoryName: "Shoes"}, nil).Once()
+	suite.mockRepo.On("FindCategoryByID", int64(1)).Return(&model.Category{ID: 1, CategoryName: "Sneakers"}, nil).Once()
+
+	_, err := cached.FindCategoryByID(1, false)
+	suite.NoError(err)
+	suite.NoError(cached.HandleEvent(context.Background(), &CategoryEvent{Type: EventUpdated, CategoryID: 1}))
+	category, err := cached.FindCategoryByID(1, false)
+
+	suite.NoError(err)
+	suite.Equal("Sneakers", category.CategoryName)
+	suite.mockRepo.AssertExpectations(suite.T())
+}
+
+// TestCachedCategoryServiceSharesLoads tests that concurrent misses of the same key load it only once
+func (suite *CategoryServiceTestSuite) TestCachedCategoryServiceSharesLoads() {
+	cached, _ := suite.newCachedCategoryService()
+	release := make(chan struct{})
+	var loads int32
+	load := func() (interface{}, error) {
+		atomic.AddInt32(&loads, 1)
+		<-release
+		return &model.Category{ID: 1}, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			category := &model.Category{}
+			suite.NoError(cached.lookup("FindCategoryByID", "id:1:false", category, load))
+			suite.Equal(int64(1), category.ID)
+		}()
+	}
+	// Gives the lookups time to join the load before it finishes
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	suite.Equal(int32(1), atomic.LoadInt32(&loads))
+}
+
+// racingCache runs beforeSet right before storing a value, to interleave an invalidation with a cache fill
+type racingCache struct {
+	cache.Cache
+	beforeSet func()
+}
+
+func (c *racingCache) Set(key string, value []byte, ttl time.Duration) error {
+	if c.beforeSet != nil {
+		c.beforeSet()
+	}
+	return c.Cache.Set(key, value, ttl)
+}
+
+// TestCachedCategoryServiceInvalidationDuringFill tests that a value stored after a concurrent invalidation is not served
+func (suite *CategoryServiceTestSuite) TestCachedCategoryServiceInvalidationDuringFill() {
+	backend := &racingCache{Cache: cache.NewLRUCache(100)}
+	cached := NewCachedCategoryService(suite.service, backend, time.Minute, nil)
+	suite.mockRepo.On("FindCategoryByID", int64(1)).Return(&model.Category{ID: 1, CategoryName: "Shoes"}, nil).Once()
+	suite.mockRepo.On("FindCategoryByID", int64(1)).Return(&model.Category{ID: 1, CategoryName: "Sneakers"}, nil).Once()
+
+	backend.beforeSet = func() {
+		backend.beforeSet = nil
+		cached.Invalidate()
+	}
+	_, err := cached.FindCategoryByID(1, false)
+	suite.NoError(err)
+	category, err := cached.FindCategoryByID(1, false)
+
+	suite.NoError(err)
+	suite.Equal("Sneakers", category.CategoryName)
+	suite.mockRepo.AssertExpectations(suite.T())
+}
+
+// TestKindOf tests the classification of service, repository and database errors
+func (suite *CategoryServiceTestSuite) TestKindOf() {
+	suite.Equal(KindAlreadyExists, KindOf(ErrSlugTaken))
+	suite.Equal(KindFailedPrecondition, KindOf(ErrCategoryCycle))
+	suite.Equal(KindInvalidArgument, KindOf(fmt.Errorf("%w: missing column \"name\"", ErrInvalidImportFile)))
+	suite.Equal(KindNotFound, KindOf(gorm.ErrRecordNotFound))
+	suite.Equal(KindConflict, KindOf(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}))
+	suite.Equal(KindInternal, KindOf(errors.New("database error")))
+	suite.True(errors.Is(fmt.Errorf("%w: bad header", ErrInvalidImportFile), ErrInvalidImportFile))
+}
+
 // TestErrorHandling tests error scenarios in the service methods
 func (suite *CategoryServiceTestSuite) TestErrorHandling() {
-	category := &model.Category{CategoryName: "Test Category"}
+	category := &model.Category{CategoryName: "Test Category", SortOrder: 1, Slug: "test-category"}
+	suite.mockRepo.On("FindCategoryBySlug", "test-category").Return((*model.Category)(nil), gorm.ErrRecordNotFound)
 	suite.mockRepo.On("CreateCategory", category).Return(int64(0), errors.New("database error"))
 
 	_, err := suite.service.AddCategory(category)
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/jinzhu/gorm"
+	"github.com/tongs-dev/shopping-platform/category/domain/model"
+	"github.com/tongs-dev/shopping-platform/category/domain/repository"
+)
+
+var (
+	// ErrSlugTaken is returned when an explicitly requested slug belongs to another category.
+	ErrSlugTaken = newError(KindAlreadyExists, "SLUG_TAKEN", "category", "slug", "slug is already used by another category")
+	// ErrCategoryPathNotFound is returned when a slug path does not lead to a category.
+	ErrCategoryPathNotFound = newError(KindNotFound, "CATEGORY_PATH_NOT_FOUND", "category", "path", "category path not found")
+)
+
+// defaultSlug is used for names without any letter or digit to build a slug from.
+const defaultSlug = "category"
+
+// Slugify turns a category name into a URL slug: lowercase letters and digits separated by single hyphens,
+// e.g. "Men's Running Shoes" becomes "men-s-running-shoes". Letters of any script are kept, so "Schuhe für
+// Damen" becomes "schuhe-für-damen" and "男鞋" stays "男鞋"; combining marks stay with their letter.
+func Slugify(name string) string {
+	var builder strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsMark(r) && !hyphen && builder.Len() > 0 {
+			builder.WriteRune(r)
+		} else if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if hyphen && builder.Len() > 0 {
+				builder.WriteByte('-')
+			}
+			builder.WriteRune(r)
+			hyphen = false
+		} else {
+			hyphen = true
+		}
+	}
+	return builder.String()
+}
+
+// FindCategoryBySlug retrieves a Category by its slug from the repository. Categories the listings leave out
+// are not found unless includeHidden is set.
+func (u *CategoryService) FindCategoryBySlug(slug string, includeHidden bool) (*model.Category, error) {
+	category, err := u.CategoryRepository.FindCategoryBySlug(slug)
+	if err != nil {
+		return nil, err
+	}
+	return visibleCategory(category, listFilter(includeHidden))
+}
+
+// ResolveCategoryPath resolves a slug path such as "/men/shoes/running" from a top-level category down,
+// returning the categories along the path. Every category must be the parent of the next one, and without
+// includeHidden a hidden or unpublished category anywhere on the path makes it not found.
+func (u *CategoryService) ResolveCategoryPath(path string, includeHidden bool) ([]model.Category, error) {
+	var slugs []string
+	for _, segment := range strings.Split(path, "/") {
+		if segment != "" {
+			slugs = append(slugs, segment)
+		}
+	}
+	if len(slugs) == 0 {
+		return nil, ErrCategoryPathNotFound
+	}
+
+	categories, err := u.CategoryRepository.FindCategoriesBySlugs(slugs, listFilter(includeHidden))
+	if err != nil {
+		return nil, err
+	}
+	bySlug := make(map[string]model.Category, len(categories))
+	for _, category := range categories {
+		bySlug[category.Slug] = category
+	}
+
+	resolved := make([]model.Category, 0, len(slugs))
+	parent := int64(0)
+	for _, slug := range slugs {
+		category, ok := bySlug[slug]
+		if !ok || category.CategoryParent != parent {
+			return nil, ErrCategoryPathNotFound
+		}
+		resolved = append(resolved, category)
+		parent = category.ID
+	}
+	return resolved, nil
+}
+
+// Helper function to set the slug of a category being created: a requested slug is normalized and must be
+// free, otherwise the slug is derived from the name with a numeric suffix on collision
+func (u *CategoryService) assignSlug(category *model.Category) error {
+	if category.Slug != "" {
+		return u.claimSlug(category, 0)
+	}
+
+	base := Slugify(category.CategoryName)
+	if base == "" {
+		base = defaultSlug
+	}
+	taken, err := u.CategoryRepository.FindSlugsWithPrefix(base)
+	if err != nil {
+		return err
+	}
+	used := make(map[string]bool, len(taken))
+	for _, slug := range taken {
+		used[slug] = true
+	}
+
+	slug := base
+	for suffix := 2; used[slug]; suffix++ {
+		slug = base + "-" + strconv.Itoa(suffix)
+	}
+	category.Slug = slug
+	return nil
+}
+
+// Helper function to normalize a requested slug and check that no category other than categoryID uses it
+func (u *CategoryService) claimSlug(category *model.Category, categoryID int64) error {
+	slug := Slugify(category.Slug)
+	if slug == "" {
+		slug = defaultSlug
+	}
+
+	owner, err := u.CategoryRepository.FindCategoryBySlug(slug)
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		return err
+	}
+	if err == nil && (categoryID == 0 || owner.ID != categoryID) {
+		return ErrSlugTaken
+	}
+	category.Slug = slug
+	return nil
+}
+
+// MigrateSlugs prepares a database created before categories had slugs: it adds the slug column, gives
+// every category without a slug one derived from its name as CreateCategory would, then creates the missing
+// columns and indexes, including the unique slug index. It returns the number of categories that got a slug.
+// Run it from a single instance, as concurrent runs could hand out the same slug twice.
+func MigrateSlugs(categoryRepository repository.ICategoryRepository) (int, error) {
+	if err := categoryRepository.AddSlugColumn(); err != nil {
+		return 0, err
+	}
+	categories, err := categoryRepository.FindCategoriesWithoutSlug()
+	if err != nil {
+		return 0, err
+	}
+
+	u := &CategoryService{CategoryRepository: categoryRepository}
+	for i := range categories {
+		if err := u.assignSlug(&categories[i]); err != nil {
+			return i, err
+		}
+		if err := categoryRepository.UpdateCategorySlug(categories[i].ID, categories[i].Slug); err != nil {
+			return i, err
+		}
+	}
+	return len(categories), categoryRepository.MigrateTable()
+}
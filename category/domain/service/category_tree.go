@@ -0,0 +1,108 @@
+package service
+
+import (
+	"github.com/tongs-dev/shopping-platform/category/domain/model"
+)
+
+// CategoryNode is a Category together with its nested subcategories.
+type CategoryNode struct {
+	Category model.Category
+	Children []*CategoryNode
+}
+
+// GetCategoryTree returns the category hierarchy as nested nodes. With rootID 0 the tree starts at the
+// top-level categories (CategoryParent 0), otherwise at the given category. maxDepth limits the number of
+// levels returned, counting the root level as 1; 0 returns all levels. Without includeHidden, hidden and
+// unpublished categories are left out together with their subtrees.
+func (u *CategoryService) GetCategoryTree(rootID int64, maxDepth uint32, includeHidden bool) ([]*CategoryNode, error) {
+	filter := listFilter(includeHidden)
+	var roots []model.Category
+	if rootID == 0 {
+		categories, err := u.CategoryRepository.FindCategoryByParent(0, filter)
+		if err != nil {
+			return nil, err
+		}
+		roots = categories
+	} else {
+		root, err := u.CategoryRepository.FindCategoryByID(rootID)
+		if err != nil {
+			return nil, err
+		}
+		if root, err = visibleCategory(root, filter); err != nil {
+			return nil, err
+		}
+		roots = []model.Category{*root}
+	}
+
+	nodes := make([]*CategoryNode, 0, len(roots))
+	level := make(map[int64]*CategoryNode, len(roots))
+	// visited guards against cycles in data written before parents were validated
+	visited := make(map[int64]bool, len(roots))
+	for _, root := range roots {
+		node := &CategoryNode{Category: root}
+		nodes = append(nodes, node)
+		level[root.ID] = node
+		visited[root.ID] = true
+	}
+
+	// Loads the tree one level at a time, one query per level
+	for depth := uint32(1); len(level) > 0 && (maxDepth == 0 || depth < maxDepth); depth++ {
+		parentIDs := make([]int64, 0, len(level))
+		for id := range level {
+			parentIDs = append(parentIDs, id)
+		}
+
+		children, err := u.CategoryRepository.FindCategoriesByParents(parentIDs, filter)
+		if err != nil {
+			return nil, err
+		}
+
+		next := make(map[int64]*CategoryNode, len(children))
+		for _, child := range children {
+			if visited[child.ID] {
+				continue
+			}
+			visited[child.ID] = true
+			node := &CategoryNode{Category: child}
+			parent := level[child.CategoryParent]
+			parent.Children = append(parent.Children, node)
+			next[child.ID] = node
+		}
+		level = next
+	}
+	return nodes, nil
+}
+
+// GetCategoryAncestors returns the breadcrumb path of a category, from its top-level ancestor down to
+// the category itself. Without includeHidden, a hidden or unpublished category on the path makes the
+// category not found, as it is not reachable in the tree.
+func (u *CategoryService) GetCategoryAncestors(categoryID int64, includeHidden bool) ([]model.Category, error) {
+	filter := listFilter(includeHidden)
+	category, err := u.CategoryRepository.FindCategoryByID(categoryID)
+	if err != nil {
+		return nil, err
+	}
+	if category, err = visibleCategory(category, filter); err != nil {
+		return nil, err
+	}
+
+	path := []model.Category{*category}
+	visited := map[int64]bool{category.ID: true}
+	for category.CategoryParent != 0 && !visited[category.CategoryParent] {
+		category, err = u.CategoryRepository.FindCategoryByID(category.CategoryParent)
+		if err != nil {
+			return nil, err
+		}
+		if category, err = visibleCategory(category, filter); err != nil {
+			return nil, err
+		}
+		visited[category.ID] = true
+		path = append(path, *category)
+	}
+
+	// Reverses the path so it starts at the root
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path, nil
+}
@@ -1,8 +1,13 @@
 package handler
 
 import (
+	"bufio"
 	"context"
 	"errors"
+	"io"
+	"net/http"
+	"strings"
+	microerrors "github.com/micro/go-micro/v2/errors"
 	"github.com/prometheus/common/log"
 	"github.com/tongs-dev/shopping-platform/category/common"
 	"github.com/tongs-dev/shopping-platform/category/domain/model"
@@ -10,17 +15,55 @@ import (
 	categorypb "github.com/tongs-dev/shopping-platform/category/proto/category"
 )
 
+// IncludeHiddenPermission lets a caller list hidden and unpublished categories
+const IncludeHiddenPermission = "Category.IncludeHidden"
+
 type CategoryHandler struct {
 	CategoryService service.ICategoryService
 }
 
-// Helper function to handle error response
+// serviceName identifies the category service in the errors it returns
+const serviceName = "go.micro.service.category"
+
+// kindCodes are the HTTP status codes of the error kinds of the category service, which the go-micro gRPC server
+// turns into the matching gRPC codes. A collision with a concurrent request is Unavailable, the code clients
+// retry on, so it can be told apart from a value that already exists
+var kindCodes = map[service.ErrorKind]int32{
+	service.KindNotFound:           http.StatusNotFound,
+	service.KindAlreadyExists:      http.StatusConflict,
+	service.KindInvalidArgument:    http.StatusBadRequest,
+	service.KindConflict:           http.StatusServiceUnavailable,
+	service.KindFailedPrecondition: http.StatusPreconditionFailed,
+}
+
+// Helper function to handle error response, converting service errors to go-micro errors
 func handleErrorResponse(err error) error {
-	if err != nil {
-		log.Error(err)
+	if err == nil {
+		return nil
+	}
+	log.Error(err)
+	// Errors of the handler's own checks already are go-micro errors
+	if _, ok := err.(*microerrors.Error); ok {
 		return err
 	}
-	return nil
+	return serviceError(err)
+}
+
+// Helper function to map an error to a go-micro error with the code of its kind. The go-micro gRPC server
+// attaches these errors to the status it sends, other errors arrive as unknown errors without their details. Status is the
+// reason of the errors declared by the service, e.g. "SLUG_TAKEN", and the kind of all others, e.g. "NOT_FOUND".
+func serviceError(err error) error {
+	kind := service.KindOf(err)
+	code, ok := kindCodes[kind]
+	if !ok {
+		code = http.StatusInternalServerError
+	}
+	reason := strings.ToUpper(strings.ReplaceAll(kind.String(), " ", "_"))
+	var serviceErr *service.Error
+	if errors.As(err, &serviceErr) && serviceErr.Reason != "" {
+		reason = serviceErr.Reason
+	}
+	return &microerrors.Error{Id: serviceName, Code: code, Detail: err.Error(), Status: reason}
 }
 
 // Helper function to map category to response
@@ -60,9 +103,10 @@ func (c *CategoryHandler) UpdateCategory(ctx context.Context, request *categoryp
 	return nil
 }
 
-// DeleteCategory provides a service to delete a category by ID
+// DeleteCategory provides a service to delete a category by ID, handling its subcategories according to the policy
 func (c *CategoryHandler) DeleteCategory(ctx context.Context, request *categorypb.DeleteCategoryRequest, response *categorypb.DeleteCategoryResponse) error {
-	if err := c.CategoryService.DeleteCategory(request.CategoryId); err != nil {
+	// The protobuf enum values match service.DeletePolicy
+	if err := c.CategoryService.DeleteCategory(request.CategoryId, service.DeletePolicy(request.Policy)); err != nil {
 		return handleErrorResponse(err)
 	}
 
@@ -72,44 +116,373 @@ func (c *CategoryHandler) DeleteCategory(ctx context.Context, request *categoryp
 
 // FindCategoryByName finds a category by its name
 func (c *CategoryHandler) FindCategoryByName(ctx context.Context, request *categorypb.FindByNameRequest, response *categorypb.CategoryResponse) error {
-	category, err := c.CategoryService.FindCategoryByName(request.CategoryName)
+	category, err := c.CategoryService.FindCategoryByName(request.CategoryName, canIncludeHidden(ctx))
 	if err != nil {
 		return handleErrorResponse(err)
 	}
+	if err := c.CategoryService.LocalizeCategories([]*model.Category{category}, request.Locale); err != nil {
+		return handleErrorResponse(err)
+	}
 
 	return mapCategoryToResponse(category, response)
 }
 
 // FindCategoryByID finds a category by its ID
 func (c *CategoryHandler) FindCategoryByID(ctx context.Context, request *categorypb.FindByIdRequest, response *categorypb.CategoryResponse) error {
-	category, err := c.CategoryService.FindCategoryByID(request.CategoryId)
+	category, err := c.CategoryService.FindCategoryByID(request.CategoryId, canIncludeHidden(ctx))
 	if err != nil {
 		return handleErrorResponse(err)
 	}
+	if err := c.CategoryService.LocalizeCategories([]*model.Category{category}, request.Locale); err != nil {
+		return handleErrorResponse(err)
+	}
 
 	return mapCategoryToResponse(category, response)
 }
 
+// FindCategoryBySlug finds a category by its URL slug
+func (c *CategoryHandler) FindCategoryBySlug(ctx context.Context, request *categorypb.FindBySlugRequest, response *categorypb.CategoryResponse) error {
+	category, err := c.CategoryService.FindCategoryBySlug(request.Slug, canIncludeHidden(ctx))
+	if err != nil {
+		return handleErrorResponse(err)
+	}
+	if err := c.CategoryService.LocalizeCategories([]*model.Category{category}, request.Locale); err != nil {
+		return handleErrorResponse(err)
+	}
+
+	return mapCategoryToResponse(category, response)
+}
+
+// ResolveCategoryPath finds the categories along a slug path, the last one being the category the path points to
+func (c *CategoryHandler) ResolveCategoryPath(ctx context.Context, request *categorypb.ResolvePathRequest, response *categorypb.FindAllResponse) error {
+	categorySlice, err := c.CategoryService.ResolveCategoryPath(request.Path, canIncludeHidden(ctx))
+	if err != nil {
+		return handleErrorResponse(err)
+	}
+	if err := c.localizeCategories(categorySlice, request.Locale); err != nil {
+		return handleErrorResponse(err)
+	}
+
+	return mapCategoriesToResponse(categorySlice, response)
+}
+
 // FindCategoryByLevel finds categories by their level
 func (c *CategoryHandler) FindCategoryByLevel(ctx context.Context, request *categorypb.FindByLevelRequest, response *categorypb.FindAllResponse) error {
-	return c.findCategories(ctx, request.Level, response)
+	return c.findCategories(ctx, request.Level, request.IncludeHidden, request.Locale, response)
 }
 
 // FindCategoryByParent finds categories by their parent ID
 func (c *CategoryHandler) FindCategoryByParent(ctx context.Context, request *categorypb.FindByParentRequest, response *categorypb.FindAllResponse) error {
-	return c.findCategories(ctx, request.ParentId, response)
+	return c.findCategories(ctx, request.ParentId, request.IncludeHidden, request.Locale, response)
 }
 
 // FindAllCategory retrieves all categories
 func (c *CategoryHandler) FindAllCategory(ctx context.Context, request *categorypb.FindAllRequest, response *categorypb.FindAllResponse) error {
-	categorySlice, err := c.CategoryService.FindAllCategory()
+	if err := checkIncludeHidden(ctx, request.IncludeHidden); err != nil {
+		return handleErrorResponse(err)
+	}
+
+	categorySlice, err := c.CategoryService.FindAllCategory(request.IncludeHidden)
 	if err != nil {
 		return handleErrorResponse(err)
 	}
+	if err := c.localizeCategories(categorySlice, request.Locale); err != nil {
+		return handleErrorResponse(err)
+	}
 
 	return mapCategoriesToResponse(categorySlice, response)
 }
 
+// GetCategoryTree returns the nested category hierarchy, optionally rooted at a category and depth-limited
+func (c *CategoryHandler) GetCategoryTree(ctx context.Context, request *categorypb.CategoryTreeRequest, response *categorypb.CategoryTreeResponse) error {
+	nodes, err := c.CategoryService.GetCategoryTree(request.RootId, request.MaxDepth, canIncludeHidden(ctx))
+	if err != nil {
+		return handleErrorResponse(err)
+	}
+	if err := c.CategoryService.LocalizeCategories(collectNodeCategories(nodes, nil), request.Locale); err != nil {
+		return handleErrorResponse(err)
+	}
+
+	for _, node := range nodes {
+		treeNode, err := mapNodeToResponse(node)
+		if err != nil {
+			return handleErrorResponse(err)
+		}
+		response.Nodes = append(response.Nodes, treeNode)
+	}
+	return nil
+}
+
+// GetCategoryAncestors returns the breadcrumb path from the top-level category down to a category
+func (c *CategoryHandler) GetCategoryAncestors(ctx context.Context, request *categorypb.FindByIdRequest, response *categorypb.FindAllResponse) error {
+	categorySlice, err := c.CategoryService.GetCategoryAncestors(request.CategoryId, canIncludeHidden(ctx))
+	if err != nil {
+		return handleErrorResponse(err)
+	}
+	if err := c.localizeCategories(categorySlice, request.Locale); err != nil {
+		return handleErrorResponse(err)
+	}
+
+	return mapCategoriesToResponse(categorySlice, response)
+}
+
+// MoveCategory moves a category with its subtree under a new parent and returns the moved subtree
+func (c *CategoryHandler) MoveCategory(ctx context.Context, request *categorypb.MoveCategoryRequest, response *categorypb.MoveCategoryResponse) error {
+	node, err := c.CategoryService.MoveCategory(request.CategoryId, request.ParentId, request.Position)
+	if err != nil {
+		return handleErrorResponse(err)
+	}
+
+	subtree, err := mapNodeToResponse(node)
+	if err != nil {
+		return handleErrorResponse(err)
+	}
+
+	response.Message = "Category moved successfully"
+	response.Subtree = subtree
+	return nil
+}
+
+// UpsertCategoryTranslation creates or replaces the name and description of a category in a locale
+func (c *CategoryHandler) UpsertCategoryTranslation(ctx context.Context, request *categorypb.CategoryTranslationRequest, response *categorypb.CategoryTranslationResponse) error {
+	translation := &model.CategoryTranslation{}
+	if err := common.SwapTo(request, translation); err != nil {
+		return handleErrorResponse(err)
+	}
+
+	if err := c.CategoryService.UpsertCategoryTranslation(translation); err != nil {
+		return handleErrorResponse(err)
+	}
+
+	response.Message = "Category translation saved successfully"
+	return nil
+}
+
+// DeleteCategoryTranslation deletes the translation of a category in a locale
+func (c *CategoryHandler) DeleteCategoryTranslation(ctx context.Context, request *categorypb.DeleteTranslationRequest, response *categorypb.CategoryTranslationResponse) error {
+	if err := c.CategoryService.DeleteCategoryTranslation(request.CategoryId, request.Locale); err != nil {
+		return handleErrorResponse(err)
+	}
+
+	response.Message = "Category translation deleted successfully"
+	return nil
+}
+
+// importChunkSize is the size of the file chunks ExportCategories streams
+const importChunkSize = 32 * 1024
+
+// ImportCategories creates categories from a CSV or JSON file streamed in chunks and replies with a per-row report
+func (c *CategoryHandler) ImportCategories(ctx context.Context, stream categorypb.Category_ImportCategoriesStream) error {
+	// go-micro clients open the stream with an empty message, the options come with the first chunk
+	var first *categorypb.ImportCategoriesRequest
+	var err error
+	for first == nil || (len(first.Data) == 0 && !first.Last) {
+		if first, err = stream.Recv(); err != nil {
+			return handleErrorResponse(err)
+		}
+	}
+	options := service.ImportOptions{
+		// The protobuf enum values match service.DataFormat
+		Format:       service.DataFormat(first.Format),
+		DryRun:       first.DryRun,
+		AllOrNothing: first.AllOrNothing,
+	}
+
+	// Feeds the received chunks to the importer while it parses them
+	reader, writer := io.Pipe()
+	go func() {
+		message := first
+		for {
+			_, err := writer.Write(message.Data)
+			if err != nil || message.Last {
+				writer.CloseWithError(err)
+				return
+			}
+			if message, err = stream.Recv(); err != nil {
+				// A client closing the stream without a last message ends the file as well
+				if err == io.EOF {
+					err = nil
+				}
+				writer.CloseWithError(err)
+				return
+			}
+		}
+	}()
+
+	report, err := c.CategoryService.ImportCategories(reader, options)
+	// Unblocks the receiving goroutine if the importer stopped reading early
+	reader.Close()
+	if err != nil {
+		return handleErrorResponse(err)
+	}
+
+	response := &categorypb.ImportCategoriesResponse{
+		Rows:      int32(report.Rows),
+		Imported:  int32(report.Imported),
+		Committed: report.Committed,
+	}
+	for _, rowError := range report.Errors {
+		response.Errors = append(response.Errors, &categorypb.ImportRowError{
+			Row:          int32(rowError.Row),
+			CategoryName: rowError.CategoryName,
+			Message:      rowError.Message,
+		})
+	}
+	return stream.SendMsg(response)
+}
+
+// ExportCategories streams all categories as a CSV or JSON file that ImportCategories accepts
+func (c *CategoryHandler) ExportCategories(ctx context.Context, request *categorypb.ExportCategoriesRequest, stream categorypb.Category_ExportCategoriesStream) error {
+	writer := bufio.NewWriterSize(&exportStreamWriter{stream: stream}, importChunkSize)
+	// The protobuf enum values match service.DataFormat
+	if err := c.CategoryService.ExportCategories(writer, service.DataFormat(request.Format)); err != nil {
+		return handleErrorResponse(err)
+	}
+	if err := writer.Flush(); err != nil {
+		return handleErrorResponse(err)
+	}
+	return stream.Close()
+}
+
+// exportStreamWriter sends everything written to it as ExportCategoriesResponse chunks
+type exportStreamWriter struct {
+	stream categorypb.Category_ExportCategoriesStream
+}
+
+func (w *exportStreamWriter) Write(data []byte) (int, error) {
+	// The message is sent before Write returns, but copying keeps the chunk independent of the caller's buffer
+	chunk := append([]byte(nil), data...)
+	if err := w.stream.Send(&categorypb.ExportCategoriesResponse{Data: chunk}); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// CreateCategoryAttribute defines a new product attribute of a category
+func (c *CategoryHandler) CreateCategoryAttribute(ctx context.Context, request *categorypb.CategoryAttributeRequest, response *categorypb.CreateCategoryAttributeResponse) error {
+	attributeID, err := c.CategoryService.AddCategoryAttribute(mapRequestToAttribute(request))
+	if err != nil {
+		return handleErrorResponse(err)
+	}
+
+	response.Message = "Category attribute created successfully"
+	response.AttributeId = attributeID
+	return nil
+}
+
+// UpdateCategoryAttribute replaces the definition of a product attribute
+func (c *CategoryHandler) UpdateCategoryAttribute(ctx context.Context, request *categorypb.CategoryAttributeRequest, response *categorypb.CategoryAttributeMessageResponse) error {
+	if err := c.CategoryService.UpdateCategoryAttribute(mapRequestToAttribute(request)); err != nil {
+		return handleErrorResponse(err)
+	}
+
+	response.Message = "Category attribute updated successfully"
+	return nil
+}
+
+// DeleteCategoryAttribute deletes a product attribute definition
+func (c *CategoryHandler) DeleteCategoryAttribute(ctx context.Context, request *categorypb.DeleteCategoryAttributeRequest, response *categorypb.CategoryAttributeMessageResponse) error {
+	if err := c.CategoryService.DeleteCategoryAttribute(request.AttributeId); err != nil {
+		return handleErrorResponse(err)
+	}
+
+	response.Message = "Category attribute deleted successfully"
+	return nil
+}
+
+// FindCategoryAttributes provides the attribute schema of a category, optionally with inherited attributes
+func (c *CategoryHandler) FindCategoryAttributes(ctx context.Context, request *categorypb.FindCategoryAttributesRequest, response *categorypb.FindCategoryAttributesResponse) error {
+	attributes, err := c.CategoryService.FindCategoryAttributes(request.CategoryId, request.Inherited)
+	if err != nil {
+		return handleErrorResponse(err)
+	}
+
+	for i := range attributes {
+		response.Attributes = append(response.Attributes, mapAttributeToResponse(&attributes[i]))
+	}
+	return nil
+}
+
+// ValidateCategoryAttributes checks product attribute values against the attribute schema of a category
+func (c *CategoryHandler) ValidateCategoryAttributes(ctx context.Context, request *categorypb.ValidateAttributesRequest, response *categorypb.ValidateAttributesResponse) error {
+	violations, err := c.CategoryService.ValidateCategoryAttributes(request.CategoryId, request.Attributes)
+	if err != nil {
+		return handleErrorResponse(err)
+	}
+
+	response.Valid = len(violations) == 0
+	for _, violation := range violations {
+		response.Violations = append(response.Violations, &categorypb.AttributeViolation{
+			Name:    violation.Name,
+			Message: violation.Message,
+		})
+	}
+	return nil
+}
+
+// Helper function to map an attribute request to an attribute definition, the type names match in lowercase
+func mapRequestToAttribute(request *categorypb.CategoryAttributeRequest) *model.CategoryAttribute {
+	return &model.CategoryAttribute{
+		ID:          request.Id,
+		CategoryID:  request.CategoryId,
+		Name:        request.Name,
+		Type:        model.AttributeType(strings.ToLower(request.Type.String())),
+		Unit:        request.Unit,
+		EnumValues:  request.EnumValues,
+		Required:    request.Required,
+		Inheritable: request.Inheritable,
+	}
+}
+
+// Helper function to map an attribute definition to its response
+func mapAttributeToResponse(attribute *model.CategoryAttribute) *categorypb.CategoryAttributeResponse {
+	return &categorypb.CategoryAttributeResponse{
+		Id:          attribute.ID,
+		CategoryId:  attribute.CategoryID,
+		Name:        attribute.Name,
+		Type:        categorypb.AttributeType(categorypb.AttributeType_value[strings.ToUpper(string(attribute.Type))]),
+		Unit:        attribute.Unit,
+		EnumValues:  attribute.EnumValues,
+		Required:    attribute.Required,
+		Inheritable: attribute.Inheritable,
+	}
+}
+
+// Helper function to translate a slice of categories in place
+func (c *CategoryHandler) localizeCategories(categorySlice []model.Category, locale string) error {
+	categories := make([]*model.Category, len(categorySlice))
+	for i := range categorySlice {
+		categories[i] = &categorySlice[i]
+	}
+	return c.CategoryService.LocalizeCategories(categories, locale)
+}
+
+// Helper function to collect the categories of a tree so they are translated with one lookup
+func collectNodeCategories(nodes []*service.CategoryNode, categories []*model.Category) []*model.Category {
+	for _, node := range nodes {
+		categories = append(categories, &node.Category)
+		categories = collectNodeCategories(node.Children, categories)
+	}
+	return categories
+}
+
+// Utility function to map a category tree node and its subcategories to a response
+func mapNodeToResponse(node *service.CategoryNode) (*categorypb.CategoryNode, error) {
+	treeNode := &categorypb.CategoryNode{Category: &categorypb.CategoryResponse{}}
+	if err := mapCategoryToResponse(&node.Category, treeNode.Category); err != nil {
+		return nil, err
+	}
+
+	for _, child := range node.Children {
+		childNode, err := mapNodeToResponse(child)
+		if err != nil {
+			return nil, err
+		}
+		treeNode.Children = append(treeNode.Children, childNode)
+	}
+	return treeNode, nil
+}
+
 // Utility function to map multiple categories to a response
 func mapCategoriesToResponse(categorySlice []model.Category, response *categorypb.FindAllResponse) error {
 	for _, cg := range categorySlice {
@@ -124,15 +497,19 @@ func mapCategoriesToResponse(categorySlice []model.Category, response *categoryp
 }
 
 // Helper function to reduce duplication in FindCategoryByLevel and FindCategoryByParent
-func (c *CategoryHandler) findCategories(ctx context.Context, parentOrLevel interface{}, response *categorypb.FindAllResponse) error {
+func (c *CategoryHandler) findCategories(ctx context.Context, parentOrLevel interface{}, includeHidden bool, locale string, response *categorypb.FindAllResponse) error {
+	if err := checkIncludeHidden(ctx, includeHidden); err != nil {
+		return handleErrorResponse(err)
+	}
+
 	var categorySlice []model.Category
 	var err error
 
 	switch v := parentOrLevel.(type) {
 	case uint32:
-		categorySlice, err = c.CategoryService.FindCategoryByLevel(v)
+		categorySlice, err = c.CategoryService.FindCategoryByLevel(v, includeHidden)
 	case int64:
-		categorySlice, err = c.CategoryService.FindCategoryByParent(v)
+		categorySlice, err = c.CategoryService.FindCategoryByParent(v, includeHidden)
 	default:
 		err = errors.New("invalid parameter type")
 	}
@@ -140,6 +517,31 @@ func (c *CategoryHandler) findCategories(ctx context.Context, parentOrLevel inte
 	if err != nil {
 		return handleErrorResponse(err)
 	}
+	if err := c.localizeCategories(categorySlice, locale); err != nil {
+		return handleErrorResponse(err)
+	}
 
 	return mapCategoriesToResponse(categorySlice, response)
 }
+
+// Helper function to show hidden categories in lookups and trees only to callers holding IncludeHiddenPermission,
+// everyone else gets them as not found
+func canIncludeHidden(ctx context.Context) bool {
+	user, ok := common.AuthUserFromContext(ctx)
+	return ok && user.HasPermission(IncludeHiddenPermission)
+}
+
+// Helper function to allow listing hidden categories only to callers holding IncludeHiddenPermission
+func checkIncludeHidden(ctx context.Context, includeHidden bool) error {
+	if !includeHidden {
+		return nil
+	}
+	user, ok := common.AuthUserFromContext(ctx)
+	if !ok {
+		return microerrors.Unauthorized(serviceName, "authentication required to include hidden categories")
+	}
+	if !user.HasPermission(IncludeHiddenPermission) {
+		return microerrors.Forbidden(serviceName, "user %s is not allowed to include hidden categories", user.UserName)
+	}
+	return nil
+}
@@ -3,11 +3,24 @@ package handler
 import (
 	"context"
 	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
 	"testing"
 
+	"github.com/go-sql-driver/mysql"
+	"github.com/jinzhu/gorm"
+	"github.com/micro/go-micro/v2/client"
+	grpcclient "github.com/micro/go-micro/v2/client/grpc"
+	microerrors "github.com/micro/go-micro/v2/errors"
+	"github.com/micro/go-micro/v2/registry/memory"
+	"github.com/micro/go-micro/v2/server"
+	grpcserver "github.com/micro/go-micro/v2/server/grpc"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+	"github.com/tongs-dev/shopping-platform/category/common"
 	"github.com/tongs-dev/shopping-platform/category/domain/model"
+	"github.com/tongs-dev/shopping-platform/category/domain/service"
 	categorypb "github.com/tongs-dev/shopping-platform/category/proto/category"
 )
 
@@ -26,36 +39,147 @@ func (m *MockCategoryService) UpdateCategory(category *model.Category) error {
 	return args.Error(0)
 }
 
-func (m *MockCategoryService) DeleteCategory(categoryID int64) error {
-	args := m.Called(categoryID)
+func (m *MockCategoryService) DeleteCategory(categoryID int64, policy service.DeletePolicy) error {
+	args := m.Called(categoryID, policy)
 	return args.Error(0)
 }
 
-func (m *MockCategoryService) FindCategoryByID(categoryID int64) (*model.Category, error) {
-	args := m.Called(categoryID)
+func (m *MockCategoryService) FindCategoryByID(categoryID int64, includeHidden bool) (*model.Category, error) {
+	args := m.Called(categoryID, includeHidden)
 	return args.Get(0).(*model.Category), args.Error(1)
 }
 
-func (m *MockCategoryService) FindCategoryByName(categoryName string) (*model.Category, error) {
-	args := m.Called(categoryName)
+func (m *MockCategoryService) FindCategoryByName(categoryName string, includeHidden bool) (*model.Category, error) {
+	args := m.Called(categoryName, includeHidden)
 	return args.Get(0).(*model.Category), args.Error(1)
 }
 
-func (m *MockCategoryService) FindCategoryByLevel(level uint32) ([]model.Category, error) {
-	args := m.Called(level)
+func (m *MockCategoryService) FindCategoryByLevel(level uint32, includeHidden bool) ([]model.Category, error) {
+	args := m.Called(level, includeHidden)
 	return args.Get(0).([]model.Category), args.Error(1)
 }
 
-func (m *MockCategoryService) FindCategoryByParent(parent int64) ([]model.Category, error) {
-	args := m.Called(parent)
+func (m *MockCategoryService) FindCategoryByParent(parent int64, includeHidden bool) ([]model.Category, error) {
+	args := m.Called(parent, includeHidden)
 	return args.Get(0).([]model.Category), args.Error(1)
 }
 
-func (m *MockCategoryService) FindAllCategory() ([]model.Category, error) {
-	args := m.Called()
+func (m *MockCategoryService) FindAllCategory(includeHidden bool) ([]model.Category, error) {
+	args := m.Called(includeHidden)
 	return args.Get(0).([]model.Category), args.Error(1)
 }
 
+func (m *MockCategoryService) GetCategoryTree(rootID int64, maxDepth uint32, includeHidden bool) ([]*service.CategoryNode, error) {
+	args := m.Called(rootID, maxDepth, includeHidden)
+	return args.Get(0).([]*service.CategoryNode), args.Error(1)
+}
+
+func (m *MockCategoryService) GetCategoryAncestors(categoryID int64, includeHidden bool) ([]model.Category, error) {
+	args := m.Called(categoryID, includeHidden)
+	return args.Get(0).([]model.Category), args.Error(1)
+}
+
+func (m *MockCategoryService) MoveCategory(categoryID int64, parentID int64, position int32) (*service.CategoryNode, error) {
+	args := m.Called(categoryID, parentID, position)
+	return args.Get(0).(*service.CategoryNode), args.Error(1)
+}
+
+func (m *MockCategoryService) FindCategoryBySlug(slug string, includeHidden bool) (*model.Category, error) {
+	args := m.Called(slug, includeHidden)
+	return args.Get(0).(*model.Category), args.Error(1)
+}
+
+func (m *MockCategoryService) ResolveCategoryPath(path string, includeHidden bool) ([]model.Category, error) {
+	args := m.Called(path, includeHidden)
+	return args.Get(0).([]model.Category), args.Error(1)
+}
+
+func (m *MockCategoryService) UpsertCategoryTranslation(translation *model.CategoryTranslation) error {
+	args := m.Called(translation)
+	return args.Error(0)
+}
+
+func (m *MockCategoryService) DeleteCategoryTranslation(categoryID int64, locale string) error {
+	args := m.Called(categoryID, locale)
+	return args.Error(0)
+}
+
+func (m *MockCategoryService) LocalizeCategories(categories []*model.Category, locale string) error {
+	args := m.Called(categories, locale)
+	return args.Error(0)
+}
+
+func (m *MockCategoryService) ImportCategories(r io.Reader, options service.ImportOptions) (*service.ImportReport, error) {
+	args := m.Called(r, options)
+	return args.Get(0).(*service.ImportReport), args.Error(1)
+}
+
+func (m *MockCategoryService) ExportCategories(w io.Writer, format service.DataFormat) error {
+	args := m.Called(w, format)
+	return args.Error(0)
+}
+
+func (m *MockCategoryService) AddCategoryAttribute(attribute *model.CategoryAttribute) (int64, error) {
+	args := m.Called(attribute)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockCategoryService) UpdateCategoryAttribute(attribute *model.CategoryAttribute) error {
+	args := m.Called(attribute)
+	return args.Error(0)
+}
+
+func (m *MockCategoryService) DeleteCategoryAttribute(attributeID int64) error {
+	args := m.Called(attributeID)
+	return args.Error(0)
+}
+
+func (m *MockCategoryService) FindCategoryAttributes(categoryID int64, inherited bool) ([]model.CategoryAttribute, error) {
+	args := m.Called(categoryID, inherited)
+	return args.Get(0).([]model.CategoryAttribute), args.Error(1)
+}
+
+func (m *MockCategoryService) ValidateCategoryAttributes(categoryID int64, values map[string]string) ([]service.AttributeViolation, error) {
+	args := m.Called(categoryID, values)
+	return args.Get(0).([]service.AttributeViolation), args.Error(1)
+}
+
+// fakeImportStream replays import requests and keeps the response sent back
+type fakeImportStream struct {
+	categorypb.Category_ImportCategoriesStream
+	requests []*categorypb.ImportCategoriesRequest
+	response *categorypb.ImportCategoriesResponse
+}
+
+func (s *fakeImportStream) Recv() (*categorypb.ImportCategoriesRequest, error) {
+	if len(s.requests) == 0 {
+		return nil, io.EOF
+	}
+	request := s.requests[0]
+	s.requests = s.requests[1:]
+	return request, nil
+}
+
+func (s *fakeImportStream) SendMsg(message interface{}) error {
+	s.response = message.(*categorypb.ImportCategoriesResponse)
+	return nil
+}
+
+// fakeExportStream collects the chunks sent by an export
+type fakeExportStream struct {
+	categorypb.Category_ExportCategoriesStream
+	data []byte
+}
+
+func (s *fakeExportStream) Send(response *categorypb.ExportCategoriesResponse) error {
+	s.data = append(s.data, response.Data...)
+	return nil
+}
+
+func (s *fakeExportStream) Close() error {
+	return nil
+}
+
 // CategoryHandlerTestSuite is the test suite for CategoryHandler
 type CategoryHandlerTestSuite struct {
 	suite.Suite
@@ -67,6 +191,8 @@ type CategoryHandlerTestSuite struct {
 func (suite *CategoryHandlerTestSuite) SetupTest() {
 	suite.mockService = new(MockCategoryService)
 	suite.handler = &CategoryHandler{CategoryService: suite.mockService}
+	// Responses stay in the default locale unless a test expects a translation
+	suite.mockService.On("LocalizeCategories", mock.Anything, "").Return(nil).Maybe()
 }
 
 // TestCreateCategory tests the CreateCategory method
@@ -91,11 +217,36 @@ func (suite *CategoryHandlerTestSuite) TestCreateCategoryError() {
 
 	err := suite.handler.CreateCategory(context.Background(), categoryRequest, response)
 
-	suite.Error(err)
-	suite.Equal("database error", err.Error())
+	microErr := microerrors.FromError(err)
+	suite.Equal(int32(http.StatusInternalServerError), microErr.Code)
+	suite.Equal("database error", microErr.Detail)
 	suite.mockService.AssertExpectations(suite.T())
 }
 
+// TestCreateCategorySlugTaken tests that a taken slug is a conflict with the reason as status
+func (suite *CategoryHandlerTestSuite) TestCreateCategorySlugTaken() {
+	categoryRequest := &categorypb.CategoryRequest{CategoryName: "New Category", Slug: "shoes"}
+	suite.mockService.On("AddCategory", mock.AnythingOfType("*model.Category")).Return(int64(0), service.ErrSlugTaken)
+
+	err := suite.handler.CreateCategory(context.Background(), categoryRequest, &categorypb.CreateCategoryResponse{})
+
+	microErr := microerrors.FromError(err)
+	suite.Equal(int32(http.StatusConflict), microErr.Code)
+	suite.Equal("SLUG_TAKEN", microErr.Status)
+}
+
+// TestCreateCategoryDuplicateKey tests that a unique key violation of a concurrent request is a conflict
+func (suite *CategoryHandlerTestSuite) TestCreateCategoryDuplicateKey() {
+	duplicate := &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'shoes' for key 'slug'"}
+	suite.mockService.On("AddCategory", mock.AnythingOfType("*model.Category")).Return(int64(0), duplicate)
+
+	err := suite.handler.CreateCategory(context.Background(), &categorypb.CategoryRequest{CategoryName: "Shoes"}, &categorypb.CreateCategoryResponse{})
+
+	microErr := microerrors.FromError(err)
+	suite.Equal(int32(http.StatusServiceUnavailable), microErr.Code)
+	suite.Equal("CONFLICT", microErr.Status)
+}
+
 // TestUpdateCategory tests the UpdateCategory method
 func (suite *CategoryHandlerTestSuite) TestUpdateCategory() {
 	categoryRequest := &categorypb.CategoryRequest{CategoryName: "Updated Category"}
@@ -113,10 +264,10 @@ func (suite *CategoryHandlerTestSuite) TestUpdateCategory() {
 
 // TestDeleteCategory tests the DeleteCategory method
 func (suite *CategoryHandlerTestSuite) TestDeleteCategory() {
-	categoryRequest := &categorypb.DeleteCategoryRequest{CategoryId: 1}
+	categoryRequest := &categorypb.DeleteCategoryRequest{CategoryId: 1, Policy: categorypb.DeletePolicy_CASCADE}
 	response := &categorypb.DeleteCategoryResponse{}
 
-	suite.mockService.On("DeleteCategory", int64(1)).Return(nil)
+	suite.mockService.On("DeleteCategory", int64(1), service.DeleteCascade).Return(nil)
 
 	err := suite.handler.DeleteCategory(context.Background(), categoryRequest, response)
 
@@ -125,13 +276,25 @@ func (suite *CategoryHandlerTestSuite) TestDeleteCategory() {
 	suite.mockService.AssertExpectations(suite.T())
 }
 
+// TestDeleteCategoryHasChildren tests that a rejected delete is a failed precondition with the reason as status
+func (suite *CategoryHandlerTestSuite) TestDeleteCategoryHasChildren() {
+	categoryRequest := &categorypb.DeleteCategoryRequest{CategoryId: 1, Policy: categorypb.DeletePolicy_REJECT}
+	suite.mockService.On("DeleteCategory", int64(1), service.DeleteReject).Return(service.ErrCategoryHasChildren)
+
+	err := suite.handler.DeleteCategory(context.Background(), categoryRequest, &categorypb.DeleteCategoryResponse{})
+
+	microErr := microerrors.FromError(err)
+	suite.Equal(int32(http.StatusPreconditionFailed), microErr.Code)
+	suite.Equal("CATEGORY_HAS_CHILDREN", microErr.Status)
+}
+
 // TestFindCategoryByName tests the FindCategoryByName method
 func (suite *CategoryHandlerTestSuite) TestFindCategoryByName() {
 	categoryRequest := &categorypb.FindByNameRequest{CategoryName: "Test Category"}
 	response := &categorypb.CategoryResponse{}
 	category := &model.Category{ID: 1, CategoryName: "Test Category"}
 
-	suite.mockService.On("FindCategoryByName", "Test Category").Return(category, nil)
+	suite.mockService.On("FindCategoryByName", "Test Category", false).Return(category, nil)
 
 	err := suite.handler.FindCategoryByName(context.Background(), categoryRequest, response)
 
@@ -147,7 +310,7 @@ func (suite *CategoryHandlerTestSuite) TestFindCategoryByID() {
 	response := &categorypb.CategoryResponse{}
 	category := &model.Category{ID: 1, CategoryName: "Test Category"}
 
-	suite.mockService.On("FindCategoryByID", int64(1)).Return(category, nil)
+	suite.mockService.On("FindCategoryByID", int64(1), false).Return(category, nil)
 
 	err := suite.handler.FindCategoryByID(context.Background(), categoryRequest, response)
 
@@ -157,6 +320,15 @@ func (suite *CategoryHandlerTestSuite) TestFindCategoryByID() {
 	suite.mockService.AssertExpectations(suite.T())
 }
 
+// TestFindCategoryByIDNotFound tests that a missing category is NotFound instead of an internal error
+func (suite *CategoryHandlerTestSuite) TestFindCategoryByIDNotFound() {
+	suite.mockService.On("FindCategoryByID", int64(9), false).Return((*model.Category)(nil), gorm.ErrRecordNotFound)
+
+	err := suite.handler.FindCategoryByID(context.Background(), &categorypb.FindByIdRequest{CategoryId: 9}, &categorypb.CategoryResponse{})
+
+	suite.Equal(int32(http.StatusNotFound), microerrors.FromError(err).Code)
+}
+
 // TestFindAllCategory tests the FindAllCategory method
 func (suite *CategoryHandlerTestSuite) TestFindAllCategory() {
 	categoryRequest := &categorypb.FindAllRequest{}
@@ -166,7 +338,7 @@ func (suite *CategoryHandlerTestSuite) TestFindAllCategory() {
 		{ID: 2, CategoryName: "Category 2"},
 	}
 
-	suite.mockService.On("FindAllCategory").Return(categories, nil)
+	suite.mockService.On("FindAllCategory", false).Return(categories, nil)
 
 	err := suite.handler.FindAllCategory(context.Background(), categoryRequest, response)
 
@@ -175,6 +347,276 @@ func (suite *CategoryHandlerTestSuite) TestFindAllCategory() {
 	suite.mockService.AssertExpectations(suite.T())
 }
 
+// TestFindAllCategoryIncludeHidden tests that only permitted callers can list hidden categories
+func (suite *CategoryHandlerTestSuite) TestFindAllCategoryIncludeHidden() {
+	request := &categorypb.FindAllRequest{IncludeHidden: true}
+
+	err := suite.handler.FindAllCategory(context.Background(), request, &categorypb.FindAllResponse{})
+	suite.Equal(int32(http.StatusUnauthorized), microerrors.FromError(err).Code)
+
+	shopper := common.NewAuthContext(context.Background(), &common.AuthUser{UserName: "shopper"})
+	err = suite.handler.FindAllCategory(shopper, request, &categorypb.FindAllResponse{})
+	suite.Equal(int32(http.StatusForbidden), microerrors.FromError(err).Code)
+
+	suite.mockService.On("FindAllCategory", true).Return([]model.Category{{ID: 1, Hidden: true}}, nil)
+	admin := common.NewAuthContext(context.Background(), &common.AuthUser{UserName: "admin", Permissions: []string{"Category.*"}})
+	response := &categorypb.FindAllResponse{}
+	err = suite.handler.FindAllCategory(admin, request, response)
+
+	suite.NoError(err)
+	suite.True(response.Category[0].Hidden)
+	suite.mockService.AssertExpectations(suite.T())
+}
+
+// TestFindCategoryByIDIncludeHidden tests that hidden categories are only looked up for callers holding the permission
+func (suite *CategoryHandlerTestSuite) TestFindCategoryByIDIncludeHidden() {
+	admin := common.NewAuthContext(context.Background(), &common.AuthUser{ID: 1, Permissions: []string{IncludeHiddenPermission}})
+	viewer := common.NewAuthContext(context.Background(), &common.AuthUser{ID: 2})
+	suite.mockService.On("FindCategoryByID", int64(1), true).Return(&model.Category{ID: 1, Hidden: true}, nil)
+	suite.mockService.On("FindCategoryByID", int64(1), false).Return((*model.Category)(nil), gorm.ErrRecordNotFound)
+
+	response := &categorypb.CategoryResponse{}
+	err := suite.handler.FindCategoryByID(admin, &categorypb.FindByIdRequest{CategoryId: 1}, response)
+	suite.NoError(err)
+	suite.True(response.Hidden)
+
+	err = suite.handler.FindCategoryByID(viewer, &categorypb.FindByIdRequest{CategoryId: 1}, &categorypb.CategoryResponse{})
+	suite.Equal(int32(http.StatusNotFound), microerrors.FromError(err).Code)
+}
+
+// TestGetCategoryTree tests the GetCategoryTree method
+func (suite *CategoryHandlerTestSuite) TestGetCategoryTree() {
+	request := &categorypb.CategoryTreeRequest{RootId: 1, MaxDepth: 2}
+	response := &categorypb.CategoryTreeResponse{}
+	nodes := []*service.CategoryNode{{
+		Category: model.Category{ID: 1, CategoryName: "Clothing"},
+		Children: []*service.CategoryNode{{Category: model.Category{ID: 2, CategoryName: "Men", CategoryParent: 1}}},
+	}}
+
+	suite.mockService.On("GetCategoryTree", int64(1), uint32(2), false).Return(nodes, nil)
+
+	err := suite.handler.GetCategoryTree(context.Background(), request, response)
+
+	suite.NoError(err)
+	suite.Len(response.Nodes, 1)
+	suite.Equal("Clothing", response.Nodes[0].Category.CategoryName)
+	suite.Equal(int64(1), response.Nodes[0].Children[0].Category.CategoryParent)
+	suite.mockService.AssertExpectations(suite.T())
+}
+
+// TestMoveCategory tests the MoveCategory method
+func (suite *CategoryHandlerTestSuite) TestMoveCategory() {
+	request := &categorypb.MoveCategoryRequest{CategoryId: 2, ParentId: 3, Position: 1}
+	response := &categorypb.MoveCategoryResponse{}
+	node := &service.CategoryNode{
+		Category: model.Category{ID: 2, CategoryName: "Men", CategoryParent: 3, SortOrder: 1},
+		Children: []*service.CategoryNode{{Category: model.Category{ID: 4, CategoryName: "Shirts", CategoryParent: 2}}},
+	}
+
+	suite.mockService.On("MoveCategory", int64(2), int64(3), int32(1)).Return(node, nil)
+
+	err := suite.handler.MoveCategory(context.Background(), request, response)
+
+	suite.NoError(err)
+	suite.Equal("Category moved successfully", response.Message)
+	suite.Equal(int32(1), response.Subtree.Category.SortOrder)
+	suite.Len(response.Subtree.Children, 1)
+	suite.mockService.AssertExpectations(suite.T())
+}
+
+// TestResolveCategoryPath tests the ResolveCategoryPath method
+func (suite *CategoryHandlerTestSuite) TestResolveCategoryPath() {
+	request := &categorypb.ResolvePathRequest{Path: "/men/shoes"}
+	response := &categorypb.FindAllResponse{}
+	path := []model.Category{
+		{ID: 1, CategoryName: "Men", Slug: "men"},
+		{ID: 2, CategoryName: "Shoes", Slug: "shoes", CategoryParent: 1, SeoTitle: "Men's Shoes"},
+	}
+
+	suite.mockService.On("ResolveCategoryPath", "/men/shoes", false).Return(path, nil)
+
+	err := suite.handler.ResolveCategoryPath(context.Background(), request, response)
+
+	suite.NoError(err)
+	suite.Len(response.Category, 2)
+	suite.Equal("shoes", response.Category[1].Slug)
+	suite.Equal("Men's Shoes", response.Category[1].SeoTitle)
+	suite.mockService.AssertExpectations(suite.T())
+}
+
+// TestFindCategoryByIDLocalized tests that FindCategoryByID returns the category in the requested locale
+func (suite *CategoryHandlerTestSuite) TestFindCategoryByIDLocalized() {
+	request := &categorypb.FindByIdRequest{CategoryId: 1, Locale: "de-AT"}
+	response := &categorypb.CategoryResponse{}
+
+	suite.mockService.On("FindCategoryByID", int64(1), false).Return(&model.Category{ID: 1, CategoryName: "Shoes"}, nil)
+	suite.mockService.On("LocalizeCategories", mock.AnythingOfType("[]*model.Category"), "de-AT").Return(nil).Run(func(args mock.Arguments) {
+		category := args.Get(0).([]*model.Category)[0]
+		category.CategoryName = "Schuhe"
+		category.Locale = "de"
+	})
+
+	err := suite.handler.FindCategoryByID(context.Background(), request, response)
+
+	suite.NoError(err)
+	suite.Equal("Schuhe", response.CategoryName)
+	suite.Equal("de", response.Locale)
+	suite.mockService.AssertExpectations(suite.T())
+}
+
+// TestUpsertCategoryTranslation tests the UpsertCategoryTranslation method
+func (suite *CategoryHandlerTestSuite) TestUpsertCategoryTranslation() {
+	request := &categorypb.CategoryTranslationRequest{CategoryId: 1, Locale: "de", CategoryName: "Schuhe"}
+	response := &categorypb.CategoryTranslationResponse{}
+	translation := &model.CategoryTranslation{CategoryID: 1, Locale: "de", CategoryName: "Schuhe"}
+
+	suite.mockService.On("UpsertCategoryTranslation", translation).Return(nil)
+
+	err := suite.handler.UpsertCategoryTranslation(context.Background(), request, response)
+
+	suite.NoError(err)
+	suite.Equal("Category translation saved successfully", response.Message)
+	suite.mockService.AssertExpectations(suite.T())
+}
+
+// TestImportCategories tests that ImportCategories joins the streamed chunks and reports the row errors
+func (suite *CategoryHandlerTestSuite) TestImportCategories() {
+	stream := &fakeImportStream{requests: []*categorypb.ImportCategoriesRequest{
+		{},
+		{Format: categorypb.DataFormat_JSON, DryRun: true, Data: []byte(`[{"name": "Sh`)},
+		{Data: []byte(`oes"}]`), Last: true},
+	}}
+	report := &service.ImportReport{Rows: 2, Imported: 1, Errors: []service.ImportRowError{{Row: 2, CategoryName: "Boots", Message: "parent \"missing\" not found"}}}
+
+	suite.mockService.On("ImportCategories", mock.Anything, service.ImportOptions{Format: service.FormatJSON, DryRun: true}).Return(report, nil).Run(func(args mock.Arguments) {
+		data, err := ioutil.ReadAll(args.Get(0).(io.Reader))
+		suite.NoError(err)
+		suite.Equal(`[{"name": "Shoes"}]`, string(data))
+	})
+
+	err := suite.handler.ImportCategories(context.Background(), stream)
+
+	suite.NoError(err)
+	suite.Equal(int32(2), stream.response.Rows)
+	suite.Equal(int32(1), stream.response.Imported)
+	suite.False(stream.response.Committed)
+	suite.Len(stream.response.Errors, 1)
+	suite.Equal("Boots", stream.response.Errors[0].CategoryName)
+	suite.mockService.AssertExpectations(suite.T())
+}
+
+// TestExportCategories tests that ExportCategories streams the exported file
+func (suite *CategoryHandlerTestSuite) TestExportCategories() {
+	stream := &fakeExportStream{}
+	suite.mockService.On("ExportCategories", mock.Anything, service.FormatCSV).Return(nil).Run(func(args mock.Arguments) {
+		_, err := io.WriteString(args.Get(0).(io.Writer), "name\nShoes\n")
+		suite.NoError(err)
+	})
+
+	err := suite.handler.ExportCategories(context.Background(), &categorypb.ExportCategoriesRequest{}, stream)
+
+	suite.NoError(err)
+	suite.Equal("name\nShoes\n", string(stream.data))
+}
+
+// TestCreateCategoryAttribute tests that CreateCategoryAttribute maps the attribute type
+func (suite *CategoryHandlerTestSuite) TestCreateCategoryAttribute() {
+	request := &categorypb.CategoryAttributeRequest{CategoryId: 1, Name: "size", Type: categorypb.AttributeType_ENUM, EnumValues: []string{"S", "M"}, Required: true}
+	response := &categorypb.CreateCategoryAttributeResponse{}
+	attribute := &model.CategoryAttribute{CategoryID: 1, Name: "size", Type: model.AttributeEnum, EnumValues: []string{"S", "M"}, Required: true}
+
+	suite.mockService.On("AddCategoryAttribute", attribute).Return(int64(7), nil)
+
+	err := suite.handler.CreateCategoryAttribute(context.Background(), request, response)
+
+	suite.NoError(err)
+	suite.Equal("Category attribute created successfully", response.Message)
+	suite.Equal(int64(7), response.AttributeId)
+	suite.mockService.AssertExpectations(suite.T())
+}
+
+// TestCreateCategoryAttributeInvalid tests that an invalid definition is a bad request with the reason as status
+func (suite *CategoryHandlerTestSuite) TestCreateCategoryAttributeInvalid() {
+	request := &categorypb.CategoryAttributeRequest{CategoryId: 1, Name: "size", Type: categorypb.AttributeType_STRING, Unit: "cm"}
+	suite.mockService.On("AddCategoryAttribute", mock.AnythingOfType("*model.CategoryAttribute")).Return(int64(0), service.ErrUnitNotAllowed)
+
+	err := suite.handler.CreateCategoryAttribute(context.Background(), request, &categorypb.CreateCategoryAttributeResponse{})
+
+	microErr := microerrors.FromError(err)
+	suite.Equal(int32(http.StatusBadRequest), microErr.Code)
+	suite.Equal("UNIT_NOT_ALLOWED", microErr.Status)
+}
+
+// TestFindCategoryAttributes tests that FindCategoryAttributes returns the schema with inherited attributes
+func (suite *CategoryHandlerTestSuite) TestFindCategoryAttributes() {
+	request := &categorypb.FindCategoryAttributesRequest{CategoryId: 2, Inherited: true}
+	response := &categorypb.FindCategoryAttributesResponse{}
+
+	suite.mockService.On("FindCategoryAttributes", int64(2), true).Return([]model.CategoryAttribute{
+		{ID: 1, CategoryID: 1, Name: "weight", Type: model.AttributeNumber, Unit: "kg", Inheritable: true},
+	}, nil)
+
+	err := suite.handler.FindCategoryAttributes(context.Background(), request, response)
+
+	suite.NoError(err)
+	suite.Len(response.Attributes, 1)
+	suite.Equal(categorypb.AttributeType_NUMBER, response.Attributes[0].Type)
+	suite.Equal("kg", response.Attributes[0].Unit)
+	suite.Equal(int64(1), response.Attributes[0].CategoryId)
+}
+
+// TestValidateCategoryAttributes tests that ValidateCategoryAttributes reports the violations
+func (suite *CategoryHandlerTestSuite) TestValidateCategoryAttributes() {
+	values := map[string]string{"size": "XL"}
+	request := &categorypb.ValidateAttributesRequest{CategoryId: 1, Attributes: values}
+	response := &categorypb.ValidateAttributesResponse{}
+
+	suite.mockService.On("ValidateCategoryAttributes", int64(1), values).Return([]service.AttributeViolation{
+		{Name: "size", Message: "must be one of S, M"},
+	}, nil)
+
+	err := suite.handler.ValidateCategoryAttributes(context.Background(), request, response)
+
+	suite.NoError(err)
+	suite.False(response.Valid)
+	suite.Len(response.Violations, 1)
+	suite.Equal("size", response.Violations[0].Name)
+}
+
+// TestErrorsThroughMicroServer tests that codes and statuses reach a client through the go-micro gRPC server
+// the service runs on
+func (suite *CategoryHandlerTestSuite) TestErrorsThroughMicroServer() {
+	microRegistry := memory.NewRegistry()
+	microServer := grpcserver.NewServer(
+		server.Name(serviceName),
+		server.Address("127.0.0.1:0"),
+		server.Registry(microRegistry),
+	)
+	suite.Require().NoError(categorypb.RegisterCategoryHandler(microServer, suite.handler))
+	suite.Require().NoError(microServer.Start())
+	defer microServer.Stop()
+	categoryClient := categorypb.NewCategoryService(serviceName, grpcclient.NewClient(client.Registry(microRegistry)))
+
+	suite.mockService.On("FindCategoryByID", int64(1), false).Return((*model.Category)(nil), gorm.ErrRecordNotFound)
+	_, err := categoryClient.FindCategoryByID(context.Background(), &categorypb.FindByIdRequest{CategoryId: 1})
+	microErr := microerrors.FromError(err)
+	suite.Equal(int32(http.StatusNotFound), microErr.Code)
+	suite.Equal("NOT_FOUND", microErr.Status)
+
+	suite.mockService.On("DeleteCategory", int64(1), service.DeleteReject).Return(service.ErrCategoryHasChildren)
+	_, err = categoryClient.DeleteCategory(context.Background(), &categorypb.DeleteCategoryRequest{CategoryId: 1, Policy: categorypb.DeletePolicy_REJECT})
+	microErr = microerrors.FromError(err)
+	suite.Equal(int32(http.StatusPreconditionFailed), microErr.Code)
+	suite.Equal("CATEGORY_HAS_CHILDREN", microErr.Status)
+
+	duplicate := &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'shoes' for key 'slug'"}
+	suite.mockService.On("AddCategory", mock.AnythingOfType("*model.Category")).Return(int64(0), duplicate)
+	_, err = categoryClient.CreateCategory(context.Background(), &categorypb.CategoryRequest{CategoryName: "Shoes"})
+	microErr = microerrors.FromError(err)
+	suite.Equal(int32(http.StatusServiceUnavailable), microErr.Code)
+	suite.Equal("CONFLICT", microErr.Status)
+}
+
 // Run the tests
 func TestCategoryHandlerTestSuite(t *testing.T) {
 	suite.Run(t, new(CategoryHandlerTestSuite))
@@ -1,22 +1,58 @@
 package main
 
 import (
+	"context"
 	"github.com/micro/go-micro/v2/config"
 	"github.com/tongs-dev/shopping-platform/category/handler"
 	categorypb "github.com/tongs-dev/shopping-platform/category/proto/category"
 	"log"
 	"os"
+	"time"
 
 	"github.com/jinzhu/gorm"
 	_ "github.com/jinzhu/gorm/dialects/mysql"
 	"github.com/micro/go-micro/v2"
 	"github.com/micro/go-micro/v2/registry"
+	"github.com/micro/go-micro/v2/server"
+	grpcserver "github.com/micro/go-micro/v2/server/grpc"
 	"github.com/micro/go-plugins/registry/consul/v2"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/tongs-dev/shopping-platform/category/common"
+	"github.com/tongs-dev/shopping-platform/category/domain/cache"
 	"github.com/tongs-dev/shopping-platform/category/domain/repository"
 	categoryService "github.com/tongs-dev/shopping-platform/category/domain/service"
 )
 
+// publicEndpoints are the read-only RPCs that can be called without an access token
+var publicEndpoints = []string{
+	"Category.FindCategoryByName",
+	"Category.FindCategoryByID",
+	"Category.FindCategoryByLevel",
+	"Category.FindCategoryByParent",
+	"Category.FindAllCategory",
+	"Category.GetCategoryTree",
+	"Category.GetCategoryAncestors",
+	"Category.FindCategoryBySlug",
+	"Category.ResolveCategoryPath",
+	"Category.FindCategoryAttributes",
+	"Category.ValidateCategoryAttributes",
+}
+
+// restrictedEndpoints are the catalog mutations that need a matching role permission
+var restrictedEndpoints = []string{
+	"Category.CreateCategory",
+	"Category.UpdateCategory",
+	"Category.DeleteCategory",
+	"Category.MoveCategory",
+	"Category.UpsertCategoryTranslation",
+	"Category.DeleteCategoryTranslation",
+	"Category.ImportCategories",
+	"Category.ExportCategories",
+	"Category.CreateCategoryAttribute",
+	"Category.UpdateCategoryAttribute",
+	"Category.DeleteCategoryAttribute",
+}
+
 // setupConsulConfig loads the Consul configuration
 func setupConsulConfig() (config.Config, error) {
 	consulConfig, err := common.GetConsulConfig("127.0.0.1", 8500, "/micro/config")
@@ -56,14 +92,45 @@ func setupMySQLConnection(config config.Config) (*gorm.DB, error) {
 	return db, nil
 }
 
-// setupService initializes the microservice with Consul registry and config
-func setupService(consulRegistry registry.Registry) micro.Service {
-	return micro.NewService(
+// setupCache creates the cache backend of the category lookups, nil if caching is disabled
+func setupCache(config config.Config) cache.Cache {
+	switch backend := config.Get("cache", "backend").String("lru"); backend {
+	case "none":
+		return nil
+	case "redis":
+		return cache.NewRedisCache(cache.RedisOptions{
+			Addr:     config.Get("cache", "redis", "addr").String("127.0.0.1:6379"),
+			Password: config.Get("cache", "redis", "password").String(""),
+			DB:       config.Get("cache", "redis", "db").Int(0),
+		})
+	case "lru":
+		return cache.NewLRUCache(config.Get("cache", "size").Int(10000))
+	default:
+		log.Fatalf("Unknown cache backend: %s", backend)
+		return nil
+	}
+}
+
+// setupService initializes the microservice with Consul registry and config. It serves gRPC, so the errors of
+// the handler reach clients with their gRPC code
+func setupService(consulRegistry registry.Registry, authSecret string, sessionCacheTTL time.Duration) micro.Service {
+	service := micro.NewService(
+		micro.Server(grpcserver.NewServer()),
 		micro.Name("go.micro.service.category"),
 		micro.Version("latest"),
 		micro.Address("127.0.0.1:8082"),
 		micro.Registry(consulRegistry),
+		micro.WrapHandler(
+			common.NewAuthHandlerWrapper(authSecret, publicEndpoints...),
+			common.NewPermissionHandlerWrapper(restrictedEndpoints...),
+		),
 	)
+
+	// Revoked login sessions are rejected by asking the user service, answers are cached for sessionCacheTTL
+	service.Server().Init(server.WrapHandler(common.NewSessionHandlerWrapper(
+		common.NewRemoteSessionValidator(service.Client(), "go.micro.service.user", sessionCacheTTL),
+	)))
+	return service
 }
 
 func main() {
@@ -75,7 +142,14 @@ func main() {
 	}
 
 	consulRegistry := setupConsulRegistry()
-	service := setupService(consulRegistry)
+
+	// Secret shared with the user service to verify access tokens, there is no default so tokens cannot be forged
+	authSecret := consulConfig.Get("auth", "secret").String("")
+	if authSecret == "" {
+		log.Fatal("Missing auth secret in Consul config (auth.secret)")
+	}
+	sessionCacheTTL := consulConfig.Get("auth", "session_cache_ttl").Duration(5 * time.Second)
+	service := setupService(consulRegistry, authSecret, sessionCacheTTL)
 
 	// Establish MySQL connection
 	db, err := setupMySQLConnection(consulConfig)
@@ -88,11 +162,53 @@ func main() {
 	// Initialise service
 	service.Init()
 
+	// Locale of the category names stored on the categories themselves, other locales are translations
+	defaultLocale, err := categoryService.NormalizeLocale(consulConfig.Get("i18n", "default_locale").String("en"))
+	if err != nil {
+		log.Fatalf("Invalid default locale: %v", err)
+	}
+
 	// Set up the category data service
-	categoryDataService := categoryService.NewCategoryService(repository.NewCategoryRepository(db))
+	categoryRepository := repository.NewCategoryRepository(db)
+	// Brings a database from before slugs up to date, enabled on one instance for a single start
+	if consulConfig.Get("mysql", "migrate_slugs").Bool(false) {
+		migrated, err := categoryService.MigrateSlugs(categoryRepository)
+		if err != nil {
+			log.Fatalf("Error migrating category slugs: %v", err)
+		}
+		log.Printf("Assigned slugs to %d categories", migrated)
+	}
+	categoryDataService := categoryService.NewCategoryService(categoryRepository, defaultLocale)
+
+	// Publishes the category change events written to the outbox until the service stops
+	eventRelay := categoryService.NewEventRelay(categoryRepository, service.Options().Broker)
+	eventRelay.Interval = consulConfig.Get("events", "publish_interval").Duration(time.Second)
+	eventRelay.Retention = consulConfig.Get("events", "retention").Duration(24 * time.Hour)
+	eventRelay.LeaseTTL = consulConfig.Get("events", "lease_ttl").Duration(30 * time.Second)
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	defer stopRelay()
+	go eventRelay.Run(relayCtx)
+
+	// Cache the category lookups, cleared by every change event so all instances drop stale categories
+	var handlerService categoryService.ICategoryService = categoryDataService
+	if cacheBackend := setupCache(consulConfig); cacheBackend != nil {
+		cacheMetrics, err := categoryService.NewCacheMetrics(prometheus.DefaultRegisterer)
+		if err != nil {
+			log.Fatalf("Error registering cache metrics: %v", err)
+		}
+		cacheTTL := consulConfig.Get("cache", "ttl").Duration(30 * time.Second)
+		cachedService := categoryService.NewCachedCategoryService(categoryDataService, cacheBackend, cacheTTL, cacheMetrics)
+		if err := micro.RegisterSubscriber(categoryService.EventTopic, service.Server(), cachedService.HandleEvent); err != nil {
+			log.Fatalf("Error subscribing to category events: %v", err)
+		}
+		handlerService = cachedService
+	}
+
+	// Expose the metrics to Prometheus
+	common.PrometheusBoot(consulConfig.Get("metrics", "port").Int(9093))
 
 	// Register the handler
-	err = categorypb.RegisterCategoryHandler(service.Server(), &handler.CategoryHandler{CategoryService: categoryDataService})
+	err = categorypb.RegisterCategoryHandler(service.Server(), &handler.CategoryHandler{CategoryService: handlerService})
 	if err != nil {
 		log.Fatalf("Error registering category handler: %v", err)
 	}
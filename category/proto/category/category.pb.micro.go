@@ -50,6 +50,20 @@ type CategoryService interface {
 	FindCategoryByLevel(ctx context.Context, in *FindByLevelRequest, opts ...client.CallOption) (*FindAllResponse, error)
 	FindCategoryByParent(ctx context.Context, in *FindByParentRequest, opts ...client.CallOption) (*FindAllResponse, error)
 	FindAllCategory(ctx context.Context, in *FindAllRequest, opts ...client.CallOption) (*FindAllResponse, error)
+	GetCategoryTree(ctx context.Context, in *CategoryTreeRequest, opts ...client.CallOption) (*CategoryTreeResponse, error)
+	GetCategoryAncestors(ctx context.Context, in *FindByIdRequest, opts ...client.CallOption) (*FindAllResponse, error)
+	MoveCategory(ctx context.Context, in *MoveCategoryRequest, opts ...client.CallOption) (*MoveCategoryResponse, error)
+	FindCategoryBySlug(ctx context.Context, in *FindBySlugRequest, opts ...client.CallOption) (*CategoryResponse, error)
+	ResolveCategoryPath(ctx context.Context, in *ResolvePathRequest, opts ...client.CallOption) (*FindAllResponse, error)
+	UpsertCategoryTranslation(ctx context.Context, in *CategoryTranslationRequest, opts ...client.CallOption) (*CategoryTranslationResponse, error)
+	DeleteCategoryTranslation(ctx context.Context, in *DeleteTranslationRequest, opts ...client.CallOption) (*CategoryTranslationResponse, error)
+	ImportCategories(ctx context.Context, opts ...client.CallOption) (Category_ImportCategoriesService, error)
+	ExportCategories(ctx context.Context, in *ExportCategoriesRequest, opts ...client.CallOption) (Category_ExportCategoriesService, error)
+	CreateCategoryAttribute(ctx context.Context, in *CategoryAttributeRequest, opts ...client.CallOption) (*CreateCategoryAttributeResponse, error)
+	UpdateCategoryAttribute(ctx context.Context, in *CategoryAttributeRequest, opts ...client.CallOption) (*CategoryAttributeMessageResponse, error)
+	DeleteCategoryAttribute(ctx context.Context, in *DeleteCategoryAttributeRequest, opts ...client.CallOption) (*CategoryAttributeMessageResponse, error)
+	FindCategoryAttributes(ctx context.Context, in *FindCategoryAttributesRequest, opts ...client.CallOption) (*FindCategoryAttributesResponse, error)
+	ValidateCategoryAttributes(ctx context.Context, in *ValidateAttributesRequest, opts ...client.CallOption) (*ValidateAttributesResponse, error)
 }
 
 type categoryService struct {
@@ -144,6 +158,216 @@ func (c *categoryService) FindAllCategory(ctx context.Context, in *FindAllReques
 	return out, nil
 }
 
+func (c *categoryService) GetCategoryTree(ctx context.Context, in *CategoryTreeRequest, opts ...client.CallOption) (*CategoryTreeResponse, error) {
+	req := c.c.NewRequest(c.name, "Category.GetCategoryTree", in)
+	out := new(CategoryTreeResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *categoryService) GetCategoryAncestors(ctx context.Context, in *FindByIdRequest, opts ...client.CallOption) (*FindAllResponse, error) {
+	req := c.c.NewRequest(c.name, "Category.GetCategoryAncestors", in)
+	out := new(FindAllResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *categoryService) MoveCategory(ctx context.Context, in *MoveCategoryRequest, opts ...client.CallOption) (*MoveCategoryResponse, error) {
+	req := c.c.NewRequest(c.name, "Category.MoveCategory", in)
+	out := new(MoveCategoryResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *categoryService) FindCategoryBySlug(ctx context.Context, in *FindBySlugRequest, opts ...client.CallOption) (*CategoryResponse, error) {
+	req := c.c.NewRequest(c.name, "Category.FindCategoryBySlug", in)
+	out := new(CategoryResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *categoryService) ResolveCategoryPath(ctx context.Context, in *ResolvePathRequest, opts ...client.CallOption) (*FindAllResponse, error) {
+	req := c.c.NewRequest(c.name, "Category.ResolveCategoryPath", in)
+	out := new(FindAllResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *categoryService) UpsertCategoryTranslation(ctx context.Context, in *CategoryTranslationRequest, opts ...client.CallOption) (*CategoryTranslationResponse, error) {
+	req := c.c.NewRequest(c.name, "Category.UpsertCategoryTranslation", in)
+	out := new(CategoryTranslationResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *categoryService) DeleteCategoryTranslation(ctx context.Context, in *DeleteTranslationRequest, opts ...client.CallOption) (*CategoryTranslationResponse, error) {
+	req := c.c.NewRequest(c.name, "Category.DeleteCategoryTranslation", in)
+	out := new(CategoryTranslationResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *categoryService) ImportCategories(ctx context.Context, opts ...client.CallOption) (Category_ImportCategoriesService, error) {
+	req := c.c.NewRequest(c.name, "Category.ImportCategories", &ImportCategoriesRequest{})
+	stream, err := c.c.Stream(ctx, req, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &categoryServiceImportCategories{stream}, nil
+}
+
+type Category_ImportCategoriesService interface {
+	Context() context.Context
+	SendMsg(interface{}) error
+	RecvMsg(interface{}) error
+	Close() error
+	Send(*ImportCategoriesRequest) error
+}
+
+type categoryServiceImportCategories struct {
+	stream client.Stream
+}
+
+func (x *categoryServiceImportCategories) Close() error {
+	return x.stream.Close()
+}
+
+func (x *categoryServiceImportCategories) Context() context.Context {
+	return x.stream.Context()
+}
+
+func (x *categoryServiceImportCategories) SendMsg(m interface{}) error {
+	return x.stream.Send(m)
+}
+
+func (x *categoryServiceImportCategories) RecvMsg(m interface{}) error {
+	return x.stream.Recv(m)
+}
+
+func (x *categoryServiceImportCategories) Send(m *ImportCategoriesRequest) error {
+	return x.stream.Send(m)
+}
+
+func (c *categoryService) ExportCategories(ctx context.Context, in *ExportCategoriesRequest, opts ...client.CallOption) (Category_ExportCategoriesService, error) {
+	req := c.c.NewRequest(c.name, "Category.ExportCategories", &ExportCategoriesRequest{})
+	stream, err := c.c.Stream(ctx, req, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := stream.Send(in); err != nil {
+		return nil, err
+	}
+	return &categoryServiceExportCategories{stream}, nil
+}
+
+type Category_ExportCategoriesService interface {
+	Context() context.Context
+	SendMsg(interface{}) error
+	RecvMsg(interface{}) error
+	Close() error
+	Recv() (*ExportCategoriesResponse, error)
+}
+
+type categoryServiceExportCategories struct {
+	stream client.Stream
+}
+
+func (x *categoryServiceExportCategories) Close() error {
+	return x.stream.Close()
+}
+
+func (x *categoryServiceExportCategories) Context() context.Context {
+	return x.stream.Context()
+}
+
+func (x *categoryServiceExportCategories) SendMsg(m interface{}) error {
+	return x.stream.Send(m)
+}
+
+func (x *categoryServiceExportCategories) RecvMsg(m interface{}) error {
+	return x.stream.Recv(m)
+}
+
+func (x *categoryServiceExportCategories) Recv() (*ExportCategoriesResponse, error) {
+	m := new(ExportCategoriesResponse)
+	err := x.stream.Recv(m)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *categoryService) CreateCategoryAttribute(ctx context.Context, in *CategoryAttributeRequest, opts ...client.CallOption) (*CreateCategoryAttributeResponse, error) {
+	req := c.c.NewRequest(c.name, "Category.CreateCategoryAttribute", in)
+	out := new(CreateCategoryAttributeResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *categoryService) UpdateCategoryAttribute(ctx context.Context, in *CategoryAttributeRequest, opts ...client.CallOption) (*CategoryAttributeMessageResponse, error) {
+	req := c.c.NewRequest(c.name, "Category.UpdateCategoryAttribute", in)
+	out := new(CategoryAttributeMessageResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *categoryService) DeleteCategoryAttribute(ctx context.Context, in *DeleteCategoryAttributeRequest, opts ...client.CallOption) (*CategoryAttributeMessageResponse, error) {
+	req := c.c.NewRequest(c.name, "Category.DeleteCategoryAttribute", in)
+	out := new(CategoryAttributeMessageResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *categoryService) FindCategoryAttributes(ctx context.Context, in *FindCategoryAttributesRequest, opts ...client.CallOption) (*FindCategoryAttributesResponse, error) {
+	req := c.c.NewRequest(c.name, "Category.FindCategoryAttributes", in)
+	out := new(FindCategoryAttributesResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *categoryService) ValidateCategoryAttributes(ctx context.Context, in *ValidateAttributesRequest, opts ...client.CallOption) (*ValidateAttributesResponse, error) {
+	req := c.c.NewRequest(c.name, "Category.ValidateCategoryAttributes", in)
+	out := new(ValidateAttributesResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for Category service
 
 type CategoryHandler interface {
@@ -155,6 +379,20 @@ type CategoryHandler interface {
 	FindCategoryByLevel(context.Context, *FindByLevelRequest, *FindAllResponse) error
 	FindCategoryByParent(context.Context, *FindByParentRequest, *FindAllResponse) error
 	FindAllCategory(context.Context, *FindAllRequest, *FindAllResponse) error
+	GetCategoryTree(context.Context, *CategoryTreeRequest, *CategoryTreeResponse) error
+	GetCategoryAncestors(context.Context, *FindByIdRequest, *FindAllResponse) error
+	MoveCategory(context.Context, *MoveCategoryRequest, *MoveCategoryResponse) error
+	FindCategoryBySlug(context.Context, *FindBySlugRequest, *CategoryResponse) error
+	ResolveCategoryPath(context.Context, *ResolvePathRequest, *FindAllResponse) error
+	UpsertCategoryTranslation(context.Context, *CategoryTranslationRequest, *CategoryTranslationResponse) error
+	DeleteCategoryTranslation(context.Context, *DeleteTranslationRequest, *CategoryTranslationResponse) error
+	ImportCategories(context.Context, Category_ImportCategoriesStream) error
+	ExportCategories(context.Context, *ExportCategoriesRequest, Category_ExportCategoriesStream) error
+	CreateCategoryAttribute(context.Context, *CategoryAttributeRequest, *CreateCategoryAttributeResponse) error
+	UpdateCategoryAttribute(context.Context, *CategoryAttributeRequest, *CategoryAttributeMessageResponse) error
+	DeleteCategoryAttribute(context.Context, *DeleteCategoryAttributeRequest, *CategoryAttributeMessageResponse) error
+	FindCategoryAttributes(context.Context, *FindCategoryAttributesRequest, *FindCategoryAttributesResponse) error
+	ValidateCategoryAttributes(context.Context, *ValidateAttributesRequest, *ValidateAttributesResponse) error
 }
 
 func RegisterCategoryHandler(s server.Server, hdlr CategoryHandler, opts ...server.HandlerOption) error {
@@ -167,6 +405,20 @@ func RegisterCategoryHandler(s server.Server, hdlr CategoryHandler, opts ...serv
 		FindCategoryByLevel(ctx context.Context, in *FindByLevelRequest, out *FindAllResponse) error
 		FindCategoryByParent(ctx context.Context, in *FindByParentRequest, out *FindAllResponse) error
 		FindAllCategory(ctx context.Context, in *FindAllRequest, out *FindAllResponse) error
+		GetCategoryTree(ctx context.Context, in *CategoryTreeRequest, out *CategoryTreeResponse) error
+		GetCategoryAncestors(ctx context.Context, in *FindByIdRequest, out *FindAllResponse) error
+		MoveCategory(ctx context.Context, in *MoveCategoryRequest, out *MoveCategoryResponse) error
+		FindCategoryBySlug(ctx context.Context, in *FindBySlugRequest, out *CategoryResponse) error
+		ResolveCategoryPath(ctx context.Context, in *ResolvePathRequest, out *FindAllResponse) error
+		UpsertCategoryTranslation(ctx context.Context, in *CategoryTranslationRequest, out *CategoryTranslationResponse) error
+		DeleteCategoryTranslation(ctx context.Context, in *DeleteTranslationRequest, out *CategoryTranslationResponse) error
+		ImportCategories(ctx context.Context, stream server.Stream) error
+		ExportCategories(ctx context.Context, stream server.Stream) error
+		CreateCategoryAttribute(ctx context.Context, in *CategoryAttributeRequest, out *CreateCategoryAttributeResponse) error
+		UpdateCategoryAttribute(ctx context.Context, in *CategoryAttributeRequest, out *CategoryAttributeMessageResponse) error
+		DeleteCategoryAttribute(ctx context.Context, in *DeleteCategoryAttributeRequest, out *CategoryAttributeMessageResponse) error
+		FindCategoryAttributes(ctx context.Context, in *FindCategoryAttributesRequest, out *FindCategoryAttributesResponse) error
+		ValidateCategoryAttributes(ctx context.Context, in *ValidateAttributesRequest, out *ValidateAttributesResponse) error
 	}
 	type Category struct {
 		category
@@ -210,3 +462,131 @@ func (h *categoryHandler) FindCategoryByParent(ctx context.Context, in *FindByPa
 func (h *categoryHandler) FindAllCategory(ctx context.Context, in *FindAllRequest, out *FindAllResponse) error {
 	return h.CategoryHandler.FindAllCategory(ctx, in, out)
 }
+
+func (h *categoryHandler) GetCategoryTree(ctx context.Context, in *CategoryTreeRequest, out *CategoryTreeResponse) error {
+	return h.CategoryHandler.GetCategoryTree(ctx, in, out)
+}
+
+func (h *categoryHandler) GetCategoryAncestors(ctx context.Context, in *FindByIdRequest, out *FindAllResponse) error {
+	return h.CategoryHandler.GetCategoryAncestors(ctx, in, out)
+}
+
+func (h *categoryHandler) MoveCategory(ctx context.Context, in *MoveCategoryRequest, out *MoveCategoryResponse) error {
+	return h.CategoryHandler.MoveCategory(ctx, in, out)
+}
+
+func (h *categoryHandler) FindCategoryBySlug(ctx context.Context, in *FindBySlugRequest, out *CategoryResponse) error {
+	return h.CategoryHandler.FindCategoryBySlug(ctx, in, out)
+}
+
+func (h *categoryHandler) ResolveCategoryPath(ctx context.Context, in *ResolvePathRequest, out *FindAllResponse) error {
+	return h.CategoryHandler.ResolveCategoryPath(ctx, in, out)
+}
+
+func (h *categoryHandler) UpsertCategoryTranslation(ctx context.Context, in *CategoryTranslationRequest, out *CategoryTranslationResponse) error {
+	return h.CategoryHandler.UpsertCategoryTranslation(ctx, in, out)
+}
+
+func (h *categoryHandler) DeleteCategoryTranslation(ctx context.Context, in *DeleteTranslationRequest, out *CategoryTranslationResponse) error {
+	return h.CategoryHandler.DeleteCategoryTranslation(ctx, in, out)
+}
+
+func (h *categoryHandler) ImportCategories(ctx context.Context, stream server.Stream) error {
+	return h.CategoryHandler.ImportCategories(ctx, &categoryImportCategoriesStream{stream})
+}
+
+type Category_ImportCategoriesStream interface {
+	Context() context.Context
+	SendMsg(interface{}) error
+	RecvMsg(interface{}) error
+	Close() error
+	Recv() (*ImportCategoriesRequest, error)
+}
+
+type categoryImportCategoriesStream struct {
+	stream server.Stream
+}
+
+func (x *categoryImportCategoriesStream) Close() error {
+	return x.stream.Close()
+}
+
+func (x *categoryImportCategoriesStream) Context() context.Context {
+	return x.stream.Context()
+}
+
+func (x *categoryImportCategoriesStream) SendMsg(m interface{}) error {
+	return x.stream.Send(m)
+}
+
+func (x *categoryImportCategoriesStream) RecvMsg(m interface{}) error {
+	return x.stream.Recv(m)
+}
+
+func (x *categoryImportCategoriesStream) Recv() (*ImportCategoriesRequest, error) {
+	m := new(ImportCategoriesRequest)
+	if err := x.stream.Recv(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (h *categoryHandler) ExportCategories(ctx context.Context, stream server.Stream) error {
+	m := new(ExportCategoriesRequest)
+	if err := stream.Recv(m); err != nil {
+		return err
+	}
+	return h.CategoryHandler.ExportCategories(ctx, m, &categoryExportCategoriesStream{stream})
+}
+
+type Category_ExportCategoriesStream interface {
+	Context() context.Context
+	SendMsg(interface{}) error
+	RecvMsg(interface{}) error
+	Close() error
+	Send(*ExportCategoriesResponse) error
+}
+
+type categoryExportCategoriesStream struct {
+	stream server.Stream
+}
+
+func (x *categoryExportCategoriesStream) Close() error {
+	return x.stream.Close()
+}
+
+func (x *categoryExportCategoriesStream) Context() context.Context {
+	return x.stream.Context()
+}
+
+func (x *categoryExportCategoriesStream) SendMsg(m interface{}) error {
+	return x.stream.Send(m)
+}
+
+func (x *categoryExportCategoriesStream) RecvMsg(m interface{}) error {
+	return x.stream.Recv(m)
+}
+
+func (x *categoryExportCategoriesStream) Send(m *ExportCategoriesResponse) error {
+	return x.stream.Send(m)
+}
+
+func (h *categoryHandler) CreateCategoryAttribute(ctx context.Context, in *CategoryAttributeRequest, out *CreateCategoryAttributeResponse) error {
+	return h.CategoryHandler.CreateCategoryAttribute(ctx, in, out)
+}
+
+func (h *categoryHandler) UpdateCategoryAttribute(ctx context.Context, in *CategoryAttributeRequest, out *CategoryAttributeMessageResponse) error {
+	return h.CategoryHandler.UpdateCategoryAttribute(ctx, in, out)
+}
+
+func (h *categoryHandler) DeleteCategoryAttribute(ctx context.Context, in *DeleteCategoryAttributeRequest, out *CategoryAttributeMessageResponse) error {
+	return h.CategoryHandler.DeleteCategoryAttribute(ctx, in, out)
+}
+
+func (h *categoryHandler) FindCategoryAttributes(ctx context.Context, in *FindCategoryAttributesRequest, out *FindCategoryAttributesResponse) error {
+	return h.CategoryHandler.FindCategoryAttributes(ctx, in, out)
+}
+
+func (h *categoryHandler) ValidateCategoryAttributes(ctx context.Context, in *ValidateAttributesRequest, out *ValidateAttributesResponse) error {
+	return h.CategoryHandler.ValidateCategoryAttributes(ctx, in, out)
+}
@@ -0,0 +1,101 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/micro/go-micro/v2/client"
+	"github.com/micro/go-micro/v2/errors"
+	"github.com/micro/go-micro/v2/server"
+)
+
+// SessionValidator reports whether the login session of an authenticated caller is still active.
+type SessionValidator func(ctx context.Context, accessToken string, user *AuthUser) (bool, error)
+
+// NewSessionHandlerWrapper returns a handler wrapper that rejects callers whose session has been revoked,
+// so a stolen access token stops working before it expires. It must be registered after the auth handler
+// wrapper; anonymous callers and tokens without a session pass through, and callers of public endpoints
+// continue anonymously when their session cannot be confirmed.
+func NewSessionHandlerWrapper(validate SessionValidator) server.HandlerWrapper {
+	return func(next server.HandlerFunc) server.HandlerFunc {
+		return func(ctx context.Context, req server.Request, rsp interface{}) error {
+			user, ok := AuthUserFromContext(ctx)
+			if !ok || user.SessionID == 0 {
+				return next(ctx, req, rsp)
+			}
+
+			accessToken, _ := bearerToken(ctx)
+			active, err := validate(ctx, accessToken, user)
+			if (err != nil || !active) && isOptionalAuth(ctx) {
+				return next(withoutAuthUser(ctx), req, rsp)
+			}
+			if err != nil {
+				return errors.New(req.Service(), fmt.Sprintf("failed to validate session: %v", err), http.StatusServiceUnavailable)
+			}
+			if !active {
+				return errors.Unauthorized(req.Service(), "session %d has been revoked", user.SessionID)
+			}
+			return next(ctx, req, rsp)
+		}
+	}
+}
+
+// validateSessionRequest and validateSessionResponse mirror the User.ValidateSession messages,
+// they are sent as JSON so services do not need the user proto package.
+type validateSessionRequest struct {
+	AccessToken string `json:"access_token,omitempty"`
+}
+
+type validateSessionResponse struct {
+	Active bool `json:"active,omitempty"`
+}
+
+// sessionCacheSweepSize is the cache size from which expired entries are dropped on insert.
+const sessionCacheSweepSize = 1024
+
+type sessionCacheEntry struct {
+	active    bool
+	expiresAt time.Time
+}
+
+// NewRemoteSessionValidator returns a SessionValidator asking the user service (e.g. "go.micro.service.user")
+// through User.ValidateSession. Answers are cached per session for cacheTTL to keep calls off the hot path;
+// a zero cacheTTL checks every request.
+func NewRemoteSessionValidator(c client.Client, userService string, cacheTTL time.Duration) SessionValidator {
+	var mu sync.Mutex
+	cache := make(map[int64]sessionCacheEntry)
+
+	return func(ctx context.Context, accessToken string, user *AuthUser) (bool, error) {
+		now := time.Now()
+		mu.Lock()
+		entry, ok := cache[user.SessionID]
+		mu.Unlock()
+		if ok && now.Before(entry.expiresAt) {
+			return entry.active, nil
+		}
+
+		req := c.NewRequest(userService, "User.ValidateSession",
+			&validateSessionRequest{AccessToken: accessToken}, client.WithContentType("application/json"))
+		rsp := &validateSessionResponse{}
+		if err := c.Call(ctx, req, rsp); err != nil {
+			return false, err
+		}
+
+		if cacheTTL > 0 {
+			mu.Lock()
+			if len(cache) >= sessionCacheSweepSize {
+				for sessionID, cached := range cache {
+					if !now.Before(cached.expiresAt) {
+						delete(cache, sessionID)
+					}
+				}
+			}
+			cache[user.SessionID] = sessionCacheEntry{active: rsp.Active, expiresAt: now.Add(cacheTTL)}
+			mu.Unlock()
+		}
+		return rsp.Active, nil
+	}
+}
@@ -0,0 +1,178 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/micro/go-micro/v2/errors"
+	"github.com/micro/go-micro/v2/metadata"
+	"github.com/micro/go-micro/v2/server"
+)
+
+// AuthClaims are the claims carried by access tokens issued by the user service.
+type AuthClaims struct {
+	UserID   int64    `json:"uid"`
+	UserName string   `json:"name"`
+	Roles    []string `json:"roles,omitempty"`
+	// Permissions are RPC endpoints such as "Category.CreateCategory", "Category.*" or "*"
+	Permissions []string `json:"perms,omitempty"`
+	// SessionID is the login session the token belongs to, see NewSessionHandlerWrapper
+	SessionID int64 `json:"sid,omitempty"`
+	jwt.StandardClaims
+}
+
+// AuthUser is the authenticated caller of an RPC.
+type AuthUser struct {
+	ID          int64
+	UserName    string
+	Roles       []string
+	Permissions []string
+	SessionID   int64
+}
+
+// HasPermission reports whether the user is granted the given endpoint, either directly,
+// through a "Service.*" wildcard or through the "*" wildcard.
+func (u *AuthUser) HasPermission(endpoint string) bool {
+	service := strings.SplitN(endpoint, ".", 2)[0]
+	for _, permission := range u.Permissions {
+		if permission == "*" || permission == endpoint || permission == service+".*" {
+			return true
+		}
+	}
+	return false
+}
+
+type authUserKey struct{}
+
+// optionalAuthKey marks a context whose caller was attached on a public endpoint.
+type optionalAuthKey struct{}
+
+// NewAuthContext returns a copy of ctx carrying the authenticated user.
+func NewAuthContext(ctx context.Context, user *AuthUser) context.Context {
+	return context.WithValue(ctx, authUserKey{}, user)
+}
+
+// AuthUserFromContext returns the authenticated user stored in ctx by the auth handler wrapper.
+func AuthUserFromContext(ctx context.Context) (*AuthUser, bool) {
+	user, ok := ctx.Value(authUserKey{}).(*AuthUser)
+	return user, ok && user != nil
+}
+
+// withoutAuthUser returns a copy of ctx in which no user is authenticated.
+func withoutAuthUser(ctx context.Context) context.Context {
+	return context.WithValue(ctx, authUserKey{}, (*AuthUser)(nil))
+}
+
+// isOptionalAuth reports whether the caller in ctx was attached on a public endpoint.
+func isOptionalAuth(ctx context.Context) bool {
+	optional, _ := ctx.Value(optionalAuthKey{}).(bool)
+	return optional
+}
+
+// ParseAccessToken verifies the signature and expiry of an access token and returns its claims.
+func ParseAccessToken(secret string, accessToken string) (*AuthClaims, error) {
+	claims := &AuthClaims{}
+	token, err := jwt.ParseWithClaims(accessToken, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(secret), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
+	return claims, nil
+}
+
+// bearerToken extracts the token from the "Authorization: Bearer <token>" request metadata.
+func bearerToken(ctx context.Context) (string, bool) {
+	header, ok := metadata.Get(ctx, "Authorization")
+	if !ok {
+		return "", false
+	}
+
+	parts := strings.SplitN(header, " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") || parts[1] == "" {
+		return "", false
+	}
+	return parts[1], true
+}
+
+// NewAuthHandlerWrapper returns a handler wrapper that requires a valid bearer token on every RPC
+// except the public endpoints (e.g. "Product.FindAllProduct"), and puts the caller into the context.
+// Public endpoints still get the caller when a valid token is sent, invalid tokens are ignored there.
+func NewAuthHandlerWrapper(secret string, publicEndpoints ...string) server.HandlerWrapper {
+	public := make(map[string]bool, len(publicEndpoints))
+	for _, endpoint := range publicEndpoints {
+		public[endpoint] = true
+	}
+
+	return func(next server.HandlerFunc) server.HandlerFunc {
+		return func(ctx context.Context, req server.Request, rsp interface{}) error {
+			accessToken, ok := bearerToken(ctx)
+			if public[req.Endpoint()] {
+				if ok {
+					if claims, err := ParseAccessToken(secret, accessToken); err == nil {
+						ctx = context.WithValue(NewAuthContext(ctx, authUser(claims)), optionalAuthKey{}, true)
+					}
+				}
+				return next(ctx, req, rsp)
+			}
+
+			if !ok {
+				return errors.Unauthorized(req.Service(), "missing bearer token for %s", req.Endpoint())
+			}
+
+			claims, err := ParseAccessToken(secret, accessToken)
+			if err != nil {
+				return errors.Unauthorized(req.Service(), "invalid access token: %v", err)
+			}
+
+			ctx = NewAuthContext(ctx, authUser(claims))
+			return next(ctx, req, rsp)
+		}
+	}
+}
+
+// authUser converts verified token claims into the caller stored in the context.
+func authUser(claims *AuthClaims) *AuthUser {
+	return &AuthUser{
+		ID:          claims.UserID,
+		UserName:    claims.UserName,
+		Roles:       claims.Roles,
+		Permissions: claims.Permissions,
+		SessionID:   claims.SessionID,
+	}
+}
+
+// NewPermissionHandlerWrapper returns a handler wrapper that only lets the restricted endpoints
+// through when the authenticated user holds a matching permission. It must be registered after
+// the auth handler wrapper so the user is already in the context.
+func NewPermissionHandlerWrapper(restrictedEndpoints ...string) server.HandlerWrapper {
+	restricted := make(map[string]bool, len(restrictedEndpoints))
+	for _, endpoint := range restrictedEndpoints {
+		restricted[endpoint] = true
+	}
+
+	return func(next server.HandlerFunc) server.HandlerFunc {
+		return func(ctx context.Context, req server.Request, rsp interface{}) error {
+			if !restricted[req.Endpoint()] {
+				return next(ctx, req, rsp)
+			}
+
+			user, ok := AuthUserFromContext(ctx)
+			if !ok {
+				return errors.Unauthorized(req.Service(), "authentication required for %s", req.Endpoint())
+			}
+			if !user.HasPermission(req.Endpoint()) {
+				return errors.Forbidden(req.Service(), "user %s is not allowed to call %s", user.UserName, req.Endpoint())
+			}
+			return next(ctx, req, rsp)
+		}
+	}
+}
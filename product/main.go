@@ -6,11 +6,13 @@ import (
 	"github.com/micro/go-micro/v2"
 	"github.com/micro/go-micro/v2/config"
 	"github.com/micro/go-micro/v2/registry"
+	"github.com/micro/go-micro/v2/server"
 	"github.com/micro/go-plugins/registry/consul/v2"
 	opentracingPlugin "github.com/micro/go-plugins/wrapper/trace/opentracing/v2"
 	"github.com/opentracing/opentracing-go"
 	"log"
 	"os"
+	"time"
 
 	"github.com/tongs-dev/shopping-platform/product/common"
 	"github.com/tongs-dev/shopping-platform/product/domain/repository"
@@ -19,6 +21,19 @@ import (
 	productpb "github.com/tongs-dev/shopping-platform/product/proto/product"
 )
 
+// publicEndpoints are the read-only RPCs that can be called without an access token
+var publicEndpoints = []string{
+	"Product.FindProductByID",
+	"Product.FindAllProduct",
+}
+
+// restrictedEndpoints are the catalog mutations that need a matching role permission
+var restrictedEndpoints = []string{
+	"Product.AddProduct",
+	"Product.UpdateProduct",
+	"Product.DeleteProductByID",
+}
+
 // setupConsulConfig loads the Consul configuration
 func setupConsulConfig() (config.Config, error) {
 	consulConfig, err := common.GetConsulConfig("127.0.0.1", 8500, "/micro/config")
@@ -59,14 +74,24 @@ func setupMySQLConnection(config config.Config) (*gorm.DB, error) {
 }
 
 // setupService initializes the microservice with Consul registry and config
-func setupService(consul registry.Registry) micro.Service {
-	return micro.NewService(
+func setupService(consul registry.Registry, authSecret string, sessionCacheTTL time.Duration) micro.Service {
+	service := micro.NewService(
 		micro.Name("go.micro.service.product"),
 		micro.Version("latest"),
 		micro.Address("127.0.0.1:8085"),
 		micro.Registry(consul),
-		micro.WrapHandler(opentracingPlugin.NewHandlerWrapper(opentracing.GlobalTracer())),
+		micro.WrapHandler(
+			opentracingPlugin.NewHandlerWrapper(opentracing.GlobalTracer()),
+			common.NewAuthHandlerWrapper(authSecret, publicEndpoints...),
+			common.NewPermissionHandlerWrapper(restrictedEndpoints...),
+		),
 	)
+
+	// Revoked login sessions are rejected by asking the user service, answers are cached for sessionCacheTTL
+	service.Server().Init(server.WrapHandler(common.NewSessionHandlerWrapper(
+		common.NewRemoteSessionValidator(service.Client(), "go.micro.service.user", sessionCacheTTL),
+	)))
+	return service
 }
 
 func main() {
@@ -86,7 +111,13 @@ func main() {
 	defer io.Close()
 	opentracing.SetGlobalTracer(tracer)
 
-	service := setupService(consulRegistry)
+	// Secret shared with the user service to verify access tokens, there is no default so tokens cannot be forged
+	authSecret := consulConfig.Get("auth", "secret").String("")
+	if authSecret == "" {
+		log.Fatal("Missing auth secret in Consul config (auth.secret)")
+	}
+	sessionCacheTTL := consulConfig.Get("auth", "session_cache_ttl").Duration(5 * time.Second)
+	service := setupService(consulRegistry, authSecret, sessionCacheTTL)
 
 	// Establish MySQL connection
 	db, err := setupMySQLConnection(consulConfig)
@@ -4,8 +4,10 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"os"
 
 	"github.com/micro/go-micro/v2"
+	"github.com/micro/go-micro/v2/metadata"
 	"github.com/micro/go-micro/v2/registry"
 	opentracingPlugin "github.com/micro/go-plugins/wrapper/trace/opentracing/v2"
 	"github.com/opentracing/opentracing-go"
@@ -76,8 +78,11 @@ func main() {
 		},
 	}
 
+	// AddProduct requires an access token issued by the user service's Login
+	ctx := metadata.Set(context.TODO(), "Authorization", "Bearer "+os.Getenv("ACCESS_TOKEN"))
+
 	// Add the product using the product service
-	response, err := productService.AddProduct(context.TODO(), productAdd)
+	response, err := productService.AddProduct(ctx, productAdd)
 	if err != nil {
 		log.Println("Failed to add product:", err)
 		return
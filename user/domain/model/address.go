@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+type Address struct {
+	// primary key
+	ID int64 `gorm:"primary_key;not_null;auto_increment"`
+	// owner of the address
+	UserID    int64 `gorm:"index;not_null"`
+	Recipient string
+	Line1     string
+	Line2     string
+	City      string
+	Region    string
+	// stored normalized for the country, e.g. upper-case UK postcodes
+	PostalCode string
+	// ISO 3166-1 alpha-2 country code
+	Country string `gorm:"size:2"`
+	Phone   string
+	// at most one address per user carries each default flag
+	IsDefaultShipping bool
+	IsDefaultBilling  bool
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
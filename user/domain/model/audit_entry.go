@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+type AuditEntry struct {
+	// primary key
+	ID int64 `gorm:"primary_key;not_null;auto_increment"`
+	// user the entry is about, kept when the user is erased
+	UserID int64 `gorm:"index;not_null"`
+	// user who performed the action, equal to UserID for self-service actions
+	ActorID int64
+	// what happened, one of the service.Audit* actions
+	Action string `gorm:"not_null"`
+	// free-form context without personal data, e.g. the reason of an erasure
+	Detail    string
+	CreatedAt time.Time
+}
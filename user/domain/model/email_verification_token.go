@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+type EmailVerificationToken struct {
+	// primary key
+	ID int64 `gorm:"primary_key;not_null;auto_increment"`
+	// user whose email is confirmed by this token
+	UserID int64 `gorm:"index;not_null"`
+	// sha256 of the token sent to the user, the raw value is never stored
+	TokenHash string `gorm:"unique_index;not_null"`
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+type LinkedIdentity struct {
+	// primary key
+	ID int64 `gorm:"primary_key;not_null;auto_increment"`
+	// local user the external account signs in as
+	UserID int64 `gorm:"index;not_null"`
+	// identity provider name and the account ID at that provider, unique together
+	Provider string `gorm:"unique_index:idx_provider_subject;not_null"`
+	Subject  string `gorm:"unique_index:idx_provider_subject;not_null"`
+	// email reported by the provider when the identity was linked
+	Email     string
+	CreatedAt time.Time
+}
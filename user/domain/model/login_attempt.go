@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+type LoginAttempt struct {
+	// primary key
+	ID int64 `gorm:"primary_key;not_null;auto_increment"`
+	// throttled subject, "user:<user name>" or "addr:<client address>"
+	Subject string `gorm:"unique_index;not_null"`
+	// consecutive failed logins since the last success or unlock
+	FailedCount  int
+	LastFailedAt time.Time
+	// the subject cannot log in before this time
+	LockedUntil time.Time
+}
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+type PasswordResetToken struct {
+	// primary key
+	ID int64 `gorm:"primary_key;not_null;auto_increment"`
+	// user allowed to reset their password with this token
+	UserID int64 `gorm:"index;not_null"`
+	// sha256 of the token sent to the user, the raw value is never stored
+	TokenHash string `gorm:"unique_index;not_null"`
+	ExpiresAt time.Time
+	// set once the token has been consumed, tokens are single-use
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+type RefreshToken struct {
+	// primary key
+	ID int64 `gorm:"primary_key;not_null;auto_increment"`
+	// owner of the token
+	UserID int64 `gorm:"index;not_null"`
+	// login session the token belongs to, rotation keeps the session
+	SessionID int64 `gorm:"index"`
+	// sha256 of the opaque token handed to the client, the raw value is never stored
+	TokenHash string `gorm:"unique_index;not_null"`
+	ExpiresAt time.Time
+	Revoked   bool
+	CreatedAt time.Time
+}
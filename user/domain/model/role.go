@@ -0,0 +1,11 @@
+package model
+
+type Role struct {
+	// primary key
+	ID int64 `gorm:"primary_key;not_null;auto_increment"`
+	// role name, referenced from User.Roles
+	Name string `gorm:"unique_index;not_null"`
+	// comma-separated RPC endpoints the role may call, e.g. "Category.*,Product.UpdateProduct" or "*"
+	Permissions string
+	Description string
+}
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+type Session struct {
+	// primary key
+	ID int64 `gorm:"primary_key;not_null;auto_increment"`
+	// user who logged in
+	UserID int64 `gorm:"index;not_null"`
+	// client details recorded at login
+	Device    string
+	IPAddress string
+	UserAgent string
+	CreatedAt time.Time
+	// last time one of the session's tokens was refreshed or validated
+	LastSeenAt time.Time
+	// expiry of the session's current refresh token
+	ExpiresAt time.Time
+	// set when the session is revoked, its access tokens are rejected from then on
+	RevokedAt *time.Time
+}
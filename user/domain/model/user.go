@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 type User struct {
 	// primary key
 	ID int64 `gorm:"primary_key;not_null;auto_increment"`
@@ -7,7 +9,26 @@ type User struct {
 	UserName string `gorm:"unique_index;not_null"`
 	// other fields
 	FirstName string
-	//...
+	LastName  string
+	// contact details, verification messages are sent to Email
+	Email string `gorm:"index"`
+	Phone string
+	// set once the user has confirmed Email with a verification token
+	EmailVerified bool
 	// password
 	HashPassword string
+	// comma-separated role names carried in issued access tokens
+	Roles string
+	// TOTP secret encrypted with the MFA key, set from EnrollTOTP until DisableTOTP
+	TOTPSecret string
+	// set once the enrolled secret has been confirmed with a valid code, Login then requires VerifyMFA
+	TOTPEnabled bool
+	// last TOTP time step accepted, codes cannot be replayed within their window
+	TOTPLastStep int64
+	// comma-separated sha256 hashes of the unused recovery codes
+	RecoveryCodes string
+	// set when the personal data of the user has been erased, the row is kept for referential integrity
+	ErasedAt  *time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
 }
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/tongs-dev/shopping-platform/user/domain/model"
+)
+
+// IAddressRepository defines the contract for address book persistence.
+type IAddressRepository interface {
+	InitTable() error
+	CreateAddress(*model.Address) (int64, error)
+	FindAddressByID(int64) (*model.Address, error)
+	FindAddressesByUserID(int64) ([]model.Address, error)
+	UpdateAddress(*model.Address) error
+	DeleteAddressByID(int64) error
+	DeleteAddressesByUserID(int64) error
+	SetDefaultAddress(userID int64, addressID int64, shipping bool, billing bool) error
+}
+
+// NewAddressRepository returns an implementation of IAddressRepository using GORM.
+func NewAddressRepository(db *gorm.DB) IAddressRepository {
+	return &AddressRepository{mysqlDb: db}
+}
+
+// AddressRepository is the concrete implementation of IAddressRepository.
+type AddressRepository struct {
+	mysqlDb *gorm.DB
+}
+
+// InitTable creates the address table if it doesn't exist.
+func (r *AddressRepository) InitTable() error {
+	return r.mysqlDb.CreateTable(&model.Address{}).Error
+}
+
+// CreateAddress inserts a new address and returns the generated ID.
+func (r *AddressRepository) CreateAddress(address *model.Address) (int64, error) {
+	if err := r.mysqlDb.Create(address).Error; err != nil {
+		return 0, err
+	}
+	return address.ID, nil
+}
+
+// FindAddressByID retrieves an address by ID.
+func (r *AddressRepository) FindAddressByID(addressID int64) (*model.Address, error) {
+	address := &model.Address{}
+	err := r.mysqlDb.First(address, addressID).Error
+	if err != nil {
+		return nil, err
+	}
+	return address, nil
+}
+
+// FindAddressesByUserID retrieves the address book of a user, oldest first.
+func (r *AddressRepository) FindAddressesByUserID(userID int64) ([]model.Address, error) {
+	var addresses []model.Address
+	err := r.mysqlDb.Where("user_id = ?", userID).Order("id").Find(&addresses).Error
+	if err != nil {
+		return nil, err
+	}
+	return addresses, nil
+}
+
+// UpdateAddress saves every field of an address, including cleared optional lines.
+func (r *AddressRepository) UpdateAddress(address *model.Address) error {
+	return r.mysqlDb.Save(address).Error
+}
+
+// DeleteAddressByID removes an address by ID.
+func (r *AddressRepository) DeleteAddressByID(addressID int64) error {
+	result := r.mysqlDb.Where("id = ?", addressID).Delete(&model.Address{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeleteAddressesByUserID removes the whole address book of a user.
+func (r *AddressRepository) DeleteAddressesByUserID(userID int64) error {
+	return r.mysqlDb.Where("user_id = ?", userID).Delete(&model.Address{}).Error
+}
+
+// SetDefaultAddress makes an address the user's default for shipping and/or billing, clearing the flag
+// on the user's other addresses in the same transaction.
+func (r *AddressRepository) SetDefaultAddress(userID int64, addressID int64, shipping bool, billing bool) error {
+	return r.mysqlDb.Transaction(func(tx *gorm.DB) error {
+		for column, enabled := range map[string]bool{"is_default_shipping": shipping, "is_default_billing": billing} {
+			if !enabled {
+				continue
+			}
+			if err := tx.Model(&model.Address{}).Where("user_id = ?", userID).Update(column, false).Error; err != nil {
+				return err
+			}
+			result := tx.Model(&model.Address{}).Where("id = ? AND user_id = ?", addressID, userID).Update(column, true)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return gorm.ErrRecordNotFound
+			}
+		}
+		return nil
+	})
+}
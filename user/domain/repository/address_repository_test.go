@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/tongs-dev/shopping-platform/user/domain/model"
+)
+
+// TestAddressRepository contains the MySQL integration tests for the AddressRepository.
+func TestAddressRepository(t *testing.T) {
+	db := setupTestDB(t)
+	err := db.DropTableIfExists(&model.Address{}).AutoMigrate(&model.Address{}).Error
+	assert.NoError(t, err, "Failed to migrate address table")
+	repo := &AddressRepository{mysqlDb: db}
+
+	newAddress := func(userID int64) *model.Address {
+		return &model.Address{UserID: userID, Recipient: "John Doe", Line1: "1 Main St", City: "Springfield",
+			PostalCode: "12345", Country: "US"}
+	}
+
+	t.Run("CreateAndFindAddress", func(t *testing.T) {
+		addressID, err := repo.CreateAddress(newAddress(1))
+		assert.NoError(t, err)
+		assert.NotZero(t, addressID)
+
+		found, err := repo.FindAddressByID(addressID)
+		assert.NoError(t, err)
+		assert.Equal(t, "Springfield", found.City)
+
+		addresses, err := repo.FindAddressesByUserID(1)
+		assert.NoError(t, err)
+		assert.Len(t, addresses, 1)
+	})
+
+	t.Run("SetDefaultAddress", func(t *testing.T) {
+		firstID, _ := repo.CreateAddress(newAddress(2))
+		secondID, _ := repo.CreateAddress(newAddress(2))
+
+		assert.NoError(t, repo.SetDefaultAddress(2, firstID, true, true))
+		assert.NoError(t, repo.SetDefaultAddress(2, secondID, true, false))
+
+		first, _ := repo.FindAddressByID(firstID)
+		second, _ := repo.FindAddressByID(secondID)
+		assert.False(t, first.IsDefaultShipping)
+		assert.True(t, first.IsDefaultBilling)
+		assert.True(t, second.IsDefaultShipping)
+
+		err := repo.SetDefaultAddress(3, firstID, true, false)
+		assert.True(t, gorm.IsRecordNotFoundError(err))
+	})
+
+	t.Run("UpdateAndDeleteAddress", func(t *testing.T) {
+		address := newAddress(4)
+		addressID, _ := repo.CreateAddress(address)
+
+		address.Line2 = ""
+		address.City = "Shelbyville"
+		assert.NoError(t, repo.UpdateAddress(address))
+
+		found, _ := repo.FindAddressByID(addressID)
+		assert.Equal(t, "Shelbyville", found.City)
+
+		assert.NoError(t, repo.DeleteAddressByID(addressID))
+		assert.True(t, gorm.IsRecordNotFoundError(repo.DeleteAddressByID(addressID)))
+	})
+}
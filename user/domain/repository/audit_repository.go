@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/tongs-dev/shopping-platform/user/domain/model"
+)
+
+// IAuditRepository defines the contract for audit trail persistence.
+type IAuditRepository interface {
+	InitTable() error
+	CreateAuditEntry(*model.AuditEntry) (int64, error)
+	FindAuditEntriesByUserID(int64) ([]model.AuditEntry, error)
+}
+
+// NewAuditRepository returns an implementation of IAuditRepository using GORM.
+func NewAuditRepository(db *gorm.DB) IAuditRepository {
+	return &AuditRepository{mysqlDb: db}
+}
+
+// AuditRepository is the concrete implementation of IAuditRepository.
+type AuditRepository struct {
+	mysqlDb *gorm.DB
+}
+
+// InitTable creates the audit_entry table if it doesn't exist.
+func (r *AuditRepository) InitTable() error {
+	return r.mysqlDb.CreateTable(&model.AuditEntry{}).Error
+}
+
+// CreateAuditEntry appends an entry to the audit trail and returns the generated ID.
+func (r *AuditRepository) CreateAuditEntry(entry *model.AuditEntry) (int64, error) {
+	if err := r.mysqlDb.Create(entry).Error; err != nil {
+		return 0, err
+	}
+	return entry.ID, nil
+}
+
+// FindAuditEntriesByUserID retrieves the audit trail of a user, oldest first.
+func (r *AuditRepository) FindAuditEntriesByUserID(userID int64) ([]model.AuditEntry, error) {
+	var entries []model.AuditEntry
+	err := r.mysqlDb.Where("user_id = ?", userID).Order("id").Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
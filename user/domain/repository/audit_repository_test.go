@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tongs-dev/shopping-platform/user/domain/model"
+)
+
+// TestAuditRepository contains the MySQL integration tests for the AuditRepository.
+func TestAuditRepository(t *testing.T) {
+	db := setupTestDB(t)
+	err := db.DropTableIfExists(&model.AuditEntry{}).AutoMigrate(&model.AuditEntry{}).Error
+	assert.NoError(t, err, "Failed to migrate audit_entry table")
+	repo := &AuditRepository{mysqlDb: db}
+
+	t.Run("CreateAndFindAuditEntries", func(t *testing.T) {
+		_, err := repo.CreateAuditEntry(&model.AuditEntry{UserID: 1, ActorID: 1, Action: "password_changed"})
+		assert.NoError(t, err)
+		_, err = repo.CreateAuditEntry(&model.AuditEntry{UserID: 1, ActorID: 2, Action: "user_erased", Detail: "request"})
+		assert.NoError(t, err)
+		repo.CreateAuditEntry(&model.AuditEntry{UserID: 2, ActorID: 2, Action: "mfa_enabled"})
+
+		entries, err := repo.FindAuditEntriesByUserID(1)
+		assert.NoError(t, err)
+		assert.Len(t, entries, 2)
+		assert.Equal(t, "user_erased", entries[1].Action)
+	})
+}
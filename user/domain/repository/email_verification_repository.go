@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/tongs-dev/shopping-platform/user/domain/model"
+)
+
+// IEmailVerificationRepository defines the contract for email verification token persistence.
+type IEmailVerificationRepository interface {
+	InitTable() error
+	CreateEmailVerificationToken(*model.EmailVerificationToken) (int64, error)
+	FindEmailVerificationTokenByHash(string) (*model.EmailVerificationToken, error)
+	DeleteEmailVerificationTokensByUserID(int64) error
+}
+
+// NewEmailVerificationRepository returns an implementation of IEmailVerificationRepository using GORM.
+func NewEmailVerificationRepository(db *gorm.DB) IEmailVerificationRepository {
+	return &EmailVerificationRepository{mysqlDb: db}
+}
+
+// EmailVerificationRepository is the concrete implementation of IEmailVerificationRepository.
+type EmailVerificationRepository struct {
+	mysqlDb *gorm.DB
+}
+
+// InitTable creates the email verification token table if it doesn't exist.
+func (r *EmailVerificationRepository) InitTable() error {
+	return r.mysqlDb.CreateTable(&model.EmailVerificationToken{}).Error
+}
+
+// CreateEmailVerificationToken inserts a new verification token and returns the generated ID.
+func (r *EmailVerificationRepository) CreateEmailVerificationToken(token *model.EmailVerificationToken) (int64, error) {
+	if err := r.mysqlDb.Create(token).Error; err != nil {
+		return 0, err
+	}
+	return token.ID, nil
+}
+
+// FindEmailVerificationTokenByHash retrieves a verification token by the hash of its raw value.
+func (r *EmailVerificationRepository) FindEmailVerificationTokenByHash(tokenHash string) (*model.EmailVerificationToken, error) {
+	token := &model.EmailVerificationToken{}
+	err := r.mysqlDb.Where("token_hash = ?", tokenHash).First(token).Error
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// DeleteEmailVerificationTokensByUserID removes every verification token of a user.
+func (r *EmailVerificationRepository) DeleteEmailVerificationTokensByUserID(userID int64) error {
+	return r.mysqlDb.Where("user_id = ?", userID).Delete(&model.EmailVerificationToken{}).Error
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/tongs-dev/shopping-platform/user/domain/model"
+)
+
+// ILinkedIdentityRepository defines the contract for external identity persistence.
+type ILinkedIdentityRepository interface {
+	InitTable() error
+	CreateLinkedIdentity(*model.LinkedIdentity) (int64, error)
+	FindLinkedIdentity(provider string, subject string) (*model.LinkedIdentity, error)
+	FindLinkedIdentitiesByUserID(int64) ([]model.LinkedIdentity, error)
+	DeleteLinkedIdentitiesByUserID(int64) error
+}
+
+// NewLinkedIdentityRepository returns an implementation of ILinkedIdentityRepository using GORM.
+func NewLinkedIdentityRepository(db *gorm.DB) ILinkedIdentityRepository {
+	return &LinkedIdentityRepository{mysqlDb: db}
+}
+
+// LinkedIdentityRepository is the concrete implementation of ILinkedIdentityRepository.
+type LinkedIdentityRepository struct {
+	mysqlDb *gorm.DB
+}
+
+// InitTable creates the linked_identity table if it doesn't exist.
+func (r *LinkedIdentityRepository) InitTable() error {
+	return r.mysqlDb.CreateTable(&model.LinkedIdentity{}).Error
+}
+
+// CreateLinkedIdentity links an external account to a user and returns the generated ID.
+func (r *LinkedIdentityRepository) CreateLinkedIdentity(linked *model.LinkedIdentity) (int64, error) {
+	if err := r.mysqlDb.Create(linked).Error; err != nil {
+		return 0, err
+	}
+	return linked.ID, nil
+}
+
+// FindLinkedIdentity retrieves the link of an external account by provider and subject.
+func (r *LinkedIdentityRepository) FindLinkedIdentity(provider string, subject string) (*model.LinkedIdentity, error) {
+	linked := &model.LinkedIdentity{}
+	err := r.mysqlDb.Where("provider = ? AND subject = ?", provider, subject).First(linked).Error
+	if err != nil {
+		return nil, err
+	}
+	return linked, nil
+}
+
+// FindLinkedIdentitiesByUserID retrieves the external accounts linked to a user.
+func (r *LinkedIdentityRepository) FindLinkedIdentitiesByUserID(userID int64) ([]model.LinkedIdentity, error) {
+	var linked []model.LinkedIdentity
+	err := r.mysqlDb.Where("user_id = ?", userID).Order("id").Find(&linked).Error
+	if err != nil {
+		return nil, err
+	}
+	return linked, nil
+}
+
+// DeleteLinkedIdentitiesByUserID unlinks every external account of a user.
+func (r *LinkedIdentityRepository) DeleteLinkedIdentitiesByUserID(userID int64) error {
+	return r.mysqlDb.Where("user_id = ?", userID).Delete(&model.LinkedIdentity{}).Error
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/tongs-dev/shopping-platform/user/domain/model"
+)
+
+// TestLinkedIdentityRepository contains the MySQL integration tests for the LinkedIdentityRepository.
+func TestLinkedIdentityRepository(t *testing.T) {
+	db := setupTestDB(t)
+	err := db.DropTableIfExists(&model.LinkedIdentity{}).AutoMigrate(&model.LinkedIdentity{}).Error
+	assert.NoError(t, err, "Failed to migrate linked_identity table")
+	repo := &LinkedIdentityRepository{mysqlDb: db}
+
+	t.Run("CreateAndFindLinkedIdentity", func(t *testing.T) {
+		_, err := repo.CreateLinkedIdentity(&model.LinkedIdentity{UserID: 1, Provider: "google", Subject: "123"})
+		assert.NoError(t, err)
+
+		linked, err := repo.FindLinkedIdentity("google", "123")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), linked.UserID)
+
+		_, err = repo.FindLinkedIdentity("github", "123")
+		assert.True(t, gorm.IsRecordNotFoundError(err))
+	})
+
+	t.Run("DuplicateSubject", func(t *testing.T) {
+		_, err := repo.CreateLinkedIdentity(&model.LinkedIdentity{UserID: 2, Provider: "google", Subject: "123"})
+		assert.Error(t, err, "A subject can only be linked once per provider")
+	})
+
+	t.Run("DeleteLinkedIdentitiesByUserID", func(t *testing.T) {
+		repo.CreateLinkedIdentity(&model.LinkedIdentity{UserID: 3, Provider: "google", Subject: "456"})
+		repo.CreateLinkedIdentity(&model.LinkedIdentity{UserID: 3, Provider: "github", Subject: "456"})
+
+		assert.NoError(t, repo.DeleteLinkedIdentitiesByUserID(3))
+
+		linked, err := repo.FindLinkedIdentitiesByUserID(3)
+		assert.NoError(t, err)
+		assert.Empty(t, linked)
+	})
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/tongs-dev/shopping-platform/user/domain/model"
+)
+
+// ILoginAttemptRepository defines the contract for persisting failed login tracking.
+type ILoginAttemptRepository interface {
+	InitTable() error
+	FindLoginAttemptBySubject(string) (*model.LoginAttempt, error)
+	SaveLoginAttempt(*model.LoginAttempt) error
+	DeleteLoginAttemptBySubject(string) error
+}
+
+// NewLoginAttemptRepository returns an implementation of ILoginAttemptRepository using GORM.
+func NewLoginAttemptRepository(db *gorm.DB) ILoginAttemptRepository {
+	return &LoginAttemptRepository{mysqlDb: db}
+}
+
+// LoginAttemptRepository is the concrete implementation of ILoginAttemptRepository.
+type LoginAttemptRepository struct {
+	mysqlDb *gorm.DB
+}
+
+// InitTable creates the login attempt table if it doesn't exist.
+func (r *LoginAttemptRepository) InitTable() error {
+	return r.mysqlDb.CreateTable(&model.LoginAttempt{}).Error
+}
+
+// FindLoginAttemptBySubject retrieves the failed login tracking of a subject.
+func (r *LoginAttemptRepository) FindLoginAttemptBySubject(subject string) (*model.LoginAttempt, error) {
+	attempt := &model.LoginAttempt{}
+	err := r.mysqlDb.Where("subject = ?", subject).First(attempt).Error
+	if err != nil {
+		return nil, err
+	}
+	return attempt, nil
+}
+
+// SaveLoginAttempt inserts or updates the failed login tracking of a subject.
+func (r *LoginAttemptRepository) SaveLoginAttempt(attempt *model.LoginAttempt) error {
+	return r.mysqlDb.Save(attempt).Error
+}
+
+// DeleteLoginAttemptBySubject clears the failed login tracking of a subject.
+func (r *LoginAttemptRepository) DeleteLoginAttemptBySubject(subject string) error {
+	return r.mysqlDb.Where("subject = ?", subject).Delete(&model.LoginAttempt{}).Error
+}
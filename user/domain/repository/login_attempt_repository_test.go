@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tongs-dev/shopping-platform/user/domain/model"
+)
+
+// TestLoginAttemptRepository contains the MySQL integration tests for the LoginAttemptRepository.
+func TestLoginAttemptRepository(t *testing.T) {
+	db := setupTestDB(t)
+	err := db.DropTableIfExists(&model.LoginAttempt{}).AutoMigrate(&model.LoginAttempt{}).Error
+	assert.NoError(t, err, "Failed to migrate login attempt table")
+	repo := &LoginAttemptRepository{mysqlDb: db}
+
+	t.Run("SaveAndFindLoginAttempt", func(t *testing.T) {
+		subject := "user:" + generateRandomString(8)
+		attempt := &model.LoginAttempt{Subject: subject, FailedCount: 1, LastFailedAt: time.Now()}
+		assert.NoError(t, repo.SaveLoginAttempt(attempt))
+
+		attempt.FailedCount = 2
+		assert.NoError(t, repo.SaveLoginAttempt(attempt), "Failed to update login attempt")
+
+		found, err := repo.FindLoginAttemptBySubject(subject)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, found.FailedCount)
+	})
+
+	t.Run("DeleteLoginAttemptBySubject", func(t *testing.T) {
+		subject := "addr:" + generateRandomString(8)
+		repo.SaveLoginAttempt(&model.LoginAttempt{Subject: subject, FailedCount: 1})
+
+		assert.NoError(t, repo.DeleteLoginAttemptBySubject(subject))
+
+		_, err := repo.FindLoginAttemptBySubject(subject)
+		assert.Error(t, err, "Expected error for cleared login attempt")
+	})
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/tongs-dev/shopping-platform/user/domain/model"
+)
+
+// IPasswordResetRepository defines the contract for password reset token persistence.
+type IPasswordResetRepository interface {
+	InitTable() error
+	CreatePasswordResetToken(*model.PasswordResetToken) (int64, error)
+	FindPasswordResetTokenByHash(string) (*model.PasswordResetToken, error)
+	MarkPasswordResetTokenUsed(int64, time.Time) error
+	DeletePasswordResetTokensByUserID(int64) error
+}
+
+// NewPasswordResetRepository returns an implementation of IPasswordResetRepository using GORM.
+func NewPasswordResetRepository(db *gorm.DB) IPasswordResetRepository {
+	return &PasswordResetRepository{mysqlDb: db}
+}
+
+// PasswordResetRepository is the concrete implementation of IPasswordResetRepository.
+type PasswordResetRepository struct {
+	mysqlDb *gorm.DB
+}
+
+// InitTable creates the password reset token table if it doesn't exist.
+func (r *PasswordResetRepository) InitTable() error {
+	return r.mysqlDb.CreateTable(&model.PasswordResetToken{}).Error
+}
+
+// CreatePasswordResetToken inserts a new reset token and returns the generated ID.
+func (r *PasswordResetRepository) CreatePasswordResetToken(token *model.PasswordResetToken) (int64, error) {
+	if err := r.mysqlDb.Create(token).Error; err != nil {
+		return 0, err
+	}
+	return token.ID, nil
+}
+
+// FindPasswordResetTokenByHash retrieves a reset token by the hash of its raw value.
+func (r *PasswordResetRepository) FindPasswordResetTokenByHash(tokenHash string) (*model.PasswordResetToken, error) {
+	token := &model.PasswordResetToken{}
+	err := r.mysqlDb.Where("token_hash = ?", tokenHash).First(token).Error
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// MarkPasswordResetTokenUsed consumes a reset token, failing if it has already been used.
+func (r *PasswordResetRepository) MarkPasswordResetTokenUsed(tokenID int64, usedAt time.Time) error {
+	result := r.mysqlDb.Model(&model.PasswordResetToken{}).
+		Where("id = ? AND used_at IS NULL", tokenID).
+		Update("used_at", usedAt)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeletePasswordResetTokensByUserID removes every reset token of a user.
+func (r *PasswordResetRepository) DeletePasswordResetTokensByUserID(userID int64) error {
+	return r.mysqlDb.Where("user_id = ?", userID).Delete(&model.PasswordResetToken{}).Error
+}
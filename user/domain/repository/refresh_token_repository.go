@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/tongs-dev/shopping-platform/user/domain/model"
+)
+
+// IRefreshTokenRepository defines the contract for refresh token persistence.
+type IRefreshTokenRepository interface {
+	InitTable() error
+	CreateRefreshToken(*model.RefreshToken) (int64, error)
+	FindRefreshTokenByHash(string) (*model.RefreshToken, error)
+	RevokeRefreshToken(int64) error
+	RevokeRefreshTokensByUserID(int64) error
+}
+
+// NewRefreshTokenRepository returns an implementation of IRefreshTokenRepository using GORM.
+func NewRefreshTokenRepository(db *gorm.DB) IRefreshTokenRepository {
+	return &RefreshTokenRepository{mysqlDb: db}
+}
+
+// RefreshTokenRepository is the concrete implementation of IRefreshTokenRepository.
+type RefreshTokenRepository struct {
+	mysqlDb *gorm.DB
+}
+
+// InitTable creates the refresh token table if it doesn't exist.
+func (r *RefreshTokenRepository) InitTable() error {
+	return r.mysqlDb.CreateTable(&model.RefreshToken{}).Error
+}
+
+// CreateRefreshToken inserts a new refresh token and returns the generated ID.
+func (r *RefreshTokenRepository) CreateRefreshToken(token *model.RefreshToken) (int64, error) {
+	if err := r.mysqlDb.Create(token).Error; err != nil {
+		return 0, err
+	}
+	return token.ID, nil
+}
+
+// FindRefreshTokenByHash retrieves a refresh token by the hash of its raw value.
+func (r *RefreshTokenRepository) FindRefreshTokenByHash(tokenHash string) (*model.RefreshToken, error) {
+	token := &model.RefreshToken{}
+	err := r.mysqlDb.Where("token_hash = ?", tokenHash).First(token).Error
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// RevokeRefreshToken marks a single refresh token as revoked.
+func (r *RefreshTokenRepository) RevokeRefreshToken(tokenID int64) error {
+	result := r.mysqlDb.Model(&model.RefreshToken{}).Where("id = ?", tokenID).Update("revoked", true)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// RevokeRefreshTokensByUserID revokes every outstanding refresh token of a user.
+func (r *RefreshTokenRepository) RevokeRefreshTokensByUserID(userID int64) error {
+	return r.mysqlDb.Model(&model.RefreshToken{}).
+		Where("user_id = ? AND revoked = ?", userID, false).
+		Update("revoked", true).Error
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tongs-dev/shopping-platform/user/domain/model"
+)
+
+// TestRefreshTokenRepository contains the MySQL integration tests for the RefreshTokenRepository.
+func TestRefreshTokenRepository(t *testing.T) {
+	db := setupTestDB(t)
+	err := db.DropTableIfExists(&model.RefreshToken{}).AutoMigrate(&model.RefreshToken{}).Error
+	assert.NoError(t, err, "Failed to migrate refresh token table")
+	repo := &RefreshTokenRepository{mysqlDb: db}
+
+	t.Run("CreateAndFindRefreshToken", func(t *testing.T) {
+		tokenHash := generateRandomString(32)
+		tokenID, err := repo.CreateRefreshToken(&model.RefreshToken{UserID: 1, TokenHash: tokenHash, ExpiresAt: time.Now().Add(time.Hour)})
+		assert.NoError(t, err)
+		assert.NotZero(t, tokenID)
+
+		found, err := repo.FindRefreshTokenByHash(tokenHash)
+		assert.NoError(t, err)
+		assert.Equal(t, tokenID, found.ID)
+		assert.False(t, found.Revoked)
+	})
+
+	t.Run("RevokeRefreshToken", func(t *testing.T) {
+		tokenHash := generateRandomString(32)
+		tokenID, _ := repo.CreateRefreshToken(&model.RefreshToken{UserID: 2, TokenHash: tokenHash, ExpiresAt: time.Now().Add(time.Hour)})
+
+		err := repo.RevokeRefreshToken(tokenID)
+		assert.NoError(t, err)
+
+		found, _ := repo.FindRefreshTokenByHash(tokenHash)
+		assert.True(t, found.Revoked)
+	})
+
+	t.Run("RevokeRefreshTokensByUserID", func(t *testing.T) {
+		first := generateRandomString(32)
+		second := generateRandomString(32)
+		repo.CreateRefreshToken(&model.RefreshToken{UserID: 3, TokenHash: first, ExpiresAt: time.Now().Add(time.Hour)})
+		repo.CreateRefreshToken(&model.RefreshToken{UserID: 3, TokenHash: second, ExpiresAt: time.Now().Add(time.Hour)})
+
+		err := repo.RevokeRefreshTokensByUserID(3)
+		assert.NoError(t, err)
+
+		found, _ := repo.FindRefreshTokenByHash(first)
+		assert.True(t, found.Revoked)
+		found, _ = repo.FindRefreshTokenByHash(second)
+		assert.True(t, found.Revoked)
+	})
+}
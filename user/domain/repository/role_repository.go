@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/tongs-dev/shopping-platform/user/domain/model"
+)
+
+// DefaultRoles are the roles created by InitTable.
+var DefaultRoles = []model.Role{
+	{Name: "admin", Permissions: "*", Description: "Full access to every service"},
+	{Name: "merchandiser", Permissions: "Category.*,Product.*", Description: "Manages the catalog"},
+	{Name: "customer", Permissions: "", Description: "Shopper with read-only catalog access"},
+}
+
+// IRoleRepository defines the contract for role-related database operations.
+type IRoleRepository interface {
+	InitTable() error
+	FindRoleByName(string) (*model.Role, error)
+	FindRolesByNames([]string) ([]model.Role, error)
+	FindAll() ([]model.Role, error)
+}
+
+// NewRoleRepository returns an implementation of IRoleRepository using GORM.
+func NewRoleRepository(db *gorm.DB) IRoleRepository {
+	return &RoleRepository{mysqlDb: db}
+}
+
+// RoleRepository is the concrete implementation of IRoleRepository.
+type RoleRepository struct {
+	mysqlDb *gorm.DB
+}
+
+// InitTable creates the role table if it doesn't exist and seeds the default roles.
+func (r *RoleRepository) InitTable() error {
+	if err := r.mysqlDb.CreateTable(&model.Role{}).Error; err != nil {
+		return err
+	}
+	for _, role := range DefaultRoles {
+		role := role
+		if err := r.mysqlDb.Create(&role).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FindRoleByName retrieves a role by its unique name.
+func (r *RoleRepository) FindRoleByName(name string) (*model.Role, error) {
+	role := &model.Role{}
+	err := r.mysqlDb.Where("name = ?", name).First(role).Error
+	if err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+// FindRolesByNames retrieves every role whose name is in the given list.
+func (r *RoleRepository) FindRolesByNames(names []string) ([]model.Role, error) {
+	var roles []model.Role
+	if len(names) == 0 {
+		return roles, nil
+	}
+	err := r.mysqlDb.Where("name IN (?)", names).Find(&roles).Error
+	if err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+// FindAll retrieves all roles from the database.
+func (r *RoleRepository) FindAll() ([]model.Role, error) {
+	var roles []model.Role
+	err := r.mysqlDb.Find(&roles).Error
+	if err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
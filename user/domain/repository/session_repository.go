@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/tongs-dev/shopping-platform/user/domain/model"
+)
+
+// ISessionRepository defines the contract for login session persistence.
+type ISessionRepository interface {
+	InitTable() error
+	CreateSession(*model.Session) (int64, error)
+	FindSessionByID(int64) (*model.Session, error)
+	FindActiveSessionsByUserID(int64, time.Time) ([]model.Session, error)
+	TouchSession(sessionID int64, lastSeenAt time.Time, expiresAt time.Time) error
+	RevokeSession(int64, time.Time) error
+	RevokeSessionsByUserID(int64, time.Time) error
+	FindSessionsByUserID(int64) ([]model.Session, error)
+	AnonymizeSessionsByUserID(int64) error
+}
+
+// NewSessionRepository returns an implementation of ISessionRepository using GORM.
+func NewSessionRepository(db *gorm.DB) ISessionRepository {
+	return &SessionRepository{mysqlDb: db}
+}
+
+// SessionRepository is the concrete implementation of ISessionRepository.
+type SessionRepository struct {
+	mysqlDb *gorm.DB
+}
+
+// InitTable creates the session table if it doesn't exist.
+func (r *SessionRepository) InitTable() error {
+	return r.mysqlDb.CreateTable(&model.Session{}).Error
+}
+
+// CreateSession inserts a new session and returns the generated ID.
+func (r *SessionRepository) CreateSession(session *model.Session) (int64, error) {
+	if err := r.mysqlDb.Create(session).Error; err != nil {
+		return 0, err
+	}
+	return session.ID, nil
+}
+
+// FindSessionByID retrieves a session by ID.
+func (r *SessionRepository) FindSessionByID(sessionID int64) (*model.Session, error) {
+	session := &model.Session{}
+	err := r.mysqlDb.First(session, sessionID).Error
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// FindActiveSessionsByUserID retrieves the sessions of a user that are neither revoked nor expired at now,
+// most recently used first.
+func (r *SessionRepository) FindActiveSessionsByUserID(userID int64, now time.Time) ([]model.Session, error) {
+	var sessions []model.Session
+	err := r.mysqlDb.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now).
+		Order("last_seen_at desc").Find(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// TouchSession records activity on a session and, when non-zero, extends its expiry.
+func (r *SessionRepository) TouchSession(sessionID int64, lastSeenAt time.Time, expiresAt time.Time) error {
+	updates := map[string]interface{}{"last_seen_at": lastSeenAt}
+	if !expiresAt.IsZero() {
+		updates["expires_at"] = expiresAt
+	}
+	return r.mysqlDb.Model(&model.Session{}).Where("id = ?", sessionID).Updates(updates).Error
+}
+
+// RevokeSession marks a single session as revoked.
+func (r *SessionRepository) RevokeSession(sessionID int64, revokedAt time.Time) error {
+	result := r.mysqlDb.Model(&model.Session{}).
+		Where("id = ? AND revoked_at IS NULL", sessionID).
+		Update("revoked_at", revokedAt)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// RevokeSessionsByUserID revokes every outstanding session of a user.
+func (r *SessionRepository) RevokeSessionsByUserID(userID int64, revokedAt time.Time) error {
+	return r.mysqlDb.Model(&model.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", revokedAt).Error
+}
+
+// FindSessionsByUserID retrieves every session of a user, including revoked and expired ones, oldest first.
+func (r *SessionRepository) FindSessionsByUserID(userID int64) ([]model.Session, error) {
+	var sessions []model.Session
+	err := r.mysqlDb.Where("user_id = ?", userID).Order("id").Find(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// AnonymizeSessionsByUserID clears the client details recorded for the sessions of a user.
+func (r *SessionRepository) AnonymizeSessionsByUserID(userID int64) error {
+	return r.mysqlDb.Model(&model.Session{}).Where("user_id = ?", userID).Updates(map[string]interface{}{
+		"device":     "",
+		"ip_address": "",
+		"user_agent": "",
+	}).Error
+}
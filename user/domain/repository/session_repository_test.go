@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tongs-dev/shopping-platform/user/domain/model"
+)
+
+// TestSessionRepository contains the MySQL integration tests for the SessionRepository.
+func TestSessionRepository(t *testing.T) {
+	db := setupTestDB(t)
+	err := db.DropTableIfExists(&model.Session{}).AutoMigrate(&model.Session{}).Error
+	assert.NoError(t, err, "Failed to migrate session table")
+	repo := &SessionRepository{mysqlDb: db}
+	now := time.Now()
+
+	t.Run("CreateAndFindSession", func(t *testing.T) {
+		sessionID, err := repo.CreateSession(&model.Session{UserID: 1, Device: "laptop", LastSeenAt: now, ExpiresAt: now.Add(time.Hour)})
+		assert.NoError(t, err)
+		assert.NotZero(t, sessionID)
+
+		found, err := repo.FindSessionByID(sessionID)
+		assert.NoError(t, err)
+		assert.Equal(t, "laptop", found.Device)
+		assert.Nil(t, found.RevokedAt)
+	})
+
+	t.Run("FindActiveSessionsByUserID", func(t *testing.T) {
+		repo.CreateSession(&model.Session{UserID: 2, LastSeenAt: now, ExpiresAt: now.Add(time.Hour)})
+		repo.CreateSession(&model.Session{UserID: 2, LastSeenAt: now, ExpiresAt: now.Add(-time.Hour)})
+		revokedID, _ := repo.CreateSession(&model.Session{UserID: 2, LastSeenAt: now, ExpiresAt: now.Add(time.Hour)})
+		assert.NoError(t, repo.RevokeSession(revokedID, now))
+
+		sessions, err := repo.FindActiveSessionsByUserID(2, now)
+		assert.NoError(t, err)
+		assert.Len(t, sessions, 1)
+	})
+
+	t.Run("RevokeSessionsByUserID", func(t *testing.T) {
+		repo.CreateSession(&model.Session{UserID: 3, LastSeenAt: now, ExpiresAt: now.Add(time.Hour)})
+		repo.CreateSession(&model.Session{UserID: 3, LastSeenAt: now, ExpiresAt: now.Add(time.Hour)})
+
+		err := repo.RevokeSessionsByUserID(3, now)
+		assert.NoError(t, err)
+
+		sessions, _ := repo.FindActiveSessionsByUserID(3, now)
+		assert.Empty(t, sessions)
+	})
+
+	t.Run("AnonymizeSessionsByUserID", func(t *testing.T) {
+		repo.CreateSession(&model.Session{UserID: 4, Device: "phone", IPAddress: "10.0.0.1", UserAgent: "app",
+			LastSeenAt: now, ExpiresAt: now.Add(time.Hour)})
+
+		err := repo.AnonymizeSessionsByUserID(4)
+		assert.NoError(t, err)
+
+		sessions, err := repo.FindSessionsByUserID(4)
+		assert.NoError(t, err)
+		assert.Len(t, sessions, 1)
+		assert.Empty(t, sessions[0].IPAddress)
+		assert.Empty(t, sessions[0].Device)
+	})
+}
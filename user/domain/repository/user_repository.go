@@ -1,19 +1,63 @@
 package repository
 
 import (
+	"fmt"
 	"github.com/jinzhu/gorm"
 	"github.com/tongs-dev/shopping-platform/user/domain/model"
+	"strings"
+	"time"
 )
 
+// UserSortFields maps the sort fields accepted by SearchUsers to their columns.
+var UserSortFields = map[string]string{
+	"id":         "id",
+	"user_name":  "user_name",
+	"email":      "email",
+	"created_at": "created_at",
+}
+
+// UserSearch filters, orders and pages the users returned by SearchUsers. Zero values do not filter.
+type UserSearch struct {
+	// UserNamePrefix matches user names starting with the prefix
+	UserNamePrefix string
+	Email          string
+	// Role matches users holding the role
+	Role          string
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	// Locked matches users whose login is (or is not) locked at Now, see model.LoginAttempt
+	Locked        *bool
+	EmailVerified *bool
+	// IncludeErased also returns users whose personal data has been erased
+	IncludeErased bool
+	Now           time.Time
+
+	// SortField is a key of UserSortFields, ties are broken by ID in the same direction
+	SortField  string
+	Descending bool
+	// AfterValue and AfterID continue after the row with that sort value and ID, unless AfterID is 0
+	AfterValue interface{}
+	AfterID    int64
+	Limit      int
+}
+
 // IUserRepository defines the contract for user-related database operations.
 type IUserRepository interface {
 	InitTable() error
 	FindUserByName(string) (*model.User, error)
 	FindUserByID(int64) (*model.User, error)
+	FindUsersByVerifiedEmail(string) ([]model.User, error)
 	CreateUser(*model.User) (int64, error)
 	DeleteUserByID(int64) error
 	UpdateUser(*model.User) error
+	UpdateUserProfile(*model.User) error
+	UpdateUserRoles(int64, string) error
+	MarkEmailVerified(int64) error
+	UpdateUserMFA(*model.User) error
+	AnonymizeUser(userID int64, erasedAt time.Time) error
 	FindAll() ([]model.User, error)
+	SearchUsers(UserSearch) ([]model.User, error)
+	FindPage(offset int, limit int) ([]model.User, int64, error)
 }
 
 // NewUserRepository returns an implementation of IUserRepository using GORM.
@@ -51,6 +95,16 @@ func (u *UserRepository) FindUserByID(userID int64) (*model.User, error) {
 	return user, nil
 }
 
+// FindUsersByVerifiedEmail retrieves the users who have verified the given email address.
+func (u *UserRepository) FindUsersByVerifiedEmail(email string) ([]model.User, error) {
+	var users []model.User
+	err := u.mysqlDb.Where("email = ? AND email_verified = ?", email, true).Find(&users).Error
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
 // CreateUser inserts a new user into the database and returns the generated ID.
 func (u *UserRepository) CreateUser(user *model.User) (int64, error) {
 	err := u.mysqlDb.Create(user).Error
@@ -77,6 +131,83 @@ func (u *UserRepository) UpdateUser(user *model.User) error {
 	return u.mysqlDb.Model(user).Updates(user).Error
 }
 
+// UpdateUserProfile writes the profile columns of a user, including clearing them.
+func (u *UserRepository) UpdateUserProfile(user *model.User) error {
+	return u.mysqlDb.Model(&model.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+		"first_name": user.FirstName,
+		"last_name":  user.LastName,
+		"phone":      user.Phone,
+	}).Error
+}
+
+// UpdateUserRoles overwrites the role list of a user, including clearing it.
+func (u *UserRepository) UpdateUserRoles(userID int64, roles string) error {
+	result := u.mysqlDb.Model(&model.User{}).Where("id = ?", userID).Update("roles", roles)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// MarkEmailVerified flags the email address of a user as confirmed.
+func (u *UserRepository) MarkEmailVerified(userID int64) error {
+	result := u.mysqlDb.Model(&model.User{}).Where("id = ?", userID).Update("email_verified", true)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// UpdateUserMFA writes the TOTP and recovery code columns of a user, including clearing them.
+func (u *UserRepository) UpdateUserMFA(user *model.User) error {
+	result := u.mysqlDb.Model(&model.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+		"totp_secret":    user.TOTPSecret,
+		"totp_enabled":   user.TOTPEnabled,
+		"totp_last_step": user.TOTPLastStep,
+		"recovery_codes": user.RecoveryCodes,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// AnonymizeUser replaces the personal and credential columns of a user with placeholders and sets erased_at.
+// The row and its ID stay so records referencing the user remain valid.
+func (u *UserRepository) AnonymizeUser(userID int64, erasedAt time.Time) error {
+	result := u.mysqlDb.Model(&model.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"user_name":      fmt.Sprintf("erased-%d", userID),
+		"first_name":     "",
+		"last_name":      "",
+		"email":          "",
+		"phone":          "",
+		"email_verified": false,
+		"hash_password":  "",
+		"roles":          "",
+		"totp_secret":    "",
+		"totp_enabled":   false,
+		"totp_last_step": 0,
+		"recovery_codes": "",
+		"erased_at":      erasedAt,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
 // FindAll retrieves all users from the database.
 func (u *UserRepository) FindAll() ([]model.User, error) {
 	var users []model.User
@@ -86,3 +217,87 @@ func (u *UserRepository) FindAll() ([]model.User, error) {
 	}
 	return users, nil
 }
+
+// SearchUsers returns up to search.Limit users matching the filters, ordered by the sort field and ID.
+// Pages are continued with keyset conditions on the last row instead of offsets, so they stay cheap.
+func (u *UserRepository) SearchUsers(search UserSearch) ([]model.User, error) {
+	column, ok := UserSortFields[search.SortField]
+	if !ok {
+		return nil, fmt.Errorf("unknown sort field %q", search.SortField)
+	}
+
+	userTable := u.mysqlDb.NewScope(&model.User{}).QuotedTableName()
+	attemptTable := u.mysqlDb.NewScope(&model.LoginAttempt{}).QuotedTableName()
+
+	query := u.mysqlDb.Model(&model.User{})
+	if search.UserNamePrefix != "" {
+		escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(search.UserNamePrefix)
+		query = query.Where("user_name LIKE ?", escaped+"%")
+	}
+	if search.Email != "" {
+		query = query.Where("email = ?", search.Email)
+	}
+	if search.Role != "" {
+		query = query.Where("FIND_IN_SET(?, roles) > 0", search.Role)
+	}
+	if !search.CreatedAfter.IsZero() {
+		query = query.Where("created_at >= ?", search.CreatedAfter)
+	}
+	if !search.CreatedBefore.IsZero() {
+		query = query.Where("created_at < ?", search.CreatedBefore)
+	}
+	if search.EmailVerified != nil {
+		query = query.Where("email_verified = ?", *search.EmailVerified)
+	}
+	if search.Locked != nil {
+		locked := fmt.Sprintf("EXISTS (SELECT 1 FROM %s WHERE %s.subject = CONCAT('user:', %s.user_name) AND %s.locked_until > ?)",
+			attemptTable, attemptTable, userTable, attemptTable)
+		if !*search.Locked {
+			locked = "NOT " + locked
+		}
+		query = query.Where(locked, search.Now)
+	}
+	if !search.IncludeErased {
+		query = query.Where("erased_at IS NULL")
+	}
+
+	direction, comparison := "ASC", ">"
+	if search.Descending {
+		direction, comparison = "DESC", "<"
+	}
+	if search.AfterID != 0 {
+		if column == "id" {
+			query = query.Where("id "+comparison+" ?", search.AfterID)
+		} else {
+			query = query.Where(fmt.Sprintf("(%s %s ? OR (%s = ? AND id %s ?))", column, comparison, column, comparison),
+				search.AfterValue, search.AfterValue, search.AfterID)
+		}
+	}
+
+	order := "id " + direction
+	if column != "id" {
+		order = column + " " + direction + ", " + order
+	}
+
+	var users []model.User
+	err := query.Order(order).Limit(search.Limit).Find(&users).Error
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// FindPage retrieves one page of users ordered by ID together with the total number of users.
+func (u *UserRepository) FindPage(offset int, limit int) ([]model.User, int64, error) {
+	var total int64
+	if err := u.mysqlDb.Model(&model.User{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var users []model.User
+	err := u.mysqlDb.Order("id").Offset(offset).Limit(limit).Find(&users).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
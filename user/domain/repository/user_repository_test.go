@@ -63,6 +63,69 @@ func TestUserRepository(t *testing.T) {
 		assert.Equal(t, "NewName", updatedUser.FirstName)
 	})
 
+	t.Run("UpdateUserProfile", func(t *testing.T) {
+		user := &model.User{UserName: generateRandomString(5), FirstName: "John", LastName: "Doe", Phone: "+15550100"}
+		userID, _ := repo.CreateUser(user)
+
+		err := repo.UpdateUserProfile(&model.User{ID: userID, FirstName: "Jane"})
+		assert.NoError(t, err, "Failed to update user profile")
+
+		updatedUser, _ := repo.FindUserByID(userID)
+		assert.Equal(t, "Jane", updatedUser.FirstName)
+		assert.Empty(t, updatedUser.LastName, "Last name should be cleared")
+		assert.Empty(t, updatedUser.Phone, "Phone should be cleared")
+	})
+
+	t.Run("UpdateUserRoles", func(t *testing.T) {
+		user := &model.User{UserName: generateRandomString(5), FirstName: "John", Roles: "customer"}
+		userID, _ := repo.CreateUser(user)
+
+		err := repo.UpdateUserRoles(userID, "")
+		assert.NoError(t, err, "Failed to clear user roles")
+
+		updatedUser, _ := repo.FindUserByID(userID)
+		assert.Equal(t, "", updatedUser.Roles)
+	})
+
+	t.Run("MarkEmailVerified", func(t *testing.T) {
+		user := &model.User{UserName: generateRandomString(5), Email: "john@example.com"}
+		userID, _ := repo.CreateUser(user)
+
+		err := repo.MarkEmailVerified(userID)
+		assert.NoError(t, err, "Failed to mark email verified")
+
+		updatedUser, _ := repo.FindUserByID(userID)
+		assert.True(t, updatedUser.EmailVerified)
+	})
+
+	t.Run("FindUsersByVerifiedEmail", func(t *testing.T) {
+		email := generateRandomString(8) + "@example.com"
+		verifiedID, _ := repo.CreateUser(&model.User{UserName: generateRandomString(5), Email: email})
+		repo.CreateUser(&model.User{UserName: generateRandomString(5), Email: email})
+		assert.NoError(t, repo.MarkEmailVerified(verifiedID))
+
+		users, err := repo.FindUsersByVerifiedEmail(email)
+		assert.NoError(t, err)
+		assert.Len(t, users, 1)
+		assert.Equal(t, verifiedID, users[0].ID)
+	})
+
+	t.Run("AnonymizeUser", func(t *testing.T) {
+		user := &model.User{UserName: generateRandomString(5), FirstName: "John", Email: "john@example.com",
+			HashPassword: "hash", Roles: "customer"}
+		userID, _ := repo.CreateUser(user)
+
+		err := repo.AnonymizeUser(userID, time.Now())
+		assert.NoError(t, err, "Failed to anonymize user")
+
+		erasedUser, err := repo.FindUserByID(userID)
+		assert.NoError(t, err, "Anonymized user should be kept")
+		assert.Equal(t, fmt.Sprintf("erased-%d", userID), erasedUser.UserName)
+		assert.Empty(t, erasedUser.Email)
+		assert.Empty(t, erasedUser.HashPassword)
+		assert.NotNil(t, erasedUser.ErasedAt)
+	})
+
 	t.Run("DeleteUserByID", func(t *testing.T) {
 		user := &model.User{UserName: generateRandomString(5), FirstName: "John"}
 		userID, _ := repo.CreateUser(user)
@@ -85,6 +148,56 @@ func TestUserRepository(t *testing.T) {
 		assert.Len(t, users, 2, "Expected 2 users")
 	})
 
+	t.Run("SearchUsers", func(t *testing.T) {
+		clearTable(t, db)
+		err := db.DropTableIfExists(&model.LoginAttempt{}).AutoMigrate(&model.LoginAttempt{}).Error
+		assert.NoError(t, err, "Failed to migrate login_attempt table")
+
+		aliceID, _ := repo.CreateUser(&model.User{UserName: "alice", Roles: "customer,admin"})
+		repo.CreateUser(&model.User{UserName: "albert", Roles: "customer"})
+		bobID, _ := repo.CreateUser(&model.User{UserName: "bob", Roles: "customer"})
+		erasedID, _ := repo.CreateUser(&model.User{UserName: "al_erased"})
+		assert.NoError(t, repo.AnonymizeUser(erasedID, time.Now()))
+		db.Create(&model.LoginAttempt{Subject: "user:bob", LockedUntil: time.Now().Add(time.Hour)})
+
+		search := UserSearch{UserNamePrefix: "al", SortField: "user_name", Now: time.Now(), Limit: 10}
+		users, err := repo.SearchUsers(search)
+		assert.NoError(t, err)
+		assert.Len(t, users, 2, "Erased users are excluded")
+		assert.Equal(t, "albert", users[0].UserName)
+
+		search.AfterValue, search.AfterID = users[0].UserName, users[0].ID
+		users, _ = repo.SearchUsers(search)
+		assert.Len(t, users, 1)
+		assert.Equal(t, aliceID, users[0].ID)
+
+		users, _ = repo.SearchUsers(UserSearch{Role: "admin", SortField: "id", Now: time.Now(), Limit: 10})
+		assert.Len(t, users, 1)
+		assert.Equal(t, aliceID, users[0].ID)
+
+		locked := true
+		users, _ = repo.SearchUsers(UserSearch{Locked: &locked, SortField: "id", Now: time.Now(), Limit: 10})
+		assert.Len(t, users, 1)
+		assert.Equal(t, bobID, users[0].ID)
+
+		users, _ = repo.SearchUsers(UserSearch{SortField: "id", Descending: true, Now: time.Now(), Limit: 2})
+		assert.Len(t, users, 2)
+		assert.Equal(t, bobID, users[0].ID)
+	})
+
+	t.Run("FindPage", func(t *testing.T) {
+		clearTable(t, db)
+
+		for i := 0; i < 3; i++ {
+			repo.CreateUser(&model.User{UserName: generateRandomString(8), FirstName: "Page"})
+		}
+
+		users, total, err := repo.FindPage(2, 2)
+		assert.NoError(t, err, "Failed to retrieve a page of users")
+		assert.Equal(t, int64(3), total)
+		assert.Len(t, users, 1, "Expected the last user only")
+	})
+
 	t.Run("CreateUserWithSameUsername", func(t *testing.T) {
 		user := &model.User{UserName: generateRandomString(5), FirstName: "John"}
 		_, err := repo.CreateUser(user)
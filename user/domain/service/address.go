@@ -0,0 +1,199 @@
+package service
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+	"github.com/tongs-dev/shopping-platform/user/domain/model"
+)
+
+// ErrAddressNotFound is returned when an address does not exist or belongs to another user.
+var ErrAddressNotFound = errors.New("address not found")
+
+// postalCodeFormats maps the supported ISO 3166-1 alpha-2 country codes to the format of their postal codes.
+// A nil format means the country does not use postal codes.
+var postalCodeFormats = map[string]*regexp.Regexp{
+	"AT": regexp.MustCompile(`^\d{4}$`),
+	"AU": regexp.MustCompile(`^\d{4}$`),
+	"BE": regexp.MustCompile(`^\d{4}$`),
+	"BR": regexp.MustCompile(`^\d{5}-?\d{3}$`),
+	"CA": regexp.MustCompile(`^[A-Z]\d[A-Z] ?\d[A-Z]\d$`),
+	"CH": regexp.MustCompile(`^\d{4}$`),
+	"CN": regexp.MustCompile(`^\d{6}$`),
+	"DE": regexp.MustCompile(`^\d{5}$`),
+	"DK": regexp.MustCompile(`^\d{4}$`),
+	"ES": regexp.MustCompile(`^\d{5}$`),
+	"FI": regexp.MustCompile(`^\d{5}$`),
+	"FR": regexp.MustCompile(`^\d{5}$`),
+	"GB": regexp.MustCompile(`^[A-Z]{1,2}\d[A-Z\d]? ?\d[A-Z]{2}$`),
+	"HK": nil,
+	"IE": regexp.MustCompile(`^[A-Z]\d[\dW] ?[A-Z\d]{4}$`),
+	"IN": regexp.MustCompile(`^\d{6}$`),
+	"IT": regexp.MustCompile(`^\d{5}$`),
+	"JP": regexp.MustCompile(`^\d{3}-?\d{4}$`),
+	"KR": regexp.MustCompile(`^\d{5}$`),
+	"MX": regexp.MustCompile(`^\d{5}$`),
+	"NL": regexp.MustCompile(`^\d{4} ?[A-Z]{2}$`),
+	"NO": regexp.MustCompile(`^\d{4}$`),
+	"NZ": regexp.MustCompile(`^\d{4}$`),
+	"PL": regexp.MustCompile(`^\d{2}-\d{3}$`),
+	"PT": regexp.MustCompile(`^\d{4}-\d{3}$`),
+	"SE": regexp.MustCompile(`^\d{3} ?\d{2}$`),
+	"SG": regexp.MustCompile(`^\d{6}$`),
+	"US": regexp.MustCompile(`^\d{5}(-\d{4})?$`),
+}
+
+// phoneFormat accepts international numbers with optional separators, e.g. "+1 (555) 010-0100".
+var phoneFormat = regexp.MustCompile(`^\+?[\d\s().-]{6,20}$`)
+
+// AddressViolation describes one invalid field of an address.
+type AddressViolation struct {
+	Field       string
+	Description string
+}
+
+// AddressValidationError lists every invalid field of a rejected address.
+type AddressValidationError struct {
+	Violations []AddressViolation
+}
+
+func (e *AddressValidationError) Error() string {
+	descriptions := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		descriptions = append(descriptions, violation.Field+" "+violation.Description)
+	}
+	return "invalid address: " + strings.Join(descriptions, "; ")
+}
+
+// ValidateAddress normalizes an address in place (trimmed fields, upper-case country and postal code)
+// and returns an *AddressValidationError listing all invalid fields.
+func ValidateAddress(address *model.Address) error {
+	for _, field := range []*string{&address.Recipient, &address.Line1, &address.Line2, &address.City,
+		&address.Region, &address.Phone} {
+		*field = strings.TrimSpace(*field)
+	}
+	address.Country = strings.ToUpper(strings.TrimSpace(address.Country))
+	address.PostalCode = strings.ToUpper(strings.TrimSpace(address.PostalCode))
+
+	var violations []AddressViolation
+	required := []struct{ field, value string }{
+		{"recipient", address.Recipient},
+		{"line1", address.Line1},
+		{"city", address.City},
+	}
+	for _, r := range required {
+		if r.value == "" {
+			violations = append(violations, AddressViolation{r.field, "is required"})
+		}
+	}
+
+	format, supported := postalCodeFormats[address.Country]
+	switch {
+	case !supported:
+		violations = append(violations, AddressViolation{"country", "must be a supported ISO 3166-1 alpha-2 code"})
+	case format == nil:
+		address.PostalCode = ""
+	case !format.MatchString(address.PostalCode):
+		violations = append(violations, AddressViolation{"postal_code", "is not a valid postal code for " + address.Country})
+	}
+
+	if address.Phone != "" && !phoneFormat.MatchString(address.Phone) {
+		violations = append(violations, AddressViolation{"phone", "is not a valid phone number"})
+	}
+
+	if len(violations) > 0 {
+		return &AddressValidationError{Violations: violations}
+	}
+	return nil
+}
+
+// AddAddress validates and stores a new address of address.UserID. The first address of a user becomes
+// the default for shipping and billing; otherwise the default flags set on the address are applied.
+func (u *UserDataService) AddAddress(address *model.Address) (int64, error) {
+	if err := ValidateAddress(address); err != nil {
+		return 0, err
+	}
+
+	existing, err := u.AddressRepository.FindAddressesByUserID(address.UserID)
+	if err != nil {
+		return 0, err
+	}
+
+	shipping, billing := address.IsDefaultShipping, address.IsDefaultBilling
+	if len(existing) == 0 {
+		shipping, billing = true, true
+	}
+	address.IsDefaultShipping, address.IsDefaultBilling = false, false
+
+	addressID, err := u.AddressRepository.CreateAddress(address)
+	if err != nil {
+		return 0, err
+	}
+
+	if shipping || billing {
+		if err := u.AddressRepository.SetDefaultAddress(address.UserID, addressID, shipping, billing); err != nil {
+			return 0, err
+		}
+		address.IsDefaultShipping, address.IsDefaultBilling = shipping, billing
+	}
+	return addressID, nil
+}
+
+// UpdateAddress validates and replaces the fields of one of the user's addresses. Default flags are kept,
+// they are changed with SetDefaultAddress.
+func (u *UserDataService) UpdateAddress(userID int64, address *model.Address) error {
+	stored, err := u.findOwnAddress(userID, address.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := ValidateAddress(address); err != nil {
+		return err
+	}
+
+	address.UserID = userID
+	address.IsDefaultShipping = stored.IsDefaultShipping
+	address.IsDefaultBilling = stored.IsDefaultBilling
+	address.CreatedAt = stored.CreatedAt
+	return u.AddressRepository.UpdateAddress(address)
+}
+
+// DeleteAddress removes one of the user's addresses.
+func (u *UserDataService) DeleteAddress(userID int64, addressID int64) error {
+	if _, err := u.findOwnAddress(userID, addressID); err != nil {
+		return err
+	}
+
+	return u.AddressRepository.DeleteAddressByID(addressID)
+}
+
+// ListAddresses returns the address book of a user.
+func (u *UserDataService) ListAddresses(userID int64) ([]model.Address, error) {
+	return u.AddressRepository.FindAddressesByUserID(userID)
+}
+
+// SetDefaultAddress makes one of the user's addresses the default for shipping and/or billing.
+func (u *UserDataService) SetDefaultAddress(userID int64, addressID int64, shipping bool, billing bool) error {
+	if _, err := u.findOwnAddress(userID, addressID); err != nil {
+		return err
+	}
+
+	return u.AddressRepository.SetDefaultAddress(userID, addressID, shipping, billing)
+}
+
+// findOwnAddress retrieves an address, reporting addresses of other users as ErrAddressNotFound.
+func (u *UserDataService) findOwnAddress(userID int64, addressID int64) (*model.Address, error) {
+	address, err := u.AddressRepository.FindAddressByID(addressID)
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrAddressNotFound
+		}
+		return nil, err
+	}
+	if address.UserID != userID {
+		return nil, ErrAddressNotFound
+	}
+	return address, nil
+}
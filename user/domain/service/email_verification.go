@@ -0,0 +1,82 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/tongs-dev/shopping-platform/user/domain/model"
+	"github.com/tongs-dev/shopping-platform/user/notify"
+)
+
+var (
+	// ErrEmailRequired is returned when registering without an email while verified emails are required.
+	ErrEmailRequired = errors.New("email is required")
+	// ErrEmailNotVerified is returned by IssueToken for unverified users while verified emails are required.
+	ErrEmailNotVerified = errors.New("email address not verified")
+)
+
+// sendEmailVerification issues a verification token for the user's email and sends it through the notifier.
+// Earlier tokens of the user are discarded.
+func (u *UserDataService) sendEmailVerification(user *model.User) error {
+	if err := u.EmailVerificationRepository.DeleteEmailVerificationTokensByUserID(user.ID); err != nil {
+		return err
+	}
+
+	verificationToken, err := generateOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(u.TokenConfig.EmailVerificationTTL)
+	_, err = u.EmailVerificationRepository.CreateEmailVerificationToken(&model.EmailVerificationToken{
+		UserID:    user.ID,
+		TokenHash: hashToken(verificationToken),
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	return u.Notifier.Send(notify.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Use this code to verify your email address: %s\nIt expires at %s.",
+			verificationToken, expiresAt.Format(time.RFC1123)),
+	})
+}
+
+// VerifyEmail consumes a verification token produced at registration and marks the user's email as verified.
+func (u *UserDataService) VerifyEmail(verificationToken string) error {
+	if verificationToken == "" {
+		return ErrInvalidToken
+	}
+
+	stored, err := u.EmailVerificationRepository.FindEmailVerificationTokenByHash(hashToken(verificationToken))
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return ErrInvalidToken
+		}
+		return err
+	}
+
+	if time.Now().After(stored.ExpiresAt) {
+		return ErrInvalidToken
+	}
+
+	if err := u.UserRepository.MarkEmailVerified(stored.UserID); err != nil {
+		return err
+	}
+
+	// Tokens are single-use, verifying once discards every outstanding token of the user
+	return u.EmailVerificationRepository.DeleteEmailVerificationTokensByUserID(stored.UserID)
+}
+
+// contactAddress returns where notifications for the user are delivered, the user name when no email is known.
+func contactAddress(user *model.User) string {
+	if user.Email != "" {
+		return user.Email
+	}
+	return user.UserName
+}
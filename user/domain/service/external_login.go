@@ -0,0 +1,236 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/jinzhu/gorm"
+	"github.com/tongs-dev/shopping-platform/user/domain/model"
+	"github.com/tongs-dev/shopping-platform/user/identity"
+)
+
+// externalLoginAudience prefixes the audience of external login state tokens, followed by the provider name.
+const externalLoginAudience = "external_login:"
+
+// AuditIdentityLinked is recorded when an external identity is linked to a user.
+const AuditIdentityLinked = "identity_linked"
+
+var (
+	// ErrUnknownProvider is returned for identity providers that are not configured.
+	ErrUnknownProvider = errors.New("unknown identity provider")
+	// ErrIdentityLinked is returned when linking an external identity that already belongs to another user.
+	ErrIdentityLinked = errors.New("external identity is linked to another user")
+)
+
+// ExternalLoginStart is the first step of a login with an identity provider.
+type ExternalLoginStart struct {
+	// AuthorizationURL is where the client sends the user to sign in at the provider
+	AuthorizationURL string
+	// State must be kept by the client, compared with the state returned to the redirect URL
+	// and passed to CompleteExternalLogin
+	State string
+}
+
+// BeginExternalLogin starts a login with an identity provider. When accessToken belongs to an active session,
+// the external identity is linked to that user on completion instead of signing in or creating an account.
+func (u *UserDataService) BeginExternalLogin(providerName string, accessToken string) (*ExternalLoginStart, error) {
+	provider, ok := u.IdentityProviders[providerName]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	var linkUserID int64
+	if accessToken != "" {
+		active, err := u.ValidateSession(accessToken)
+		if err != nil {
+			return nil, err
+		}
+		if !active {
+			return nil, ErrInvalidToken
+		}
+		claims, err := ParseAccessToken(u.TokenConfig, accessToken)
+		if err != nil {
+			return nil, err
+		}
+		linkUserID = claims.UserID
+	}
+
+	state, err := u.signExternalLoginState(providerName, linkUserID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return &ExternalLoginStart{AuthorizationURL: provider.AuthCodeURL(state), State: state}, nil
+}
+
+// CompleteExternalLogin redeems the authorization code returned by the provider and signs the user in.
+// Unknown identities are linked to the user that started a linking login, to the only local user with
+// the same verified email when the provider verified it too, or to a new passwordless account.
+// Users with TOTP enabled get an MFA challenge as with IssueToken.
+func (u *UserDataService) CompleteExternalLogin(providerName string, code string, state string, client ClientInfo) (*TokenPair, error) {
+	provider, ok := u.IdentityProviders[providerName]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	linkUserID, err := u.parseExternalLoginState(providerName, state)
+	if err != nil {
+		return nil, err
+	}
+
+	external, err := provider.Exchange(code)
+	if err != nil {
+		if errors.Is(err, identity.ErrInvalidCode) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	user, err := u.findExternalUser(external, linkUserID)
+	if err != nil {
+		return nil, err
+	}
+	if user.ErasedAt != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	if u.TokenConfig.RequireVerifiedEmail && !user.EmailVerified {
+		return nil, ErrEmailNotVerified
+	}
+
+	if user.TOTPEnabled {
+		challenge, err := u.signMFAChallenge(user, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		return &TokenPair{MFAChallenge: challenge}, nil
+	}
+
+	return u.startSession(user, client)
+}
+
+// findExternalUser returns the user an external identity signs in as, linking the identity first if needed.
+func (u *UserDataService) findExternalUser(external *identity.ExternalIdentity, linkUserID int64) (*model.User, error) {
+	linked, err := u.LinkedIdentityRepository.FindLinkedIdentity(external.Provider, external.Subject)
+	if err == nil {
+		if linkUserID != 0 && linked.UserID != linkUserID {
+			return nil, ErrIdentityLinked
+		}
+		return u.UserRepository.FindUserByID(linked.UserID)
+	}
+	if !gorm.IsRecordNotFoundError(err) {
+		return nil, err
+	}
+
+	var user *model.User
+	switch {
+	case linkUserID != 0:
+		user, err = u.UserRepository.FindUserByID(linkUserID)
+	case external.Email != "" && external.EmailVerified:
+		var users []model.User
+		users, err = u.UserRepository.FindUsersByVerifiedEmail(external.Email)
+		// An ambiguous email never picks an account
+		if err == nil && len(users) == 1 {
+			user = &users[0]
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		if user, err = u.createExternalUser(external); err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = u.LinkedIdentityRepository.CreateLinkedIdentity(&model.LinkedIdentity{
+		UserID:   user.ID,
+		Provider: external.Provider,
+		Subject:  external.Subject,
+		Email:    external.Email,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := u.recordAudit(user.ID, user.ID, AuditIdentityLinked, external.Provider); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// createExternalUser registers a passwordless user for an external identity. The provider's email is trusted
+// when the provider verified it, otherwise a verification token is sent as with AddUser.
+func (u *UserDataService) createExternalUser(external *identity.ExternalIdentity) (*model.User, error) {
+	user := &model.User{
+		// Provider and subject are unique together, so the generated name cannot collide with another link
+		UserName:      fmt.Sprintf("%s:%s", external.Provider, external.Subject),
+		FirstName:     external.GivenName,
+		LastName:      external.FamilyName,
+		Email:         external.Email,
+		EmailVerified: external.Email != "" && external.EmailVerified,
+		Roles:         DefaultRole,
+	}
+	userID, err := u.UserRepository.CreateUser(user)
+	if err != nil {
+		return nil, err
+	}
+	user.ID = userID
+
+	if user.Email != "" && !user.EmailVerified {
+		if err := u.sendEmailVerification(user); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
+// signExternalLoginState creates the state token binding a login to its provider and, when linking, to the user.
+func (u *UserDataService) signExternalLoginState(providerName string, linkUserID int64, now time.Time) (string, error) {
+	nonce, err := generateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	claims := jwt.StandardClaims{
+		Id:        nonce,
+		Audience:  externalLoginAudience + providerName,
+		Issuer:    u.TokenConfig.Issuer,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(u.TokenConfig.ExternalLoginTTL).Unix(),
+	}
+	if linkUserID != 0 {
+		claims.Subject = strconv.FormatInt(linkUserID, 10)
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(u.externalLoginKey())
+}
+
+// parseExternalLoginState verifies a state token for the provider and returns the user to link, 0 for a login.
+func (u *UserDataService) parseExternalLoginState(providerName string, state string) (int64, error) {
+	claims := &jwt.StandardClaims{}
+	token, err := jwt.ParseWithClaims(state, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return u.externalLoginKey(), nil
+	})
+	if err != nil || !token.Valid || !claims.VerifyAudience(externalLoginAudience+providerName, true) {
+		return 0, ErrInvalidToken
+	}
+
+	if claims.Subject == "" {
+		return 0, nil
+	}
+	linkUserID, err := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidToken
+	}
+	return linkUserID, nil
+}
+
+// externalLoginKey derives the state signing key from the access token secret, so state tokens
+// cannot be used as access tokens or MFA challenges.
+func (u *UserDataService) externalLoginKey() []byte {
+	return []byte(u.TokenConfig.Secret + ":external")
+}
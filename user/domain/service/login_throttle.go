@@ -0,0 +1,141 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/tongs-dev/shopping-platform/user/domain/model"
+)
+
+var (
+	// ErrAccountLocked is returned while a user name or client address is locked out.
+	ErrAccountLocked = errors.New("too many failed logins, temporarily locked")
+	// ErrLoginThrottled is returned when a login is attempted before the back-off delay has passed.
+	ErrLoginThrottled = errors.New("too many failed logins, retry later")
+)
+
+// LockoutConfig controls brute-force protection on login.
+type LockoutConfig struct {
+	// MaxUserAttempts is the number of consecutive failures after which a user name is locked.
+	MaxUserAttempts int
+	// MaxAddressAttempts is the number of consecutive failures after which a client address is locked.
+	MaxAddressAttempts int
+	// LockoutDuration is how long a locked user name or client address stays locked.
+	LockoutDuration time.Duration
+	// BaseBackoff is the delay enforced after the first failure, doubled with every further failure.
+	BaseBackoff time.Duration
+	// MaxBackoff caps the delay enforced between two attempts.
+	MaxBackoff time.Duration
+}
+
+// loginSubject is a throttled key together with the number of failures that locks it.
+type loginSubject struct {
+	subject     string
+	maxAttempts int
+}
+
+// userSubject is the throttling key of a user name.
+func userSubject(userName string) string {
+	return "user:" + userName
+}
+
+// addressSubject is the throttling key of a client address.
+func addressSubject(clientAddr string) string {
+	return "addr:" + clientAddr
+}
+
+// loginSubjects returns the keys a login attempt is tracked under.
+func (u *UserDataService) loginSubjects(userName string, clientAddr string) []loginSubject {
+	subjects := []loginSubject{{subject: userSubject(userName), maxAttempts: u.LockoutConfig.MaxUserAttempts}}
+	if clientAddr != "" {
+		subjects = append(subjects, loginSubject{subject: addressSubject(clientAddr), maxAttempts: u.LockoutConfig.MaxAddressAttempts})
+	}
+	return subjects
+}
+
+// checkLoginAllowed rejects the attempt if any subject is locked or still inside its back-off window.
+func (u *UserDataService) checkLoginAllowed(subjects []loginSubject, now time.Time) error {
+	for _, s := range subjects {
+		attempt, err := u.LoginAttemptRepository.FindLoginAttemptBySubject(s.subject)
+		if err != nil {
+			if gorm.IsRecordNotFoundError(err) {
+				continue
+			}
+			return err
+		}
+
+		if now.Before(attempt.LockedUntil) {
+			return fmt.Errorf("%w, retry in %s", ErrAccountLocked, attempt.LockedUntil.Sub(now).Round(time.Second))
+		}
+
+		if attempt.LockedUntil.IsZero() && attempt.FailedCount > 0 {
+			retryAt := attempt.LastFailedAt.Add(u.backoff(attempt.FailedCount))
+			if now.Before(retryAt) {
+				return fmt.Errorf("%w, retry in %s", ErrLoginThrottled, retryAt.Sub(now).Round(time.Second))
+			}
+		}
+	}
+	return nil
+}
+
+// recordLoginFailure counts a failed attempt against every subject and locks those reaching their limit.
+func (u *UserDataService) recordLoginFailure(subjects []loginSubject, now time.Time) error {
+	for _, s := range subjects {
+		attempt, err := u.LoginAttemptRepository.FindLoginAttemptBySubject(s.subject)
+		if err != nil {
+			if !gorm.IsRecordNotFoundError(err) {
+				return err
+			}
+			attempt = &model.LoginAttempt{Subject: s.subject}
+		}
+
+		// An expired lockout, or failures older than the lockout window, start a fresh series of attempts
+		expired := !attempt.LockedUntil.IsZero() && !now.Before(attempt.LockedUntil)
+		stale := attempt.LockedUntil.IsZero() && !now.Before(attempt.LastFailedAt.Add(u.LockoutConfig.LockoutDuration))
+		if expired || stale {
+			attempt.FailedCount = 0
+			attempt.LockedUntil = time.Time{}
+		}
+
+		attempt.FailedCount++
+		attempt.LastFailedAt = now
+		if s.maxAttempts > 0 && attempt.FailedCount >= s.maxAttempts {
+			attempt.LockedUntil = now.Add(u.LockoutConfig.LockoutDuration)
+		}
+
+		if err := u.LoginAttemptRepository.SaveLoginAttempt(attempt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordLoginSuccess clears the failure tracking of the user name. The client address keeps its failures
+// until they expire, otherwise logging into an owned account between guesses would reset the address.
+func (u *UserDataService) recordLoginSuccess(userName string) error {
+	return u.LoginAttemptRepository.DeleteLoginAttemptBySubject(userSubject(userName))
+}
+
+// backoff returns the delay enforced after the given number of consecutive failures.
+func (u *UserDataService) backoff(failedCount int) time.Duration {
+	delay := u.LockoutConfig.BaseBackoff
+	for i := 1; i < failedCount && delay < u.LockoutConfig.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if u.LockoutConfig.MaxBackoff > 0 && delay > u.LockoutConfig.MaxBackoff {
+		delay = u.LockoutConfig.MaxBackoff
+	}
+	return delay
+}
+
+// UnlockUser clears the lockout and failure tracking of a user so they can log in again immediately.
+func (u *UserDataService) UnlockUser(userID int64) error {
+	user, err := u.UserRepository.FindUserByID(userID)
+	if err != nil {
+		return err
+	}
+
+	return u.LoginAttemptRepository.DeleteLoginAttemptBySubject(userSubject(user.UserName))
+}
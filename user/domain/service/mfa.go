@@ -0,0 +1,399 @@
+package service
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/tongs-dev/shopping-platform/user/domain/model"
+)
+
+const (
+	// totpPeriod is the lifetime of a TOTP code in seconds (RFC 6238 default).
+	totpPeriod = 30
+	// totpDigits is the number of digits of a TOTP code.
+	totpDigits = 6
+	// totpSkew is the number of time steps accepted before and after the current one.
+	totpSkew = 1
+	// recoveryCodeCount is the number of recovery codes handed out by ConfirmTOTP.
+	recoveryCodeCount = 10
+	// mfaAudience marks MFA challenge tokens so they are never mistaken for access tokens.
+	mfaAudience = "mfa"
+)
+
+var (
+	// ErrInvalidMFACode is returned when a TOTP or recovery code does not match.
+	ErrInvalidMFACode = errors.New("invalid verification code")
+	// ErrMFANotEnrolled is returned when confirming or disabling TOTP without an enrolled secret.
+	ErrMFANotEnrolled = errors.New("two-factor authentication is not enrolled")
+	// ErrMFAAlreadyEnabled is returned when enrolling while TOTP is already enabled.
+	ErrMFAAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+)
+
+// MFAConfig holds the settings of TOTP based two-factor authentication.
+type MFAConfig struct {
+	// Issuer is shown by authenticator apps next to the account name.
+	Issuer string
+	// EncryptionKey encrypts TOTP secrets at rest, an AES-256 key is derived from it.
+	EncryptionKey string
+	// ChallengeTTL is how long the MFA challenge returned by Login can be completed with VerifyMFA.
+	ChallengeTTL time.Duration
+	// RequiredFor lists the endpoints that need a second factor. Users without TOTP are not granted the
+	// permissions covering them, e.g. "*" or "Category.*" for "Category.DeleteCategory", until they enable it.
+	RequiredFor []string
+}
+
+// TOTPEnrollment is the secret of a pending TOTP enrollment in the forms authenticator apps accept.
+type TOTPEnrollment struct {
+	// Secret is the base32 encoded secret for manual entry.
+	Secret string
+	// ProvisioningURI is the otpauth:// URI usually rendered as a QR code.
+	ProvisioningURI string
+}
+
+// EnrollTOTP generates a new TOTP secret for the user. It only takes effect once confirmed with ConfirmTOTP.
+func (u *UserDataService) EnrollTOTP(userID int64) (*TOTPEnrollment, error) {
+	user, err := u.UserRepository.FindUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	raw := make([]byte, 20)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(raw)
+
+	encrypted, err := u.encryptSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+	user.TOTPSecret = encrypted
+	user.TOTPLastStep = 0
+	user.RecoveryCodes = ""
+	if err := u.UserRepository.UpdateUserMFA(user); err != nil {
+		return nil, err
+	}
+
+	label := url.PathEscape(u.MFAConfig.Issuer + ":" + user.UserName)
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", u.MFAConfig.Issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", strconv.Itoa(totpDigits))
+	query.Set("period", strconv.Itoa(totpPeriod))
+
+	return &TOTPEnrollment{
+		Secret:          secret,
+		ProvisioningURI: "otpauth://totp/" + label + "?" + query.Encode(),
+	}, nil
+}
+
+// ConfirmTOTP enables TOTP after checking a code from the enrolled secret and returns one-time recovery codes.
+// The recovery codes are only stored hashed and cannot be shown again.
+func (u *UserDataService) ConfirmTOTP(userID int64, code string) ([]string, error) {
+	user, err := u.UserRepository.FindUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrMFANotEnrolled
+	}
+
+	step, ok, err := u.checkTOTP(user, code, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	recoveryCodes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		buf := make([]byte, 5)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		recoveryCode := hex.EncodeToString(buf)
+		recoveryCodes = append(recoveryCodes, recoveryCode)
+		hashes = append(hashes, hashToken(recoveryCode))
+	}
+
+	user.TOTPEnabled = true
+	user.TOTPLastStep = step
+	user.RecoveryCodes = strings.Join(hashes, ",")
+	if err := u.UserRepository.UpdateUserMFA(user); err != nil {
+		return nil, err
+	}
+	if err := u.recordAudit(userID, userID, AuditMFAEnabled, ""); err != nil {
+		return nil, err
+	}
+	// Sessions started without the second factor end, so withheld permissions are only granted after VerifyMFA
+	if err := u.RevokeAllSessions(userID, 0); err != nil {
+		return nil, err
+	}
+	return recoveryCodes, nil
+}
+
+// DisableTOTP turns two-factor authentication off after confirming the password and a TOTP or recovery code.
+func (u *UserDataService) DisableTOTP(userID int64, pwd string, code string) error {
+	user, err := u.UserRepository.FindUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		return ErrMFANotEnrolled
+	}
+
+	if err := u.verifyPwd(user.UserName, pwd); err != nil {
+		return err
+	}
+	if err := u.verifyMFACode(user, code); err != nil {
+		return err
+	}
+
+	user.TOTPSecret = ""
+	user.TOTPEnabled = false
+	user.TOTPLastStep = 0
+	user.RecoveryCodes = ""
+	if err := u.UserRepository.UpdateUserMFA(user); err != nil {
+		return err
+	}
+	return u.recordAudit(userID, userID, AuditMFADisabled, "")
+}
+
+// VerifyMFA completes a login that IssueToken answered with an MFA challenge.
+// Wrong codes count as failed logins of the user, see LockoutConfig.
+func (u *UserDataService) VerifyMFA(mfaToken string, code string, client ClientInfo) (*TokenPair, error) {
+	userID, err := u.parseMFAChallenge(mfaToken)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := u.UserRepository.FindUserByID(userID)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	if !user.TOTPEnabled {
+		return nil, ErrInvalidToken
+	}
+
+	now := time.Now()
+	subjects := u.loginSubjects(user.UserName, client.Address)
+	if err := u.checkLoginAllowed(subjects, now); err != nil {
+		return nil, err
+	}
+
+	if err := u.verifyMFACode(user, code); err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			if recordErr := u.recordLoginFailure(subjects, now); recordErr != nil {
+				return nil, recordErr
+			}
+		}
+		return nil, err
+	}
+
+	if err := u.recordLoginSuccess(user.UserName); err != nil {
+		return nil, err
+	}
+
+	return u.startSession(user, client)
+}
+
+// verifyMFACode accepts a current TOTP code or consumes an unused recovery code, persisting the change.
+func (u *UserDataService) verifyMFACode(user *model.User, code string) error {
+	code = strings.TrimSpace(code)
+
+	step, ok, err := u.checkTOTP(user, code, time.Now())
+	if err != nil {
+		return err
+	}
+	if ok {
+		user.TOTPLastStep = step
+		return u.UserRepository.UpdateUserMFA(user)
+	}
+
+	codeHash := hashToken(strings.ToLower(code))
+	hashes := SplitList(user.RecoveryCodes)
+	for i, hash := range hashes {
+		if subtle.ConstantTimeCompare([]byte(hash), []byte(codeHash)) == 1 {
+			user.RecoveryCodes = strings.Join(append(hashes[:i:i], hashes[i+1:]...), ",")
+			return u.UserRepository.UpdateUserMFA(user)
+		}
+	}
+	return ErrInvalidMFACode
+}
+
+// checkTOTP validates a code against the user's secret within the allowed skew and returns its time step.
+// Steps at or before the last accepted one are refused so a code cannot be used twice.
+func (u *UserDataService) checkTOTP(user *model.User, code string, now time.Time) (int64, bool, error) {
+	if len(code) != totpDigits {
+		return 0, false, nil
+	}
+
+	secret, err := u.decryptSecret(user.TOTPSecret)
+	if err != nil {
+		return 0, false, err
+	}
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		return 0, false, err
+	}
+
+	current := now.Unix() / totpPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step <= user.TOTPLastStep {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, step)), []byte(code)) == 1 {
+			return step, true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// totpCode computes the HOTP value (RFC 4226) of a key for a time step.
+func totpCode(key []byte, step int64) string {
+	counter := make([]byte, 8)
+	binary.BigEndian.PutUint64(counter, uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000)
+}
+
+// signMFAChallenge creates the short-lived token that identifies a login waiting for its second factor.
+// It is signed with a key derived from the token secret, so other services reject it as an access token.
+func (u *UserDataService) signMFAChallenge(user *model.User, now time.Time) (string, error) {
+	claims := jwt.StandardClaims{
+		Subject:   strconv.FormatInt(user.ID, 10),
+		Audience:  mfaAudience,
+		Issuer:    u.TokenConfig.Issuer,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(u.MFAConfig.ChallengeTTL).Unix(),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(u.mfaChallengeKey())
+}
+
+// parseMFAChallenge verifies an MFA challenge token and returns the user it was issued for.
+func (u *UserDataService) parseMFAChallenge(mfaToken string) (int64, error) {
+	claims := &jwt.StandardClaims{}
+	token, err := jwt.ParseWithClaims(mfaToken, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return u.mfaChallengeKey(), nil
+	})
+	if err != nil || !token.Valid || !claims.VerifyAudience(mfaAudience, true) {
+		return 0, ErrInvalidToken
+	}
+
+	userID, err := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidToken
+	}
+	return userID, nil
+}
+
+// withholdMFAPermissions drops the permissions covering an endpoint of MFAConfig.RequiredFor unless the user has
+// TOTP enabled.
+func (u *UserDataService) withholdMFAPermissions(user *model.User, permissions []string) []string {
+	if user.TOTPEnabled || len(u.MFAConfig.RequiredFor) == 0 {
+		return permissions
+	}
+
+	var granted []string
+	for _, permission := range permissions {
+		required := false
+		for _, endpoint := range u.MFAConfig.RequiredFor {
+			if permissionCovers(permission, endpoint) {
+				required = true
+				break
+			}
+		}
+		if !required {
+			granted = append(granted, permission)
+		}
+	}
+	return granted
+}
+
+// permissionCovers reports whether a permission grants the endpoint, directly or through a wildcard.
+func permissionCovers(permission string, endpoint string) bool {
+	service := strings.SplitN(endpoint, ".", 2)[0]
+	return permission == "*" || permission == endpoint || permission == service+".*"
+}
+
+// mfaChallengeKey derives the key signing MFA challenges from the token secret, so a challenge never verifies
+// as an access token.
+func (u *UserDataService) mfaChallengeKey() []byte {
+	return []byte(u.TokenConfig.Secret + ":" + mfaAudience)
+}
+
+// encryptSecret encrypts a TOTP secret with AES-GCM, the nonce is prepended to the ciphertext.
+func (u *UserDataService) encryptSecret(secret string) (string, error) {
+	gcm, err := u.secretCipher()
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(secret), nil)), nil
+}
+
+// decryptSecret reverses encryptSecret.
+func (u *UserDataService) decryptSecret(encrypted string) (string, error) {
+	gcm, err := u.secretCipher()
+	if err != nil {
+		return "", err
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil || len(data) < gcm.NonceSize() {
+		return "", errors.New("malformed TOTP secret")
+	}
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+// secretCipher returns the AES-256-GCM cipher of the TOTP secrets, keyed with the SHA-256 of the encryption key.
+func (u *UserDataService) secretCipher() (cipher.AEAD, error) {
+	key := sha256.Sum256([]byte(u.MFAConfig.EncryptionKey))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// CommonPasswords is the built-in deny-list of passwords that are too easy to guess.
+var CommonPasswords = []string{
+	"123456", "12345678", "123456789", "1234567890", "password", "password1", "password123",
+	"qwerty", "qwerty123", "abc123", "111111", "iloveyou", "admin", "admin123", "welcome",
+	"letmein", "monkey", "dragon", "sunshine", "football", "shopping",
+}
+
+// PasswordPolicy controls which passwords are accepted and how they are hashed.
+type PasswordPolicy struct {
+	// MinLength is the minimum number of characters of a password.
+	MinLength int
+	// RequireUpper, RequireLower, RequireDigit and RequireSymbol enforce character classes.
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// DenyList holds passwords that are always rejected, compared case-insensitively.
+	DenyList []string
+	// Cost is the bcrypt cost of new hashes; stored hashes with a lower cost are upgraded on login.
+	Cost int
+}
+
+// PasswordViolation is a single rule of the password policy that a password breaks.
+type PasswordViolation struct {
+	Rule        string
+	Description string
+}
+
+// PasswordPolicyError lists every rule a rejected password breaks.
+type PasswordPolicyError struct {
+	Violations []PasswordViolation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	descriptions := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		descriptions = append(descriptions, violation.Description)
+	}
+	return "password does not meet the policy: " + strings.Join(descriptions, "; ")
+}
+
+// Validate checks a password of the given user against the policy and returns a *PasswordPolicyError
+// listing all violations.
+func (p PasswordPolicy) Validate(userName string, pwd string) error {
+	var violations []PasswordViolation
+
+	if len([]rune(pwd)) < p.MinLength {
+		violations = append(violations, PasswordViolation{"min_length", fmt.Sprintf("must be at least %d characters", p.MinLength)})
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range pwd {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSymbol = true
+		}
+	}
+	if p.RequireUpper && !hasUpper {
+		violations = append(violations, PasswordViolation{"upper", "must contain an upper-case letter"})
+	}
+	if p.RequireLower && !hasLower {
+		violations = append(violations, PasswordViolation{"lower", "must contain a lower-case letter"})
+	}
+	if p.RequireDigit && !hasDigit {
+		violations = append(violations, PasswordViolation{"digit", "must contain a digit"})
+	}
+	if p.RequireSymbol && !hasSymbol {
+		violations = append(violations, PasswordViolation{"symbol", "must contain a symbol"})
+	}
+
+	for _, denied := range p.DenyList {
+		if strings.EqualFold(pwd, denied) {
+			violations = append(violations, PasswordViolation{"common", "is too common"})
+			break
+		}
+	}
+
+	if userName != "" && strings.EqualFold(pwd, userName) {
+		violations = append(violations, PasswordViolation{"username", "must not equal the username"})
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// cost returns the bcrypt cost used for new hashes, falling back to bcrypt.DefaultCost.
+func (p PasswordPolicy) cost() int {
+	if p.Cost < bcrypt.MinCost {
+		return bcrypt.DefaultCost
+	}
+	return p.Cost
+}
+
+// needsRehash reports whether a stored hash was created with a lower cost than the policy requires.
+func (p PasswordPolicy) needsRehash(hashed string) bool {
+	cost, err := bcrypt.Cost([]byte(hashed))
+	return err == nil && cost < p.cost()
+}
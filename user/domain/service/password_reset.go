@@ -0,0 +1,103 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/tongs-dev/shopping-platform/user/domain/model"
+	"github.com/tongs-dev/shopping-platform/user/notify"
+)
+
+// RequestPasswordReset issues a single-use reset token for the user and sends it through the notifier.
+// Unknown user names are ignored so callers cannot probe which accounts exist.
+func (u *UserDataService) RequestPasswordReset(userName string) error {
+	user, err := u.UserRepository.FindUserByName(userName)
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil
+		}
+		return err
+	}
+
+	// Only the most recently requested token stays valid
+	if err := u.PasswordResetRepository.DeletePasswordResetTokensByUserID(user.ID); err != nil {
+		return err
+	}
+
+	resetToken, err := generateOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(u.TokenConfig.PasswordResetTTL)
+	_, err = u.PasswordResetRepository.CreatePasswordResetToken(&model.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: hashToken(resetToken),
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	return u.Notifier.Send(notify.Message{
+		To:      contactAddress(user),
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Use this code to reset your password: %s\nIt expires at %s.",
+			resetToken, expiresAt.Format(time.RFC1123)),
+	})
+}
+
+// ConfirmPasswordReset consumes a reset token and sets the new password, ending existing sessions.
+func (u *UserDataService) ConfirmPasswordReset(resetToken string, newPwd string) error {
+	if resetToken == "" {
+		return ErrInvalidToken
+	}
+
+	stored, err := u.PasswordResetRepository.FindPasswordResetTokenByHash(hashToken(resetToken))
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return ErrInvalidToken
+		}
+		return err
+	}
+
+	now := time.Now()
+	if stored.UsedAt != nil || now.After(stored.ExpiresAt) {
+		return ErrInvalidToken
+	}
+
+	user, err := u.UserRepository.FindUserByID(stored.UserID)
+	if err != nil {
+		return err
+	}
+
+	// Validates before consuming the token so a rejected password can be retried
+	if err := u.PasswordPolicy.Validate(user.UserName, newPwd); err != nil {
+		return err
+	}
+
+	// Consuming the token first guarantees a concurrent confirm cannot reuse it
+	if err := u.PasswordResetRepository.MarkPasswordResetTokenUsed(stored.ID, now); err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return ErrInvalidToken
+		}
+		return err
+	}
+
+	user.HashPassword = newPwd
+	if err := u.UpdateUser(user, true); err != nil {
+		return err
+	}
+
+	if err := u.RevokeAllSessions(user.ID, 0); err != nil {
+		return err
+	}
+
+	if err := u.recordAudit(user.ID, user.ID, AuditPasswordReset, ""); err != nil {
+		return err
+	}
+
+	// The user proved ownership of the account, so a pending lockout no longer applies
+	return u.LoginAttemptRepository.DeleteLoginAttemptBySubject(userSubject(user.UserName))
+}
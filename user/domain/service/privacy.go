@@ -0,0 +1,243 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/tongs-dev/shopping-platform/user/domain/model"
+)
+
+// Actions recorded in the audit trail.
+const (
+	AuditPasswordChanged = "password_changed"
+	AuditPasswordReset   = "password_reset"
+	AuditMFAEnabled      = "mfa_enabled"
+	AuditMFADisabled     = "mfa_disabled"
+	AuditDataExported    = "data_exported"
+	AuditUserErased      = "user_erased"
+)
+
+// ErrUserErased is returned when erasing a user whose personal data has already been erased.
+var ErrUserErased = errors.New("user already erased")
+
+// UserDataExport is everything the user service holds about a user, see ExportUserData.
+type UserDataExport struct {
+	ExportedAt   time.Time            `json:"exported_at"`
+	Profile      ExportedProfile      `json:"profile"`
+	Addresses    []ExportedAddress    `json:"addresses"`
+	Sessions     []ExportedSession    `json:"sessions"`
+	AuditEntries []ExportedAuditEntry `json:"audit_entries"`
+	// LinkedIdentities are the external accounts the user signs in with
+	LinkedIdentities []ExportedLinkedIdentity `json:"linked_identities"`
+}
+
+// ExportedProfile is the account of a user without credentials and secrets.
+type ExportedProfile struct {
+	UserID        int64      `json:"user_id"`
+	UserName      string     `json:"user_name"`
+	FirstName     string     `json:"first_name"`
+	LastName      string     `json:"last_name"`
+	Email         string     `json:"email"`
+	Phone         string     `json:"phone"`
+	EmailVerified bool       `json:"email_verified"`
+	Roles         []string   `json:"roles"`
+	TOTPEnabled   bool       `json:"totp_enabled"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	ErasedAt      *time.Time `json:"erased_at,omitempty"`
+}
+
+// ExportedAddress is one entry of the address book of a user.
+type ExportedAddress struct {
+	AddressID         int64     `json:"address_id"`
+	Recipient         string    `json:"recipient"`
+	Line1             string    `json:"line1"`
+	Line2             string    `json:"line2"`
+	City              string    `json:"city"`
+	Region            string    `json:"region"`
+	PostalCode        string    `json:"postal_code"`
+	Country           string    `json:"country"`
+	Phone             string    `json:"phone"`
+	IsDefaultShipping bool      `json:"is_default_shipping"`
+	IsDefaultBilling  bool      `json:"is_default_billing"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// ExportedSession is a login session of a user, including revoked and expired ones.
+type ExportedSession struct {
+	SessionID  int64      `json:"session_id"`
+	Device     string     `json:"device"`
+	IPAddress  string     `json:"ip_address"`
+	UserAgent  string     `json:"user_agent"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// ExportedLinkedIdentity is an external account linked to a user.
+type ExportedLinkedIdentity struct {
+	Provider  string    `json:"provider"`
+	Subject   string    `json:"subject"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ExportedAuditEntry is one entry of the audit trail of a user.
+type ExportedAuditEntry struct {
+	Action    string    `json:"action"`
+	ActorID   int64     `json:"actor_id"`
+	Detail    string    `json:"detail"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ExportUserData collects everything held about a user for a data access request and records the export,
+// performed by actorID, in the audit trail.
+func (u *UserDataService) ExportUserData(userID int64, actorID int64) (*UserDataExport, error) {
+	user, err := u.UserRepository.FindUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	addresses, err := u.AddressRepository.FindAddressesByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := u.SessionRepository.FindSessionsByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := u.AuditRepository.FindAuditEntriesByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	linkedIdentities, err := u.LinkedIdentityRepository.FindLinkedIdentitiesByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &UserDataExport{
+		ExportedAt: time.Now(),
+		Profile: ExportedProfile{
+			UserID:        user.ID,
+			UserName:      user.UserName,
+			FirstName:     user.FirstName,
+			LastName:      user.LastName,
+			Email:         user.Email,
+			Phone:         user.Phone,
+			EmailVerified: user.EmailVerified,
+			Roles:         SplitList(user.Roles),
+			TOTPEnabled:   user.TOTPEnabled,
+			CreatedAt:     user.CreatedAt,
+			UpdatedAt:     user.UpdatedAt,
+			ErasedAt:      user.ErasedAt,
+		},
+		Addresses:        make([]ExportedAddress, 0, len(addresses)),
+		Sessions:         make([]ExportedSession, 0, len(sessions)),
+		AuditEntries:     make([]ExportedAuditEntry, 0, len(entries)),
+		LinkedIdentities: make([]ExportedLinkedIdentity, 0, len(linkedIdentities)),
+	}
+	for _, address := range addresses {
+		export.Addresses = append(export.Addresses, ExportedAddress{
+			AddressID:         address.ID,
+			Recipient:         address.Recipient,
+			Line1:             address.Line1,
+			Line2:             address.Line2,
+			City:              address.City,
+			Region:            address.Region,
+			PostalCode:        address.PostalCode,
+			Country:           address.Country,
+			Phone:             address.Phone,
+			IsDefaultShipping: address.IsDefaultShipping,
+			IsDefaultBilling:  address.IsDefaultBilling,
+			CreatedAt:         address.CreatedAt,
+			UpdatedAt:         address.UpdatedAt,
+		})
+	}
+	for _, session := range sessions {
+		export.Sessions = append(export.Sessions, ExportedSession{
+			SessionID:  session.ID,
+			Device:     session.Device,
+			IPAddress:  session.IPAddress,
+			UserAgent:  session.UserAgent,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			ExpiresAt:  session.ExpiresAt,
+			RevokedAt:  session.RevokedAt,
+		})
+	}
+	for _, entry := range entries {
+		export.AuditEntries = append(export.AuditEntries, ExportedAuditEntry{
+			Action:    entry.Action,
+			ActorID:   entry.ActorID,
+			Detail:    entry.Detail,
+			CreatedAt: entry.CreatedAt,
+		})
+	}
+	for _, linked := range linkedIdentities {
+		export.LinkedIdentities = append(export.LinkedIdentities, ExportedLinkedIdentity{
+			Provider:  linked.Provider,
+			Subject:   linked.Subject,
+			Email:     linked.Email,
+			CreatedAt: linked.CreatedAt,
+		})
+	}
+
+	if err := u.recordAudit(userID, actorID, AuditDataExported, ""); err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+// EraseUser anonymizes the personal data of a user for a right-to-erasure request. The user row and its ID
+// are kept, so orders and other records referencing the user stay valid, but the name, contact details,
+// credentials and roles are replaced, sessions are ended and stripped of client details, addresses,
+// linked identities and pending tokens are deleted. The erasure, performed by actorID, is recorded in the audit trail with reason.
+func (u *UserDataService) EraseUser(userID int64, actorID int64, reason string) error {
+	user, err := u.UserRepository.FindUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if user.ErasedAt != nil {
+		return ErrUserErased
+	}
+
+	if err := u.RevokeAllSessions(userID, 0); err != nil {
+		return err
+	}
+	if err := u.SessionRepository.AnonymizeSessionsByUserID(userID); err != nil {
+		return err
+	}
+	if err := u.AddressRepository.DeleteAddressesByUserID(userID); err != nil {
+		return err
+	}
+	if err := u.LinkedIdentityRepository.DeleteLinkedIdentitiesByUserID(userID); err != nil {
+		return err
+	}
+	if err := u.PasswordResetRepository.DeletePasswordResetTokensByUserID(userID); err != nil {
+		return err
+	}
+	if err := u.EmailVerificationRepository.DeleteEmailVerificationTokensByUserID(userID); err != nil {
+		return err
+	}
+	// Login attempts are keyed by the user name, which is about to disappear
+	if err := u.LoginAttemptRepository.DeleteLoginAttemptBySubject(userSubject(user.UserName)); err != nil {
+		return err
+	}
+	if err := u.UserRepository.AnonymizeUser(userID, time.Now()); err != nil {
+		return err
+	}
+
+	return u.recordAudit(userID, actorID, AuditUserErased, reason)
+}
+
+// recordAudit appends an entry to the audit trail of a user.
+func (u *UserDataService) recordAudit(userID int64, actorID int64, action string, detail string) error {
+	_, err := u.AuditRepository.CreateAuditEntry(&model.AuditEntry{
+		UserID:  userID,
+		ActorID: actorID,
+		Action:  action,
+		Detail:  detail,
+	})
+	return err
+}
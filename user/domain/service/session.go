@@ -0,0 +1,138 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/tongs-dev/shopping-platform/user/domain/model"
+)
+
+// sessionTouchInterval limits how often ValidateSession records activity on a session.
+const sessionTouchInterval = time.Minute
+
+// ErrSessionNotFound is returned when a session does not exist, belongs to another user or has ended.
+var ErrSessionNotFound = errors.New("session not found")
+
+// ClientInfo describes the client a login comes from.
+type ClientInfo struct {
+	// Address is the client IP, also used for login throttling.
+	Address   string
+	UserAgent string
+	// Device is a name the client gives itself, e.g. "Alice's phone".
+	Device string
+}
+
+// startSession records a new session for the client and issues its first token pair.
+func (u *UserDataService) startSession(user *model.User, client ClientInfo) (*TokenPair, error) {
+	now := time.Now()
+	session := &model.Session{
+		UserID:     user.ID,
+		Device:     client.Device,
+		IPAddress:  client.Address,
+		UserAgent:  client.UserAgent,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(u.TokenConfig.RefreshTokenTTL),
+	}
+	sessionID, err := u.SessionRepository.CreateSession(session)
+	if err != nil {
+		return nil, err
+	}
+	session.ID = sessionID
+
+	return u.issueTokenPair(user, session)
+}
+
+// findActiveSession retrieves a session of the user that is neither revoked nor expired.
+func (u *UserDataService) findActiveSession(sessionID int64, userID int64) (*model.Session, error) {
+	session, err := u.SessionRepository.FindSessionByID(sessionID)
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	if session.UserID != userID || session.RevokedAt != nil || time.Now().After(session.ExpiresAt) {
+		return nil, ErrInvalidToken
+	}
+	return session, nil
+}
+
+// ListSessions returns the active sessions of a user, most recently used first.
+func (u *UserDataService) ListSessions(userID int64) ([]model.Session, error) {
+	return u.SessionRepository.FindActiveSessionsByUserID(userID, time.Now())
+}
+
+// RevokeSession ends one session of a user; its refresh token and access tokens stop working immediately.
+func (u *UserDataService) RevokeSession(userID int64, sessionID int64) error {
+	session, err := u.SessionRepository.FindSessionByID(sessionID)
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return ErrSessionNotFound
+		}
+		return err
+	}
+	if session.UserID != userID {
+		return ErrSessionNotFound
+	}
+
+	err = u.SessionRepository.RevokeSession(sessionID, time.Now())
+	if gorm.IsRecordNotFoundError(err) {
+		return ErrSessionNotFound
+	}
+	return err
+}
+
+// RevokeAllSessions ends every session of a user except exceptSessionID, pass 0 to end all of them.
+func (u *UserDataService) RevokeAllSessions(userID int64, exceptSessionID int64) error {
+	now := time.Now()
+	if exceptSessionID == 0 {
+		if err := u.RefreshTokenRepository.RevokeRefreshTokensByUserID(userID); err != nil {
+			return err
+		}
+		return u.SessionRepository.RevokeSessionsByUserID(userID, now)
+	}
+
+	sessions, err := u.SessionRepository.FindActiveSessionsByUserID(userID, now)
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		if session.ID == exceptSessionID {
+			continue
+		}
+		if err := u.SessionRepository.RevokeSession(session.ID, now); err != nil && !gorm.IsRecordNotFoundError(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateSession reports whether the session of a valid access token is still active.
+// Tokens issued before sessions existed carry no session and are accepted until they expire.
+func (u *UserDataService) ValidateSession(accessToken string) (bool, error) {
+	claims, err := ParseAccessToken(u.TokenConfig, accessToken)
+	if err != nil {
+		return false, err
+	}
+	if claims.SessionID == 0 {
+		return true, nil
+	}
+
+	session, err := u.findActiveSession(claims.SessionID, claims.UserID)
+	if err != nil {
+		if errors.Is(err, ErrInvalidToken) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	now := time.Now()
+	if now.Sub(session.LastSeenAt) > sessionTouchInterval {
+		if err := u.SessionRepository.TouchSession(session.ID, now, time.Time{}); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
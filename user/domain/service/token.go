@@ -0,0 +1,128 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/tongs-dev/shopping-platform/user/domain/model"
+)
+
+// TokenType is the scheme clients use when presenting an access token.
+const TokenType = "Bearer"
+
+var (
+	// ErrInvalidCredentials is returned when a username/password pair does not match.
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	// ErrInvalidToken is returned when a token is malformed, expired or revoked.
+	ErrInvalidToken = errors.New("invalid or expired token")
+)
+
+// TokenConfig holds the settings used to sign and expire issued tokens.
+type TokenConfig struct {
+	// Secret is the HMAC key used to sign access tokens.
+	Secret string
+	// Issuer is written to the "iss" claim of access tokens.
+	Issuer string
+	// AccessTokenTTL is the lifetime of an access token.
+	AccessTokenTTL time.Duration
+	// RefreshTokenTTL is the lifetime of a refresh token.
+	RefreshTokenTTL time.Duration
+	// PasswordResetTTL is the lifetime of a password reset token.
+	PasswordResetTTL time.Duration
+	// EmailVerificationTTL is the lifetime of an email verification token.
+	EmailVerificationTTL time.Duration
+	// ExternalLoginTTL is how long a login started with BeginExternalLogin can be completed.
+	ExternalLoginTTL time.Duration
+	// RequireVerifiedEmail refuses tokens to users that have not verified their email yet.
+	RequireVerifiedEmail bool
+}
+
+// TokenClaims are the claims carried by a signed access token.
+type TokenClaims struct {
+	UserID   int64    `json:"uid"`
+	UserName string   `json:"name"`
+	Roles    []string `json:"roles,omitempty"`
+	// Permissions are the RPC endpoints granted by the user's roles
+	Permissions []string `json:"perms,omitempty"`
+	// SessionID is the login session the token belongs to
+	SessionID int64 `json:"sid,omitempty"`
+	jwt.StandardClaims
+}
+
+// TokenPair is the set of credentials handed to a client after authentication.
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	TokenType    string
+	// ExpiresIn is the access token lifetime in seconds.
+	ExpiresIn int64
+	// MFAChallenge is set instead of the tokens when the user must complete the login with VerifyMFA.
+	MFAChallenge string
+}
+
+// SignAccessToken creates a signed access token for the given user, the permissions granted by their roles
+// and the session it belongs to.
+func SignAccessToken(config TokenConfig, user *model.User, permissions []string, sessionID int64, now time.Time) (string, error) {
+	claims := TokenClaims{
+		UserID:      user.ID,
+		UserName:    user.UserName,
+		Roles:       SplitList(user.Roles),
+		Permissions: permissions,
+		SessionID:   sessionID,
+		StandardClaims: jwt.StandardClaims{
+			Subject:   fmt.Sprintf("%d", user.ID),
+			Issuer:    config.Issuer,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(config.AccessTokenTTL).Unix(),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(config.Secret))
+}
+
+// ParseAccessToken verifies the signature and expiry of an access token and returns its claims.
+func ParseAccessToken(config TokenConfig, accessToken string) (*TokenClaims, error) {
+	claims := &TokenClaims{}
+	token, err := jwt.ParseWithClaims(accessToken, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(config.Secret), nil
+	})
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// SplitList turns a stored comma-separated list (roles, permissions) into a slice.
+func SplitList(list string) []string {
+	var result []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// generateOpaqueToken returns a random URL-safe token suitable for refresh tokens.
+func generateOpaqueToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashToken returns the hex encoded sha256 of a raw token, which is what gets persisted.
+func hashToken(rawToken string) string {
+	sum := sha256.Sum256([]byte(rawToken))
+	return hex.EncodeToString(sum[:])
+}
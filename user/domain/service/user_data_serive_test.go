@@ -1,11 +1,20 @@
 package service
 
 import (
+	"encoding/base32"
+	"encoding/json"
+	"errors"
 	"testing"
+	"time"
 
+	"github.com/jinzhu/gorm"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/tongs-dev/shopping-platform/user/domain/model"
+	"github.com/tongs-dev/shopping-platform/user/domain/repository"
+	"github.com/tongs-dev/shopping-platform/user/identity"
+	"github.com/tongs-dev/shopping-platform/user/notify"
+	"golang.org/x/crypto/bcrypt"
 )
 
 // MockUserRepository is a mock implementation of IUserRepository.
@@ -24,11 +33,26 @@ func (m *MockUserRepository) FindUserByID(userID int64) (*model.User, error) {
 	return args.Get(0).(*model.User), args.Error(1)
 }
 
+func (m *MockUserRepository) FindUsersByVerifiedEmail(email string) ([]model.User, error) {
+	args := m.Called(email)
+	return args.Get(0).([]model.User), args.Error(1)
+}
+
 func (m *MockUserRepository) FindAll() ([]model.User, error) {
 	args := m.Called()
 	return args.Get(0).([]model.User), args.Error(1)
 }
 
+func (m *MockUserRepository) SearchUsers(search repository.UserSearch) ([]model.User, error) {
+	args := m.Called(search)
+	return args.Get(0).([]model.User), args.Error(1)
+}
+
+func (m *MockUserRepository) FindPage(offset int, limit int) ([]model.User, int64, error) {
+	args := m.Called(offset, limit)
+	return args.Get(0).([]model.User), args.Get(1).(int64), args.Error(2)
+}
+
 func (m *MockUserRepository) CreateUser(user *model.User) (int64, error) {
 	args := m.Called(user)
 	return args.Get(0).(int64), args.Error(1)
@@ -44,15 +68,436 @@ func (m *MockUserRepository) UpdateUser(user *model.User) error {
 	return args.Error(0)
 }
 
+func (m *MockUserRepository) UpdateUserProfile(user *model.User) error {
+	args := m.Called(user)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UpdateUserRoles(userID int64, roles string) error {
+	args := m.Called(userID, roles)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) MarkEmailVerified(userID int64) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UpdateUserMFA(user *model.User) error {
+	args := m.Called(user)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) AnonymizeUser(userID int64, erasedAt time.Time) error {
+	args := m.Called(userID, erasedAt)
+	return args.Error(0)
+}
+
 func (m *MockUserRepository) FindUserByName(userName string) (*model.User, error) {
 	args := m.Called(userName)
 	return args.Get(0).(*model.User), args.Error(1)
 }
 
+// MockRefreshTokenRepository is a mock implementation of IRefreshTokenRepository.
+type MockRefreshTokenRepository struct {
+	mock.Mock
+}
+
+func (m *MockRefreshTokenRepository) InitTable() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockRefreshTokenRepository) CreateRefreshToken(token *model.RefreshToken) (int64, error) {
+	args := m.Called(token)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRefreshTokenRepository) FindRefreshTokenByHash(tokenHash string) (*model.RefreshToken, error) {
+	args := m.Called(tokenHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.RefreshToken), args.Error(1)
+}
+
+func (m *MockRefreshTokenRepository) RevokeRefreshToken(tokenID int64) error {
+	args := m.Called(tokenID)
+	return args.Error(0)
+}
+
+func (m *MockRefreshTokenRepository) RevokeRefreshTokensByUserID(userID int64) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+// MockRoleRepository is a mock implementation of IRoleRepository.
+type MockRoleRepository struct {
+	mock.Mock
+}
+
+func (m *MockRoleRepository) InitTable() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockRoleRepository) FindRoleByName(name string) (*model.Role, error) {
+	args := m.Called(name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Role), args.Error(1)
+}
+
+func (m *MockRoleRepository) FindRolesByNames(names []string) ([]model.Role, error) {
+	args := m.Called(names)
+	return args.Get(0).([]model.Role), args.Error(1)
+}
+
+func (m *MockRoleRepository) FindAll() ([]model.Role, error) {
+	args := m.Called()
+	return args.Get(0).([]model.Role), args.Error(1)
+}
+
+// MockLoginAttemptRepository is a mock implementation of ILoginAttemptRepository.
+type MockLoginAttemptRepository struct {
+	mock.Mock
+}
+
+func (m *MockLoginAttemptRepository) InitTable() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockLoginAttemptRepository) FindLoginAttemptBySubject(subject string) (*model.LoginAttempt, error) {
+	args := m.Called(subject)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.LoginAttempt), args.Error(1)
+}
+
+func (m *MockLoginAttemptRepository) SaveLoginAttempt(attempt *model.LoginAttempt) error {
+	args := m.Called(attempt)
+	return args.Error(0)
+}
+
+func (m *MockLoginAttemptRepository) DeleteLoginAttemptBySubject(subject string) error {
+	args := m.Called(subject)
+	return args.Error(0)
+}
+
+// MockPasswordResetRepository is a mock implementation of IPasswordResetRepository.
+type MockPasswordResetRepository struct {
+	mock.Mock
+}
+
+func (m *MockPasswordResetRepository) InitTable() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockPasswordResetRepository) CreatePasswordResetToken(token *model.PasswordResetToken) (int64, error) {
+	args := m.Called(token)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockPasswordResetRepository) FindPasswordResetTokenByHash(tokenHash string) (*model.PasswordResetToken, error) {
+	args := m.Called(tokenHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.PasswordResetToken), args.Error(1)
+}
+
+func (m *MockPasswordResetRepository) MarkPasswordResetTokenUsed(tokenID int64, usedAt time.Time) error {
+	args := m.Called(tokenID, usedAt)
+	return args.Error(0)
+}
+
+func (m *MockPasswordResetRepository) DeletePasswordResetTokensByUserID(userID int64) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+// MockEmailVerificationRepository is a mock implementation of IEmailVerificationRepository.
+type MockEmailVerificationRepository struct {
+	mock.Mock
+}
+
+func (m *MockEmailVerificationRepository) InitTable() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockEmailVerificationRepository) CreateEmailVerificationToken(token *model.EmailVerificationToken) (int64, error) {
+	args := m.Called(token)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockEmailVerificationRepository) FindEmailVerificationTokenByHash(tokenHash string) (*model.EmailVerificationToken, error) {
+	args := m.Called(tokenHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.EmailVerificationToken), args.Error(1)
+}
+
+func (m *MockEmailVerificationRepository) DeleteEmailVerificationTokensByUserID(userID int64) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+func (m *MockAddressRepository) DeleteAddressesByUserID(userID int64) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+// MockLinkedIdentityRepository is a mock implementation of ILinkedIdentityRepository.
+type MockLinkedIdentityRepository struct {
+	mock.Mock
+}
+
+func (m *MockLinkedIdentityRepository) InitTable() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockLinkedIdentityRepository) CreateLinkedIdentity(linked *model.LinkedIdentity) (int64, error) {
+	args := m.Called(linked)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockLinkedIdentityRepository) FindLinkedIdentity(provider string, subject string) (*model.LinkedIdentity, error) {
+	args := m.Called(provider, subject)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.LinkedIdentity), args.Error(1)
+}
+
+func (m *MockLinkedIdentityRepository) FindLinkedIdentitiesByUserID(userID int64) ([]model.LinkedIdentity, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]model.LinkedIdentity), args.Error(1)
+}
+
+func (m *MockLinkedIdentityRepository) DeleteLinkedIdentitiesByUserID(userID int64) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+// MockAuditRepository is a mock implementation of IAuditRepository.
+type MockAuditRepository struct {
+	mock.Mock
+}
+
+func (m *MockAuditRepository) InitTable() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockAuditRepository) CreateAuditEntry(entry *model.AuditEntry) (int64, error) {
+	args := m.Called(entry)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockAuditRepository) FindAuditEntriesByUserID(userID int64) ([]model.AuditEntry, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]model.AuditEntry), args.Error(1)
+}
+
+// MockAddressRepository is a mock implementation of IAddressRepository.
+type MockAddressRepository struct {
+	mock.Mock
+}
+
+func (m *MockAddressRepository) InitTable() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockAddressRepository) CreateAddress(address *model.Address) (int64, error) {
+	args := m.Called(address)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockAddressRepository) FindAddressByID(addressID int64) (*model.Address, error) {
+	args := m.Called(addressID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Address), args.Error(1)
+}
+
+func (m *MockAddressRepository) FindAddressesByUserID(userID int64) ([]model.Address, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]model.Address), args.Error(1)
+}
+
+func (m *MockAddressRepository) UpdateAddress(address *model.Address) error {
+	args := m.Called(address)
+	return args.Error(0)
+}
+
+func (m *MockAddressRepository) DeleteAddressByID(addressID int64) error {
+	args := m.Called(addressID)
+	return args.Error(0)
+}
+
+func (m *MockAddressRepository) SetDefaultAddress(userID int64, addressID int64, shipping bool, billing bool) error {
+	args := m.Called(userID, addressID, shipping, billing)
+	return args.Error(0)
+}
+
+// MockSessionRepository is a mock implementation of ISessionRepository.
+type MockSessionRepository struct {
+	mock.Mock
+}
+
+func (m *MockSessionRepository) InitTable() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockSessionRepository) CreateSession(session *model.Session) (int64, error) {
+	args := m.Called(session)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockSessionRepository) FindSessionByID(sessionID int64) (*model.Session, error) {
+	args := m.Called(sessionID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.Session), args.Error(1)
+}
+
+func (m *MockSessionRepository) FindActiveSessionsByUserID(userID int64, now time.Time) ([]model.Session, error) {
+	args := m.Called(userID, now)
+	return args.Get(0).([]model.Session), args.Error(1)
+}
+
+func (m *MockSessionRepository) TouchSession(sessionID int64, lastSeenAt time.Time, expiresAt time.Time) error {
+	args := m.Called(sessionID, lastSeenAt, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepository) RevokeSession(sessionID int64, revokedAt time.Time) error {
+	args := m.Called(sessionID, revokedAt)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepository) RevokeSessionsByUserID(userID int64, revokedAt time.Time) error {
+	args := m.Called(userID, revokedAt)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepository) FindSessionsByUserID(userID int64) ([]model.Session, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]model.Session), args.Error(1)
+}
+
+func (m *MockSessionRepository) AnonymizeSessionsByUserID(userID int64) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+// MockNotifier is a mock implementation of notify.INotifier.
+type MockNotifier struct {
+	mock.Mock
+}
+
+func (m *MockNotifier) Send(message notify.Message) error {
+	args := m.Called(message)
+	return args.Error(0)
+}
+
+// savedLoginAttempts returns the attempts passed to SaveLoginAttempt, in call order.
+func savedLoginAttempts(m *MockLoginAttemptRepository) []*model.LoginAttempt {
+	var saved []*model.LoginAttempt
+	for _, call := range m.Calls {
+		if call.Method == "SaveLoginAttempt" {
+			saved = append(saved, call.Arguments.Get(0).(*model.LoginAttempt))
+		}
+	}
+	return saved
+}
+
+var testTokenConfig = TokenConfig{
+	Secret:               "test-secret",
+	Issuer:               "test",
+	AccessTokenTTL:       time.Minute,
+	RefreshTokenTTL:      time.Hour,
+	PasswordResetTTL:     time.Hour,
+	EmailVerificationTTL: time.Hour,
+	ExternalLoginTTL:     time.Minute,
+}
+
+var testLockoutConfig = LockoutConfig{
+	MaxUserAttempts:    3,
+	MaxAddressAttempts: 10,
+	LockoutDuration:    time.Minute,
+	BaseBackoff:        time.Second,
+	MaxBackoff:         4 * time.Second,
+}
+
+var testPasswordPolicy = PasswordPolicy{
+	MinLength: 8,
+	DenyList:  CommonPasswords,
+	Cost:      bcrypt.DefaultCost,
+}
+
+var testMFAConfig = MFAConfig{
+	Issuer:        "Test",
+	EncryptionKey: "test-mfa-key",
+	ChallengeTTL:  time.Minute,
+}
+
+// userDataServiceMocks bundles the repository mocks behind a service created by newTestUserDataService.
+type userDataServiceMocks struct {
+	userRepo    *MockUserRepository
+	tokenRepo   *MockRefreshTokenRepository
+	roleRepo    *MockRoleRepository
+	attemptRepo *MockLoginAttemptRepository
+	sessionRepo *MockSessionRepository
+	resetRepo   *MockPasswordResetRepository
+	verifyRepo  *MockEmailVerificationRepository
+	addressRepo *MockAddressRepository
+	auditRepo   *MockAuditRepository
+	linkRepo    *MockLinkedIdentityRepository
+	notifier    *MockNotifier
+	provider    *identity.FakeProvider
+}
+
+// Helper function to create a service backed by fresh repository mocks
+func newTestUserDataService() (IUserDataService, *userDataServiceMocks) {
+	mocks := &userDataServiceMocks{
+		userRepo:    new(MockUserRepository),
+		tokenRepo:   new(MockRefreshTokenRepository),
+		roleRepo:    new(MockRoleRepository),
+		attemptRepo: new(MockLoginAttemptRepository),
+		sessionRepo: new(MockSessionRepository),
+		resetRepo:   new(MockPasswordResetRepository),
+		verifyRepo:  new(MockEmailVerificationRepository),
+		addressRepo: new(MockAddressRepository),
+		auditRepo:   new(MockAuditRepository),
+		linkRepo:    new(MockLinkedIdentityRepository),
+		notifier:    new(MockNotifier),
+		provider:    identity.NewFakeProvider("fake"),
+	}
+	service := NewUserDataService(mocks.userRepo, mocks.tokenRepo, mocks.roleRepo, mocks.attemptRepo, mocks.sessionRepo,
+		mocks.resetRepo,
+		mocks.verifyRepo, mocks.addressRepo, mocks.auditRepo, mocks.linkRepo, mocks.notifier,
+		[]identity.IProvider{mocks.provider}, testTokenConfig, testLockoutConfig, testPasswordPolicy,
+		testMFAConfig)
+	// Audit entries are written by many operations, tests inspect auditRepo.Calls when they matter
+	mocks.auditRepo.On("CreateAuditEntry", mock.AnythingOfType("*model.AuditEntry")).Return(int64(1), nil).Maybe()
+	return service, mocks
+}
+
 // Test AddUser: Ensures the user is created successfully.
 func TestAddUser(t *testing.T) {
-	mockRepo := new(MockUserRepository)
-	service := NewUserDataService(mockRepo)
+	service, mocks := newTestUserDataService()
 
 	user := &model.User{
 		UserName:     "testuser",
@@ -60,29 +505,60 @@ func TestAddUser(t *testing.T) {
 		HashPassword: "securepassword",
 	}
 
-	mockRepo.On("CreateUser", mock.Anything).Return(int64(1), nil)
+	mocks.userRepo.On("CreateUser", mock.Anything).Return(int64(1), nil)
 
 	userID, err := service.AddUser(user)
 	assert.NoError(t, err)
 	assert.Equal(t, int64(1), userID)
+	assert.Equal(t, DefaultRole, user.Roles)
+	mocks.notifier.AssertNotCalled(t, "Send", mock.Anything)
+}
+
+// Test AddUser: Ensures a verification token is sent to the email of a new user.
+func TestAddUserSendsEmailVerification(t *testing.T) {
+	service, mocks := newTestUserDataService()
+
+	user := &model.User{UserName: "testuser", Email: "john@example.com", HashPassword: "securepassword"}
+
+	mocks.userRepo.On("CreateUser", mock.Anything).Return(int64(1), nil)
+	mocks.verifyRepo.On("DeleteEmailVerificationTokensByUserID", int64(1)).Return(nil)
+	mocks.verifyRepo.On("CreateEmailVerificationToken", mock.AnythingOfType("*model.EmailVerificationToken")).Return(int64(1), nil)
+	mocks.notifier.On("Send", mock.AnythingOfType("notify.Message")).Return(nil)
+
+	_, err := service.AddUser(user)
+	assert.NoError(t, err)
+
+	stored := mocks.verifyRepo.Calls[1].Arguments.Get(0).(*model.EmailVerificationToken)
+	message := mocks.notifier.Calls[0].Arguments.Get(0).(notify.Message)
+	assert.Equal(t, int64(1), stored.UserID)
+	assert.Equal(t, "john@example.com", message.To)
+	assert.False(t, user.EmailVerified)
+}
+
+// Test AddUser: Ensures an email is mandatory while verified emails are required.
+func TestAddUserEmailRequired(t *testing.T) {
+	service, mocks := newTestUserDataService()
+	service.(*UserDataService).TokenConfig.RequireVerifiedEmail = true
+
+	_, err := service.AddUser(&model.User{UserName: "testuser", HashPassword: "securepassword"})
+	assert.ErrorIs(t, err, ErrEmailRequired)
+	mocks.userRepo.AssertNotCalled(t, "CreateUser", mock.Anything)
 }
 
 // Test DeleteUser: Ensures a user is deleted correctly.
 func TestDeleteUser(t *testing.T) {
-	mockRepo := new(MockUserRepository)
-	service := NewUserDataService(mockRepo)
+	service, mocks := newTestUserDataService()
 
-	mockRepo.On("DeleteUserByID", int64(1)).Return(nil)
+	mocks.userRepo.On("DeleteUserByID", int64(1)).Return(nil)
 
 	err := service.DeleteUser(1)
 	assert.NoError(t, err)
-	mockRepo.AssertCalled(t, "DeleteUserByID", int64(1))
+	mocks.userRepo.AssertCalled(t, "DeleteUserByID", int64(1))
 }
 
 // Test UpdateUser: Ensures a user update works correctly.
 func TestUpdateUser(t *testing.T) {
-	mockRepo := new(MockUserRepository)
-	service := NewUserDataService(mockRepo)
+	service, mocks := newTestUserDataService()
 
 	user := &model.User{
 		ID:        1,
@@ -90,17 +566,29 @@ func TestUpdateUser(t *testing.T) {
 		FirstName: "UpdatedName",
 	}
 
-	mockRepo.On("UpdateUser", user).Return(nil)
+	mocks.userRepo.On("UpdateUser", user).Return(nil)
 
 	err := service.UpdateUser(user, false)
 	assert.NoError(t, err)
-	mockRepo.AssertCalled(t, "UpdateUser", user)
+	mocks.userRepo.AssertCalled(t, "UpdateUser", user)
+}
+
+// Test UpdateProfile: Ensures empty profile fields are passed on so they get cleared.
+func TestUpdateProfileClearsFields(t *testing.T) {
+	service, mocks := newTestUserDataService()
+
+	profile := &model.User{ID: 1, FirstName: "John"}
+	mocks.userRepo.On("UpdateUserProfile", profile).Return(nil)
+
+	err := service.UpdateProfile(profile)
+	assert.NoError(t, err)
+	mocks.userRepo.AssertCalled(t, "UpdateUserProfile", profile)
+	mocks.userRepo.AssertNotCalled(t, "UpdateUser", mock.Anything)
 }
 
 // Test FindUserByName: Ensures a user can be found by username.
 func TestFindUserByName(t *testing.T) {
-	mockRepo := new(MockUserRepository)
-	service := NewUserDataService(mockRepo)
+	service, mocks := newTestUserDataService()
 
 	expectedUser := &model.User{
 		ID:        1,
@@ -108,7 +596,7 @@ func TestFindUserByName(t *testing.T) {
 		FirstName: "John",
 	}
 
-	mockRepo.On("FindUserByName", "testuser").Return(expectedUser, nil)
+	mocks.userRepo.On("FindUserByName", "testuser").Return(expectedUser, nil)
 
 	user, err := service.FindUserByName("testuser")
 	assert.NoError(t, err)
@@ -117,8 +605,7 @@ func TestFindUserByName(t *testing.T) {
 
 // Test CheckPwd: Ensures password validation works.
 func TestCheckPwd(t *testing.T) {
-	mockRepo := new(MockUserRepository)
-	service := NewUserDataService(mockRepo)
+	service, mocks := newTestUserDataService()
 
 	// Hash password
 	hashedPwd, _ := GeneratePassword("securepassword")
@@ -129,9 +616,1115 @@ func TestCheckPwd(t *testing.T) {
 		HashPassword: string(hashedPwd),
 	}
 
-	mockRepo.On("FindUserByName", "testuser").Return(expectedUser, nil)
+	mocks.userRepo.On("FindUserByName", "testuser").Return(expectedUser, nil)
 
 	isValid, err := service.CheckPwd("testuser", "securepassword")
 	assert.NoError(t, err)
 	assert.True(t, isValid)
 }
+
+// Test IssueToken: Ensures a valid login returns a signed access token and a stored refresh token.
+func TestIssueToken(t *testing.T) {
+	service, mocks := newTestUserDataService()
+
+	hashedPwd, _ := GeneratePassword("securepassword")
+	expectedUser := &model.User{
+		ID:           1,
+		UserName:     "testuser",
+		HashPassword: string(hashedPwd),
+		Roles:        "customer, admin",
+	}
+
+	mocks.userRepo.On("FindUserByName", "testuser").Return(expectedUser, nil)
+	mocks.roleRepo.On("FindRolesByNames", []string{"customer", "admin"}).Return([]model.Role{
+		{Name: "customer", Permissions: ""},
+		{Name: "admin", Permissions: "*, Category.*"},
+	}, nil)
+	mocks.tokenRepo.On("CreateRefreshToken", mock.AnythingOfType("*model.RefreshToken")).Return(int64(1), nil)
+	mocks.sessionRepo.On("CreateSession", mock.AnythingOfType("*model.Session")).Return(int64(5), nil)
+	mocks.attemptRepo.On("FindLoginAttemptBySubject", mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+	mocks.attemptRepo.On("DeleteLoginAttemptBySubject", mock.Anything).Return(nil)
+
+	tokenPair, err := service.IssueToken("testuser", "securepassword", ClientInfo{Address: "10.0.0.1"})
+	assert.NoError(t, err)
+	assert.Equal(t, TokenType, tokenPair.TokenType)
+	assert.Equal(t, int64(60), tokenPair.ExpiresIn)
+	assert.NotEmpty(t, tokenPair.RefreshToken)
+
+	claims, err := ParseAccessToken(testTokenConfig, tokenPair.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), claims.UserID)
+	assert.Equal(t, "testuser", claims.UserName)
+	assert.Equal(t, []string{"customer", "admin"}, claims.Roles)
+	assert.Equal(t, []string{"*", "Category.*"}, claims.Permissions)
+	assert.Equal(t, int64(5), claims.SessionID)
+
+	// The refresh token must be persisted hashed, never in plain text
+	stored := mocks.tokenRepo.Calls[0].Arguments.Get(0).(*model.RefreshToken)
+	assert.Equal(t, hashToken(tokenPair.RefreshToken), stored.TokenHash)
+	assert.Equal(t, int64(1), stored.UserID)
+	assert.Equal(t, int64(5), stored.SessionID)
+
+	session := mocks.sessionRepo.Calls[0].Arguments.Get(0).(*model.Session)
+	assert.Equal(t, "10.0.0.1", session.IPAddress)
+}
+
+// Test IssueToken: Ensures a wrong password is reported as invalid credentials.
+func TestIssueTokenWrongPassword(t *testing.T) {
+	service, mocks := newTestUserDataService()
+
+	hashedPwd, _ := GeneratePassword("securepassword")
+	mocks.userRepo.On("FindUserByName", "testuser").Return(&model.User{ID: 1, UserName: "testuser", HashPassword: string(hashedPwd)}, nil)
+
+	mocks.attemptRepo.On("FindLoginAttemptBySubject", mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+	mocks.attemptRepo.On("SaveLoginAttempt", mock.AnythingOfType("*model.LoginAttempt")).Return(nil)
+
+	_, err := service.IssueToken("testuser", "wrongpassword", ClientInfo{Address: "10.0.0.1"})
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	mocks.tokenRepo.AssertNotCalled(t, "CreateRefreshToken", mock.Anything)
+
+	// The failure is counted against both the user name and the client address
+	mocks.attemptRepo.AssertNumberOfCalls(t, "SaveLoginAttempt", 2)
+	saved := savedLoginAttempts(mocks.attemptRepo)
+	assert.Equal(t, "user:testuser", saved[0].Subject)
+	assert.Equal(t, "addr:10.0.0.1", saved[1].Subject)
+	assert.Equal(t, 1, saved[0].FailedCount)
+}
+
+// Test RefreshToken: Ensures a refresh token is rotated into a new token pair.
+func TestRefreshToken(t *testing.T) {
+	service, mocks := newTestUserDataService()
+
+	stored := &model.RefreshToken{ID: 7, UserID: 1, TokenHash: hashToken("raw-token"), ExpiresAt: time.Now().Add(time.Hour)}
+	mocks.tokenRepo.On("FindRefreshTokenByHash", hashToken("raw-token")).Return(stored, nil)
+	mocks.tokenRepo.On("RevokeRefreshToken", int64(7)).Return(nil)
+	mocks.tokenRepo.On("CreateRefreshToken", mock.AnythingOfType("*model.RefreshToken")).Return(int64(8), nil)
+	mocks.sessionRepo.On("CreateSession", mock.AnythingOfType("*model.Session")).Return(int64(5), nil)
+	mocks.userRepo.On("FindUserByID", int64(1)).Return(&model.User{ID: 1, UserName: "testuser"}, nil)
+	mocks.roleRepo.On("FindRolesByNames", []string(nil)).Return([]model.Role{}, nil)
+
+	tokenPair, err := service.RefreshToken("raw-token")
+	assert.NoError(t, err)
+	assert.NotEqual(t, "raw-token", tokenPair.RefreshToken)
+	mocks.tokenRepo.AssertCalled(t, "RevokeRefreshToken", int64(7))
+}
+
+// Test RefreshToken: Ensures revoked and expired refresh tokens are rejected.
+func TestRefreshTokenRejected(t *testing.T) {
+	service, mocks := newTestUserDataService()
+
+	mocks.tokenRepo.On("FindRefreshTokenByHash", hashToken("revoked")).Return(&model.RefreshToken{ID: 1, Revoked: true, ExpiresAt: time.Now().Add(time.Hour)}, nil)
+	mocks.tokenRepo.On("FindRefreshTokenByHash", hashToken("expired")).Return(&model.RefreshToken{ID: 2, ExpiresAt: time.Now().Add(-time.Hour)}, nil)
+	mocks.tokenRepo.On("FindRefreshTokenByHash", hashToken("unknown")).Return(nil, errors.New("record not found"))
+
+	_, err := service.RefreshToken("revoked")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	_, err = service.RefreshToken("expired")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	_, err = service.RefreshToken("unknown")
+	assert.Error(t, err)
+}
+
+// Test RevokeToken: Ensures logout revokes the stored refresh token.
+func TestRevokeToken(t *testing.T) {
+	service, mocks := newTestUserDataService()
+
+	stored := &model.RefreshToken{ID: 3, UserID: 1, SessionID: 5, ExpiresAt: time.Now().Add(time.Hour)}
+	mocks.tokenRepo.On("FindRefreshTokenByHash", hashToken("raw-token")).Return(stored, nil)
+	mocks.tokenRepo.On("RevokeRefreshToken", int64(3)).Return(nil)
+	mocks.sessionRepo.On("RevokeSession", int64(5), mock.AnythingOfType("time.Time")).Return(nil)
+
+	err := service.RevokeToken("raw-token")
+	assert.NoError(t, err)
+	mocks.tokenRepo.AssertCalled(t, "RevokeRefreshToken", int64(3))
+	mocks.sessionRepo.AssertCalled(t, "RevokeSession", int64(5), mock.AnythingOfType("time.Time"))
+}
+
+// Test ParseAccessToken: Ensures tampered and expired access tokens are rejected.
+func TestParseAccessTokenRejectsInvalid(t *testing.T) {
+	user := &model.User{ID: 1, UserName: "testuser"}
+
+	token, _ := SignAccessToken(testTokenConfig, user, nil, 0, time.Now())
+	_, err := ParseAccessToken(TokenConfig{Secret: "other-secret"}, token)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	expired, _ := SignAccessToken(testTokenConfig, user, nil, 0, time.Now().Add(-time.Hour))
+	_, err = ParseAccessToken(testTokenConfig, expired)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+// Test AssignRole: Ensures a defined role is appended to the user's roles.
+func TestAssignRole(t *testing.T) {
+	service, mocks := newTestUserDataService()
+
+	mocks.roleRepo.On("FindRoleByName", "merchandiser").Return(&model.Role{Name: "merchandiser"}, nil)
+	mocks.userRepo.On("FindUserByID", int64(1)).Return(&model.User{ID: 1, Roles: "customer"}, nil)
+	mocks.userRepo.On("UpdateUserRoles", int64(1), "customer,merchandiser").Return(nil)
+
+	err := service.AssignRole(1, "merchandiser")
+	assert.NoError(t, err)
+	mocks.userRepo.AssertCalled(t, "UpdateUserRoles", int64(1), "customer,merchandiser")
+}
+
+// Test AssignRole: Ensures unknown roles are rejected.
+func TestAssignUnknownRole(t *testing.T) {
+	service, mocks := newTestUserDataService()
+
+	mocks.roleRepo.On("FindRoleByName", "superuser").Return(nil, gorm.ErrRecordNotFound)
+
+	err := service.AssignRole(1, "superuser")
+	assert.ErrorIs(t, err, ErrRoleNotFound)
+	mocks.userRepo.AssertNotCalled(t, "UpdateUserRoles", mock.Anything, mock.Anything)
+}
+
+// Test RevokeRole: Ensures the role is removed, even when it is the last one.
+func TestRevokeRole(t *testing.T) {
+	service, mocks := newTestUserDataService()
+
+	mocks.roleRepo.On("FindRoleByName", "admin").Return(&model.Role{Name: "admin"}, nil)
+	mocks.userRepo.On("FindUserByID", int64(1)).Return(&model.User{ID: 1, Roles: "admin"}, nil)
+	mocks.userRepo.On("UpdateUserRoles", int64(1), "").Return(nil)
+
+	err := service.RevokeRole(1, "admin")
+	assert.NoError(t, err)
+	mocks.userRepo.AssertCalled(t, "UpdateUserRoles", int64(1), "")
+}
+
+// Test ChangePassword: Ensures the new password is hashed and old refresh tokens are revoked.
+func TestChangePassword(t *testing.T) {
+	service, mocks := newTestUserDataService()
+
+	hashedPwd, _ := GeneratePassword("oldpassword")
+	user := &model.User{ID: 1, UserName: "testuser", HashPassword: string(hashedPwd)}
+	mocks.userRepo.On("FindUserByID", int64(1)).Return(user, nil)
+	mocks.userRepo.On("FindUserByName", "testuser").Return(user, nil)
+	mocks.userRepo.On("UpdateUser", user).Return(nil)
+	mocks.tokenRepo.On("RevokeRefreshTokensByUserID", int64(1)).Return(nil)
+	mocks.sessionRepo.On("RevokeSessionsByUserID", int64(1), mock.AnythingOfType("time.Time")).Return(nil)
+
+	err := service.ChangePassword(1, "oldpassword", "newpassword")
+	assert.NoError(t, err)
+
+	isValid, _ := ValidatePassword("newpassword", user.HashPassword)
+	assert.True(t, isValid)
+	mocks.tokenRepo.AssertCalled(t, "RevokeRefreshTokensByUserID", int64(1))
+}
+
+// Test ChangePassword: Ensures a wrong current password leaves the account untouched.
+func TestChangePasswordWrongCurrent(t *testing.T) {
+	service, mocks := newTestUserDataService()
+
+	hashedPwd, _ := GeneratePassword("oldpassword")
+	user := &model.User{ID: 1, UserName: "testuser", HashPassword: string(hashedPwd)}
+	mocks.userRepo.On("FindUserByID", int64(1)).Return(user, nil)
+	mocks.userRepo.On("FindUserByName", "testuser").Return(user, nil)
+
+	err := service.ChangePassword(1, "wrongpassword", "newpassword")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	mocks.userRepo.AssertNotCalled(t, "UpdateUser", mock.Anything)
+}
+
+// Test DeleteAccount: Ensures the account is deleted once the password is confirmed.
+func TestDeleteAccount(t *testing.T) {
+	service, mocks := newTestUserDataService()
+
+	hashedPwd, _ := GeneratePassword("securepassword")
+	user := &model.User{ID: 1, UserName: "testuser", HashPassword: string(hashedPwd)}
+	mocks.userRepo.On("FindUserByID", int64(1)).Return(user, nil)
+	mocks.userRepo.On("FindUserByName", "testuser").Return(user, nil)
+	mocks.tokenRepo.On("RevokeRefreshTokensByUserID", int64(1)).Return(nil)
+	mocks.sessionRepo.On("RevokeSessionsByUserID", int64(1), mock.AnythingOfType("time.Time")).Return(nil)
+	mocks.userRepo.On("DeleteUserByID", int64(1)).Return(nil)
+
+	err := service.DeleteAccount(1, "securepassword")
+	assert.NoError(t, err)
+	mocks.userRepo.AssertCalled(t, "DeleteUserByID", int64(1))
+}
+
+// Test FindUsers: Ensures page numbers and sizes are normalized into an offset and limit.
+func TestFindUsers(t *testing.T) {
+	service, mocks := newTestUserDataService()
+
+	mocks.userRepo.On("FindPage", 0, DefaultPageSize).Return([]model.User{{ID: 1}}, int64(1), nil)
+	mocks.userRepo.On("FindPage", 200, MaxPageSize).Return([]model.User{}, int64(1), nil)
+
+	users, total, err := service.FindUsers(0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, users, 1)
+	assert.Equal(t, int64(1), total)
+
+	_, _, err = service.FindUsers(3, 1000)
+	assert.NoError(t, err)
+	mocks.userRepo.AssertCalled(t, "FindPage", 200, MaxPageSize)
+}
+
+// Test IssueToken: Ensures a locked account is rejected without checking the password.
+func TestIssueTokenLocked(t *testing.T) {
+	service, mocks := newTestUserDataService()
+
+	mocks.attemptRepo.On("FindLoginAttemptBySubject", "user:testuser").Return(&model.LoginAttempt{
+		Subject:     "user:testuser",
+		FailedCount: 3,
+		LockedUntil: time.Now().Add(time.Minute),
+	}, nil)
+
+	_, err := service.IssueToken("testuser", "securepassword", ClientInfo{})
+	assert.ErrorIs(t, err, ErrAccountLocked)
+	mocks.userRepo.AssertNotCalled(t, "FindUserByName", mock.Anything)
+}
+
+// Test IssueToken: Ensures a retry inside the back-off window is throttled.
+func TestIssueTokenBackoff(t *testing.T) {
+	service, mocks := newTestUserDataService()
+
+	mocks.attemptRepo.On("FindLoginAttemptBySubject", "user:testuser").Return(nil, gorm.ErrRecordNotFound)
+	mocks.attemptRepo.On("FindLoginAttemptBySubject", "addr:10.0.0.1").Return(&model.LoginAttempt{
+		Subject:      "addr:10.0.0.1",
+		FailedCount:  2,
+		LastFailedAt: time.Now(),
+	}, nil)
+
+	_, err := service.IssueToken("testuser", "securepassword", ClientInfo{Address: "10.0.0.1"})
+	assert.ErrorIs(t, err, ErrLoginThrottled)
+}
+
+// Test IssueToken: Ensures reaching the attempt limit locks the user name.
+func TestIssueTokenLocksAfterMaxAttempts(t *testing.T) {
+	service, mocks := newTestUserDataService()
+
+	hashedPwd, _ := GeneratePassword("securepassword")
+	mocks.userRepo.On("FindUserByName", "testuser").Return(&model.User{ID: 1, UserName: "testuser", HashPassword: string(hashedPwd)}, nil)
+	mocks.attemptRepo.On("FindLoginAttemptBySubject", "user:testuser").Return(&model.LoginAttempt{
+		Subject:      "user:testuser",
+		FailedCount:  2,
+		LastFailedAt: time.Now().Add(-10 * time.Second),
+	}, nil)
+	mocks.attemptRepo.On("SaveLoginAttempt", mock.AnythingOfType("*model.LoginAttempt")).Return(nil)
+
+	_, err := service.IssueToken("testuser", "wrongpassword", ClientInfo{})
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	saved := savedLoginAttempts(mocks.attemptRepo)
+	assert.Len(t, saved, 1)
+	assert.Equal(t, 3, saved[0].FailedCount)
+	assert.True(t, saved[0].LockedUntil.After(time.Now()))
+}
+
+// Test IssueToken: Ensures failures older than the lockout window start a fresh series.
+func TestIssueTokenStaleFailuresExpire(t *testing.T) {
+	service, mocks := newTestUserDataService()
+
+	hashedPwd, _ := GeneratePassword("securepassword")
+	mocks.userRepo.On("FindUserByName", "testuser").Return(&model.User{ID: 1, UserName: "testuser", HashPassword: string(hashedPwd)}, nil)
+	mocks.attemptRepo.On("FindLoginAttemptBySubject", "user:testuser").Return(&model.LoginAttempt{
+		Subject:      "user:testuser",
+		FailedCount:  2,
+		LastFailedAt: time.Now().Add(-time.Hour),
+	}, nil)
+	mocks.attemptRepo.On("SaveLoginAttempt", mock.AnythingOfType("*model.LoginAttempt")).Return(nil)
+
+	_, err := service.IssueToken("testuser", "wrongpassword", ClientInfo{})
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	saved := savedLoginAttempts(mocks.attemptRepo)
+	assert.Len(t, saved, 1)
+	assert.Equal(t, 1, saved[0].FailedCount)
+	assert.True(t, saved[0].LockedUntil.IsZero())
+}
+
+// Test IssueToken: Ensures a successful login clears the user name but keeps the failures of the address.
+func TestIssueTokenKeepsAddressFailures(t *testing.T) {
+	service, mocks := newTestUserDataService()
+
+	hashedPwd, _ := GeneratePassword("securepassword")
+	mocks.userRepo.On("FindUserByName", "attacker").Return(&model.User{ID: 1, UserName: "attacker", HashPassword: string(hashedPwd)}, nil)
+	mocks.roleRepo.On("FindRolesByNames", mock.Anything).Return([]model.Role{}, nil)
+	mocks.tokenRepo.On("CreateRefreshToken", mock.AnythingOfType("*model.RefreshToken")).Return(int64(1), nil)
+	mocks.sessionRepo.On("CreateSession", mock.AnythingOfType("*model.Session")).Return(int64(1), nil)
+	mocks.attemptRepo.On("FindLoginAttemptBySubject", "user:attacker").Return(nil, gorm.ErrRecordNotFound)
+	mocks.attemptRepo.On("FindLoginAttemptBySubject", "addr:10.0.0.1").Return(&model.LoginAttempt{
+		Subject:      "addr:10.0.0.1",
+		FailedCount:  5,
+		LastFailedAt: time.Now().Add(-10 * time.Second),
+	}, nil)
+	mocks.attemptRepo.On("DeleteLoginAttemptBySubject", "user:attacker").Return(nil)
+
+	_, err := service.IssueToken("attacker", "securepassword", ClientInfo{Address: "10.0.0.1"})
+	assert.NoError(t, err)
+	mocks.attemptRepo.AssertCalled(t, "DeleteLoginAttemptBySubject", "user:attacker")
+	mocks.attemptRepo.AssertNotCalled(t, "DeleteLoginAttemptBySubject", "addr:10.0.0.1")
+}
+
+// Test backoff: Ensures the delay doubles per failure and is capped.
+func TestBackoff(t *testing.T) {
+	service := &UserDataService{LockoutConfig: testLockoutConfig}
+
+	assert.Equal(t, time.Second, service.backoff(1))
+	assert.Equal(t, 2*time.Second, service.backoff(2))
+	assert.Equal(t, 4*time.Second, service.backoff(3))
+	assert.Equal(t, 4*time.Second, service.backoff(10))
+}
+
+// Test UnlockUser: Ensures the lockout of the user name is cleared.
+func TestUnlockUser(t *testing.T) {
+	service, mocks := newTestUserDataService()
+
+	mocks.userRepo.On("FindUserByID", int64(1)).Return(&model.User{ID: 1, UserName: "testuser"}, nil)
+	mocks.attemptRepo.On("DeleteLoginAttemptBySubject", "user:testuser").Return(nil)
+
+	err := service.UnlockUser(1)
+	assert.NoError(t, err)
+	mocks.attemptRepo.AssertCalled(t, "DeleteLoginAttemptBySubject", "user:testuser")
+}
+
+// Test RequestPasswordReset: Ensures a hashed token is stored and the raw token is sent to the user.
+func TestRequestPasswordReset(t *testing.T) {
+	service, mocks := newTestUserDataService()
+
+	mocks.userRepo.On("FindUserByName", "testuser").Return(&model.User{ID: 1, UserName: "testuser"}, nil)
+	mocks.resetRepo.On("DeletePasswordResetTokensByUserID", int64(1)).Return(nil)
+	mocks.resetRepo.On("CreatePasswordResetToken", mock.AnythingOfType("*model.PasswordResetToken")).Return(int64(1), nil)
+	mocks.notifier.On("Send", mock.AnythingOfType("notify.Message")).Return(nil)
+
+	err := service.RequestPasswordReset("testuser")
+	assert.NoError(t, err)
+
+	stored := mocks.resetRepo.Calls[1].Arguments.Get(0).(*model.PasswordResetToken)
+	message := mocks.notifier.Calls[0].Arguments.Get(0).(notify.Message)
+	assert.Equal(t, "testuser", message.To)
+	assert.NotContains(t, message.Body, stored.TokenHash)
+	assert.True(t, stored.ExpiresAt.After(time.Now()))
+}
+
+// Test RequestPasswordReset: Ensures unknown users are silently ignored.
+func TestRequestPasswordResetUnknownUser(t *testing.T) {
+	service, mocks := newTestUserDataService()
+
+	mocks.userRepo.On("FindUserByName", "nobody").Return((*model.User)(nil), gorm.ErrRecordNotFound)
+
+	err := service.RequestPasswordReset("nobody")
+	assert.NoError(t, err)
+	mocks.notifier.AssertNotCalled(t, "Send", mock.Anything)
+}
+
+// Test ConfirmPasswordReset: Ensures the token is consumed and the new password is hashed.
+func TestConfirmPasswordReset(t *testing.T) {
+	service, mocks := newTestUserDataService()
+
+	user := &model.User{ID: 1, UserName: "testuser"}
+	mocks.resetRepo.On("FindPasswordResetTokenByHash", hashToken("reset-token")).Return(&model.PasswordResetToken{
+		ID: 5, UserID: 1, ExpiresAt: time.Now().Add(time.Hour),
+	}, nil)
+	mocks.userRepo.On("FindUserByID", int64(1)).Return(user, nil)
+	mocks.resetRepo.On("MarkPasswordResetTokenUsed", int64(5), mock.AnythingOfType("time.Time")).Return(nil)
+	mocks.userRepo.On("UpdateUser", user).Return(nil)
+	mocks.tokenRepo.On("RevokeRefreshTokensByUserID", int64(1)).Return(nil)
+	mocks.sessionRepo.On("RevokeSessionsByUserID", int64(1), mock.AnythingOfType("time.Time")).Return(nil)
+	mocks.attemptRepo.On("DeleteLoginAttemptBySubject", "user:testuser").Return(nil)
+
+	err := service.ConfirmPasswordReset("reset-token", "newpassword")
+	assert.NoError(t, err)
+
+	isValid, _ := ValidatePassword("newpassword", user.HashPassword)
+	assert.True(t, isValid)
+	mocks.tokenRepo.AssertCalled(t, "RevokeRefreshTokensByUserID", int64(1))
+}
+
+// Test ConfirmPasswordReset: Ensures used and expired tokens are rejected.
+func TestConfirmPasswordResetRejected(t *testing.T) {
+	service, mocks := newTestUserDataService()
+
+	usedAt := time.Now().Add(-time.Minute)
+	mocks.resetRepo.On("FindPasswordResetTokenByHash", hashToken("used")).Return(&model.PasswordResetToken{
+		ID: 1, UserID: 1, ExpiresAt: time.Now().Add(time.Hour), UsedAt: &usedAt,
+	}, nil)
+	mocks.resetRepo.On("FindPasswordResetTokenByHash", hashToken("expired")).Return(&model.PasswordResetToken{
+		ID: 2, UserID: 1, ExpiresAt: time.Now().Add(-time.Hour),
+	}, nil)
+
+	assert.ErrorIs(t, service.ConfirmPasswordReset("used", "newpassword"), ErrInvalidToken)
+	assert.ErrorIs(t, service.ConfirmPasswordReset("expired", "newpassword"), ErrInvalidToken)
+	mocks.userRepo.AssertNotCalled(t, "UpdateUser", mock.Anything)
+}
+
+// Test IssueToken: Ensures unverified users are refused while verified emails are required.
+func TestIssueTokenEmailNotVerified(t *testing.T) {
+	service, mocks := newTestUserDataService()
+	service.(*UserDataService).TokenConfig.RequireVerifiedEmail = true
+
+	hashedPwd, _ := GeneratePassword("securepassword")
+	mocks.userRepo.On("FindUserByName", "testuser").Return(&model.User{
+		ID: 1, UserName: "testuser", HashPassword: string(hashedPwd),
+	}, nil)
+	mocks.attemptRepo.On("FindLoginAttemptBySubject", mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+	mocks.attemptRepo.On("DeleteLoginAttemptBySubject", mock.Anything).Return(nil)
+
+	_, err := service.IssueToken("testuser", "securepassword", ClientInfo{})
+	assert.ErrorIs(t, err, ErrEmailNotVerified)
+	mocks.tokenRepo.AssertNotCalled(t, "CreateRefreshToken", mock.Anything)
+}
+
+// Test VerifyEmail: Ensures a valid token marks the email verified and is discarded.
+func TestVerifyEmail(t *testing.T) {
+	service, mocks := newTestUserDataService()
+
+	mocks.verifyRepo.On("FindEmailVerificationTokenByHash", hashToken("verify-token")).Return(&model.EmailVerificationToken{
+		ID: 3, UserID: 1, ExpiresAt: time.Now().Add(time.Hour),
+	}, nil)
+	mocks.userRepo.On("MarkEmailVerified", int64(1)).Return(nil)
+	mocks.verifyRepo.On("DeleteEmailVerificationTokensByUserID", int64(1)).Return(nil)
+
+	err := service.VerifyEmail("verify-token")
+	assert.NoError(t, err)
+	mocks.userRepo.AssertCalled(t, "MarkEmailVerified", int64(1))
+	mocks.verifyRepo.AssertCalled(t, "DeleteEmailVerificationTokensByUserID", int64(1))
+}
+
+// Test VerifyEmail: Ensures unknown and expired tokens are rejected.
+func TestVerifyEmailRejected(t *testing.T) {
+	service, mocks := newTestUserDataService()
+
+	mocks.verifyRepo.On("FindEmailVerificationTokenByHash", hashToken("unknown")).Return(nil, gorm.ErrRecordNotFound)
+	mocks.verifyRepo.On("FindEmailVerificationTokenByHash", hashToken("expired")).Return(&model.EmailVerificationToken{
+		ID: 3, UserID: 1, ExpiresAt: time.Now().Add(-time.Hour),
+	}, nil)
+
+	assert.ErrorIs(t, service.VerifyEmail("unknown"), ErrInvalidToken)
+	assert.ErrorIs(t, service.VerifyEmail("expired"), ErrInvalidToken)
+	mocks.userRepo.AssertNotCalled(t, "MarkEmailVerified", mock.Anything)
+}
+
+// Test PasswordPolicy.Validate: Ensures every broken rule is reported.
+func TestPasswordPolicyValidate(t *testing.T) {
+	policy := PasswordPolicy{
+		MinLength:     10,
+		RequireUpper:  true,
+		RequireLower:  true,
+		RequireDigit:  true,
+		RequireSymbol: true,
+		DenyList:      CommonPasswords,
+	}
+
+	assert.NoError(t, policy.Validate("testuser", "Str0ng-Passw0rd"))
+
+	var policyErr *PasswordPolicyError
+	assert.ErrorAs(t, policy.Validate("testuser", "password"), &policyErr)
+	var rules []string
+	for _, violation := range policyErr.Violations {
+		rules = append(rules, violation.Rule)
+	}
+	assert.Equal(t, []string{"min_length", "upper", "digit", "symbol", "common"}, rules)
+
+	assert.ErrorAs(t, policy.Validate("Str0ng-Passw0rd", "str0ng-passw0rd"), &policyErr)
+	assert.Equal(t, "username", policyErr.Violations[len(policyErr.Violations)-1].Rule)
+}
+
+// Test AddUser and ChangePassword: Ensures passwords breaking the policy are rejected before hashing.
+func TestWeakPasswordRejected(t *testing.T) {
+	service, mocks := newTestUserDataService()
+
+	_, err := service.AddUser(&model.User{UserName: "testuser", HashPassword: "qwerty"})
+	var policyErr *PasswordPolicyError
+	assert.ErrorAs(t, err, &policyErr)
+	mocks.userRepo.AssertNotCalled(t, "CreateUser", mock.Anything)
+
+	hashedPwd, _ := GeneratePassword("oldpassword")
+	user := &model.User{ID: 1, UserName: "testuser", HashPassword: string(hashedPwd)}
+	mocks.userRepo.On("FindUserByID", int64(1)).Return(user, nil)
+	mocks.userRepo.On("FindUserByName", "testuser").Return(user, nil)
+
+	err = service.ChangePassword(1, "oldpassword", "testuser")
+	assert.ErrorAs(t, err, &policyErr)
+	mocks.userRepo.AssertNotCalled(t, "UpdateUser", mock.Anything)
+}
+
+// Test IssueToken: Ensures a hash with an outdated bcrypt cost is upgraded after a successful login.
+func TestIssueTokenUpgradesHash(t *testing.T) {
+	service, mocks := newTestUserDataService()
+
+	hashedPwd, _ := GeneratePasswordWithCost("securepassword", bcrypt.MinCost)
+	user := &model.User{ID: 1, UserName: "testuser", HashPassword: string(hashedPwd)}
+
+	mocks.userRepo.On("FindUserByName", "testuser").Return(user, nil)
+	mocks.userRepo.On("UpdateUser", mock.AnythingOfType("*model.User")).Return(nil)
+	mocks.roleRepo.On("FindRolesByNames", mock.Anything).Return([]model.Role{}, nil)
+	mocks.tokenRepo.On("CreateRefreshToken", mock.AnythingOfType("*model.RefreshToken")).Return(int64(1), nil)
+	mocks.sessionRepo.On("CreateSession", mock.AnythingOfType("*model.Session")).Return(int64(5), nil)
+	mocks.attemptRepo.On("FindLoginAttemptBySubject", mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+	mocks.attemptRepo.On("DeleteLoginAttemptBySubject", mock.Anything).Return(nil)
+
+	_, err := service.IssueToken("testuser", "securepassword", ClientInfo{})
+	assert.NoError(t, err)
+
+	var upgraded *model.User
+	for _, call := range mocks.userRepo.Calls {
+		if call.Method == "UpdateUser" {
+			upgraded = call.Arguments.Get(0).(*model.User)
+		}
+	}
+	if assert.NotNil(t, upgraded) {
+		cost, _ := bcrypt.Cost([]byte(upgraded.HashPassword))
+		assert.Equal(t, bcrypt.DefaultCost, cost)
+		isValid, _ := ValidatePassword("securepassword", upgraded.HashPassword)
+		assert.True(t, isValid)
+	}
+}
+
+// Test totpCode: Ensures codes match the RFC 6238 SHA1 test vectors.
+func TestTOTPCode(t *testing.T) {
+	key := []byte("12345678901234567890")
+	assert.Equal(t, "287082", totpCode(key, 59/totpPeriod))
+	assert.Equal(t, "081804", totpCode(key, 1111111109/totpPeriod))
+}
+
+// newTOTPUser returns a user with TOTP enabled and the raw key to compute codes with.
+func newTOTPUser(t *testing.T, service IUserDataService) (*model.User, []byte) {
+	key := []byte("12345678901234567890")
+	encrypted, err := service.(*UserDataService).encryptSecret(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(key))
+	assert.NoError(t, err)
+
+	hashedPwd, _ := GeneratePassword("securepassword")
+	return &model.User{
+		ID:            1,
+		UserName:      "testuser",
+		HashPassword:  string(hashedPwd),
+		TOTPSecret:    encrypted,
+		TOTPEnabled:   true,
+		RecoveryCodes: hashToken("0123456789") + "," + hashToken("abcdefabcd"),
+	}, key
+}
+
+// Test EnrollTOTP and ConfirmTOTP: Ensures the secret is stored encrypted and confirmation enables TOTP.
+func TestEnrollAndConfirmTOTP(t *testing.T) {
+	service, mocks := newTestUserDataService()
+
+	user := &model.User{ID: 1, UserName: "testuser"}
+	mocks.userRepo.On("FindUserByID", int64(1)).Return(user, nil)
+	mocks.userRepo.On("UpdateUserMFA", user).Return(nil)
+	mocks.tokenRepo.On("RevokeRefreshTokensByUserID", int64(1)).Return(nil)
+	mocks.sessionRepo.On("RevokeSessionsByUserID", int64(1), mock.AnythingOfType("time.Time")).Return(nil)
+
+	enrollment, err := service.EnrollTOTP(1)
+	assert.NoError(t, err)
+	assert.NotContains(t, user.TOTPSecret, enrollment.Secret)
+	assert.Contains(t, enrollment.ProvisioningURI, "otpauth://totp/Test:testuser?")
+	assert.False(t, user.TOTPEnabled)
+
+	key, _ := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(enrollment.Secret)
+	_, err = service.ConfirmTOTP(1, "000000x")
+	assert.ErrorIs(t, err, ErrInvalidMFACode)
+
+	recoveryCodes, err := service.ConfirmTOTP(1, totpCode(key, time.Now().Unix()/totpPeriod))
+	assert.NoError(t, err)
+	assert.Len(t, recoveryCodes, recoveryCodeCount)
+	assert.True(t, user.TOTPEnabled)
+	assert.Len(t, SplitList(user.RecoveryCodes), recoveryCodeCount)
+	mocks.sessionRepo.AssertCalled(t, "RevokeSessionsByUserID", int64(1), mock.AnythingOfType("time.Time"))
+
+	_, err = service.EnrollTOTP(1)
+	assert.ErrorIs(t, err, ErrMFAAlreadyEnabled)
+}
+
+// Test IssueToken and VerifyMFA: Ensures TOTP users get a challenge that is completed with a single-use code.
+func TestLoginWithMFA(t *testing.T) {
+	service, mocks := newTestUserDataService()
+	user, key := newTOTPUser(t, service)
+
+	mocks.userRepo.On("FindUserByName", "testuser").Return(user, nil)
+	mocks.userRepo.On("FindUserByID", int64(1)).Return(user, nil)
+	mocks.userRepo.On("UpdateUserMFA", user).Return(nil)
+	mocks.roleRepo.On("FindRolesByNames", mock.Anything).Return([]model.Role{}, nil)
+	mocks.tokenRepo.On("CreateRefreshToken", mock.AnythingOfType("*model.RefreshToken")).Return(int64(1), nil)
+	mocks.sessionRepo.On("CreateSession", mock.AnythingOfType("*model.Session")).Return(int64(5), nil)
+	mocks.attemptRepo.On("FindLoginAttemptBySubject", mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+	mocks.attemptRepo.On("DeleteLoginAttemptBySubject", mock.Anything).Return(nil)
+	mocks.attemptRepo.On("SaveLoginAttempt", mock.Anything).Return(nil)
+
+	challenge, err := service.IssueToken("testuser", "securepassword", ClientInfo{})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, challenge.MFAChallenge)
+	assert.Empty(t, challenge.AccessToken)
+	mocks.tokenRepo.AssertNotCalled(t, "CreateRefreshToken", mock.Anything)
+
+	// The challenge must not be usable as an access token
+	_, err = ParseAccessToken(testTokenConfig, challenge.MFAChallenge)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	code := totpCode(key, time.Now().Unix()/totpPeriod)
+	tokenPair, err := service.VerifyMFA(challenge.MFAChallenge, code, ClientInfo{})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, tokenPair.AccessToken)
+
+	// A code cannot be replayed
+	_, err = service.VerifyMFA(challenge.MFAChallenge, code, ClientInfo{})
+	assert.ErrorIs(t, err, ErrInvalidMFACode)
+	assert.Len(t, savedLoginAttempts(mocks.attemptRepo), 1)
+
+	// Recovery codes work once
+	_, err = service.VerifyMFA(challenge.MFAChallenge, "ABCDEFABCD", ClientInfo{})
+	assert.NoError(t, err)
+	assert.Equal(t, hashToken("0123456789"), user.RecoveryCodes)
+
+	_, err = service.VerifyMFA("not-a-challenge", code, ClientInfo{})
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+// Test IssueToken: Ensures users without TOTP are not granted permissions of endpoints requiring a second factor.
+func TestIssueTokenWithholdsMFAPermissions(t *testing.T) {
+	service, mocks := newTestUserDataService()
+	service.(*UserDataService).MFAConfig.RequiredFor = []string{"Category.DeleteCategory"}
+
+	hashedPwd, _ := GeneratePassword("securepassword")
+	user := &model.User{ID: 1, UserName: "admin", HashPassword: string(hashedPwd), Roles: "admin, editor"}
+	mocks.userRepo.On("FindUserByName", "admin").Return(user, nil)
+	mocks.roleRepo.On("FindRolesByNames", []string{"admin", "editor"}).Return([]model.Role{
+		{Name: "admin", Permissions: "*, Category.*, Category.DeleteCategory"},
+		{Name: "editor", Permissions: "Category.UpdateCategory, User.UnlockUser"},
+	}, nil)
+	mocks.tokenRepo.On("CreateRefreshToken", mock.AnythingOfType("*model.RefreshToken")).Return(int64(1), nil)
+	mocks.sessionRepo.On("CreateSession", mock.AnythingOfType("*model.Session")).Return(int64(5), nil)
+	mocks.attemptRepo.On("FindLoginAttemptBySubject", mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+	mocks.attemptRepo.On("DeleteLoginAttemptBySubject", mock.Anything).Return(nil)
+
+	tokenPair, err := service.IssueToken("admin", "securepassword", ClientInfo{})
+	assert.NoError(t, err)
+	claims, err := ParseAccessToken(testTokenConfig, tokenPair.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Category.UpdateCategory", "User.UnlockUser"}, claims.Permissions)
+
+	// With TOTP enabled every permission is granted once the challenge is completed
+	permissions := service.(*UserDataService).withholdMFAPermissions(&model.User{TOTPEnabled: true}, []string{"*", "Category.*"})
+	assert.Equal(t, []string{"*", "Category.*"}, permissions)
+}
+
+// Test DisableTOTP: Ensures the password and a code are required and the secret is cleared.
+func TestDisableTOTP(t *testing.T) {
+	service, mocks := newTestUserDataService()
+	user, key := newTOTPUser(t, service)
+
+	mocks.userRepo.On("FindUserByID", int64(1)).Return(user, nil)
+	mocks.userRepo.On("FindUserByName", "testuser").Return(user, nil)
+	mocks.userRepo.On("UpdateUserMFA", user).Return(nil)
+
+	err := service.DisableTOTP(1, "wrongpassword", totpCode(key, time.Now().Unix()/totpPeriod))
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	err = service.DisableTOTP(1, "securepassword", totpCode(key, time.Now().Unix()/totpPeriod))
+	assert.NoError(t, err)
+	assert.False(t, user.TOTPEnabled)
+	assert.Empty(t, user.TOTPSecret)
+	assert.Empty(t, user.RecoveryCodes)
+}
+
+// Test RefreshToken: Ensures rotation stays in the session and extends it.
+func TestRefreshTokenKeepsSession(t *testing.T) {
+	service, mocks := newTestUserDataService()
+
+	stored := &model.RefreshToken{ID: 7, UserID: 1, SessionID: 5, ExpiresAt: time.Now().Add(time.Hour)}
+	mocks.tokenRepo.On("FindRefreshTokenByHash", hashToken("raw-token")).Return(stored, nil)
+	mocks.tokenRepo.On("RevokeRefreshToken", int64(7)).Return(nil)
+	mocks.tokenRepo.On("CreateRefreshToken", mock.AnythingOfType("*model.RefreshToken")).Return(int64(8), nil)
+	mocks.userRepo.On("FindUserByID", int64(1)).Return(&model.User{ID: 1, UserName: "testuser"}, nil)
+	mocks.roleRepo.On("FindRolesByNames", []string(nil)).Return([]model.Role{}, nil)
+	mocks.sessionRepo.On("FindSessionByID", int64(5)).Return(&model.Session{ID: 5, UserID: 1, ExpiresAt: time.Now().Add(time.Hour)}, nil)
+	mocks.sessionRepo.On("TouchSession", int64(5), mock.AnythingOfType("time.Time"), mock.AnythingOfType("time.Time")).Return(nil)
+
+	tokenPair, err := service.RefreshToken("raw-token")
+	assert.NoError(t, err)
+	mocks.sessionRepo.AssertNotCalled(t, "CreateSession", mock.Anything)
+
+	claims, _ := ParseAccessToken(testTokenConfig, tokenPair.AccessToken)
+	assert.Equal(t, int64(5), claims.SessionID)
+	rotated := mocks.tokenRepo.Calls[2].Arguments.Get(0).(*model.RefreshToken)
+	assert.Equal(t, int64(5), rotated.SessionID)
+}
+
+// Test RefreshToken: Ensures refresh tokens of a revoked session are rejected.
+func TestRefreshTokenRevokedSession(t *testing.T) {
+	service, mocks := newTestUserDataService()
+
+	revokedAt := time.Now()
+	stored := &model.RefreshToken{ID: 7, UserID: 1, SessionID: 5, ExpiresAt: time.Now().Add(time.Hour)}
+	mocks.tokenRepo.On("FindRefreshTokenByHash", hashToken("raw-token")).Return(stored, nil)
+	mocks.tokenRepo.On("RevokeRefreshToken", int64(7)).Return(nil)
+	mocks.userRepo.On("FindUserByID", int64(1)).Return(&model.User{ID: 1, UserName: "testuser"}, nil)
+	mocks.sessionRepo.On("FindSessionByID", int64(5)).Return(&model.Session{
+		ID: 5, UserID: 1, ExpiresAt: time.Now().Add(time.Hour), RevokedAt: &revokedAt,
+	}, nil)
+
+	_, err := service.RefreshToken("raw-token")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	mocks.tokenRepo.AssertNotCalled(t, "CreateRefreshToken", mock.Anything)
+}
+
+// Test RevokeSession: Ensures users can only revoke their own sessions.
+func TestRevokeSession(t *testing.T) {
+	service, mocks := newTestUserDataService()
+
+	mocks.sessionRepo.On("FindSessionByID", int64(5)).Return(&model.Session{ID: 5, UserID: 1}, nil)
+	mocks.sessionRepo.On("FindSessionByID", int64(6)).Return(&model.Session{ID: 6, UserID: 2}, nil)
+	mocks.sessionRepo.On("RevokeSession", int64(5), mock.AnythingOfType("time.Time")).Return(nil)
+
+	assert.NoError(t, service.RevokeSession(1, 5))
+	assert.ErrorIs(t, service.RevokeSession(1, 6), ErrSessionNotFound)
+	mocks.sessionRepo.AssertNotCalled(t, "RevokeSession", int64(6), mock.Anything)
+}
+
+// Test RevokeAllSessions: Ensures the kept session survives while the others are revoked.
+func TestRevokeAllSessionsKeepsCurrent(t *testing.T) {
+	service, mocks := newTestUserDataService()
+
+	mocks.sessionRepo.On("FindActiveSessionsByUserID", int64(1), mock.AnythingOfType("time.Time")).Return([]model.Session{
+		{ID: 5, UserID: 1}, {ID: 6, UserID: 1}, {ID: 7, UserID: 1},
+	}, nil)
+	mocks.sessionRepo.On("RevokeSession", mock.Anything, mock.AnythingOfType("time.Time")).Return(nil)
+
+	err := service.RevokeAllSessions(1, 6)
+	assert.NoError(t, err)
+	mocks.sessionRepo.AssertCalled(t, "RevokeSession", int64(5), mock.AnythingOfType("time.Time"))
+	mocks.sessionRepo.AssertCalled(t, "RevokeSession", int64(7), mock.AnythingOfType("time.Time"))
+	mocks.sessionRepo.AssertNotCalled(t, "RevokeSession", int64(6), mock.Anything)
+}
+
+// Test ValidateSession: Ensures access tokens of revoked sessions are reported inactive.
+func TestValidateSession(t *testing.T) {
+	service, mocks := newTestUserDataService()
+
+	user := &model.User{ID: 1, UserName: "testuser"}
+	revokedAt := time.Now()
+	mocks.sessionRepo.On("FindSessionByID", int64(5)).Return(&model.Session{
+		ID: 5, UserID: 1, LastSeenAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour),
+	}, nil)
+	mocks.sessionRepo.On("FindSessionByID", int64(6)).Return(&model.Session{
+		ID: 6, UserID: 1, LastSeenAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour), RevokedAt: &revokedAt,
+	}, nil)
+
+	active, _ := SignAccessToken(testTokenConfig, user, nil, 5, time.Now())
+	revoked, _ := SignAccessToken(testTokenConfig, user, nil, 6, time.Now())
+
+	ok, err := service.ValidateSession(active)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = service.ValidateSession(revoked)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = service.ValidateSession("not-a-token")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+// Test AddAddress: Ensures the first address of a user becomes the default for shipping and billing.
+func TestAddAddressFirstBecomesDefault(t *testing.T) {
+	service, mocks := newTestUserDataService()
+
+	address := &model.Address{UserID: 1, Recipient: "John Doe", Line1: "1 Main St", City: "Springfield",
+		PostalCode: "12345", Country: "us"}
+
+	mocks.addressRepo.On("FindAddressesByUserID", int64(1)).Return([]model.Address{}, nil)
+	mocks.addressRepo.On("CreateAddress", address).Return(int64(3), nil)
+	mocks.addressRepo.On("SetDefaultAddress", int64(1), int64(3), true, true).Return(nil)
+
+	addressID, err := service.AddAddress(address)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), addressID)
+	assert.Equal(t, "US", address.Country)
+	assert.True(t, address.IsDefaultShipping)
+	assert.True(t, address.IsDefaultBilling)
+}
+
+// Test AddAddress: Ensures every invalid field is reported and nothing is stored.
+func TestAddAddressValidation(t *testing.T) {
+	service, mocks := newTestUserDataService()
+
+	_, err := service.AddAddress(&model.Address{UserID: 1, Line1: "1 Main St", City: "Springfield",
+		PostalCode: "1234", Country: "US", Phone: "call me"})
+
+	var validationErr *AddressValidationError
+	assert.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, []AddressViolation{
+		{"recipient", "is required"},
+		{"postal_code", "is not a valid postal code for US"},
+		{"phone", "is not a valid phone number"},
+	}, validationErr.Violations)
+	mocks.addressRepo.AssertNotCalled(t, "CreateAddress", mock.Anything)
+}
+
+// Test ValidateAddress: Ensures postal codes follow the format of their country.
+func TestValidateAddressPostalCodes(t *testing.T) {
+	valid := func(country string, postalCode string) bool {
+		return ValidateAddress(&model.Address{Recipient: "John Doe", Line1: "1 Main St", City: "City",
+			Country: country, PostalCode: postalCode}) == nil
+	}
+
+	assert.True(t, valid("US", "12345-6789"))
+	assert.True(t, valid("gb", "sw1a 1aa"))
+	assert.True(t, valid("CA", "K1A 0B1"))
+	assert.True(t, valid("HK", ""))
+	assert.False(t, valid("DE", "1234"))
+	assert.False(t, valid("XX", "12345"))
+}
+
+// Test UpdateAddress: Ensures addresses of other users cannot be changed.
+func TestUpdateAddressOtherUser(t *testing.T) {
+	service, mocks := newTestUserDataService()
+
+	mocks.addressRepo.On("FindAddressByID", int64(3)).Return(&model.Address{ID: 3, UserID: 2}, nil)
+
+	err := service.UpdateAddress(1, &model.Address{ID: 3, Recipient: "John Doe", Line1: "1 Main St",
+		City: "Springfield", PostalCode: "12345", Country: "US"})
+	assert.ErrorIs(t, err, ErrAddressNotFound)
+	mocks.addressRepo.AssertNotCalled(t, "UpdateAddress", mock.Anything)
+}
+
+// Test UpdateAddress: Ensures the default flags of the stored address are kept.
+func TestUpdateAddressKeepsDefaults(t *testing.T) {
+	service, mocks := newTestUserDataService()
+
+	mocks.addressRepo.On("FindAddressByID", int64(3)).
+		Return(&model.Address{ID: 3, UserID: 1, IsDefaultShipping: true}, nil)
+	mocks.addressRepo.On("UpdateAddress", mock.AnythingOfType("*model.Address")).Return(nil)
+
+	address := &model.Address{ID: 3, Recipient: "John Doe", Line1: "2 Main St", City: "Springfield",
+		PostalCode: "12345", Country: "US", IsDefaultBilling: true}
+	assert.NoError(t, service.UpdateAddress(1, address))
+	assert.Equal(t, int64(1), address.UserID)
+	assert.True(t, address.IsDefaultShipping)
+	assert.False(t, address.IsDefaultBilling)
+}
+
+// Test SetDefaultAddress: Ensures a missing address is reported as not found.
+func TestSetDefaultAddressNotFound(t *testing.T) {
+	service, mocks := newTestUserDataService()
+
+	mocks.addressRepo.On("FindAddressByID", int64(3)).Return(nil, gorm.ErrRecordNotFound)
+
+	err := service.SetDefaultAddress(1, 3, true, false)
+	assert.ErrorIs(t, err, ErrAddressNotFound)
+}
+
+// Test ExportUserData: Ensures the export carries the profile without secrets and records the export.
+func TestExportUserData(t *testing.T) {
+	service, mocks := newTestUserDataService()
+
+	user := &model.User{ID: 1, UserName: "testuser", Email: "john@example.com", HashPassword: "hash",
+		TOTPSecret: "secret", Roles: "customer"}
+	mocks.userRepo.On("FindUserByID", int64(1)).Return(user, nil)
+	mocks.addressRepo.On("FindAddressesByUserID", int64(1)).Return([]model.Address{{ID: 3, City: "Springfield"}}, nil)
+	mocks.sessionRepo.On("FindSessionsByUserID", int64(1)).Return([]model.Session{{ID: 5, IPAddress: "10.0.0.1"}}, nil)
+	mocks.auditRepo.On("FindAuditEntriesByUserID", int64(1)).Return([]model.AuditEntry{{Action: AuditPasswordChanged}}, nil)
+	mocks.linkRepo.On("FindLinkedIdentitiesByUserID", int64(1)).Return([]model.LinkedIdentity{{Provider: "fake", Subject: "42"}}, nil)
+
+	export, err := service.ExportUserData(1, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, "john@example.com", export.Profile.Email)
+	assert.Equal(t, []string{"customer"}, export.Profile.Roles)
+	assert.Equal(t, "Springfield", export.Addresses[0].City)
+	assert.Equal(t, "10.0.0.1", export.Sessions[0].IPAddress)
+	assert.Equal(t, AuditPasswordChanged, export.AuditEntries[0].Action)
+	assert.Equal(t, "42", export.LinkedIdentities[0].Subject)
+
+	encoded, err := json.Marshal(export)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(encoded), "secret")
+
+	entry := mocks.auditRepo.Calls[1].Arguments.Get(0).(*model.AuditEntry)
+	assert.Equal(t, AuditDataExported, entry.Action)
+	assert.Equal(t, int64(2), entry.ActorID)
+}
+
+// Test EraseUser: Ensures personal data is removed while the user row is kept and the erasure is audited.
+func TestEraseUser(t *testing.T) {
+	service, mocks := newTestUserDataService()
+
+	mocks.userRepo.On("FindUserByID", int64(1)).Return(&model.User{ID: 1, UserName: "testuser"}, nil)
+	mocks.tokenRepo.On("RevokeRefreshTokensByUserID", int64(1)).Return(nil)
+	mocks.sessionRepo.On("RevokeSessionsByUserID", int64(1), mock.AnythingOfType("time.Time")).Return(nil)
+	mocks.sessionRepo.On("AnonymizeSessionsByUserID", int64(1)).Return(nil)
+	mocks.addressRepo.On("DeleteAddressesByUserID", int64(1)).Return(nil)
+	mocks.linkRepo.On("DeleteLinkedIdentitiesByUserID", int64(1)).Return(nil)
+	mocks.resetRepo.On("DeletePasswordResetTokensByUserID", int64(1)).Return(nil)
+	mocks.verifyRepo.On("DeleteEmailVerificationTokensByUserID", int64(1)).Return(nil)
+	mocks.attemptRepo.On("DeleteLoginAttemptBySubject", userSubject("testuser")).Return(nil)
+	mocks.userRepo.On("AnonymizeUser", int64(1), mock.AnythingOfType("time.Time")).Return(nil)
+
+	err := service.EraseUser(1, 2, "customer request")
+	assert.NoError(t, err)
+	mocks.userRepo.AssertNotCalled(t, "DeleteUserByID", mock.Anything)
+
+	entry := mocks.auditRepo.Calls[0].Arguments.Get(0).(*model.AuditEntry)
+	assert.Equal(t, AuditUserErased, entry.Action)
+	assert.Equal(t, "customer request", entry.Detail)
+}
+
+// Test EraseUser: Ensures an erased user cannot be erased again.
+func TestEraseUserAlreadyErased(t *testing.T) {
+	service, mocks := newTestUserDataService()
+
+	erasedAt := time.Now()
+	mocks.userRepo.On("FindUserByID", int64(1)).Return(&model.User{ID: 1, ErasedAt: &erasedAt}, nil)
+
+	err := service.EraseUser(1, 2, "")
+	assert.ErrorIs(t, err, ErrUserErased)
+	mocks.userRepo.AssertNotCalled(t, "AnonymizeUser", mock.Anything, mock.Anything)
+}
+
+// Test CompleteExternalLogin: Ensures an unknown identity creates a passwordless user and signs them in.
+func TestCompleteExternalLoginCreatesUser(t *testing.T) {
+	service, mocks := newTestUserDataService()
+
+	start, err := service.BeginExternalLogin("fake", "")
+	assert.NoError(t, err)
+	assert.Contains(t, start.AuthorizationURL, "state=")
+	code := mocks.provider.Authorize(identity.ExternalIdentity{Subject: "42", Email: "john@example.com",
+		EmailVerified: true, GivenName: "John"})
+
+	mocks.linkRepo.On("FindLinkedIdentity", "fake", "42").Return(nil, gorm.ErrRecordNotFound)
+	mocks.userRepo.On("FindUsersByVerifiedEmail", "john@example.com").Return([]model.User{}, nil)
+	mocks.userRepo.On("CreateUser", mock.AnythingOfType("*model.User")).Return(int64(7), nil)
+	mocks.linkRepo.On("CreateLinkedIdentity", mock.AnythingOfType("*model.LinkedIdentity")).Return(int64(1), nil)
+	mocks.roleRepo.On("FindRolesByNames", []string{DefaultRole}).Return([]model.Role{}, nil)
+	mocks.sessionRepo.On("CreateSession", mock.AnythingOfType("*model.Session")).Return(int64(3), nil)
+	mocks.tokenRepo.On("CreateRefreshToken", mock.AnythingOfType("*model.RefreshToken")).Return(int64(1), nil)
+
+	tokens, err := service.CompleteExternalLogin("fake", code, start.State, ClientInfo{})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, tokens.AccessToken)
+
+	created := mocks.userRepo.Calls[1].Arguments.Get(0).(*model.User)
+	assert.Equal(t, "fake:42", created.UserName)
+	assert.Equal(t, "", created.HashPassword)
+	assert.True(t, created.EmailVerified)
+	linked := mocks.linkRepo.Calls[1].Arguments.Get(0).(*model.LinkedIdentity)
+	assert.Equal(t, int64(7), linked.UserID)
+
+	// Codes are single-use
+	_, err = service.CompleteExternalLogin("fake", code, start.State, ClientInfo{})
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+// Test CompleteExternalLogin: Ensures a linked identity signs in as its user without creating anything.
+func TestCompleteExternalLoginLinkedIdentity(t *testing.T) {
+	service, mocks := newTestUserDataService()
+
+	start, _ := service.BeginExternalLogin("fake", "")
+	code := mocks.provider.Authorize(identity.ExternalIdentity{Subject: "42"})
+
+	mocks.linkRepo.On("FindLinkedIdentity", "fake", "42").Return(&model.LinkedIdentity{UserID: 1}, nil)
+	mocks.userRepo.On("FindUserByID", int64(1)).Return(&model.User{ID: 1, UserName: "testuser", TOTPEnabled: true}, nil)
+
+	tokens, err := service.CompleteExternalLogin("fake", code, start.State, ClientInfo{})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, tokens.MFAChallenge, "TOTP users must complete the login with VerifyMFA")
+	mocks.userRepo.AssertNotCalled(t, "CreateUser", mock.Anything)
+}
+
+// Test CompleteExternalLogin: Ensures a linking login refuses identities linked to another user.
+func TestCompleteExternalLoginIdentityLinkedElsewhere(t *testing.T) {
+	service, mocks := newTestUserDataService()
+
+	mocks.sessionRepo.On("FindSessionByID", int64(5)).
+		Return(&model.Session{ID: 5, UserID: 1, LastSeenAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour)}, nil)
+	accessToken, _ := SignAccessToken(testTokenConfig, &model.User{ID: 1, UserName: "testuser"}, nil, 5, time.Now())
+
+	start, err := service.BeginExternalLogin("fake", accessToken)
+	assert.NoError(t, err)
+	code := mocks.provider.Authorize(identity.ExternalIdentity{Subject: "42"})
+
+	mocks.linkRepo.On("FindLinkedIdentity", "fake", "42").Return(&model.LinkedIdentity{UserID: 2}, nil)
+
+	_, err = service.CompleteExternalLogin("fake", code, start.State, ClientInfo{})
+	assert.ErrorIs(t, err, ErrIdentityLinked)
+}
+
+// Test CompleteExternalLogin: Ensures the state is bound to the provider it was issued for.
+func TestCompleteExternalLoginInvalidState(t *testing.T) {
+	service, _ := newTestUserDataService()
+
+	_, err := service.BeginExternalLogin("other", "")
+	assert.ErrorIs(t, err, ErrUnknownProvider)
+
+	_, err = service.CompleteExternalLogin("fake", "code", "not-a-state", ClientInfo{})
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+// Test ChangePassword and DeleteAccount: Ensures an account created by an external login sets a first password
+// without a current one and can then delete itself.
+func TestExternalAccountSetsFirstPassword(t *testing.T) {
+	service, mocks := newTestUserDataService()
+
+	user := &model.User{ID: 1, UserName: "fake:subject-1"}
+	mocks.userRepo.On("FindUserByID", int64(1)).Return(user, nil)
+	mocks.userRepo.On("FindUserByName", "fake:subject-1").Return(user, nil)
+	mocks.userRepo.On("UpdateUser", user).Return(nil)
+	mocks.userRepo.On("DeleteUserByID", int64(1)).Return(nil)
+	mocks.tokenRepo.On("RevokeRefreshTokensByUserID", int64(1)).Return(nil)
+	mocks.sessionRepo.On("RevokeSessionsByUserID", int64(1), mock.AnythingOfType("time.Time")).Return(nil)
+
+	err := service.DeleteAccount(1, "")
+	assert.ErrorIs(t, err, ErrPasswordNotSet)
+	mocks.userRepo.AssertNotCalled(t, "DeleteUserByID", mock.Anything)
+
+	err = service.ChangePassword(1, "", "newpassword")
+	assert.NoError(t, err)
+	isValid, _ := ValidatePassword("newpassword", user.HashPassword)
+	assert.True(t, isValid)
+
+	// Once set, the password is required like for any other account
+	err = service.ChangePassword(1, "", "otherpassword")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	err = service.DeleteAccount(1, "newpassword")
+	assert.NoError(t, err)
+	mocks.userRepo.AssertCalled(t, "DeleteUserByID", int64(1))
+}
+
+// Test SearchUsers: Ensures a full page returns a cursor that continues after its last user.
+func TestSearchUsersCursor(t *testing.T) {
+	service, mocks := newTestUserDataService()
+
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	mocks.userRepo.On("SearchUsers", mock.MatchedBy(func(search repository.UserSearch) bool {
+		return search.AfterID == 0
+	})).Return([]model.User{{ID: 1}, {ID: 2, CreatedAt: createdAt}, {ID: 3}}, nil)
+	mocks.userRepo.On("SearchUsers", mock.MatchedBy(func(search repository.UserSearch) bool {
+		return search.AfterID == 2
+	})).Return([]model.User{{ID: 3}}, nil)
+
+	query := UserSearchQuery{Role: "admin", SortField: "created_at", Descending: true, PageSize: 2}
+	users, nextCursor, err := service.SearchUsers(query)
+	assert.NoError(t, err)
+	assert.Len(t, users, 2)
+	assert.NotEmpty(t, nextCursor)
+
+	first := mocks.userRepo.Calls[0].Arguments.Get(0).(repository.UserSearch)
+	assert.Equal(t, 3, first.Limit)
+	assert.Equal(t, "admin", first.Role)
+
+	query.Cursor = nextCursor
+	users, nextCursor, err = service.SearchUsers(query)
+	assert.NoError(t, err)
+	assert.Len(t, users, 1)
+	assert.Empty(t, nextCursor)
+
+	second := mocks.userRepo.Calls[1].Arguments.Get(0).(repository.UserSearch)
+	assert.True(t, createdAt.Equal(second.AfterValue.(time.Time)))
+}
+
+// Test SearchUsers: Ensures unknown sort fields and cursors of another order are rejected.
+func TestSearchUsersInvalidQuery(t *testing.T) {
+	service, mocks := newTestUserDataService()
+
+	_, _, err := service.SearchUsers(UserSearchQuery{SortField: "hash_password"})
+	assert.ErrorIs(t, err, ErrInvalidSortField)
+
+	cursor, _ := encodeUserCursor(userCursor{SortField: "id", ID: 5})
+	_, _, err = service.SearchUsers(UserSearchQuery{SortField: "user_name", Cursor: cursor})
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+
+	_, _, err = service.SearchUsers(UserSearchQuery{Cursor: "not-a-cursor"})
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+	mocks.userRepo.AssertNotCalled(t, "SearchUsers", mock.Anything)
+}
@@ -2,9 +2,14 @@ package service
 
 import (
 	"errors"
+	"github.com/jinzhu/gorm"
 	"github.com/tongs-dev/shopping-platform/user/domain/model"
 	"github.com/tongs-dev/shopping-platform/user/domain/repository"
+	"github.com/tongs-dev/shopping-platform/user/identity"
+	"github.com/tongs-dev/shopping-platform/user/notify"
 	"golang.org/x/crypto/bcrypt"
+	"strings"
+	"time"
 )
 
 // IUserDataService defines the contract for user-related operations.
@@ -12,47 +17,180 @@ type IUserDataService interface {
 	AddUser(*model.User) (int64, error)
 	DeleteUser(int64) error
 	UpdateUser(user *model.User, isChangePwd bool) (err error)
+	UpdateProfile(user *model.User) error
 	FindUserByName(string) (*model.User, error)
+	FindUserByID(int64) (*model.User, error)
+	FindUsers(page int, pageSize int) (users []model.User, total int64, err error)
+	SearchUsers(query UserSearchQuery) (users []model.User, nextCursor string, err error)
 	CheckPwd(userName string, pwd string) (isOk bool, err error)
+	IssueToken(userName string, pwd string, client ClientInfo) (*TokenPair, error)
+	RefreshToken(refreshToken string) (*TokenPair, error)
+	RevokeToken(refreshToken string) error
+	AssignRole(userID int64, roleName string) error
+	RevokeRole(userID int64, roleName string) error
+	ListRoles() ([]model.Role, error)
+	ChangePassword(userID int64, currentPwd string, newPwd string) error
+	DeleteAccount(userID int64, pwd string) error
+	UnlockUser(userID int64) error
+	RequestPasswordReset(userName string) error
+	ConfirmPasswordReset(resetToken string, newPwd string) error
+	VerifyEmail(verificationToken string) error
+	EnrollTOTP(userID int64) (*TOTPEnrollment, error)
+	ConfirmTOTP(userID int64, code string) ([]string, error)
+	DisableTOTP(userID int64, pwd string, code string) error
+	VerifyMFA(mfaToken string, code string, client ClientInfo) (*TokenPair, error)
+	ListSessions(userID int64) ([]model.Session, error)
+	RevokeSession(userID int64, sessionID int64) error
+	RevokeAllSessions(userID int64, exceptSessionID int64) error
+	ValidateSession(accessToken string) (bool, error)
+	AddAddress(address *model.Address) (int64, error)
+	UpdateAddress(userID int64, address *model.Address) error
+	DeleteAddress(userID int64, addressID int64) error
+	ListAddresses(userID int64) ([]model.Address, error)
+	SetDefaultAddress(userID int64, addressID int64, shipping bool, billing bool) error
+	ExportUserData(userID int64, actorID int64) (*UserDataExport, error)
+	EraseUser(userID int64, actorID int64, reason string) error
+	BeginExternalLogin(providerName string, accessToken string) (*ExternalLoginStart, error)
+	CompleteExternalLogin(providerName string, code string, state string, client ClientInfo) (*TokenPair, error)
 }
 
 // NewUserDataService returns an implementation of IUserDataService.
-func NewUserDataService(userRepository repository.IUserRepository) IUserDataService {
-	return &UserDataService{UserRepository: userRepository}
+func NewUserDataService(
+	userRepository repository.IUserRepository,
+	refreshTokenRepository repository.IRefreshTokenRepository,
+	roleRepository repository.IRoleRepository,
+	loginAttemptRepository repository.ILoginAttemptRepository,
+	sessionRepository repository.ISessionRepository,
+	passwordResetRepository repository.IPasswordResetRepository,
+	emailVerificationRepository repository.IEmailVerificationRepository,
+	addressRepository repository.IAddressRepository,
+	auditRepository repository.IAuditRepository,
+	linkedIdentityRepository repository.ILinkedIdentityRepository,
+	notifier notify.INotifier,
+	identityProviders []identity.IProvider,
+	tokenConfig TokenConfig,
+	lockoutConfig LockoutConfig,
+	passwordPolicy PasswordPolicy,
+	mfaConfig MFAConfig,
+) IUserDataService {
+	providers := make(map[string]identity.IProvider, len(identityProviders))
+	for _, provider := range identityProviders {
+		providers[provider.Name()] = provider
+	}
+
+	return &UserDataService{
+		UserRepository:              userRepository,
+		RefreshTokenRepository:      refreshTokenRepository,
+		RoleRepository:              roleRepository,
+		LoginAttemptRepository:      loginAttemptRepository,
+		SessionRepository:           sessionRepository,
+		PasswordResetRepository:     passwordResetRepository,
+		EmailVerificationRepository: emailVerificationRepository,
+		AddressRepository:           addressRepository,
+		AuditRepository:             auditRepository,
+		LinkedIdentityRepository:    linkedIdentityRepository,
+		Notifier:                    notifier,
+		IdentityProviders:           providers,
+		TokenConfig:                 tokenConfig,
+		LockoutConfig:               lockoutConfig,
+		PasswordPolicy:              passwordPolicy,
+		MFAConfig:                   mfaConfig,
+	}
 }
 
 // UserDataService is the concrete implementation of IUserDataService.
 type UserDataService struct {
-	UserRepository repository.IUserRepository
+	UserRepository              repository.IUserRepository
+	RefreshTokenRepository      repository.IRefreshTokenRepository
+	RoleRepository              repository.IRoleRepository
+	LoginAttemptRepository      repository.ILoginAttemptRepository
+	SessionRepository           repository.ISessionRepository
+	PasswordResetRepository     repository.IPasswordResetRepository
+	EmailVerificationRepository repository.IEmailVerificationRepository
+	AddressRepository           repository.IAddressRepository
+	AuditRepository             repository.IAuditRepository
+	LinkedIdentityRepository    repository.ILinkedIdentityRepository
+	Notifier                    notify.INotifier
+	IdentityProviders           map[string]identity.IProvider
+	TokenConfig                 TokenConfig
+	LockoutConfig               LockoutConfig
+	PasswordPolicy              PasswordPolicy
+	MFAConfig                   MFAConfig
 }
 
+// DefaultRole is the role given to newly registered users.
+const DefaultRole = "customer"
+
+const (
+	// DefaultPageSize is used by FindUsers when no page size is requested.
+	DefaultPageSize = 20
+	// MaxPageSize caps the page size accepted by FindUsers.
+	MaxPageSize = 100
+)
+
+// ErrRoleNotFound is returned when assigning or revoking a role that is not defined.
+var ErrRoleNotFound = errors.New("role not found")
+
+// ErrPasswordMismatch is returned when a plaintext password does not match the stored hash.
+var ErrPasswordMismatch = errors.New("password mismatch")
+
+// ErrPasswordNotSet is returned when confirming with a password an account created by an external login that
+// has no password yet.
+var ErrPasswordNotSet = errors.New("account has no password, set one with ChangePassword first")
+
 // GeneratePassword hashes the provided plaintext password using bcrypt.
 func GeneratePassword(userPassword string) ([]byte, error) {
-	return bcrypt.GenerateFromPassword([]byte(userPassword), bcrypt.DefaultCost)
+	return GeneratePasswordWithCost(userPassword, bcrypt.DefaultCost)
+}
+
+// GeneratePasswordWithCost hashes the provided plaintext password using bcrypt with the given cost.
+func GeneratePasswordWithCost(userPassword string, cost int) ([]byte, error) {
+	return bcrypt.GenerateFromPassword([]byte(userPassword), cost)
 }
 
 // ValidatePassword compares a plaintext password with a stored bcrypt hash.
 func ValidatePassword(userPassword string, hashed string) (isOk bool, err error) {
 	if err := bcrypt.CompareHashAndPassword([]byte(hashed), []byte(userPassword)); err != nil {
-		return false, errors.New("password mismatch")
+		return false, ErrPasswordMismatch
 	}
 	return true, nil
 }
 
 // AddUser hashes the password and saves a new user in the database.
+// When the user has an email address a verification token is sent to it.
 func (u *UserDataService) AddUser(user *model.User) (int64, error) {
+	if user.Email == "" && u.TokenConfig.RequireVerifiedEmail {
+		return 0, ErrEmailRequired
+	}
+
+	if err := u.PasswordPolicy.Validate(user.UserName, user.HashPassword); err != nil {
+		return 0, err
+	}
+
 	// Hashes the user's password before storing
-	pwdByte, err := GeneratePassword(user.HashPassword)
+	pwdByte, err := GeneratePasswordWithCost(user.HashPassword, u.PasswordPolicy.cost())
 	if err != nil {
 		return 0, err
 	}
 	user.HashPassword = string(pwdByte)
 
+	// New users start as plain shoppers
+	if user.Roles == "" {
+		user.Roles = DefaultRole
+	}
+
 	// Saves the user to the repository
 	userID, err := u.UserRepository.CreateUser(user)
 	if err != nil {
 		return 0, err
 	}
+	user.ID = userID
+
+	if user.Email != "" {
+		if err := u.sendEmailVerification(user); err != nil {
+			return 0, err
+		}
+	}
 
 	return userID, nil
 }
@@ -66,7 +204,7 @@ func (u *UserDataService) DeleteUser(userID int64) error {
 func (u *UserDataService) UpdateUser(user *model.User, isChangePwd bool) (err error) {
 	if isChangePwd {
 		// Hashes the new password before updating
-		pwdByte, err := GeneratePassword(user.HashPassword)
+		pwdByte, err := GeneratePasswordWithCost(user.HashPassword, u.PasswordPolicy.cost())
 		if err != nil {
 			return err
 		}
@@ -77,11 +215,36 @@ func (u *UserDataService) UpdateUser(user *model.User, isChangePwd bool) (err er
 	return u.UserRepository.UpdateUser(user)
 }
 
+// UpdateProfile replaces the first name, last name and phone of a user, empty values clear them.
+func (u *UserDataService) UpdateProfile(user *model.User) error {
+	return u.UserRepository.UpdateUserProfile(user)
+}
+
 // FindUserByName retrieves a user by username.
 func (u *UserDataService) FindUserByName(userName string) (user *model.User, err error) {
 	return u.UserRepository.FindUserByName(userName)
 }
 
+// FindUserByID retrieves a user by ID.
+func (u *UserDataService) FindUserByID(userID int64) (*model.User, error) {
+	return u.UserRepository.FindUserByID(userID)
+}
+
+// FindUsers returns one page of users, pages start at 1.
+func (u *UserDataService) FindUsers(page int, pageSize int) ([]model.User, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	if pageSize > MaxPageSize {
+		pageSize = MaxPageSize
+	}
+
+	return u.UserRepository.FindPage((page-1)*pageSize, pageSize)
+}
+
 // CheckPwd validates a user's password by retrieving their hashed password from storage.
 func (u *UserDataService) CheckPwd(userName string, pwd string) (isOk bool, err error) {
 	// Fetches user details
@@ -93,3 +256,331 @@ func (u *UserDataService) CheckPwd(userName string, pwd string) (isOk bool, err
 	// Validates password
 	return ValidatePassword(pwd, user.HashPassword)
 }
+
+// IssueToken authenticates the user with CheckPwd and returns a fresh access/refresh token pair.
+// Users with TOTP enabled get an MFA challenge instead, to be completed with VerifyMFA.
+// Failed attempts are tracked per user name and per client address, see LockoutConfig.
+// Every successful login starts a new session recording the client.
+func (u *UserDataService) IssueToken(userName string, pwd string, client ClientInfo) (*TokenPair, error) {
+	now := time.Now()
+	subjects := u.loginSubjects(userName, client.Address)
+	if err := u.checkLoginAllowed(subjects, now); err != nil {
+		return nil, err
+	}
+
+	if err := u.verifyPwd(userName, pwd); err != nil {
+		if errors.Is(err, ErrInvalidCredentials) {
+			if recordErr := u.recordLoginFailure(subjects, now); recordErr != nil {
+				return nil, recordErr
+			}
+		}
+		return nil, err
+	}
+
+	if err := u.recordLoginSuccess(userName); err != nil {
+		return nil, err
+	}
+
+	user, err := u.UserRepository.FindUserByName(userName)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.TokenConfig.RequireVerifiedEmail && !user.EmailVerified {
+		return nil, ErrEmailNotVerified
+	}
+
+	// The plaintext password is only known here, so hashes with an outdated cost are upgraded on login
+	if u.PasswordPolicy.needsRehash(user.HashPassword) {
+		if err := u.upgradePasswordHash(user, pwd); err != nil {
+			return nil, err
+		}
+	}
+
+	if user.TOTPEnabled {
+		challenge, err := u.signMFAChallenge(user, now)
+		if err != nil {
+			return nil, err
+		}
+		return &TokenPair{MFAChallenge: challenge}, nil
+	}
+
+	return u.startSession(user, client)
+}
+
+// upgradePasswordHash re-hashes the user's password with the cost of the password policy.
+func (u *UserDataService) upgradePasswordHash(user *model.User, pwd string) error {
+	pwdByte, err := GeneratePasswordWithCost(pwd, u.PasswordPolicy.cost())
+	if err != nil {
+		return err
+	}
+
+	if err := u.UserRepository.UpdateUser(&model.User{ID: user.ID, HashPassword: string(pwdByte)}); err != nil {
+		return err
+	}
+	user.HashPassword = string(pwdByte)
+	return nil
+}
+
+// RefreshToken exchanges a valid refresh token for a new token pair, revoking the old refresh token.
+// The new pair stays in the session of the old one.
+func (u *UserDataService) RefreshToken(refreshToken string) (*TokenPair, error) {
+	stored, err := u.findActiveRefreshToken(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := u.UserRepository.FindUserByID(stored.UserID)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	// Rotates the refresh token so each one can only be used once
+	if err := u.RefreshTokenRepository.RevokeRefreshToken(stored.ID); err != nil {
+		return nil, err
+	}
+
+	// Tokens issued before sessions existed move into a new session
+	if stored.SessionID == 0 {
+		return u.startSession(user, ClientInfo{})
+	}
+
+	session, err := u.findActiveSession(stored.SessionID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session.LastSeenAt = now
+	session.ExpiresAt = now.Add(u.TokenConfig.RefreshTokenTTL)
+	if err := u.SessionRepository.TouchSession(session.ID, session.LastSeenAt, session.ExpiresAt); err != nil {
+		return nil, err
+	}
+
+	return u.issueTokenPair(user, session)
+}
+
+// RevokeToken revokes a refresh token and ends its session, so neither it nor the session's access tokens
+// can be used any more.
+func (u *UserDataService) RevokeToken(refreshToken string) error {
+	stored, err := u.findActiveRefreshToken(refreshToken)
+	if err != nil {
+		return err
+	}
+
+	if err := u.RefreshTokenRepository.RevokeRefreshToken(stored.ID); err != nil {
+		return err
+	}
+
+	if stored.SessionID == 0 {
+		return nil
+	}
+	err = u.SessionRepository.RevokeSession(stored.SessionID, time.Now())
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		return err
+	}
+	return nil
+}
+
+// findActiveRefreshToken looks up a raw refresh token and checks that it is neither revoked nor expired.
+func (u *UserDataService) findActiveRefreshToken(refreshToken string) (*model.RefreshToken, error) {
+	if refreshToken == "" {
+		return nil, ErrInvalidToken
+	}
+
+	stored, err := u.RefreshTokenRepository.FindRefreshTokenByHash(hashToken(refreshToken))
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	if stored.Revoked || time.Now().After(stored.ExpiresAt) {
+		return nil, ErrInvalidToken
+	}
+	return stored, nil
+}
+
+// issueTokenPair signs an access token and persists a new refresh token for the user's session.
+func (u *UserDataService) issueTokenPair(user *model.User, session *model.Session) (*TokenPair, error) {
+	now := time.Now()
+
+	permissions, err := u.permissionsFor(user)
+	if err != nil {
+		return nil, err
+	}
+	permissions = u.withholdMFAPermissions(user, permissions)
+
+	accessToken, err := SignAccessToken(u.TokenConfig, user, permissions, session.ID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := generateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = u.RefreshTokenRepository.CreateRefreshToken(&model.RefreshToken{
+		UserID:    user.ID,
+		SessionID: session.ID,
+		TokenHash: hashToken(refreshToken),
+		ExpiresAt: now.Add(u.TokenConfig.RefreshTokenTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    TokenType,
+		ExpiresIn:    int64(u.TokenConfig.AccessTokenTTL.Seconds()),
+	}, nil
+}
+
+// AssignRole grants a defined role to a user; assigning a role the user already holds is a no-op.
+func (u *UserDataService) AssignRole(userID int64, roleName string) error {
+	if _, err := u.findRole(roleName); err != nil {
+		return err
+	}
+
+	user, err := u.UserRepository.FindUserByID(userID)
+	if err != nil {
+		return err
+	}
+
+	roles := SplitList(user.Roles)
+	for _, role := range roles {
+		if role == roleName {
+			return nil
+		}
+	}
+
+	return u.UserRepository.UpdateUserRoles(userID, strings.Join(append(roles, roleName), ","))
+}
+
+// RevokeRole removes a role from a user; revoking a role the user does not hold is a no-op.
+func (u *UserDataService) RevokeRole(userID int64, roleName string) error {
+	if _, err := u.findRole(roleName); err != nil {
+		return err
+	}
+
+	user, err := u.UserRepository.FindUserByID(userID)
+	if err != nil {
+		return err
+	}
+
+	var remaining []string
+	for _, role := range SplitList(user.Roles) {
+		if role != roleName {
+			remaining = append(remaining, role)
+		}
+	}
+
+	return u.UserRepository.UpdateUserRoles(userID, strings.Join(remaining, ","))
+}
+
+// ListRoles returns every defined role with its permissions.
+func (u *UserDataService) ListRoles() ([]model.Role, error) {
+	return u.RoleRepository.FindAll()
+}
+
+// findRole retrieves a role by name, translating a missing record into ErrRoleNotFound.
+func (u *UserDataService) findRole(roleName string) (*model.Role, error) {
+	role, err := u.RoleRepository.FindRoleByName(roleName)
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil, ErrRoleNotFound
+		}
+		return nil, err
+	}
+	return role, nil
+}
+
+// permissionsFor collects the distinct permissions granted by all roles of a user.
+func (u *UserDataService) permissionsFor(user *model.User) ([]string, error) {
+	roles, err := u.RoleRepository.FindRolesByNames(SplitList(user.Roles))
+	if err != nil {
+		return nil, err
+	}
+
+	var permissions []string
+	seen := make(map[string]bool)
+	for _, role := range roles {
+		for _, permission := range SplitList(role.Permissions) {
+			if !seen[permission] {
+				seen[permission] = true
+				permissions = append(permissions, permission)
+			}
+		}
+	}
+	return permissions, nil
+}
+
+// ChangePassword replaces the user's password after verifying the current one with CheckPwd,
+// and ends every session started with the old password. Accounts created by an external login have no
+// password, their first one is set without a current password.
+func (u *UserDataService) ChangePassword(userID int64, currentPwd string, newPwd string) error {
+	user, err := u.UserRepository.FindUserByID(userID)
+	if err != nil {
+		return err
+	}
+
+	if user.HashPassword != "" {
+		if err := u.verifyPwd(user.UserName, currentPwd); err != nil {
+			return err
+		}
+	}
+
+	if err := u.PasswordPolicy.Validate(user.UserName, newPwd); err != nil {
+		return err
+	}
+
+	user.HashPassword = newPwd
+	if err := u.UpdateUser(user, true); err != nil {
+		return err
+	}
+
+	if err := u.RevokeAllSessions(userID, 0); err != nil {
+		return err
+	}
+
+	return u.recordAudit(userID, userID, AuditPasswordChanged, "")
+}
+
+// DeleteAccount deletes the user after confirming their password, ending their sessions first.
+// Accounts without a password get ErrPasswordNotSet and set one with ChangePassword first.
+func (u *UserDataService) DeleteAccount(userID int64, pwd string) error {
+	user, err := u.UserRepository.FindUserByID(userID)
+	if err != nil {
+		return err
+	}
+
+	if user.HashPassword == "" {
+		return ErrPasswordNotSet
+	}
+
+	if err := u.verifyPwd(user.UserName, pwd); err != nil {
+		return err
+	}
+
+	if err := u.RevokeAllSessions(userID, 0); err != nil {
+		return err
+	}
+
+	return u.DeleteUser(userID)
+}
+
+// verifyPwd runs CheckPwd and reports an unknown user or a wrong password as ErrInvalidCredentials.
+func (u *UserDataService) verifyPwd(userName string, pwd string) error {
+	isOk, err := u.CheckPwd(userName, pwd)
+	if err != nil && !gorm.IsRecordNotFoundError(err) && !errors.Is(err, ErrPasswordMismatch) {
+		return err
+	}
+	if !isOk {
+		return ErrInvalidCredentials
+	}
+	return nil
+}
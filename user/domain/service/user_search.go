@@ -0,0 +1,138 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/tongs-dev/shopping-platform/user/domain/model"
+	"github.com/tongs-dev/shopping-platform/user/domain/repository"
+)
+
+var (
+	// ErrInvalidSortField is returned when searching users by a field that is not in repository.UserSortFields.
+	ErrInvalidSortField = errors.New("invalid sort field")
+	// ErrInvalidCursor is returned for malformed cursors or cursors of a search with a different order.
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// UserSearchQuery describes one page of an admin user search. Zero values do not filter.
+type UserSearchQuery struct {
+	UserNamePrefix string
+	Email          string
+	Role           string
+	CreatedAfter   time.Time
+	CreatedBefore  time.Time
+	Locked         *bool
+	EmailVerified  *bool
+	// SortField defaults to "id"
+	SortField  string
+	Descending bool
+	// Cursor is the NextCursor of the previous page, empty for the first page
+	Cursor   string
+	PageSize int
+}
+
+// userCursor is the position after the last user of a page, encoded as URL-safe base64 JSON.
+type userCursor struct {
+	SortField  string `json:"s"`
+	Descending bool   `json:"d,omitempty"`
+	Value      string `json:"v,omitempty"`
+	ID         int64  `json:"id"`
+}
+
+// SearchUsers returns one page of users matching the query and the cursor of the next page,
+// which is empty on the last page.
+func (u *UserDataService) SearchUsers(query UserSearchQuery) ([]model.User, string, error) {
+	if query.SortField == "" {
+		query.SortField = "id"
+	}
+	if _, ok := repository.UserSortFields[query.SortField]; !ok {
+		return nil, "", ErrInvalidSortField
+	}
+	if query.PageSize <= 0 {
+		query.PageSize = DefaultPageSize
+	}
+	if query.PageSize > MaxPageSize {
+		query.PageSize = MaxPageSize
+	}
+
+	search := repository.UserSearch{
+		UserNamePrefix: query.UserNamePrefix,
+		Email:          query.Email,
+		Role:           query.Role,
+		CreatedAfter:   query.CreatedAfter,
+		CreatedBefore:  query.CreatedBefore,
+		Locked:         query.Locked,
+		EmailVerified:  query.EmailVerified,
+		Now:            time.Now(),
+		SortField:      query.SortField,
+		Descending:     query.Descending,
+		// One extra row tells whether there is a next page
+		Limit: query.PageSize + 1,
+	}
+	if query.Cursor != "" {
+		cursor, err := decodeUserCursor(query.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		if cursor.SortField != query.SortField || cursor.Descending != query.Descending {
+			return nil, "", ErrInvalidCursor
+		}
+		search.AfterID = cursor.ID
+		search.AfterValue = cursor.Value
+		if query.SortField == "created_at" {
+			createdAt, err := time.Parse(time.RFC3339Nano, cursor.Value)
+			if err != nil {
+				return nil, "", ErrInvalidCursor
+			}
+			search.AfterValue = createdAt
+		}
+	}
+
+	users, err := u.UserRepository.SearchUsers(search)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(users) <= query.PageSize {
+		return users, "", nil
+	}
+
+	users = users[:query.PageSize]
+	last := users[len(users)-1]
+	cursor := userCursor{SortField: query.SortField, Descending: query.Descending, ID: last.ID}
+	switch query.SortField {
+	case "user_name":
+		cursor.Value = last.UserName
+	case "email":
+		cursor.Value = last.Email
+	case "created_at":
+		cursor.Value = last.CreatedAt.Format(time.RFC3339Nano)
+	}
+	nextCursor, err := encodeUserCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	return users, nextCursor, nil
+}
+
+func encodeUserCursor(cursor userCursor) (string, error) {
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeUserCursor(encoded string) (*userCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	cursor := &userCursor{}
+	if err := json.Unmarshal(data, cursor); err != nil || cursor.ID == 0 {
+		return nil, ErrInvalidCursor
+	}
+	return cursor, nil
+}
@@ -2,16 +2,29 @@ package handler
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jinzhu/gorm"
+	"github.com/micro/go-micro/v2/metadata"
+	"github.com/tongs-dev/shopping-platform/user/common"
 	"github.com/tongs-dev/shopping-platform/user/domain/model"
 	"github.com/tongs-dev/shopping-platform/user/domain/service"
 	userpb "github.com/tongs-dev/shopping-platform/user/proto/user"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"net"
+	"net/mail"
+	"strings"
+	"time"
 )
 
 // UserHandler is the gRPC service handler for user-related operations.
 type UserHandler struct {
 	UserDataService service.IUserDataService
+	// TrustedProxies are the gateways whose X-Forwarded-For metadata is honoured, see ParseTrustedProxies
+	TrustedProxies []*net.IPNet
 }
 
 // Register creates a new user account based on the provided request data.
@@ -20,14 +33,30 @@ func (u *UserHandler) Register(ctx context.Context, userRegisterRequest *userpb.
 		return status.Errorf(codes.InvalidArgument, "username and password are required")
 	}
 
+	if userRegisterRequest.Email != "" {
+		if _, err := mail.ParseAddress(userRegisterRequest.Email); err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid email address: %v", err)
+		}
+	}
+
 	userRegister := &model.User{
 		UserName:     userRegisterRequest.UserName,
 		FirstName:    userRegisterRequest.FirstName,
+		LastName:     userRegisterRequest.LastName,
+		Email:        userRegisterRequest.Email,
+		Phone:        userRegisterRequest.Phone,
 		HashPassword: userRegisterRequest.Pwd,
 	}
 
 	_, err := u.UserDataService.AddUser(userRegister)
 	if err != nil {
+		var policyErr *service.PasswordPolicyError
+		if errors.As(err, &policyErr) {
+			return passwordPolicyStatus("pwd", "failed to register user", policyErr)
+		}
+		if errors.Is(err, service.ErrEmailRequired) {
+			return status.Errorf(codes.InvalidArgument, "failed to register user: %v", err)
+		}
 		return status.Errorf(codes.Internal, "failed to register user: %v", err)
 	}
 
@@ -35,26 +64,140 @@ func (u *UserHandler) Register(ctx context.Context, userRegisterRequest *userpb.
 	return nil
 }
 
-// Login verifies the user's credentials and returns a success response if valid.
+// Login verifies the user's credentials and issues an access token and a refresh token if valid.
 func (u *UserHandler) Login(ctx context.Context, userLogin *userpb.UserLoginRequest, loginResponse *userpb.UserLoginResponse) error {
 	if userLogin.UserName == "" || userLogin.Pwd == "" {
 		return status.Errorf(codes.InvalidArgument, "username and password are required")
 	}
 
-	isOk, err := u.UserDataService.CheckPwd(userLogin.UserName, userLogin.Pwd)
+	tokenPair, err := u.UserDataService.IssueToken(userLogin.UserName, userLogin.Pwd, u.clientInfo(ctx, userLogin.Device))
+	if err != nil {
+		return tokenErrorStatus("login failed", err)
+	}
+
+	if tokenPair.MFAChallenge != "" {
+		loginResponse.MfaRequired = true
+		loginResponse.MfaToken = tokenPair.MFAChallenge
+		return nil
+	}
+
+	loginResponse.IsSuccess = true
+	loginResponse.AccessToken = tokenPair.AccessToken
+	loginResponse.RefreshToken = tokenPair.RefreshToken
+	loginResponse.TokenType = tokenPair.TokenType
+	loginResponse.ExpiresIn = tokenPair.ExpiresIn
+	return nil
+}
+
+// VerifyMFA completes a login that required a second factor and issues the tokens.
+func (u *UserHandler) VerifyMFA(ctx context.Context, verifyRequest *userpb.VerifyMFARequest, loginResponse *userpb.UserLoginResponse) error {
+	if verifyRequest.MfaToken == "" || verifyRequest.Code == "" {
+		return status.Errorf(codes.InvalidArgument, "mfa token and code are required")
+	}
+
+	tokenPair, err := u.UserDataService.VerifyMFA(verifyRequest.MfaToken, verifyRequest.Code, u.clientInfo(ctx, verifyRequest.Device))
+	if err != nil {
+		return tokenErrorStatus("mfa verification failed", err)
+	}
+
+	loginResponse.IsSuccess = true
+	loginResponse.AccessToken = tokenPair.AccessToken
+	loginResponse.RefreshToken = tokenPair.RefreshToken
+	loginResponse.TokenType = tokenPair.TokenType
+	loginResponse.ExpiresIn = tokenPair.ExpiresIn
+	return nil
+}
+
+// BeginExternalLogin returns the URL that signs the user in at an identity provider.
+func (u *UserHandler) BeginExternalLogin(ctx context.Context, beginRequest *userpb.BeginExternalLoginRequest, beginResponse *userpb.BeginExternalLoginResponse) error {
+	if beginRequest.Provider == "" {
+		return status.Errorf(codes.InvalidArgument, "provider is required")
+	}
+
+	start, err := u.UserDataService.BeginExternalLogin(beginRequest.Provider, beginRequest.AccessToken)
+	if err != nil {
+		return externalLoginErrorStatus("failed to begin external login", err)
+	}
+
+	beginResponse.AuthorizationUrl = start.AuthorizationURL
+	beginResponse.State = start.State
+	return nil
+}
+
+// CompleteExternalLogin redeems the provider's authorization code and issues the tokens, creating or linking
+// the account on the first login.
+func (u *UserHandler) CompleteExternalLogin(ctx context.Context, completeRequest *userpb.CompleteExternalLoginRequest, loginResponse *userpb.UserLoginResponse) error {
+	if completeRequest.Provider == "" || completeRequest.Code == "" || completeRequest.State == "" {
+		return status.Errorf(codes.InvalidArgument, "provider, code and state are required")
+	}
+
+	tokenPair, err := u.UserDataService.CompleteExternalLogin(completeRequest.Provider, completeRequest.Code,
+		completeRequest.State, u.clientInfo(ctx, completeRequest.Device))
 	if err != nil {
-		return status.Errorf(codes.Internal, "login failed: %v", err)
+		return externalLoginErrorStatus("external login failed", err)
+	}
+
+	if tokenPair.MFAChallenge != "" {
+		loginResponse.MfaRequired = true
+		loginResponse.MfaToken = tokenPair.MFAChallenge
+		return nil
 	}
 
-	loginResponse.IsSuccess = isOk
+	loginResponse.IsSuccess = true
+	loginResponse.AccessToken = tokenPair.AccessToken
+	loginResponse.RefreshToken = tokenPair.RefreshToken
+	loginResponse.TokenType = tokenPair.TokenType
+	loginResponse.ExpiresIn = tokenPair.ExpiresIn
 	return nil
 }
 
-// GetUserInfo retrieves user details based on the provided username.
+// RefreshToken exchanges a refresh token for a new access token and refresh token.
+func (u *UserHandler) RefreshToken(ctx context.Context, refreshRequest *userpb.RefreshTokenRequest, refreshResponse *userpb.RefreshTokenResponse) error {
+	if refreshRequest.RefreshToken == "" {
+		return status.Errorf(codes.InvalidArgument, "refresh token is required")
+	}
+
+	tokenPair, err := u.UserDataService.RefreshToken(refreshRequest.RefreshToken)
+	if err != nil {
+		return tokenErrorStatus("failed to refresh token", err)
+	}
+
+	refreshResponse.AccessToken = tokenPair.AccessToken
+	refreshResponse.RefreshToken = tokenPair.RefreshToken
+	refreshResponse.TokenType = tokenPair.TokenType
+	refreshResponse.ExpiresIn = tokenPair.ExpiresIn
+	return nil
+}
+
+// Logout revokes the given refresh token.
+func (u *UserHandler) Logout(ctx context.Context, logoutRequest *userpb.LogoutRequest, logoutResponse *userpb.LogoutResponse) error {
+	if logoutRequest.RefreshToken == "" {
+		return status.Errorf(codes.InvalidArgument, "refresh token is required")
+	}
+
+	if err := u.UserDataService.RevokeToken(logoutRequest.RefreshToken); err != nil {
+		return tokenErrorStatus("logout failed", err)
+	}
+
+	logoutResponse.Message = "User logged out successfully"
+	return nil
+}
+
+// GetUserInfo retrieves user details based on the provided username. Users look up themselves,
+// looking up another user needs the "User.GetUserInfo" permission.
 func (u *UserHandler) GetUserInfo(ctx context.Context, userInfoRequest *userpb.UserInfoRequest, userInfoResponse *userpb.UserInfoResponse) error {
+	caller, err := authenticatedUser(ctx)
+	if err != nil {
+		return err
+	}
+
 	if userInfoRequest.UserName == "" {
 		return status.Errorf(codes.InvalidArgument, "username is required")
 	}
+	// Checked before the lookup so the answer does not tell whether another user name exists
+	if userInfoRequest.UserName != caller.UserName && !caller.HasPermission("User.GetUserInfo") {
+		return status.Errorf(codes.PermissionDenied, "permission denied for User.GetUserInfo")
+	}
 
 	userInfo, err := u.UserDataService.FindUserByName(userInfoRequest.UserName)
 	if err != nil {
@@ -65,11 +208,806 @@ func (u *UserHandler) GetUserInfo(ctx context.Context, userInfoRequest *userpb.U
 	return nil
 }
 
+// AssignRole grants a role to a user. Callers need the "User.AssignRole" permission.
+func (u *UserHandler) AssignRole(ctx context.Context, assignRequest *userpb.AssignRoleRequest, roleResponse *userpb.RoleResponse) error {
+	if assignRequest.UserId <= 0 || assignRequest.RoleName == "" {
+		return status.Errorf(codes.InvalidArgument, "user id and role name are required")
+	}
+
+	if err := u.UserDataService.AssignRole(assignRequest.UserId, assignRequest.RoleName); err != nil {
+		return roleErrorStatus("failed to assign role", err)
+	}
+
+	roleResponse.Message = "Role assigned successfully"
+	return nil
+}
+
+// RevokeRole removes a role from a user. Callers need the "User.RevokeRole" permission.
+func (u *UserHandler) RevokeRole(ctx context.Context, revokeRequest *userpb.RevokeRoleRequest, roleResponse *userpb.RoleResponse) error {
+	if revokeRequest.UserId <= 0 || revokeRequest.RoleName == "" {
+		return status.Errorf(codes.InvalidArgument, "user id and role name are required")
+	}
+
+	if err := u.UserDataService.RevokeRole(revokeRequest.UserId, revokeRequest.RoleName); err != nil {
+		return roleErrorStatus("failed to revoke role", err)
+	}
+
+	roleResponse.Message = "Role revoked successfully"
+	return nil
+}
+
+// ListRoles returns every defined role with the RPC endpoints it grants.
+func (u *UserHandler) ListRoles(ctx context.Context, listRequest *userpb.ListRolesRequest, listResponse *userpb.ListRolesResponse) error {
+	roles, err := u.UserDataService.ListRoles()
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to list roles: %v", err)
+	}
+
+	for _, role := range roles {
+		listResponse.Roles = append(listResponse.Roles, &userpb.RoleInfo{
+			Name:        role.Name,
+			Permissions: service.SplitList(role.Permissions),
+			Description: role.Description,
+		})
+	}
+	return nil
+}
+
+// UpdateProfile replaces the profile fields of the calling user, empty fields are cleared.
+func (u *UserHandler) UpdateProfile(ctx context.Context, profileRequest *userpb.UpdateProfileRequest, userInfoResponse *userpb.UserInfoResponse) error {
+	caller, err := authenticatedUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	profile := &model.User{
+		ID:        caller.ID,
+		FirstName: profileRequest.FirstName,
+		LastName:  profileRequest.LastName,
+		Phone:     profileRequest.Phone,
+	}
+	if err := u.UserDataService.UpdateProfile(profile); err != nil {
+		return status.Errorf(codes.Internal, "failed to update profile: %v", err)
+	}
+
+	userInfo, err := u.UserDataService.FindUserByID(caller.ID)
+	if err != nil {
+		return accountErrorStatus("failed to load profile", err)
+	}
+
+	*userInfoResponse = *UserForResponse(userInfo)
+	return nil
+}
+
+// ChangePassword replaces the calling user's password; the current password is required unless the account
+// was created by an external login and has none yet.
+func (u *UserHandler) ChangePassword(ctx context.Context, changeRequest *userpb.ChangePasswordRequest, changeResponse *userpb.ChangePasswordResponse) error {
+	caller, err := authenticatedUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	if changeRequest.NewPwd == "" {
+		return status.Errorf(codes.InvalidArgument, "new password is required")
+	}
+
+	if err := u.UserDataService.ChangePassword(caller.ID, changeRequest.CurrentPwd, changeRequest.NewPwd); err != nil {
+		var policyErr *service.PasswordPolicyError
+		if errors.As(err, &policyErr) {
+			return passwordPolicyStatus("new_pwd", "failed to change password", policyErr)
+		}
+		return accountErrorStatus("failed to change password", err)
+	}
+
+	changeResponse.Message = "Password changed successfully"
+	return nil
+}
+
+// DeleteAccount deletes the calling user's account; the password is required as confirmation.
+func (u *UserHandler) DeleteAccount(ctx context.Context, deleteRequest *userpb.DeleteAccountRequest, deleteResponse *userpb.DeleteAccountResponse) error {
+	caller, err := authenticatedUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	if deleteRequest.Pwd == "" {
+		return status.Errorf(codes.InvalidArgument, "password is required")
+	}
+
+	if err := u.UserDataService.DeleteAccount(caller.ID, deleteRequest.Pwd); err != nil {
+		return accountErrorStatus("failed to delete account", err)
+	}
+
+	deleteResponse.Message = "Account deleted successfully"
+	return nil
+}
+
+// GetUserByID retrieves user details by ID. Callers need the "User.GetUserByID" permission.
+func (u *UserHandler) GetUserByID(ctx context.Context, getRequest *userpb.GetUserByIDRequest, userInfoResponse *userpb.UserInfoResponse) error {
+	if getRequest.UserId <= 0 {
+		return status.Errorf(codes.InvalidArgument, "user id is required")
+	}
+
+	userInfo, err := u.UserDataService.FindUserByID(getRequest.UserId)
+	if err != nil {
+		return accountErrorStatus("failed to get user", err)
+	}
+
+	*userInfoResponse = *UserForResponse(userInfo)
+	return nil
+}
+
+// ListUsers returns one page of users. Callers need the "User.ListUsers" permission.
+func (u *UserHandler) ListUsers(ctx context.Context, listRequest *userpb.ListUsersRequest, listResponse *userpb.ListUsersResponse) error {
+	users, total, err := u.UserDataService.FindUsers(int(listRequest.Page), int(listRequest.PageSize))
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to list users: %v", err)
+	}
+
+	for i := range users {
+		listResponse.Users = append(listResponse.Users, UserForResponse(&users[i]))
+	}
+	listResponse.Total = total
+	listResponse.Page = listRequest.Page
+	listResponse.PageSize = listRequest.PageSize
+	return nil
+}
+
+// SearchUsers returns one page of users matching the filters, continued with next_cursor.
+// Callers need the "User.SearchUsers" permission.
+func (u *UserHandler) SearchUsers(ctx context.Context, searchRequest *userpb.SearchUsersRequest, searchResponse *userpb.SearchUsersResponse) error {
+	query := service.UserSearchQuery{
+		UserNamePrefix: searchRequest.UserNamePrefix,
+		Email:          searchRequest.Email,
+		Role:           searchRequest.Role,
+		Locked:         boolFilter(searchRequest.Locked),
+		EmailVerified:  boolFilter(searchRequest.EmailVerified),
+		SortField:      searchRequest.SortBy,
+		Descending:     searchRequest.Descending,
+		Cursor:         searchRequest.Cursor,
+		PageSize:       int(searchRequest.PageSize),
+	}
+	if searchRequest.CreatedAfter > 0 {
+		query.CreatedAfter = time.Unix(searchRequest.CreatedAfter, 0)
+	}
+	if searchRequest.CreatedBefore > 0 {
+		query.CreatedBefore = time.Unix(searchRequest.CreatedBefore, 0)
+	}
+
+	users, nextCursor, err := u.UserDataService.SearchUsers(query)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidSortField) || errors.Is(err, service.ErrInvalidCursor) {
+			return status.Errorf(codes.InvalidArgument, "failed to search users: %v", err)
+		}
+		return status.Errorf(codes.Internal, "failed to search users: %v", err)
+	}
+
+	for i := range users {
+		searchResponse.Users = append(searchResponse.Users, UserForResponse(&users[i]))
+	}
+	searchResponse.NextCursor = nextCursor
+	return nil
+}
+
+// UnlockUser lifts a login lockout of a user. Callers need the "User.UnlockUser" permission.
+func (u *UserHandler) UnlockUser(ctx context.Context, unlockRequest *userpb.UnlockUserRequest, unlockResponse *userpb.UnlockUserResponse) error {
+	if unlockRequest.UserId <= 0 {
+		return status.Errorf(codes.InvalidArgument, "user id is required")
+	}
+
+	if err := u.UserDataService.UnlockUser(unlockRequest.UserId); err != nil {
+		return accountErrorStatus("failed to unlock user", err)
+	}
+
+	unlockResponse.Message = "User unlocked successfully"
+	return nil
+}
+
+// RequestPasswordReset sends a password reset token to the user. The response is the same whether or not
+// the user exists.
+func (u *UserHandler) RequestPasswordReset(ctx context.Context, resetRequest *userpb.RequestPasswordResetRequest, resetResponse *userpb.RequestPasswordResetResponse) error {
+	if resetRequest.UserName == "" {
+		return status.Errorf(codes.InvalidArgument, "username is required")
+	}
+
+	if err := u.UserDataService.RequestPasswordReset(resetRequest.UserName); err != nil {
+		return status.Errorf(codes.Internal, "failed to request password reset: %v", err)
+	}
+
+	resetResponse.Message = "If the account exists, a password reset code has been sent"
+	return nil
+}
+
+// ConfirmPasswordReset sets a new password using a reset token from RequestPasswordReset.
+func (u *UserHandler) ConfirmPasswordReset(ctx context.Context, confirmRequest *userpb.ConfirmPasswordResetRequest, confirmResponse *userpb.ConfirmPasswordResetResponse) error {
+	if confirmRequest.ResetToken == "" || confirmRequest.NewPwd == "" {
+		return status.Errorf(codes.InvalidArgument, "reset token and new password are required")
+	}
+
+	if err := u.UserDataService.ConfirmPasswordReset(confirmRequest.ResetToken, confirmRequest.NewPwd); err != nil {
+		var policyErr *service.PasswordPolicyError
+		if errors.As(err, &policyErr) {
+			return passwordPolicyStatus("new_pwd", "failed to reset password", policyErr)
+		}
+		return tokenErrorStatus("failed to reset password", err)
+	}
+
+	confirmResponse.Message = "Password reset successfully"
+	return nil
+}
+
+// VerifyEmail confirms the user's email address using the verification token sent at registration.
+func (u *UserHandler) VerifyEmail(ctx context.Context, verifyRequest *userpb.VerifyEmailRequest, verifyResponse *userpb.VerifyEmailResponse) error {
+	if verifyRequest.VerificationToken == "" {
+		return status.Errorf(codes.InvalidArgument, "verification token is required")
+	}
+
+	if err := u.UserDataService.VerifyEmail(verifyRequest.VerificationToken); err != nil {
+		return tokenErrorStatus("failed to verify email", err)
+	}
+
+	verifyResponse.Message = "Email verified successfully"
+	return nil
+}
+
+// EnrollTOTP starts TOTP enrollment for the calling user and returns the secret to add to an authenticator app.
+func (u *UserHandler) EnrollTOTP(ctx context.Context, enrollRequest *userpb.EnrollTOTPRequest, enrollResponse *userpb.EnrollTOTPResponse) error {
+	caller, err := authenticatedUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	enrollment, err := u.UserDataService.EnrollTOTP(caller.ID)
+	if err != nil {
+		return mfaErrorStatus("failed to enroll totp", err)
+	}
+
+	enrollResponse.Secret = enrollment.Secret
+	enrollResponse.ProvisioningUri = enrollment.ProvisioningURI
+	return nil
+}
+
+// ConfirmTOTP enables TOTP for the calling user and returns the one-time recovery codes. Every session of the
+// user ends, the next login is completed with VerifyMFA.
+func (u *UserHandler) ConfirmTOTP(ctx context.Context, confirmRequest *userpb.ConfirmTOTPRequest, confirmResponse *userpb.ConfirmTOTPResponse) error {
+	caller, err := authenticatedUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	if confirmRequest.Code == "" {
+		return status.Errorf(codes.InvalidArgument, "code is required")
+	}
+
+	recoveryCodes, err := u.UserDataService.ConfirmTOTP(caller.ID, confirmRequest.Code)
+	if err != nil {
+		return mfaErrorStatus("failed to confirm totp", err)
+	}
+
+	confirmResponse.RecoveryCodes = recoveryCodes
+	return nil
+}
+
+// DisableTOTP turns TOTP off for the calling user; the password and a TOTP or recovery code are required.
+func (u *UserHandler) DisableTOTP(ctx context.Context, disableRequest *userpb.DisableTOTPRequest, disableResponse *userpb.DisableTOTPResponse) error {
+	caller, err := authenticatedUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	if disableRequest.Pwd == "" || disableRequest.Code == "" {
+		return status.Errorf(codes.InvalidArgument, "password and code are required")
+	}
+
+	if err := u.UserDataService.DisableTOTP(caller.ID, disableRequest.Pwd, disableRequest.Code); err != nil {
+		return mfaErrorStatus("failed to disable totp", err)
+	}
+
+	disableResponse.Message = "Two-factor authentication disabled"
+	return nil
+}
+
+// ListSessions returns the active sessions of the calling user.
+func (u *UserHandler) ListSessions(ctx context.Context, listRequest *userpb.ListSessionsRequest, listResponse *userpb.ListSessionsResponse) error {
+	caller, err := authenticatedUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	sessions, err := u.UserDataService.ListSessions(caller.ID)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to list sessions: %v", err)
+	}
+
+	for _, session := range sessions {
+		listResponse.Sessions = append(listResponse.Sessions, &userpb.SessionInfo{
+			SessionId:  session.ID,
+			Device:     session.Device,
+			IpAddress:  session.IPAddress,
+			UserAgent:  session.UserAgent,
+			CreatedAt:  session.CreatedAt.Unix(),
+			LastSeenAt: session.LastSeenAt.Unix(),
+			ExpiresAt:  session.ExpiresAt.Unix(),
+			Current:    session.ID == caller.SessionID,
+		})
+	}
+	return nil
+}
+
+// RevokeSession ends one session of the calling user, e.g. a lost device.
+func (u *UserHandler) RevokeSession(ctx context.Context, revokeRequest *userpb.RevokeSessionRequest, revokeResponse *userpb.RevokeSessionResponse) error {
+	caller, err := authenticatedUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	if revokeRequest.SessionId <= 0 {
+		return status.Errorf(codes.InvalidArgument, "session id is required")
+	}
+
+	if err := u.UserDataService.RevokeSession(caller.ID, revokeRequest.SessionId); err != nil {
+		if errors.Is(err, service.ErrSessionNotFound) {
+			return status.Errorf(codes.NotFound, "failed to revoke session: %v", err)
+		}
+		return status.Errorf(codes.Internal, "failed to revoke session: %v", err)
+	}
+
+	revokeResponse.Message = "Session revoked successfully"
+	return nil
+}
+
+// RevokeAllSessions ends every session of the calling user, optionally keeping the current one.
+func (u *UserHandler) RevokeAllSessions(ctx context.Context, revokeRequest *userpb.RevokeAllSessionsRequest, revokeResponse *userpb.RevokeAllSessionsResponse) error {
+	caller, err := authenticatedUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	var keep int64
+	if revokeRequest.KeepCurrent {
+		keep = caller.SessionID
+	}
+
+	if err := u.UserDataService.RevokeAllSessions(caller.ID, keep); err != nil {
+		return status.Errorf(codes.Internal, "failed to revoke sessions: %v", err)
+	}
+
+	revokeResponse.Message = "Sessions revoked successfully"
+	return nil
+}
+
+// ValidateSession reports whether the session of an access token is still active. Other services call it
+// through common.NewRemoteSessionValidator.
+func (u *UserHandler) ValidateSession(ctx context.Context, validateRequest *userpb.ValidateSessionRequest, validateResponse *userpb.ValidateSessionResponse) error {
+	if validateRequest.AccessToken == "" {
+		return status.Errorf(codes.InvalidArgument, "access token is required")
+	}
+
+	active, err := u.UserDataService.ValidateSession(validateRequest.AccessToken)
+	if err != nil && !errors.Is(err, service.ErrInvalidToken) {
+		return status.Errorf(codes.Internal, "failed to validate session: %v", err)
+	}
+
+	validateResponse.Active = active
+	return nil
+}
+
+// AddAddress adds an address to the address book of the calling user.
+func (u *UserHandler) AddAddress(ctx context.Context, addRequest *userpb.AddAddressRequest, addressResponse *userpb.AddressResponse) error {
+	caller, err := authenticatedUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	if addRequest.Address == nil {
+		return status.Errorf(codes.InvalidArgument, "address is required")
+	}
+
+	address := AddressFromRequest(addRequest.Address)
+	address.ID = 0
+	address.UserID = caller.ID
+	if _, err := u.UserDataService.AddAddress(address); err != nil {
+		return addressErrorStatus("failed to add address", err)
+	}
+
+	addressResponse.Address = AddressForResponse(address)
+	return nil
+}
+
+// UpdateAddress replaces the fields of one of the calling user's addresses.
+func (u *UserHandler) UpdateAddress(ctx context.Context, updateRequest *userpb.UpdateAddressRequest, addressResponse *userpb.AddressResponse) error {
+	caller, err := authenticatedUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	if updateRequest.Address == nil || updateRequest.Address.AddressId <= 0 {
+		return status.Errorf(codes.InvalidArgument, "address id is required")
+	}
+
+	address := AddressFromRequest(updateRequest.Address)
+	if err := u.UserDataService.UpdateAddress(caller.ID, address); err != nil {
+		return addressErrorStatus("failed to update address", err)
+	}
+
+	addressResponse.Address = AddressForResponse(address)
+	return nil
+}
+
+// DeleteAddress removes one of the calling user's addresses.
+func (u *UserHandler) DeleteAddress(ctx context.Context, deleteRequest *userpb.DeleteAddressRequest, deleteResponse *userpb.DeleteAddressResponse) error {
+	caller, err := authenticatedUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	if deleteRequest.AddressId <= 0 {
+		return status.Errorf(codes.InvalidArgument, "address id is required")
+	}
+
+	if err := u.UserDataService.DeleteAddress(caller.ID, deleteRequest.AddressId); err != nil {
+		return addressErrorStatus("failed to delete address", err)
+	}
+
+	deleteResponse.Message = "Address deleted successfully"
+	return nil
+}
+
+// ListAddresses returns the address book of the calling user.
+func (u *UserHandler) ListAddresses(ctx context.Context, listRequest *userpb.ListAddressesRequest, listResponse *userpb.ListAddressesResponse) error {
+	caller, err := authenticatedUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	addresses, err := u.UserDataService.ListAddresses(caller.ID)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to list addresses: %v", err)
+	}
+
+	for i := range addresses {
+		listResponse.Addresses = append(listResponse.Addresses, AddressForResponse(&addresses[i]))
+	}
+	return nil
+}
+
+// SetDefaultAddress makes one of the calling user's addresses the default for shipping and/or billing.
+func (u *UserHandler) SetDefaultAddress(ctx context.Context, defaultRequest *userpb.SetDefaultAddressRequest, addressResponse *userpb.AddressResponse) error {
+	caller, err := authenticatedUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	if defaultRequest.AddressId <= 0 {
+		return status.Errorf(codes.InvalidArgument, "address id is required")
+	}
+	if !defaultRequest.Shipping && !defaultRequest.Billing {
+		return status.Errorf(codes.InvalidArgument, "shipping or billing must be set")
+	}
+
+	err = u.UserDataService.SetDefaultAddress(caller.ID, defaultRequest.AddressId, defaultRequest.Shipping, defaultRequest.Billing)
+	if err != nil {
+		return addressErrorStatus("failed to set default address", err)
+	}
+
+	addresses, err := u.UserDataService.ListAddresses(caller.ID)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to set default address: %v", err)
+	}
+	for i := range addresses {
+		if addresses[i].ID == defaultRequest.AddressId {
+			addressResponse.Address = AddressForResponse(&addresses[i])
+		}
+	}
+	return nil
+}
+
+// ExportUserData returns everything held about a user as a JSON bundle. Users export their own data,
+// exporting another user needs the "User.ExportUserData" permission.
+func (u *UserHandler) ExportUserData(ctx context.Context, exportRequest *userpb.ExportUserDataRequest, exportResponse *userpb.ExportUserDataResponse) error {
+	caller, err := authenticatedUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	userID := exportRequest.UserId
+	if userID == 0 {
+		userID = caller.ID
+	}
+	if userID != caller.ID && !caller.HasPermission("User.ExportUserData") {
+		return status.Errorf(codes.PermissionDenied, "permission denied for User.ExportUserData")
+	}
+
+	export, err := u.UserDataService.ExportUserData(userID, caller.ID)
+	if err != nil {
+		return accountErrorStatus("failed to export user data", err)
+	}
+
+	data, err := json.Marshal(export)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to export user data: %v", err)
+	}
+
+	exportResponse.Data = string(data)
+	return nil
+}
+
+// EraseUser anonymizes the personal data of a user. Callers need the "User.EraseUser" permission.
+func (u *UserHandler) EraseUser(ctx context.Context, eraseRequest *userpb.EraseUserRequest, eraseResponse *userpb.EraseUserResponse) error {
+	caller, err := authenticatedUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	if eraseRequest.UserId <= 0 {
+		return status.Errorf(codes.InvalidArgument, "user id is required")
+	}
+
+	if err := u.UserDataService.EraseUser(eraseRequest.UserId, caller.ID, eraseRequest.Reason); err != nil {
+		if errors.Is(err, service.ErrUserErased) {
+			return status.Errorf(codes.FailedPrecondition, "failed to erase user: %v", err)
+		}
+		return accountErrorStatus("failed to erase user", err)
+	}
+
+	eraseResponse.Message = "User erased successfully"
+	return nil
+}
+
+// boolFilter converts a BoolFilter into an optional boolean, nil for ANY.
+func boolFilter(filter userpb.BoolFilter) *bool {
+	if filter == userpb.BoolFilter_ANY {
+		return nil
+	}
+	value := filter == userpb.BoolFilter_YES
+	return &value
+}
+
+// clientInfo describes the calling client for session tracking.
+func (u *UserHandler) clientInfo(ctx context.Context, device string) service.ClientInfo {
+	userAgent, _ := metadata.Get(ctx, "User-Agent")
+	return service.ClientInfo{
+		Address:   u.clientAddress(ctx),
+		UserAgent: userAgent,
+		Device:    device,
+	}
+}
+
+// clientAddress returns the caller's address. The transport peer address is used unless the peer is a trusted
+// proxy, then X-Forwarded-For is walked from the right and the first address not belonging to a trusted proxy
+// is the client. Clients cannot pick their address by sending the header themselves.
+func (u *UserHandler) clientAddress(ctx context.Context) string {
+	// Remote is set by the server from the transport peer, overriding any metadata sent by the client
+	remote, ok := metadata.Get(ctx, "Remote")
+	if !ok {
+		return ""
+	}
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+	if !u.isTrustedProxy(remote) {
+		return remote
+	}
+
+	forwarded, ok := metadata.Get(ctx, "X-Forwarded-For")
+	if !ok || forwarded == "" {
+		return remote
+	}
+	hops := strings.Split(forwarded, ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if net.ParseIP(hop) == nil {
+			break
+		}
+		if i == 0 || !u.isTrustedProxy(hop) {
+			return hop
+		}
+	}
+	return remote
+}
+
+// isTrustedProxy reports whether the address belongs to one of the trusted proxies.
+func (u *UserHandler) isTrustedProxy(address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+	for _, proxy := range u.TrustedProxies {
+		if proxy.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseTrustedProxies parses a comma-separated list of proxy IP addresses and CIDR ranges,
+// e.g. "10.0.0.0/8, 192.168.1.10".
+func ParseTrustedProxies(list string) ([]*net.IPNet, error) {
+	var proxies []*net.IPNet
+	for _, entry := range strings.Split(list, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid proxy address %q", entry)
+			}
+			bits := 8 * net.IPv4len
+			if ip.To4() == nil {
+				bits = 8 * net.IPv6len
+			}
+			proxies = append(proxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy range %q: %w", entry, err)
+		}
+		proxies = append(proxies, network)
+	}
+	return proxies, nil
+}
+
+// authenticatedUser returns the caller put into the context by the auth handler wrapper.
+func authenticatedUser(ctx context.Context) (*common.AuthUser, error) {
+	caller, ok := common.AuthUserFromContext(ctx)
+	if !ok {
+		return nil, status.Errorf(codes.Unauthenticated, "authentication required")
+	}
+	return caller, nil
+}
+
+// accountErrorStatus maps account management errors from the data service to gRPC status errors.
+func accountErrorStatus(message string, err error) error {
+	if errors.Is(err, service.ErrInvalidCredentials) {
+		return status.Errorf(codes.PermissionDenied, "%s: %v", message, err)
+	}
+	if errors.Is(err, service.ErrPasswordNotSet) {
+		return status.Errorf(codes.FailedPrecondition, "%s: %v", message, err)
+	}
+	if gorm.IsRecordNotFoundError(err) {
+		return status.Errorf(codes.NotFound, "%s: %v", message, err)
+	}
+	return status.Errorf(codes.Internal, "%s: %v", message, err)
+}
+
+// mfaErrorStatus maps two-factor management errors from the data service to gRPC status errors.
+func mfaErrorStatus(message string, err error) error {
+	if errors.Is(err, service.ErrMFANotEnrolled) || errors.Is(err, service.ErrMFAAlreadyEnabled) {
+		return status.Errorf(codes.FailedPrecondition, "%s: %v", message, err)
+	}
+	if errors.Is(err, service.ErrInvalidMFACode) {
+		return status.Errorf(codes.InvalidArgument, "%s: %v", message, err)
+	}
+	return accountErrorStatus(message, err)
+}
+
+// roleErrorStatus maps role management errors from the data service to gRPC status errors.
+func roleErrorStatus(message string, err error) error {
+	if errors.Is(err, service.ErrRoleNotFound) || gorm.IsRecordNotFoundError(err) {
+		return status.Errorf(codes.NotFound, "%s: %v", message, err)
+	}
+	return status.Errorf(codes.Internal, "%s: %v", message, err)
+}
+
+// tokenErrorStatus maps authentication errors from the data service to gRPC status errors.
+func tokenErrorStatus(message string, err error) error {
+	if errors.Is(err, service.ErrInvalidCredentials) || errors.Is(err, service.ErrInvalidToken) ||
+		errors.Is(err, service.ErrInvalidMFACode) {
+		return status.Errorf(codes.Unauthenticated, "%s: %v", message, err)
+	}
+	if errors.Is(err, service.ErrAccountLocked) || errors.Is(err, service.ErrLoginThrottled) {
+		return status.Errorf(codes.ResourceExhausted, "%s: %v", message, err)
+	}
+	if errors.Is(err, service.ErrEmailNotVerified) {
+		return status.Errorf(codes.FailedPrecondition, "%s: %v", message, err)
+	}
+	return status.Errorf(codes.Internal, "%s: %v", message, err)
+}
+
+// externalLoginErrorStatus maps external login errors from the data service to gRPC status errors.
+func externalLoginErrorStatus(message string, err error) error {
+	if errors.Is(err, service.ErrUnknownProvider) {
+		return status.Errorf(codes.InvalidArgument, "%s: %v", message, err)
+	}
+	if errors.Is(err, service.ErrIdentityLinked) {
+		return status.Errorf(codes.AlreadyExists, "%s: %v", message, err)
+	}
+	return tokenErrorStatus(message, err)
+}
+
+// passwordPolicyStatus returns an InvalidArgument status with a field violation for every broken password rule.
+func passwordPolicyStatus(field string, message string, policyErr *service.PasswordPolicyError) error {
+	st := status.Newf(codes.InvalidArgument, "%s: %v", message, policyErr)
+
+	badRequest := &errdetails.BadRequest{}
+	for _, violation := range policyErr.Violations {
+		badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       field,
+			Description: violation.Description,
+		})
+	}
+
+	detailed, err := st.WithDetails(badRequest)
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+// addressErrorStatus maps address book errors from the data service to gRPC status errors,
+// validation errors carry a BadRequest detail with one violation per invalid field.
+func addressErrorStatus(message string, err error) error {
+	var validationErr *service.AddressValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		st := status.Newf(codes.InvalidArgument, "%s: %v", message, validationErr)
+		badRequest := &errdetails.BadRequest{}
+		for _, violation := range validationErr.Violations {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       violation.Field,
+				Description: violation.Description,
+			})
+		}
+		detailed, detailErr := st.WithDetails(badRequest)
+		if detailErr != nil {
+			return st.Err()
+		}
+		return detailed.Err()
+	case errors.Is(err, service.ErrAddressNotFound):
+		return status.Errorf(codes.NotFound, "%s: %v", message, err)
+	default:
+		return status.Errorf(codes.Internal, "%s: %v", message, err)
+	}
+}
+
+// AddressFromRequest converts a userpb.Address into a model.Address.
+func AddressFromRequest(address *userpb.Address) *model.Address {
+	return &model.Address{
+		ID:                address.AddressId,
+		Recipient:         address.Recipient,
+		Line1:             address.Line1,
+		Line2:             address.Line2,
+		City:              address.City,
+		Region:            address.Region,
+		PostalCode:        address.PostalCode,
+		Country:           address.Country,
+		Phone:             address.Phone,
+		IsDefaultShipping: address.IsDefaultShipping,
+		IsDefaultBilling:  address.IsDefaultBilling,
+	}
+}
+
+// AddressForResponse converts a model.Address into a userpb.Address.
+func AddressForResponse(address *model.Address) *userpb.Address {
+	return &userpb.Address{
+		AddressId:         address.ID,
+		Recipient:         address.Recipient,
+		Line1:             address.Line1,
+		Line2:             address.Line2,
+		City:              address.City,
+		Region:            address.Region,
+		PostalCode:        address.PostalCode,
+		Country:           address.Country,
+		Phone:             address.Phone,
+		IsDefaultShipping: address.IsDefaultShipping,
+		IsDefaultBilling:  address.IsDefaultBilling,
+	}
+}
+
 // UserForResponse converts a model.User struct into a userpb.UserInfoResponse.
 func UserForResponse(userModel *model.User) *userpb.UserInfoResponse {
 	return &userpb.UserInfoResponse{
-		UserName:  userModel.UserName,
-		FirstName: userModel.FirstName,
-		UserId:    userModel.ID,
+		UserName:      userModel.UserName,
+		FirstName:     userModel.FirstName,
+		LastName:      userModel.LastName,
+		UserId:        userModel.ID,
+		Roles:         service.SplitList(userModel.Roles),
+		Email:         userModel.Email,
+		Phone:         userModel.Phone,
+		EmailVerified: userModel.EmailVerified,
+		CreatedAt:     userModel.CreatedAt.Unix(),
+		UpdatedAt:     userModel.UpdatedAt.Unix(),
 	}
 }
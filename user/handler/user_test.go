@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	"github.com/micro/go-micro/v2/metadata"
+	"github.com/stretchr/testify/assert"
+	"github.com/tongs-dev/shopping-platform/user/common"
+	"github.com/tongs-dev/shopping-platform/user/domain/model"
+	"github.com/tongs-dev/shopping-platform/user/domain/service"
+	userpb "github.com/tongs-dev/shopping-platform/user/proto/user"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// stubUserDataService serves users by name, other IUserDataService methods are not implemented.
+type stubUserDataService struct {
+	service.IUserDataService
+	users map[string]*model.User
+}
+
+func (s *stubUserDataService) FindUserByName(userName string) (*model.User, error) {
+	if user, ok := s.users[userName]; ok {
+		return user, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// Test GetUserInfo: Ensures users only read their own details unless they hold the permission.
+func TestGetUserInfo(t *testing.T) {
+	handler := &UserHandler{UserDataService: &stubUserDataService{users: map[string]*model.User{
+		"alice": {ID: 1, UserName: "alice", Email: "alice@example.com"},
+		"bob":   {ID: 2, UserName: "bob", Email: "bob@example.com"},
+	}}}
+	request := &userpb.UserInfoRequest{UserName: "bob"}
+
+	err := handler.GetUserInfo(context.Background(), request, &userpb.UserInfoResponse{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	alice := common.NewAuthContext(context.Background(), &common.AuthUser{ID: 1, UserName: "alice"})
+	err = handler.GetUserInfo(alice, request, &userpb.UserInfoResponse{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	err = handler.GetUserInfo(alice, &userpb.UserInfoRequest{UserName: "nobody"}, &userpb.UserInfoResponse{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err), "unknown users must look like other users")
+
+	response := &userpb.UserInfoResponse{}
+	err = handler.GetUserInfo(alice, &userpb.UserInfoRequest{UserName: "alice"}, response)
+	assert.NoError(t, err)
+	assert.Equal(t, "alice@example.com", response.Email)
+
+	admin := common.NewAuthContext(context.Background(), &common.AuthUser{ID: 3, UserName: "admin", Permissions: []string{"*"}})
+	response = &userpb.UserInfoResponse{}
+	err = handler.GetUserInfo(admin, request, response)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), response.UserId)
+}
+
+// Test clientAddress: Ensures X-Forwarded-For is only honoured when the peer is a trusted proxy.
+func TestClientAddress(t *testing.T) {
+	proxies, err := ParseTrustedProxies("10.0.0.0/8, 192.168.1.10")
+	assert.NoError(t, err)
+	handler := &UserHandler{TrustedProxies: proxies}
+
+	direct := metadata.NewContext(context.Background(), metadata.Metadata{
+		"Remote":          "203.0.113.7:51000",
+		"X-Forwarded-For": "198.51.100.1",
+	})
+	assert.Equal(t, "203.0.113.7", handler.clientAddress(direct), "untrusted peer must not pick its address")
+
+	proxied := metadata.NewContext(context.Background(), metadata.Metadata{
+		"Remote":          "10.1.2.3:51000",
+		"X-Forwarded-For": "198.51.100.1, 203.0.113.7, 192.168.1.10",
+	})
+	assert.Equal(t, "203.0.113.7", handler.clientAddress(proxied), "right-most untrusted hop is the client")
+
+	noHeader := metadata.NewContext(context.Background(), metadata.Metadata{"Remote": "192.168.1.10:51000"})
+	assert.Equal(t, "192.168.1.10", handler.clientAddress(noHeader))
+}
+
+// Test ParseTrustedProxies: Ensures invalid entries are rejected.
+func TestParseTrustedProxiesInvalid(t *testing.T) {
+	_, err := ParseTrustedProxies("10.0.0.0/33")
+	assert.Error(t, err)
+
+	_, err = ParseTrustedProxies("gateway")
+	assert.Error(t, err)
+}
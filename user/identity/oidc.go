@@ -0,0 +1,136 @@
+package identity
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// OIDCConfig describes an OpenID Connect provider using the authorization code flow.
+type OIDCConfig struct {
+	Name         string
+	ClientID     string
+	ClientSecret string
+	AuthURL      string
+	TokenURL     string
+	UserInfoURL  string
+	// RedirectURL is registered with the provider and receives the code and state
+	RedirectURL string
+	Scopes      []string
+}
+
+// NewOIDCProvider returns an IProvider talking to an OpenID Connect provider over HTTP.
+func NewOIDCProvider(config OIDCConfig) IProvider {
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "email", "profile"}
+	}
+	return &OIDCProvider{config: config, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// OIDCProvider is an IProvider that reads the identity from the provider's userinfo endpoint.
+type OIDCProvider struct {
+	config OIDCConfig
+	client *http.Client
+}
+
+// Name returns the configured provider name.
+func (p *OIDCProvider) Name() string {
+	return p.config.Name
+}
+
+// AuthCodeURL returns the authorization endpoint URL for the code flow.
+func (p *OIDCProvider) AuthCodeURL(state string) string {
+	query := url.Values{
+		"response_type": {"code"},
+		"client_id":     {p.config.ClientID},
+		"redirect_uri":  {p.config.RedirectURL},
+		"scope":         {strings.Join(p.config.Scopes, " ")},
+		"state":         {state},
+	}
+	separator := "?"
+	if strings.Contains(p.config.AuthURL, "?") {
+		separator = "&"
+	}
+	return p.config.AuthURL + separator + query.Encode()
+}
+
+// Exchange redeems the code at the token endpoint and fetches the identity from the userinfo endpoint.
+func (p *OIDCProvider) Exchange(code string) (*ExternalIdentity, error) {
+	response, err := p.client.PostForm(p.config.TokenURL, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.config.RedirectURL},
+		"client_id":     {p.config.ClientID},
+		"client_secret": {p.config.ClientSecret},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	// Providers answer invalid_grant with 400 for unknown, expired and reused codes
+	if response.StatusCode == http.StatusBadRequest {
+		return nil, ErrInvalidCode
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s token endpoint returned %s", p.config.Name, response.Status)
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&token); err != nil {
+		return nil, err
+	}
+	if token.AccessToken == "" {
+		return nil, fmt.Errorf("%s token endpoint returned no access token", p.config.Name)
+	}
+
+	return p.userInfo(token.AccessToken)
+}
+
+// userInfo fetches the claims of the user the access token was issued to.
+func (p *OIDCProvider) userInfo(accessToken string) (*ExternalIdentity, error) {
+	request, err := http.NewRequest(http.MethodGet, p.config.UserInfoURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Authorization", "Bearer "+accessToken)
+
+	response, err := p.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s userinfo endpoint returned %s", p.config.Name, response.Status)
+	}
+
+	var claims struct {
+		Subject    string `json:"sub"`
+		Email      string `json:"email"`
+		GivenName  string `json:"given_name"`
+		FamilyName string `json:"family_name"`
+		// some providers send email_verified as the string "true"
+		EmailVerified interface{} `json:"email_verified"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&claims); err != nil {
+		return nil, err
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%s userinfo endpoint returned no subject", p.config.Name)
+	}
+
+	return &ExternalIdentity{
+		Provider:      p.config.Name,
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified == true || claims.EmailVerified == "true",
+		GivenName:     claims.GivenName,
+		FamilyName:    claims.FamilyName,
+	}, nil
+}
@@ -0,0 +1,28 @@
+package identity
+
+import "errors"
+
+// ErrInvalidCode is returned when an authorization code is unknown, expired or already used.
+var ErrInvalidCode = errors.New("invalid authorization code")
+
+// ExternalIdentity is the account of a user at an identity provider.
+type ExternalIdentity struct {
+	// Provider is the name of the provider the identity belongs to
+	Provider string
+	// Subject is the stable ID of the account at the provider
+	Subject       string
+	Email         string
+	EmailVerified bool
+	GivenName     string
+	FamilyName    string
+}
+
+// IProvider is an OAuth2/OpenID Connect identity provider users can sign in with.
+type IProvider interface {
+	// Name identifies the provider in RPCs and in the linked_identity table, e.g. "google"
+	Name() string
+	// AuthCodeURL returns the URL the client sends the user to, carrying state back to the redirect URL
+	AuthCodeURL(state string) string
+	// Exchange redeems an authorization code for the identity of the signed-in user
+	Exchange(code string) (*ExternalIdentity, error)
+}
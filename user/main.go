@@ -1,18 +1,118 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"github.com/jinzhu/gorm"
 	_ "github.com/jinzhu/gorm/dialects/mysql"
 	"github.com/micro/go-micro/v2"
+	"github.com/tongs-dev/shopping-platform/user/common"
 	"github.com/tongs-dev/shopping-platform/user/domain/repository"
 	"github.com/tongs-dev/shopping-platform/user/domain/service"
 	"github.com/tongs-dev/shopping-platform/user/handler"
+	"github.com/tongs-dev/shopping-platform/user/identity"
+	"github.com/tongs-dev/shopping-platform/user/notify"
 	userpb "github.com/tongs-dev/shopping-platform/user/proto/user"
 	"github.com/tongs-dev/shopping-platform/user/util"
+	"io/ioutil"
 	"log"
+	"strings"
+	"time"
 )
 
+// publicEndpoints are the RPCs that can be called without an access token
+var publicEndpoints = []string{
+	"User.Register",
+	"User.Login",
+	"User.RefreshToken",
+	"User.Logout",
+	"User.RequestPasswordReset",
+	"User.ConfirmPasswordReset",
+	"User.VerifyEmail",
+	"User.VerifyMFA",
+	"User.ValidateSession",
+	"User.BeginExternalLogin",
+	"User.CompleteExternalLogin",
+}
+
+// restrictedEndpoints are the administrative RPCs that need a matching role permission
+var restrictedEndpoints = []string{
+	"User.AssignRole",
+	"User.RevokeRole",
+	"User.ListRoles",
+	"User.GetUserByID",
+	"User.ListUsers",
+	"User.SearchUsers",
+	"User.UnlockUser",
+	"User.EraseUser",
+}
+
+// setupNotifier selects how password reset and email verification codes are delivered, NOTIFIER=file writes them to NOTIFIER_FILE
+func setupNotifier() notify.INotifier {
+	if util.GetEnv("NOTIFIER", "log") == "file" {
+		return notify.NewFileNotifier(util.GetEnv("NOTIFIER_FILE", "notifications.log"))
+	}
+	return notify.NewLogNotifier()
+}
+
+// setupPasswordPolicy builds the password policy, PASSWORD_DENY_LIST_FILE adds one denied password per line
+// to the built-in list
+func setupPasswordPolicy() service.PasswordPolicy {
+	denyList := append([]string{}, service.CommonPasswords...)
+	if path := util.GetEnv("PASSWORD_DENY_LIST_FILE", ""); path != "" {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			log.Fatalf("Failed to read password deny-list: %v", err)
+		}
+		for _, line := range strings.Split(string(content), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				denyList = append(denyList, line)
+			}
+		}
+	}
+
+	return service.PasswordPolicy{
+		MinLength:     util.GetEnvInt("PASSWORD_MIN_LENGTH", 8),
+		RequireUpper:  util.GetEnvBool("PASSWORD_REQUIRE_UPPER", true),
+		RequireLower:  util.GetEnvBool("PASSWORD_REQUIRE_LOWER", true),
+		RequireDigit:  util.GetEnvBool("PASSWORD_REQUIRE_DIGIT", true),
+		RequireSymbol: util.GetEnvBool("PASSWORD_REQUIRE_SYMBOL", false),
+		DenyList:      denyList,
+		Cost:          util.GetEnvInt("BCRYPT_COST", 12),
+	}
+}
+
+// setupIdentityProviders configures the OpenID Connect providers listed in OIDC_PROVIDERS (comma-separated names),
+// each read from OIDC_<NAME>_CLIENT_ID, _CLIENT_SECRET, _AUTH_URL, _TOKEN_URL, _USERINFO_URL, _REDIRECT_URL
+// and optionally _SCOPES
+func setupIdentityProviders() []identity.IProvider {
+	var providers []identity.IProvider
+	for _, name := range strings.Split(util.GetEnv("OIDC_PROVIDERS", ""), ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		config := identity.OIDCConfig{
+			Name:         name,
+			ClientID:     util.GetEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret: util.GetEnv(prefix+"CLIENT_SECRET", ""),
+			AuthURL:      util.GetEnv(prefix+"AUTH_URL", ""),
+			TokenURL:     util.GetEnv(prefix+"TOKEN_URL", ""),
+			UserInfoURL:  util.GetEnv(prefix+"USERINFO_URL", ""),
+			RedirectURL:  util.GetEnv(prefix+"REDIRECT_URL", ""),
+		}
+		if config.ClientID == "" || config.AuthURL == "" || config.TokenURL == "" || config.UserInfoURL == "" ||
+			config.RedirectURL == "" {
+			log.Fatalf("Incomplete configuration of identity provider %s", name)
+		}
+		if scopes := util.GetEnv(prefix+"SCOPES", ""); scopes != "" {
+			config.Scopes = strings.Fields(strings.ReplaceAll(scopes, ",", " "))
+		}
+		providers = append(providers, identity.NewOIDCProvider(config))
+	}
+	return providers
+}
+
 func main() {
 	// Read environment variables for database config
 	dbHost := util.GetEnv("DB_HOST", "localhost")
@@ -45,21 +145,116 @@ func main() {
 	// if err != nil {
 	// 	log.Fatalf("Failed to initialize database tables: %v", err)
 	// }
+	// err = repository.NewRefreshTokenRepository(db).InitTable()
+	// if err != nil {
+	// 	log.Fatalf("Failed to initialize database tables: %v", err)
+	// }
+	// err = repository.NewRoleRepository(db).InitTable()
+	// if err != nil {
+	// 	log.Fatalf("Failed to initialize database tables: %v", err)
+	// }
+	// err = repository.NewLoginAttemptRepository(db).InitTable()
+	// if err != nil {
+	// 	log.Fatalf("Failed to initialize database tables: %v", err)
+	// }
+	// err = repository.NewSessionRepository(db).InitTable()
+	// if err != nil {
+	// 	log.Fatalf("Failed to initialize database tables: %v", err)
+	// }
+	// err = repository.NewPasswordResetRepository(db).InitTable()
+	// if err != nil {
+	// 	log.Fatalf("Failed to initialize database tables: %v", err)
+	// }
+	// err = repository.NewEmailVerificationRepository(db).InitTable()
+	// if err != nil {
+	// 	log.Fatalf("Failed to initialize database tables: %v", err)
+	// }
+	// err = repository.NewAddressRepository(db).InitTable()
+	// if err != nil {
+	// 	log.Fatalf("Failed to initialize database tables: %v", err)
+	// }
+	// err = repository.NewAuditRepository(db).InitTable()
+	// if err != nil {
+	// 	log.Fatalf("Failed to initialize database tables: %v", err)
+	// }
+	// err = repository.NewLinkedIdentityRepository(db).InitTable()
+	// if err != nil {
+	// 	log.Fatalf("Failed to initialize database tables: %v", err)
+	// }
 
 	// 4. Create a new microservice instance with name and version
+	tokenConfig := service.TokenConfig{
+		Secret:               util.MustGetEnv("JWT_SECRET"),
+		Issuer:               "go.micro.service.user",
+		AccessTokenTTL:       util.GetEnvDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL:      util.GetEnvDuration("REFRESH_TOKEN_TTL", 7*24*time.Hour),
+		PasswordResetTTL:     util.GetEnvDuration("PASSWORD_RESET_TTL", 30*time.Minute),
+		EmailVerificationTTL: util.GetEnvDuration("EMAIL_VERIFICATION_TTL", 48*time.Hour),
+		ExternalLoginTTL:     util.GetEnvDuration("EXTERNAL_LOGIN_TTL", 10*time.Minute),
+		RequireVerifiedEmail: util.GetEnvBool("REQUIRE_VERIFIED_EMAIL", false),
+	}
 	srv := micro.NewService(
 		micro.Name("go.micro.service.user"),
 		micro.Version("latest"),
+		micro.WrapHandler(
+			common.NewAuthHandlerWrapper(tokenConfig.Secret, publicEndpoints...),
+			common.NewPermissionHandlerWrapper(restrictedEndpoints...),
+		),
 	)
 
 	// 5. Initialize the microservice
 	srv.Init()
 
 	// 6. Create a user service instance
-	userDataService := service.NewUserDataService(repository.NewUserRepository(db))
+	lockoutConfig := service.LockoutConfig{
+		MaxUserAttempts:    util.GetEnvInt("LOGIN_MAX_USER_ATTEMPTS", 5),
+		MaxAddressAttempts: util.GetEnvInt("LOGIN_MAX_ADDRESS_ATTEMPTS", 20),
+		LockoutDuration:    util.GetEnvDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+		BaseBackoff:        util.GetEnvDuration("LOGIN_BASE_BACKOFF", time.Second),
+		MaxBackoff:         util.GetEnvDuration("LOGIN_MAX_BACKOFF", 30*time.Second),
+	}
+	userDataService := service.NewUserDataService(
+		repository.NewUserRepository(db),
+		repository.NewRefreshTokenRepository(db),
+		repository.NewRoleRepository(db),
+		repository.NewLoginAttemptRepository(db),
+		repository.NewSessionRepository(db),
+		repository.NewPasswordResetRepository(db),
+		repository.NewEmailVerificationRepository(db),
+		repository.NewAddressRepository(db),
+		repository.NewAuditRepository(db),
+		repository.NewLinkedIdentityRepository(db),
+		setupNotifier(),
+		setupIdentityProviders(),
+		tokenConfig,
+		lockoutConfig,
+		setupPasswordPolicy(),
+		service.MFAConfig{
+			Issuer:        util.GetEnv("MFA_ISSUER", "Shopping Platform"),
+			EncryptionKey: util.MustGetEnv("MFA_ENCRYPTION_KEY"),
+			ChallengeTTL:  util.GetEnvDuration("MFA_CHALLENGE_TTL", 5*time.Minute),
+			// Accounts able to delete catalog data need a second factor for it
+			RequiredFor: service.SplitList(util.GetEnv("MFA_REQUIRED_FOR",
+				"Category.DeleteCategory,Category.DeleteCategoryTranslation,Category.DeleteCategoryAttribute,Category.ImportCategories")),
+		},
+	)
+
+	// Revoked sessions are rejected on every authenticated RPC, checked directly against the session table
+	srv.Init(micro.WrapHandler(common.NewSessionHandlerWrapper(
+		func(ctx context.Context, accessToken string, user *common.AuthUser) (bool, error) {
+			return userDataService.ValidateSession(accessToken)
+		},
+	)))
+
+	// Gateways allowed to report the client address through X-Forwarded-For, e.g. "10.0.0.0/8,192.168.1.10"
+	trustedProxies, err := handler.ParseTrustedProxies(util.GetEnv("TRUSTED_PROXIES", ""))
+	if err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
 
 	// 7. Register the user handler with the microservice
-	if err := userpb.RegisterUserHandler(srv.Server(), &handler.UserHandler{UserDataService: userDataService}); err != nil {
+	userHandler := &handler.UserHandler{UserDataService: userDataService, TrustedProxies: trustedProxies}
+	if err := userpb.RegisterUserHandler(srv.Server(), userHandler); err != nil {
 		log.Fatalf("Failed to register user service: %v", err)
 	}
 
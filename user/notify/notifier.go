@@ -0,0 +1,62 @@
+package notify
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Message is a notification addressed to a single user.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// INotifier delivers messages to users, e.g. password reset links.
+type INotifier interface {
+	Send(Message) error
+}
+
+// NewLogNotifier returns an INotifier that writes messages to the standard logger.
+func NewLogNotifier() INotifier {
+	return &LogNotifier{}
+}
+
+// LogNotifier is an INotifier for local development that only logs messages.
+type LogNotifier struct{}
+
+// Send logs the message.
+func (n *LogNotifier) Send(message Message) error {
+	log.Printf("Notification to %s: %s\n%s", message.To, message.Subject, message.Body)
+	return nil
+}
+
+// NewFileNotifier returns an INotifier that appends messages to the given file.
+func NewFileNotifier(path string) INotifier {
+	return &FileNotifier{path: path}
+}
+
+// FileNotifier is an INotifier that appends every message to a file, so it works without a mail server.
+type FileNotifier struct {
+	path string
+	mu   sync.Mutex
+}
+
+// Send appends the message to the notifier's file.
+func (n *FileNotifier) Send(message Message) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	file, err := os.OpenFile(n.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open notification file: %w", err)
+	}
+	defer file.Close()
+
+	_, err = fmt.Fprintf(file, "[%s] To: %s\nSubject: %s\n%s\n\n",
+		time.Now().Format(time.RFC3339), message.To, message.Subject, message.Body)
+	return err
+}
@@ -45,6 +45,38 @@ type UserService interface {
 	Register(ctx context.Context, in *UserRegisterRequest, opts ...client.CallOption) (*UserRegisterResponse, error)
 	Login(ctx context.Context, in *UserLoginRequest, opts ...client.CallOption) (*UserLoginResponse, error)
 	GetUserInfo(ctx context.Context, in *UserInfoRequest, opts ...client.CallOption) (*UserInfoResponse, error)
+	RefreshToken(ctx context.Context, in *RefreshTokenRequest, opts ...client.CallOption) (*RefreshTokenResponse, error)
+	Logout(ctx context.Context, in *LogoutRequest, opts ...client.CallOption) (*LogoutResponse, error)
+	AssignRole(ctx context.Context, in *AssignRoleRequest, opts ...client.CallOption) (*RoleResponse, error)
+	RevokeRole(ctx context.Context, in *RevokeRoleRequest, opts ...client.CallOption) (*RoleResponse, error)
+	ListRoles(ctx context.Context, in *ListRolesRequest, opts ...client.CallOption) (*ListRolesResponse, error)
+	UpdateProfile(ctx context.Context, in *UpdateProfileRequest, opts ...client.CallOption) (*UserInfoResponse, error)
+	ChangePassword(ctx context.Context, in *ChangePasswordRequest, opts ...client.CallOption) (*ChangePasswordResponse, error)
+	DeleteAccount(ctx context.Context, in *DeleteAccountRequest, opts ...client.CallOption) (*DeleteAccountResponse, error)
+	GetUserByID(ctx context.Context, in *GetUserByIDRequest, opts ...client.CallOption) (*UserInfoResponse, error)
+	ListUsers(ctx context.Context, in *ListUsersRequest, opts ...client.CallOption) (*ListUsersResponse, error)
+	SearchUsers(ctx context.Context, in *SearchUsersRequest, opts ...client.CallOption) (*SearchUsersResponse, error)
+	UnlockUser(ctx context.Context, in *UnlockUserRequest, opts ...client.CallOption) (*UnlockUserResponse, error)
+	RequestPasswordReset(ctx context.Context, in *RequestPasswordResetRequest, opts ...client.CallOption) (*RequestPasswordResetResponse, error)
+	ConfirmPasswordReset(ctx context.Context, in *ConfirmPasswordResetRequest, opts ...client.CallOption) (*ConfirmPasswordResetResponse, error)
+	VerifyEmail(ctx context.Context, in *VerifyEmailRequest, opts ...client.CallOption) (*VerifyEmailResponse, error)
+	EnrollTOTP(ctx context.Context, in *EnrollTOTPRequest, opts ...client.CallOption) (*EnrollTOTPResponse, error)
+	ConfirmTOTP(ctx context.Context, in *ConfirmTOTPRequest, opts ...client.CallOption) (*ConfirmTOTPResponse, error)
+	DisableTOTP(ctx context.Context, in *DisableTOTPRequest, opts ...client.CallOption) (*DisableTOTPResponse, error)
+	VerifyMFA(ctx context.Context, in *VerifyMFARequest, opts ...client.CallOption) (*UserLoginResponse, error)
+	ListSessions(ctx context.Context, in *ListSessionsRequest, opts ...client.CallOption) (*ListSessionsResponse, error)
+	RevokeSession(ctx context.Context, in *RevokeSessionRequest, opts ...client.CallOption) (*RevokeSessionResponse, error)
+	RevokeAllSessions(ctx context.Context, in *RevokeAllSessionsRequest, opts ...client.CallOption) (*RevokeAllSessionsResponse, error)
+	ValidateSession(ctx context.Context, in *ValidateSessionRequest, opts ...client.CallOption) (*ValidateSessionResponse, error)
+	AddAddress(ctx context.Context, in *AddAddressRequest, opts ...client.CallOption) (*AddressResponse, error)
+	UpdateAddress(ctx context.Context, in *UpdateAddressRequest, opts ...client.CallOption) (*AddressResponse, error)
+	DeleteAddress(ctx context.Context, in *DeleteAddressRequest, opts ...client.CallOption) (*DeleteAddressResponse, error)
+	ListAddresses(ctx context.Context, in *ListAddressesRequest, opts ...client.CallOption) (*ListAddressesResponse, error)
+	SetDefaultAddress(ctx context.Context, in *SetDefaultAddressRequest, opts ...client.CallOption) (*AddressResponse, error)
+	ExportUserData(ctx context.Context, in *ExportUserDataRequest, opts ...client.CallOption) (*ExportUserDataResponse, error)
+	EraseUser(ctx context.Context, in *EraseUserRequest, opts ...client.CallOption) (*EraseUserResponse, error)
+	BeginExternalLogin(ctx context.Context, in *BeginExternalLoginRequest, opts ...client.CallOption) (*BeginExternalLoginResponse, error)
+	CompleteExternalLogin(ctx context.Context, in *CompleteExternalLoginRequest, opts ...client.CallOption) (*UserLoginResponse, error)
 }
 
 type userService struct {
@@ -89,12 +121,364 @@ func (c *userService) GetUserInfo(ctx context.Context, in *UserInfoRequest, opts
 	return out, nil
 }
 
+func (c *userService) RefreshToken(ctx context.Context, in *RefreshTokenRequest, opts ...client.CallOption) (*RefreshTokenResponse, error) {
+	req := c.c.NewRequest(c.name, "User.RefreshToken", in)
+	out := new(RefreshTokenResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userService) Logout(ctx context.Context, in *LogoutRequest, opts ...client.CallOption) (*LogoutResponse, error) {
+	req := c.c.NewRequest(c.name, "User.Logout", in)
+	out := new(LogoutResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userService) AssignRole(ctx context.Context, in *AssignRoleRequest, opts ...client.CallOption) (*RoleResponse, error) {
+	req := c.c.NewRequest(c.name, "User.AssignRole", in)
+	out := new(RoleResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userService) RevokeRole(ctx context.Context, in *RevokeRoleRequest, opts ...client.CallOption) (*RoleResponse, error) {
+	req := c.c.NewRequest(c.name, "User.RevokeRole", in)
+	out := new(RoleResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userService) ListRoles(ctx context.Context, in *ListRolesRequest, opts ...client.CallOption) (*ListRolesResponse, error) {
+	req := c.c.NewRequest(c.name, "User.ListRoles", in)
+	out := new(ListRolesResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userService) UpdateProfile(ctx context.Context, in *UpdateProfileRequest, opts ...client.CallOption) (*UserInfoResponse, error) {
+	req := c.c.NewRequest(c.name, "User.UpdateProfile", in)
+	out := new(UserInfoResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userService) ChangePassword(ctx context.Context, in *ChangePasswordRequest, opts ...client.CallOption) (*ChangePasswordResponse, error) {
+	req := c.c.NewRequest(c.name, "User.ChangePassword", in)
+	out := new(ChangePasswordResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userService) DeleteAccount(ctx context.Context, in *DeleteAccountRequest, opts ...client.CallOption) (*DeleteAccountResponse, error) {
+	req := c.c.NewRequest(c.name, "User.DeleteAccount", in)
+	out := new(DeleteAccountResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userService) GetUserByID(ctx context.Context, in *GetUserByIDRequest, opts ...client.CallOption) (*UserInfoResponse, error) {
+	req := c.c.NewRequest(c.name, "User.GetUserByID", in)
+	out := new(UserInfoResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userService) ListUsers(ctx context.Context, in *ListUsersRequest, opts ...client.CallOption) (*ListUsersResponse, error) {
+	req := c.c.NewRequest(c.name, "User.ListUsers", in)
+	out := new(ListUsersResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userService) SearchUsers(ctx context.Context, in *SearchUsersRequest, opts ...client.CallOption) (*SearchUsersResponse, error) {
+	req := c.c.NewRequest(c.name, "User.SearchUsers", in)
+	out := new(SearchUsersResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userService) UnlockUser(ctx context.Context, in *UnlockUserRequest, opts ...client.CallOption) (*UnlockUserResponse, error) {
+	req := c.c.NewRequest(c.name, "User.UnlockUser", in)
+	out := new(UnlockUserResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userService) RequestPasswordReset(ctx context.Context, in *RequestPasswordResetRequest, opts ...client.CallOption) (*RequestPasswordResetResponse, error) {
+	req := c.c.NewRequest(c.name, "User.RequestPasswordReset", in)
+	out := new(RequestPasswordResetResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userService) ConfirmPasswordReset(ctx context.Context, in *ConfirmPasswordResetRequest, opts ...client.CallOption) (*ConfirmPasswordResetResponse, error) {
+	req := c.c.NewRequest(c.name, "User.ConfirmPasswordReset", in)
+	out := new(ConfirmPasswordResetResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userService) VerifyEmail(ctx context.Context, in *VerifyEmailRequest, opts ...client.CallOption) (*VerifyEmailResponse, error) {
+	req := c.c.NewRequest(c.name, "User.VerifyEmail", in)
+	out := new(VerifyEmailResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userService) EnrollTOTP(ctx context.Context, in *EnrollTOTPRequest, opts ...client.CallOption) (*EnrollTOTPResponse, error) {
+	req := c.c.NewRequest(c.name, "User.EnrollTOTP", in)
+	out := new(EnrollTOTPResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userService) ConfirmTOTP(ctx context.Context, in *ConfirmTOTPRequest, opts ...client.CallOption) (*ConfirmTOTPResponse, error) {
+	req := c.c.NewRequest(c.name, "User.ConfirmTOTP", in)
+	out := new(ConfirmTOTPResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userService) DisableTOTP(ctx context.Context, in *DisableTOTPRequest, opts ...client.CallOption) (*DisableTOTPResponse, error) {
+	req := c.c.NewRequest(c.name, "User.DisableTOTP", in)
+	out := new(DisableTOTPResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userService) VerifyMFA(ctx context.Context, in *VerifyMFARequest, opts ...client.CallOption) (*UserLoginResponse, error) {
+	req := c.c.NewRequest(c.name, "User.VerifyMFA", in)
+	out := new(UserLoginResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userService) ListSessions(ctx context.Context, in *ListSessionsRequest, opts ...client.CallOption) (*ListSessionsResponse, error) {
+	req := c.c.NewRequest(c.name, "User.ListSessions", in)
+	out := new(ListSessionsResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userService) RevokeSession(ctx context.Context, in *RevokeSessionRequest, opts ...client.CallOption) (*RevokeSessionResponse, error) {
+	req := c.c.NewRequest(c.name, "User.RevokeSession", in)
+	out := new(RevokeSessionResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userService) RevokeAllSessions(ctx context.Context, in *RevokeAllSessionsRequest, opts ...client.CallOption) (*RevokeAllSessionsResponse, error) {
+	req := c.c.NewRequest(c.name, "User.RevokeAllSessions", in)
+	out := new(RevokeAllSessionsResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userService) ValidateSession(ctx context.Context, in *ValidateSessionRequest, opts ...client.CallOption) (*ValidateSessionResponse, error) {
+	req := c.c.NewRequest(c.name, "User.ValidateSession", in)
+	out := new(ValidateSessionResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userService) AddAddress(ctx context.Context, in *AddAddressRequest, opts ...client.CallOption) (*AddressResponse, error) {
+	req := c.c.NewRequest(c.name, "User.AddAddress", in)
+	out := new(AddressResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userService) UpdateAddress(ctx context.Context, in *UpdateAddressRequest, opts ...client.CallOption) (*AddressResponse, error) {
+	req := c.c.NewRequest(c.name, "User.UpdateAddress", in)
+	out := new(AddressResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userService) DeleteAddress(ctx context.Context, in *DeleteAddressRequest, opts ...client.CallOption) (*DeleteAddressResponse, error) {
+	req := c.c.NewRequest(c.name, "User.DeleteAddress", in)
+	out := new(DeleteAddressResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userService) ListAddresses(ctx context.Context, in *ListAddressesRequest, opts ...client.CallOption) (*ListAddressesResponse, error) {
+	req := c.c.NewRequest(c.name, "User.ListAddresses", in)
+	out := new(ListAddressesResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userService) SetDefaultAddress(ctx context.Context, in *SetDefaultAddressRequest, opts ...client.CallOption) (*AddressResponse, error) {
+	req := c.c.NewRequest(c.name, "User.SetDefaultAddress", in)
+	out := new(AddressResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userService) ExportUserData(ctx context.Context, in *ExportUserDataRequest, opts ...client.CallOption) (*ExportUserDataResponse, error) {
+	req := c.c.NewRequest(c.name, "User.ExportUserData", in)
+	out := new(ExportUserDataResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userService) EraseUser(ctx context.Context, in *EraseUserRequest, opts ...client.CallOption) (*EraseUserResponse, error) {
+	req := c.c.NewRequest(c.name, "User.EraseUser", in)
+	out := new(EraseUserResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userService) BeginExternalLogin(ctx context.Context, in *BeginExternalLoginRequest, opts ...client.CallOption) (*BeginExternalLoginResponse, error) {
+	req := c.c.NewRequest(c.name, "User.BeginExternalLogin", in)
+	out := new(BeginExternalLoginResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userService) CompleteExternalLogin(ctx context.Context, in *CompleteExternalLoginRequest, opts ...client.CallOption) (*UserLoginResponse, error) {
+	req := c.c.NewRequest(c.name, "User.CompleteExternalLogin", in)
+	out := new(UserLoginResponse)
+	err := c.c.Call(ctx, req, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for User service
 
 type UserHandler interface {
 	Register(context.Context, *UserRegisterRequest, *UserRegisterResponse) error
 	Login(context.Context, *UserLoginRequest, *UserLoginResponse) error
 	GetUserInfo(context.Context, *UserInfoRequest, *UserInfoResponse) error
+	RefreshToken(context.Context, *RefreshTokenRequest, *RefreshTokenResponse) error
+	Logout(context.Context, *LogoutRequest, *LogoutResponse) error
+	AssignRole(context.Context, *AssignRoleRequest, *RoleResponse) error
+	RevokeRole(context.Context, *RevokeRoleRequest, *RoleResponse) error
+	ListRoles(context.Context, *ListRolesRequest, *ListRolesResponse) error
+	UpdateProfile(context.Context, *UpdateProfileRequest, *UserInfoResponse) error
+	ChangePassword(context.Context, *ChangePasswordRequest, *ChangePasswordResponse) error
+	DeleteAccount(context.Context, *DeleteAccountRequest, *DeleteAccountResponse) error
+	GetUserByID(context.Context, *GetUserByIDRequest, *UserInfoResponse) error
+	ListUsers(context.Context, *ListUsersRequest, *ListUsersResponse) error
+	SearchUsers(context.Context, *SearchUsersRequest, *SearchUsersResponse) error
+	UnlockUser(context.Context, *UnlockUserRequest, *UnlockUserResponse) error
+	RequestPasswordReset(context.Context, *RequestPasswordResetRequest, *RequestPasswordResetResponse) error
+	ConfirmPasswordReset(context.Context, *ConfirmPasswordResetRequest, *ConfirmPasswordResetResponse) error
+	VerifyEmail(context.Context, *VerifyEmailRequest, *VerifyEmailResponse) error
+	EnrollTOTP(context.Context, *EnrollTOTPRequest, *EnrollTOTPResponse) error
+	ConfirmTOTP(context.Context, *ConfirmTOTPRequest, *ConfirmTOTPResponse) error
+	DisableTOTP(context.Context, *DisableTOTPRequest, *DisableTOTPResponse) error
+	VerifyMFA(context.Context, *VerifyMFARequest, *UserLoginResponse) error
+	ListSessions(context.Context, *ListSessionsRequest, *ListSessionsResponse) error
+	RevokeSession(context.Context, *RevokeSessionRequest, *RevokeSessionResponse) error
+	RevokeAllSessions(context.Context, *RevokeAllSessionsRequest, *RevokeAllSessionsResponse) error
+	ValidateSession(context.Context, *ValidateSessionRequest, *ValidateSessionResponse) error
+	AddAddress(context.Context, *AddAddressRequest, *AddressResponse) error
+	UpdateAddress(context.Context, *UpdateAddressRequest, *AddressResponse) error
+	DeleteAddress(context.Context, *DeleteAddressRequest, *DeleteAddressResponse) error
+	ListAddresses(context.Context, *ListAddressesRequest, *ListAddressesResponse) error
+	SetDefaultAddress(context.Context, *SetDefaultAddressRequest, *AddressResponse) error
+	ExportUserData(context.Context, *ExportUserDataRequest, *ExportUserDataResponse) error
+	EraseUser(context.Context, *EraseUserRequest, *EraseUserResponse) error
+	BeginExternalLogin(context.Context, *BeginExternalLoginRequest, *BeginExternalLoginResponse) error
+	CompleteExternalLogin(context.Context, *CompleteExternalLoginRequest, *UserLoginResponse) error
 }
 
 func RegisterUserHandler(s server.Server, hdlr UserHandler, opts ...server.HandlerOption) error {
@@ -102,6 +486,38 @@ func RegisterUserHandler(s server.Server, hdlr UserHandler, opts ...server.Handl
 		Register(ctx context.Context, in *UserRegisterRequest, out *UserRegisterResponse) error
 		Login(ctx context.Context, in *UserLoginRequest, out *UserLoginResponse) error
 		GetUserInfo(ctx context.Context, in *UserInfoRequest, out *UserInfoResponse) error
+		RefreshToken(ctx context.Context, in *RefreshTokenRequest, out *RefreshTokenResponse) error
+		Logout(ctx context.Context, in *LogoutRequest, out *LogoutResponse) error
+		AssignRole(ctx context.Context, in *AssignRoleRequest, out *RoleResponse) error
+		RevokeRole(ctx context.Context, in *RevokeRoleRequest, out *RoleResponse) error
+		ListRoles(ctx context.Context, in *ListRolesRequest, out *ListRolesResponse) error
+		UpdateProfile(ctx context.Context, in *UpdateProfileRequest, out *UserInfoResponse) error
+		ChangePassword(ctx context.Context, in *ChangePasswordRequest, out *ChangePasswordResponse) error
+		DeleteAccount(ctx context.Context, in *DeleteAccountRequest, out *DeleteAccountResponse) error
+		GetUserByID(ctx context.Context, in *GetUserByIDRequest, out *UserInfoResponse) error
+		ListUsers(ctx context.Context, in *ListUsersRequest, out *ListUsersResponse) error
+		SearchUsers(ctx context.Context, in *SearchUsersRequest, out *SearchUsersResponse) error
+		UnlockUser(ctx context.Context, in *UnlockUserRequest, out *UnlockUserResponse) error
+		RequestPasswordReset(ctx context.Context, in *RequestPasswordResetRequest, out *RequestPasswordResetResponse) error
+		ConfirmPasswordReset(ctx context.Context, in *ConfirmPasswordResetRequest, out *ConfirmPasswordResetResponse) error
+		VerifyEmail(ctx context.Context, in *VerifyEmailRequest, out *VerifyEmailResponse) error
+		EnrollTOTP(ctx context.Context, in *EnrollTOTPRequest, out *EnrollTOTPResponse) error
+		ConfirmTOTP(ctx context.Context, in *ConfirmTOTPRequest, out *ConfirmTOTPResponse) error
+		DisableTOTP(ctx context.Context, in *DisableTOTPRequest, out *DisableTOTPResponse) error
+		VerifyMFA(ctx context.Context, in *VerifyMFARequest, out *UserLoginResponse) error
+		ListSessions(ctx context.Context, in *ListSessionsRequest, out *ListSessionsResponse) error
+		RevokeSession(ctx context.Context, in *RevokeSessionRequest, out *RevokeSessionResponse) error
+		RevokeAllSessions(ctx context.Context, in *RevokeAllSessionsRequest, out *RevokeAllSessionsResponse) error
+		ValidateSession(ctx context.Context, in *ValidateSessionRequest, out *ValidateSessionResponse) error
+		AddAddress(ctx context.Context, in *AddAddressRequest, out *AddressResponse) error
+		UpdateAddress(ctx context.Context, in *UpdateAddressRequest, out *AddressResponse) error
+		DeleteAddress(ctx context.Context, in *DeleteAddressRequest, out *DeleteAddressResponse) error
+		ListAddresses(ctx context.Context, in *ListAddressesRequest, out *ListAddressesResponse) error
+		SetDefaultAddress(ctx context.Context, in *SetDefaultAddressRequest, out *AddressResponse) error
+		ExportUserData(ctx context.Context, in *ExportUserDataRequest, out *ExportUserDataResponse) error
+		EraseUser(ctx context.Context, in *EraseUserRequest, out *EraseUserResponse) error
+		BeginExternalLogin(ctx context.Context, in *BeginExternalLoginRequest, out *BeginExternalLoginResponse) error
+		CompleteExternalLogin(ctx context.Context, in *CompleteExternalLoginRequest, out *UserLoginResponse) error
 	}
 	type User struct {
 		user
@@ -125,3 +541,131 @@ func (h *userHandler) Login(ctx context.Context, in *UserLoginRequest, out *User
 func (h *userHandler) GetUserInfo(ctx context.Context, in *UserInfoRequest, out *UserInfoResponse) error {
 	return h.UserHandler.GetUserInfo(ctx, in, out)
 }
+
+func (h *userHandler) RefreshToken(ctx context.Context, in *RefreshTokenRequest, out *RefreshTokenResponse) error {
+	return h.UserHandler.RefreshToken(ctx, in, out)
+}
+
+func (h *userHandler) Logout(ctx context.Context, in *LogoutRequest, out *LogoutResponse) error {
+	return h.UserHandler.Logout(ctx, in, out)
+}
+
+func (h *userHandler) AssignRole(ctx context.Context, in *AssignRoleRequest, out *RoleResponse) error {
+	return h.UserHandler.AssignRole(ctx, in, out)
+}
+
+func (h *userHandler) RevokeRole(ctx context.Context, in *RevokeRoleRequest, out *RoleResponse) error {
+	return h.UserHandler.RevokeRole(ctx, in, out)
+}
+
+func (h *userHandler) ListRoles(ctx context.Context, in *ListRolesRequest, out *ListRolesResponse) error {
+	return h.UserHandler.ListRoles(ctx, in, out)
+}
+
+func (h *userHandler) UpdateProfile(ctx context.Context, in *UpdateProfileRequest, out *UserInfoResponse) error {
+	return h.UserHandler.UpdateProfile(ctx, in, out)
+}
+
+func (h *userHandler) ChangePassword(ctx context.Context, in *ChangePasswordRequest, out *ChangePasswordResponse) error {
+	return h.UserHandler.ChangePassword(ctx, in, out)
+}
+
+func (h *userHandler) DeleteAccount(ctx context.Context, in *DeleteAccountRequest, out *DeleteAccountResponse) error {
+	return h.UserHandler.DeleteAccount(ctx, in, out)
+}
+
+func (h *userHandler) GetUserByID(ctx context.Context, in *GetUserByIDRequest, out *UserInfoResponse) error {
+	return h.UserHandler.GetUserByID(ctx, in, out)
+}
+
+func (h *userHandler) ListUsers(ctx context.Context, in *ListUsersRequest, out *ListUsersResponse) error {
+	return h.UserHandler.ListUsers(ctx, in, out)
+}
+
+func (h *userHandler) SearchUsers(ctx context.Context, in *SearchUsersRequest, out *SearchUsersResponse) error {
+	return h.UserHandler.SearchUsers(ctx, in, out)
+}
+
+func (h *userHandler) UnlockUser(ctx context.Context, in *UnlockUserRequest, out *UnlockUserResponse) error {
+	return h.UserHandler.UnlockUser(ctx, in, out)
+}
+
+func (h *userHandler) RequestPasswordReset(ctx context.Context, in *RequestPasswordResetRequest, out *RequestPasswordResetResponse) error {
+	return h.UserHandler.RequestPasswordReset(ctx, in, out)
+}
+
+func (h *userHandler) ConfirmPasswordReset(ctx context.Context, in *ConfirmPasswordResetRequest, out *ConfirmPasswordResetResponse) error {
+	return h.UserHandler.ConfirmPasswordReset(ctx, in, out)
+}
+
+func (h *userHandler) VerifyEmail(ctx context.Context, in *VerifyEmailRequest, out *VerifyEmailResponse) error {
+	return h.UserHandler.VerifyEmail(ctx, in, out)
+}
+
+func (h *userHandler) EnrollTOTP(ctx context.Context, in *EnrollTOTPRequest, out *EnrollTOTPResponse) error {
+	return h.UserHandler.EnrollTOTP(ctx, in, out)
+}
+
+func (h *userHandler) ConfirmTOTP(ctx context.Context, in *ConfirmTOTPRequest, out *ConfirmTOTPResponse) error {
+	return h.UserHandler.ConfirmTOTP(ctx, in, out)
+}
+
+func (h *userHandler) DisableTOTP(ctx context.Context, in *DisableTOTPRequest, out *DisableTOTPResponse) error {
+	return h.UserHandler.DisableTOTP(ctx, in, out)
+}
+
+func (h *userHandler) VerifyMFA(ctx context.Context, in *VerifyMFARequest, out *UserLoginResponse) error {
+	return h.UserHandler.VerifyMFA(ctx, in, out)
+}
+
+func (h *userHandler) ListSessions(ctx context.Context, in *ListSessionsRequest, out *ListSessionsResponse) error {
+	return h.UserHandler.ListSessions(ctx, in, out)
+}
+
+func (h *userHandler) RevokeSession(ctx context.Context, in *RevokeSessionRequest, out *RevokeSessionResponse) error {
+	return h.UserHandler.RevokeSession(ctx, in, out)
+}
+
+func (h *userHandler) RevokeAllSessions(ctx context.Context, in *RevokeAllSessionsRequest, out *RevokeAllSessionsResponse) error {
+	return h.UserHandler.RevokeAllSessions(ctx, in, out)
+}
+
+func (h *userHandler) ValidateSession(ctx context.Context, in *ValidateSessionRequest, out *ValidateSessionResponse) error {
+	return h.UserHandler.ValidateSession(ctx, in, out)
+}
+
+func (h *userHandler) AddAddress(ctx context.Context, in *AddAddressRequest, out *AddressResponse) error {
+	return h.UserHandler.AddAddress(ctx, in, out)
+}
+
+func (h *userHandler) UpdateAddress(ctx context.Context, in *UpdateAddressRequest, out *AddressResponse) error {
+	return h.UserHandler.UpdateAddress(ctx, in, out)
+}
+
+func (h *userHandler) DeleteAddress(ctx context.Context, in *DeleteAddressRequest, out *DeleteAddressResponse) error {
+	return h.UserHandler.DeleteAddress(ctx, in, out)
+}
+
+func (h *userHandler) ListAddresses(ctx context.Context, in *ListAddressesRequest, out *ListAddressesResponse) error {
+	return h.UserHandler.ListAddresses(ctx, in, out)
+}
+
+func (h *userHandler) SetDefaultAddress(ctx context.Context, in *SetDefaultAddressRequest, out *AddressResponse) error {
+	return h.UserHandler.SetDefaultAddress(ctx, in, out)
+}
+
+func (h *userHandler) ExportUserData(ctx context.Context, in *ExportUserDataRequest, out *ExportUserDataResponse) error {
+	return h.UserHandler.ExportUserData(ctx, in, out)
+}
+
+func (h *userHandler) EraseUser(ctx context.Context, in *EraseUserRequest, out *EraseUserResponse) error {
+	return h.UserHandler.EraseUser(ctx, in, out)
+}
+
+func (h *userHandler) BeginExternalLogin(ctx context.Context, in *BeginExternalLoginRequest, out *BeginExternalLoginResponse) error {
+	return h.UserHandler.BeginExternalLogin(ctx, in, out)
+}
+
+func (h *userHandler) CompleteExternalLogin(ctx context.Context, in *CompleteExternalLoginRequest, out *UserLoginResponse) error {
+	return h.UserHandler.CompleteExternalLogin(ctx, in, out)
+}
@@ -1,6 +1,11 @@
 package util
 
-import "os"
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
 
 // GetEnv fetches an environment variable or returns a default value
 func GetEnv(key, defaultValue string) string {
@@ -9,3 +14,57 @@ func GetEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// MustGetEnv fetches a required environment variable and stops the process when it is unset or empty
+func MustGetEnv(key string) string {
+	value, exists := os.LookupEnv(key)
+	if !exists || value == "" {
+		log.Fatalf("Missing required environment variable %s", key)
+	}
+	return value
+}
+
+// GetEnvDuration fetches an environment variable parsed as a time.Duration or returns a default value
+func GetEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid duration %q for %s, using default %s", value, key, defaultValue)
+		return defaultValue
+	}
+	return duration
+}
+
+// GetEnvInt fetches an environment variable parsed as an int or returns a default value
+func GetEnvInt(key string, defaultValue int) int {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid integer %q for %s, using default %d", value, key, defaultValue)
+		return defaultValue
+	}
+	return number
+}
+
+// GetEnvBool fetches an environment variable parsed as a bool or returns a default value
+func GetEnvBool(key string, defaultValue bool) bool {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+
+	flag, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Invalid boolean %q for %s, using default %t", value, key, defaultValue)
+		return defaultValue
+	}
+	return flag
+}